@@ -0,0 +1,40 @@
+// File: cmd/server/config.go
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"redditclone/pkg/config"
+)
+
+const configUsage = `usage: server config print [config flags]
+
+  print  show the effective configuration, after the config file, environment
+         and flags are applied, with secrets masked`
+
+// runConfig implements the "config" subcommand and returns the process exit code.
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, configUsage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("config print", flag.ContinueOnError)
+	loader := config.Flags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	cfg, err := loader.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := cfg.Print(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
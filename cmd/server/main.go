@@ -3,71 +3,304 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"redditclone/internal/repository"
+	"redditclone/internal/repository/memory"
 	"redditclone/internal/service"
+	"redditclone/pkg/config"
 	"redditclone/pkg/database"
+	"redditclone/pkg/logging"
+	"redditclone/pkg/mail"
+	"redditclone/pkg/metrics"
+	"redditclone/pkg/ratelimit"
 	"redditclone/pkg/router"
+	"redditclone/pkg/secretbox"
+	"redditclone/pkg/tracing"
 )
 
 func main() {
-	// Retrieve the server port from environment variables or default to 8080
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			os.Exit(runMigrate(os.Args[2:]))
+		case "config":
+			os.Exit(runConfig(os.Args[2:]))
+		case "admin":
+			os.Exit(runAdmin(os.Args[2:]))
+		}
 	}
 
-	// Initialize the database connection.
-	// The Connect function should establish a connection to your chosen database
-	// and return a *sql.DB instance along with any error encountered.
-	db, err := database.Connect()
+	// Load the configuration from defaults, the config file, the environment and flags.
+	fs := flag.NewFlagSet("server", flag.ExitOnError)
+	loader := config.Flags(fs)
+	fs.Parse(os.Args[1:])
+	cfg, err := loader.Load()
 	if err != nil {
-		log.Fatalf("Failed to connect to the database: %v", err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
 
-	defer func() {
-		if cerr := db.Close(); cerr != nil {
-			log.Printf("Error closing the database connection: %v", cerr)
-		}
-	}()
+	// Log structured JSON lines; the standard log package writes through the same handler.
+	slog.SetDefault(logging.New(os.Stderr, cfg.LogLevel()))
 
-	if err := database.InitializeSchema(db); err != nil {
-		log.Fatalf("Failed to initialize schema: %v", err)
-	  }
+	// Export traces through the configured exporter; none by default.
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Exporter)
+	if err != nil {
+		slog.Error("failed to set up tracing", "error", err.Error())
+		os.Exit(1)
+	}
 
-	// Initialize the HTTP router.
-	// The NewRouter function should set up all the necessary routes and return an http.Handler.
-	userRepo := repository.NewUserRepository(db)
-	subredditRepo := repository.NewSubredditRepository(db)
-	postRepo := repository.NewPostRepository(db)
-	commentRepo := repository.NewCommentRepository(db)
-	voteRepo := repository.NewVoteRepository(db)
-	messageRepo := repository.NewMessageRepository(db)
+	// Initialize the storage backend: SQLite (default), PostgreSQL or memory.
+	dbConfig := cfg.DatabaseConfig()
+	repos, healthService, closeStorage, err := openStorage(dbConfig, cfg.Database.AutoMigrate)
+	if err != nil {
+		slog.Error("failed to open storage", "error", err.Error())
+		os.Exit(1)
+	}
+
+	userRepo := repos.Users
+	subredditRepo := repos.Subreddits
+	postRepo := repos.Posts
+	commentRepo := repos.Comments
+	voteRepo := repos.Votes
+	messageRepo := repos.Messages
+	blockRepo := repos.Blocks
+	voteIntegrityRepo := repos.VoteIntegrity
+	loginAttemptRepo := repos.LoginAttempts
+	userTokenRepo := repos.UserTokens
+	recoveryCodeRepo := repos.RecoveryCodes
+	sessionRepo := repos.Sessions
+	apiKeyRepo := repos.APIKeys
+	oauthRepo := repos.OAuth
+	auditRepo := repos.Audit
+
+	// Deliver email through the configured driver; the log driver by default.
+	mailer, err := mail.New(cfg.MailConfig())
+	if err != nil {
+		slog.Error("failed to set up mail", "error", err.Error())
+		os.Exit(1)
+	}
+
+	// Encrypt TOTP secrets with the configured key; without one, two-factor enrollment is off.
+	var totpBox *secretbox.Box
+	if cfg.Auth.TOTPEncryptionKey != "" {
+		key, _ := secretbox.ParseKey(cfg.Auth.TOTPEncryptionKey) // Checked when the configuration was loaded.
+		if totpBox, err = secretbox.New(key); err != nil {
+			slog.Error("failed to set up TOTP encryption", "error", err.Error())
+			os.Exit(1)
+		}
+	}
 
 	// Initialize services
-	userService := service.NewUserService(userRepo)
-	subredditService := service.NewSubredditService(subredditRepo)
-	postService := service.NewPostService(postRepo, subredditRepo, userRepo)
-	commentService := service.NewCommentService(commentRepo, postRepo, userRepo, subredditRepo)
-	voteService := service.NewVoteService(voteRepo, postRepo, commentRepo)
-	messageService := service.NewMessageService(messageRepo, userRepo)
+	sessionService := service.NewSessionService(sessionRepo, service.SessionConfig{
+		AccessTTL:  cfg.Auth.AccessTokenTTL,
+		RefreshTTL: cfg.Auth.RefreshTokenTTL,
+	})
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo)
+	oauthService := service.NewOAuthService(oauthRepo, service.OAuthConfig{
+		AccessTTL:  cfg.Auth.AccessTokenTTL,
+		RefreshTTL: cfg.Auth.RefreshTokenTTL,
+	})
+	accountService := service.NewAccountService(userRepo, userTokenRepo, sessionService, mailer, service.AccountConfig{
+		BaseURL:         cfg.Mail.BaseURL,
+		VerificationTTL: cfg.Auth.EmailVerificationTTL,
+		ResetTTL:        cfg.Auth.PasswordResetTTL,
+		BcryptCost:      cfg.Auth.BcryptCost,
+	})
+	twoFactorService := service.NewTwoFactorService(userRepo, recoveryCodeRepo, service.TwoFactorConfig{
+		Issuer:       cfg.Auth.TOTPIssuer,
+		Box:          totpBox,
+		ChallengeTTL: cfg.Auth.TwoFactorChallengeTTL,
+	})
+	userService := service.NewUserService(userRepo, blockRepo, loginAttemptRepo, accountService, twoFactorService, sessionService, cfg.Auth.BcryptCost, service.LoginPolicy{
+		Window:            cfg.Auth.LoginFailureWindow,
+		FreeFailures:      cfg.Auth.LoginFreeFailures,
+		Backoff:           cfg.Auth.LoginBackoff,
+		LockoutFailures:   cfg.Auth.LoginLockoutFailures,
+		LockoutDuration:   cfg.Auth.LoginLockoutDuration,
+		IPFreeFailures:    cfg.Auth.LoginIPFreeFailures,
+		IPLockoutFailures: cfg.Auth.LoginIPLockoutFailures,
+	})
+	subredditService := service.NewSubredditService(subredditRepo, userRepo)
+	postService := service.NewPostService(postRepo, subredditRepo, userRepo, voteRepo)
+	commentService := service.NewCommentService(commentRepo, postRepo, userRepo, subredditRepo, blockRepo, voteRepo)
+	voteIntegrityService := service.NewVoteIntegrityService(voteIntegrityRepo, userRepo, service.DefaultVoteIntegrityConfig())
+	voteService := service.NewVoteService(voteRepo, postRepo, commentRepo, userRepo, voteIntegrityService)
+	messageService := service.NewMessageService(messageRepo, userRepo, blockRepo)
+	activityService := service.NewActivityService(userRepo, postRepo, commentRepo, voteRepo)
+	adminService := service.NewAdminService(userRepo, subredditRepo, auditRepo, sessionService, voteIntegrityService)
+	accountDeletionService := service.NewAccountDeletionService(userRepo, subredditRepo, auditRepo, sessionService, service.AccountDeletionConfig{
+		Grace: cfg.Auth.AccountDeletionGrace,
+	})
+
+	// Start the sessions_active metric from the sessions already in storage.
+	if _, err := sessionService.CountActiveSessions(context.Background()); err != nil {
+		slog.Warn("failed to count active sessions", "error", err.Error())
+	}
 
 	// Initialize the HTTP router with services
-	r := router.NewRouter(userService, subredditService, postService, commentService, voteService, messageService)
+	r := router.NewRouter(userService, subredditService, postService, commentService, voteService, messageService, activityService, healthService, accountService, twoFactorService, sessionService, apiKeyService, oauthService, adminService, accountDeletionService, router.Config{
+		CursorSecret:     cfg.Server.CursorSecret,
+		DefaultPageLimit: cfg.Pagination.DefaultLimit,
+		MaxPageLimit:     cfg.Pagination.MaxLimit,
+		TrustedProxies:   cfg.Server.TrustedProxies,
+		RateLimits:       rateLimits(cfg.RateLimit),
+	})
+
+	srv := &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.Server.Port),
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	// Serve until SIGINT or SIGTERM, then shut down in order: stop taking
+	// traffic and drain in-flight requests, flush background work, and only
+	// then close the database the requests were using.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Anonymize accounts whose deletion grace period is over, until shutdown.
+	purgeDone := make(chan struct{})
+	go func() {
+		defer close(purgeDone)
+		purgeAccounts(ctx, accountDeletionService, cfg.Auth.AccountPurgeInterval)
+	}()
+
+	slog.Info("starting server", "port", cfg.Server.Port, "storage", dbConfig.Driver)
+	exitCode := 0
+	if err := serve(ctx, srv, healthService, cfg.Server.ShutdownTimeout); err != nil {
+		slog.Error("server failed", "error", err.Error())
+		exitCode = 1
+	}
+	stop()
+	<-purgeDone
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("error flushing traces", "error", err.Error())
+	}
+	if err := closeStorage(); err != nil {
+		slog.Error("error closing the database connection", "error", err.Error())
+	}
+	slog.Info("server stopped")
+	os.Exit(exitCode)
+}
+
+// serve runs srv until ctx is cancelled, then marks the server as draining so
+// /readyz fails and waits up to shutdownTimeout for in-flight requests to finish.
+func serve(ctx context.Context, srv *http.Server, health service.HealthService, shutdownTimeout time.Duration) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down, draining in-flight requests", "timeout", shutdownTimeout.String())
+	health.Drain()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("draining requests: %v", err)
+	}
+	return nil
+}
+
+// purgeAccounts anonymizes the accounts due for deletion now and then every
+// interval, until ctx is cancelled.
+func purgeAccounts(ctx context.Context, deletions service.AccountDeletionService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		purged, err := deletions.PurgeDueAccounts(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("failed to purge deleted accounts", "error", err.Error())
+		}
+		if purged > 0 {
+			slog.Info("purged deleted accounts", "count", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
 
+// openStorage creates the repositories for the configured driver, along with
+// the health checks for that storage. SQL drivers connect and bring the schema
+// up to date first; the memory driver starts empty. The returned function
+// releases the storage.
+func openStorage(cfg database.Config, autoMigrate bool) (repository.Repositories, service.HealthService, func() error, error) {
+	if cfg.Driver == database.DriverMemory {
+		return memory.NewRepositories(), service.NewHealthService(nil, nil), func() error { return nil }, nil
+	}
 
-	// Define the server address.
-	addr := ":" + port
+	db, err := database.Connect(cfg)
+	if err != nil {
+		return repository.Repositories{}, nil, nil, err
+	}
 
-	// Log the server start-up.
-	log.Printf("Starting server on port %s", port)
+	// Bring the schema up to date, or with auto-migration off only check for pending migrations.
+	migrator, err := database.NewMigrator(db.Write, cfg.Driver, log.Writer())
+	if err == nil {
+		err = migrateOnStartup(migrator, autoMigrate)
+	}
+	if err != nil {
+		db.Close()
+		return repository.Repositories{}, nil, nil, fmt.Errorf("migrating schema: %v", err)
+	}
+	// Export pool statistics, including time spent waiting for a connection
+	metrics.RegisterDBPool("write", db.Write)
+	if db.Read != db.Write {
+		metrics.RegisterDBPool("read", db.Read)
+	}
+	return repository.NewSQLRepositories(db), service.NewHealthService(db, migrator), db.Close, nil
+}
 
-	// Start the HTTP server.
-	// http.ListenAndServe listens on the TCP network address addr and then calls Serve with handler to handle requests on incoming connections.
-	if err := http.ListenAndServe(addr, r); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+// rateLimits builds the router's rate limits from the validated configuration,
+// keeping buckets in memory. Policies with no rate are left out, so their
+// routes fall back to the default policy.
+func rateLimits(cfg config.RateLimit) router.RateLimits {
+	if !cfg.Enabled {
+		return router.RateLimits{}
+	}
+	limits := router.RateLimits{
+		Store:    ratelimit.NewMemoryStore(),
+		Policies: make(map[string]ratelimit.Policy),
+	}
+	for name, rate := range map[string]string{
+		router.PolicyDefault:  cfg.Default,
+		router.PolicyRegister: cfg.Register,
+		router.PolicyLogin:    cfg.Login,
+		router.PolicyEmail:    cfg.Email,
+		router.PolicyPosting:  cfg.Posting,
+		router.PolicyMessages: cfg.Messages,
+		router.PolicyVotes:    cfg.Votes,
+	} {
+		if policy, err := ratelimit.ParsePolicy(name, rate); err == nil {
+			limits.Policies[name] = policy
+		}
 	}
+	return limits
 }
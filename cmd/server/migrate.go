@@ -0,0 +1,122 @@
+// File: cmd/server/migrate.go
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"redditclone/pkg/config"
+	"redditclone/pkg/database"
+)
+
+const migrateUsage = `usage: server migrate <up|down|status> [-steps N] [-dry-run] [config flags]
+
+  up      apply pending migrations (all of them unless -steps is given)
+  down    revert applied migrations, newest first (one unless -steps is given)
+  status  list migrations and whether each one is applied`
+
+// runMigrate implements the "migrate" subcommand and returns the process exit code.
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("migrate "+action, flag.ContinueOnError)
+	steps := fs.Int("steps", 0, "number of migrations to apply or revert")
+	dryRun := fs.Bool("dry-run", false, "print the SQL instead of executing it")
+	loader := config.Flags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	cfg, err := loader.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	dbConfig := cfg.DatabaseConfig()
+	if dbConfig.Driver == database.DriverMemory {
+		fmt.Fprintln(os.Stderr, "The memory driver has no schema to migrate")
+		return 1
+	}
+	db, err := database.Connect(dbConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to the database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db.Write, dbConfig.Driver, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	migrator.DryRun = *dryRun
+
+	switch action {
+	case "up":
+		applied, err := migrator.Up(*steps)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+	case "down":
+		reverted, err := migrator.Down(*steps)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No applied migrations")
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d  %-30s  %s\n", status.Version, status.Name, state)
+		}
+		if err := migrator.Verify(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
+
+// migrateOnStartup verifies the schema and applies pending migrations, unless
+// autoMigrate is off, in which case it refuses to start with pending migrations.
+func migrateOnStartup(migrator *database.Migrator, autoMigrate bool) error {
+	if !autoMigrate {
+		if err := migrator.Verify(); err != nil {
+			return err
+		}
+		pending, err := migrator.Pending()
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migration(s); run `server migrate up` or enable database.auto_migrate", len(pending))
+		}
+		return nil
+	}
+	_, err := migrator.Up(0)
+	return err
+}
@@ -0,0 +1,103 @@
+// File: internal/api/handlers/account.go
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/service"
+)
+
+// AccountHandler handles email verification and password reset requests.
+type AccountHandler struct {
+	AccountService service.AccountService
+}
+
+// NewAccountHandler creates a new AccountHandler with the given AccountService.
+func NewAccountHandler(accountService service.AccountService) *AccountHandler {
+	return &AccountHandler{AccountService: accountService}
+}
+
+// SendVerificationEmail mails the user a new email verification link. Only
+// the account owner may ask for one.
+func (h *AccountHandler) SendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseUserIDParam(w, r)
+	if !ok {
+		return
+	}
+	if requestUserID(r) != userID {
+		writeError(w, r, apperr.Forbidden("Only the account owner can request a verification email"))
+		return
+	}
+
+	if err := h.AccountService.SendVerificationEmail(r.Context(), userID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Verification email sent"})
+}
+
+// VerifyEmail confirms an email address with the token from a verification email.
+func (h *AccountHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+
+	if err := h.AccountService.VerifyEmail(r.Context(), body.Token); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Email verified"})
+}
+
+// ForgotPassword mails a password reset link to the account with the given
+// email address. The response is the same whether or not the account exists.
+func (h *AccountHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+
+	if err := h.AccountService.RequestPasswordReset(r.Context(), body.Email); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "If the address belongs to an account, a reset link has been sent"})
+}
+
+// ResetPassword sets a new password with the token from a reset email.
+func (h *AccountHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+
+	if err := h.AccountService.ResetPassword(r.Context(), body.Token, body.Password); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password reset"})
+}
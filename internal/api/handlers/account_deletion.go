@@ -0,0 +1,72 @@
+// File: internal/api/handlers/account_deletion.go
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/service"
+)
+
+// AccountDeletionHandler handles deleting accounts and cancelling deletions.
+type AccountDeletionHandler struct {
+	AccountDeletionService service.AccountDeletionService
+}
+
+// NewAccountDeletionHandler creates a new AccountDeletionHandler with the given AccountDeletionService.
+func NewAccountDeletionHandler(accountDeletionService service.AccountDeletionService) *AccountDeletionHandler {
+	return &AccountDeletionHandler{AccountDeletionService: accountDeletionService}
+}
+
+// RequestDeletion schedules the account for deletion and responds with when
+// it will be anonymized. Only the owner may delete it, confirming with their
+// password as "current_password".
+func (h *AccountDeletionHandler) RequestDeletion(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.ownerID(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		CurrentPassword string `json:"current_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+
+	deletion, err := h.AccountDeletionService.RequestDeletion(r.Context(), userID, body.CurrentPassword)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(deletion)
+}
+
+// CancelDeletion takes back a scheduled deletion before it happens. Only the
+// owner may cancel it.
+func (h *AccountDeletionHandler) CancelDeletion(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.ownerID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.AccountDeletionService.CancelDeletion(r.Context(), userID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Account deletion cancelled"})
+}
+
+// ownerID parses the user ID from the path and checks that the request comes
+// from one of that user's sessions.
+func (h *AccountDeletionHandler) ownerID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	return sessionOwner(w, r, "Only the account owner can delete it")
+}
@@ -0,0 +1,145 @@
+// File: internal/api/handlers/activity.go
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// ActivityHandler handles HTTP requests for a user's activity history.
+type ActivityHandler struct {
+	ActivityService service.ActivityService
+}
+
+// NewActivityHandler creates a new ActivityHandler with the given ActivityService.
+func NewActivityHandler(activityService service.ActivityService) *ActivityHandler {
+	return &ActivityHandler{ActivityService: activityService}
+}
+
+// GetUserPosts retrieves the posts submitted by a user with sorting and pagination.
+func (h *ActivityHandler) GetUserPosts(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseUserIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse sorting and pagination parameters
+	sort := r.URL.Query().Get("sort")
+	limit, offset := parsePaginationParams(r)
+
+	// Retrieve the posts via the service
+	posts, err := h.ActivityService.GetUserPosts(r.Context(), userID, requestUserID(r), sort, limit, offset)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Respond with the posts
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(posts)
+}
+
+// GetUserComments retrieves the comments written by a user with sorting and pagination.
+func (h *ActivityHandler) GetUserComments(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseUserIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse sorting and pagination parameters
+	sort := r.URL.Query().Get("sort")
+	limit, offset := parsePaginationParams(r)
+
+	// Retrieve the comments via the service
+	comments, err := h.ActivityService.GetUserComments(r.Context(), userID, requestUserID(r), sort, limit, offset)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Respond with the comments
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comments)
+}
+
+// GetUserOverview retrieves a user's posts and comments together with sorting and pagination.
+func (h *ActivityHandler) GetUserOverview(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseUserIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse sorting and pagination parameters
+	sort := r.URL.Query().Get("sort")
+	limit, offset := parsePaginationParams(r)
+
+	// Retrieve the overview via the service
+	items, err := h.ActivityService.GetUserOverview(r.Context(), userID, requestUserID(r), sort, limit, offset)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Respond with the overview
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// GetUpvoted retrieves the posts and comments a user upvoted. Only the owner may view it.
+func (h *ActivityHandler) GetUpvoted(w http.ResponseWriter, r *http.Request) {
+	h.getVoted(w, r, "upvote")
+}
+
+// GetDownvoted retrieves the posts and comments a user downvoted. Only the owner may view it.
+func (h *ActivityHandler) GetDownvoted(w http.ResponseWriter, r *http.Request) {
+	h.getVoted(w, r, "downvote")
+}
+
+func (h *ActivityHandler) getVoted(w http.ResponseWriter, r *http.Request, voteType string) {
+	userID, ok := parseUserIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	// Voting history is private to its owner
+	if requestUserID(r) != userID {
+		writeError(w, r, apperr.Forbidden("Only the account owner can view voted items"))
+		return
+	}
+
+	// Parse pagination parameters
+	limit, offset := parsePaginationParams(r)
+
+	// Retrieve the voted items via the service
+	items, err := h.ActivityService.GetVotedItems(r.Context(), userID, voteType, limit, offset)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Respond with the voted items
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// Helper function to parse the user ID from the URL, writing a 400 response on failure
+func parseUserIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	idStr, ok := mux.Vars(r)["id"]
+	if !ok {
+		writeError(w, r, apperr.BadRequest("User ID is required"))
+		return 0, false
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid User ID"))
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,327 @@
+// File: internal/api/handlers/admin.go
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// AdminHandler handles the site admin API: roles, suspensions, subreddit
+// moderation, impersonation, the vote-integrity report and the audit log.
+type AdminHandler struct {
+	AdminService service.AdminService
+}
+
+// NewAdminHandler creates a new AdminHandler with the given AdminService.
+func NewAdminHandler(adminService service.AdminService) *AdminHandler {
+	return &AdminHandler{AdminService: adminService}
+}
+
+// SetRole makes a user an admin or an ordinary user.
+func (h *AdminHandler) SetRole(w http.ResponseWriter, r *http.Request) {
+	admin, ok := h.admin(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := parseUserIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+
+	user, err := h.AdminService.SetRole(r.Context(), admin, userID, body.Role)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// SuspendUser suspends a user site-wide, signing them out everywhere.
+func (h *AdminHandler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	admin, ok := h.admin(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := parseUserIDParam(w, r)
+	if !ok {
+		return
+	}
+	reason, ok := decodeReason(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.AdminService.SuspendUser(r.Context(), admin, userID, reason)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// UnsuspendUser lifts a user's suspension.
+func (h *AdminHandler) UnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	admin, ok := h.admin(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := parseUserIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.AdminService.UnsuspendUser(r.Context(), admin, userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// Impersonate opens a short-lived session as a user for support and returns
+// its access token. The admin's reason is required and audited.
+func (h *AdminHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	admin, ok := h.admin(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := parseUserIDParam(w, r)
+	if !ok {
+		return
+	}
+	reason, ok := decodeReason(w, r)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.AdminService.Impersonate(r.Context(), admin, userID, reason, clientInfo(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// DeleteSubreddit deletes a subreddit and everything in it.
+func (h *AdminHandler) DeleteSubreddit(w http.ResponseWriter, r *http.Request) {
+	admin, ok := h.admin(w, r)
+	if !ok {
+		return
+	}
+	subredditID, ok := parseSubredditIDParam(w, r)
+	if !ok {
+		return
+	}
+	reason, ok := decodeReason(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.AdminService.DeleteSubreddit(r.Context(), admin, subredditID, reason); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Subreddit deleted"})
+}
+
+// QuarantineSubreddit quarantines a subreddit, or updates the reason of a
+// quarantine already in place.
+func (h *AdminHandler) QuarantineSubreddit(w http.ResponseWriter, r *http.Request) {
+	admin, ok := h.admin(w, r)
+	if !ok {
+		return
+	}
+	subredditID, ok := parseSubredditIDParam(w, r)
+	if !ok {
+		return
+	}
+	reason, ok := decodeReason(w, r)
+	if !ok {
+		return
+	}
+
+	subreddit, err := h.AdminService.QuarantineSubreddit(r.Context(), admin, subredditID, reason)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subreddit)
+}
+
+// UnquarantineSubreddit lifts a subreddit's quarantine.
+func (h *AdminHandler) UnquarantineSubreddit(w http.ResponseWriter, r *http.Request) {
+	admin, ok := h.admin(w, r)
+	if !ok {
+		return
+	}
+	subredditID, ok := parseSubredditIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	subreddit, err := h.AdminService.UnquarantineSubreddit(r.Context(), admin, subredditID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subreddit)
+}
+
+// TransferSubreddit gives a subreddit a new owner.
+func (h *AdminHandler) TransferSubreddit(w http.ResponseWriter, r *http.Request) {
+	admin, ok := h.admin(w, r)
+	if !ok {
+		return
+	}
+	subredditID, ok := parseSubredditIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		OwnerID int `json:"owner_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+
+	subreddit, err := h.AdminService.TransferSubreddit(r.Context(), admin, subredditID, body.OwnerID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subreddit)
+}
+
+// GetAuditLog lists audit log entries, newest first, filtered by the
+// actor_id, action, target_type and target_id query parameters.
+func (h *AdminHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.admin(w, r); !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := models.AuditFilter{
+		Action:     query.Get("action"),
+		TargetType: query.Get("target_type"),
+	}
+	for name, dst := range map[string]*int{"actor_id": &filter.ActorID, "target_id": &filter.TargetID} {
+		if value := query.Get(name); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil || id <= 0 {
+				writeError(w, r, apperr.BadRequest("Invalid "+name))
+				return
+			}
+			*dst = id
+		}
+	}
+	limit, offset := parsePaginationParams(r)
+
+	entries, err := h.AdminService.GetAuditLog(r.Context(), filter, limit, offset)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if entries == nil {
+		entries = []*models.AuditEntry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// GetVoteReport returns a summary of flagged votes with pagination.
+func (h *AdminHandler) GetVoteReport(w http.ResponseWriter, r *http.Request) {
+	admin, ok := h.admin(w, r)
+	if !ok {
+		return
+	}
+	limit, offset := parsePaginationParams(r)
+
+	report, err := h.AdminService.GetVoteReport(r.Context(), admin, limit, offset)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// admin checks that the request comes from a site admin signed in with a
+// session of their own, and returns them as the actor. API keys, OAuth2
+// tokens, impersonation sessions and the bare X-User-ID header are refused.
+func (h *AdminHandler) admin(w http.ResponseWriter, r *http.Request) (models.Actor, bool) {
+	session := service.SessionFromContext(r.Context())
+	if session == nil {
+		writeError(w, r, apperr.Unauthorized("Sign in to use the admin API"))
+		return models.Actor{}, false
+	}
+	if session.ImpersonatorID != nil {
+		writeError(w, r, apperr.Forbidden("Impersonation sessions cannot use the admin API"))
+		return models.Actor{}, false
+	}
+	if err := h.AdminService.CheckAdmin(r.Context(), session.UserID); err != nil {
+		writeError(w, r, err)
+		return models.Actor{}, false
+	}
+	return models.Actor{UserID: session.UserID, IP: ClientIP(r)}, true
+}
+
+// parseSubredditIDParam parses the subreddit ID from the path, writing a 400
+// if it is not a number.
+func parseSubredditIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid Subreddit ID"))
+		return 0, false
+	}
+	return id, true
+}
+
+// decodeReason reads the {"reason": ...} body admin actions take.
+func decodeReason(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
+		return "", false
+	}
+	return body.Reason, true
+}
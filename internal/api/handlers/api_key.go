@@ -0,0 +1,103 @@
+// File: internal/api/handlers/api_key.go
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// APIKeyHandler handles creating, listing and revoking a user's API keys.
+type APIKeyHandler struct {
+	APIKeyService service.APIKeyService
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler with the given APIKeyService.
+func NewAPIKeyHandler(apiKeyService service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{APIKeyService: apiKeyService}
+}
+
+// CreateAPIKey creates an API key for the account and returns it, the only
+// time the key is shown.
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.ownerID(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+
+	key, err := h.APIKeyService.CreateKey(r.Context(), userID, body.Name, body.Scopes, body.ExpiresAt)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(key)
+}
+
+// ListAPIKeys lists the account's API keys with their scopes, expiry and
+// last use. The keys themselves are not shown.
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.ownerID(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := h.APIKeyService.ListKeys(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if keys == nil {
+		keys = []*models.APIKey{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// RevokeAPIKey revokes one of the account's API keys.
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.ownerID(w, r)
+	if !ok {
+		return
+	}
+	keyID, err := strconv.Atoi(mux.Vars(r)["keyID"])
+	if err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid API key ID"))
+		return
+	}
+
+	if err := h.APIKeyService.RevokeKey(r.Context(), userID, keyID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "API key revoked"})
+}
+
+// ownerID parses the user ID from the path and checks that the request comes
+// from a session of that user; keys cannot mint or revoke other keys.
+func (h *APIKeyHandler) ownerID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	return sessionOwner(w, r, "Only the account owner can manage API keys")
+}
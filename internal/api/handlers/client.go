@@ -0,0 +1,37 @@
+// File: internal/api/handlers/client.go
+
+package handlers
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"redditclone/internal/models"
+)
+
+type clientIPKey struct{}
+
+// WithClientIP returns a copy of ctx carrying the client IP address, as
+// resolved by the router.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIP returns the IP address of the client that sent r: the one stored
+// by WithClientIP, or else the host part of the connection's remote address.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok && ip != "" {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Helper function to describe the client that sent r.
+func clientInfo(r *http.Request) models.ClientInfo {
+	return models.ClientInfo{IP: ClientIP(r), UserAgent: r.UserAgent()}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"redditclone/internal/apperr"
 	"redditclone/internal/models"
 	"redditclone/internal/service"
 
@@ -16,11 +17,13 @@ import (
 // CommentHandler handles comment-related HTTP requests.
 type CommentHandler struct {
 	CommentService service.CommentService
+	Cursors        *CursorCodec
 }
 
 // NewCommentHandler creates a new CommentHandler with the given CommentService.
-func NewCommentHandler(commentService service.CommentService) *CommentHandler {
-	return &CommentHandler{CommentService: commentService}
+// Listing cursors are signed and checked with cursors.
+func NewCommentHandler(commentService service.CommentService, cursors *CursorCodec) *CommentHandler {
+	return &CommentHandler{CommentService: commentService, Cursors: cursors}
 }
 
 // AddComment adds a new comment to a post.
@@ -28,12 +31,12 @@ func (h *CommentHandler) AddComment(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	postIDStr, ok := vars["id"] // post ID from URL
 	if !ok {
-		http.Error(w, "Post ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Post ID is required"))
 		return
 	}
 	postID, err := strconv.Atoi(postIDStr)
 	if err != nil {
-		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Post ID"))
 		return
 	}
 
@@ -41,23 +44,23 @@ func (h *CommentHandler) AddComment(w http.ResponseWriter, r *http.Request) {
 	// Decode the JSON request body into the Comment model
 	err = json.NewDecoder(r.Body).Decode(&comment)
 	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
 		return
 	}
 
-	// TODO: Extract the author's user ID from the authenticated context
-	// For simplicity, assume it's provided in the request body
-	if comment.AuthorID == 0 {
-		http.Error(w, "Author ID is required", http.StatusBadRequest)
+	// The author is always the signed-in user
+	authorID, ok := signedInUserID(w, r, "comment")
+	if !ok {
 		return
 	}
 
+	comment.AuthorID = authorID
 	comment.PostID = postID
 
 	// Add the comment via the service
-	err = h.CommentService.AddComment(&comment)
+	err = h.CommentService.AddComment(r.Context(), &comment)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
@@ -72,12 +75,12 @@ func (h *CommentHandler) ReplyToComment(w http.ResponseWriter, r *http.Request)
 	vars := mux.Vars(r)
 	parentIDStr, ok := vars["id"] // parent comment ID from URL
 	if !ok {
-		http.Error(w, "Parent Comment ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Parent Comment ID is required"))
 		return
 	}
 	parentID, err := strconv.Atoi(parentIDStr)
 	if err != nil {
-		http.Error(w, "Invalid Parent Comment ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Parent Comment ID"))
 		return
 	}
 
@@ -85,23 +88,23 @@ func (h *CommentHandler) ReplyToComment(w http.ResponseWriter, r *http.Request)
 	// Decode the JSON request body into the Comment model
 	err = json.NewDecoder(r.Body).Decode(&comment)
 	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
 		return
 	}
 
-	// TODO: Extract the author's user ID from the authenticated context
-	// For simplicity, assume it's provided in the request body
-	if comment.AuthorID == 0 {
-		http.Error(w, "Author ID is required", http.StatusBadRequest)
+	// The author is always the signed-in user
+	authorID, ok := signedInUserID(w, r, "comment")
+	if !ok {
 		return
 	}
 
+	comment.AuthorID = authorID
 	comment.ParentID = &parentID
 
 	// Reply to the comment via the service
-	err = h.CommentService.ReplyToComment(&comment)
+	err = h.CommentService.ReplyToComment(r.Context(), &comment)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
@@ -116,19 +119,19 @@ func (h *CommentHandler) GetComment(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	commentIDStr, ok := vars["id"]
 	if !ok {
-		http.Error(w, "Comment ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Comment ID is required"))
 		return
 	}
 	commentID, err := strconv.Atoi(commentIDStr)
 	if err != nil {
-		http.Error(w, "Invalid Comment ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Comment ID"))
 		return
 	}
 
 	// Retrieve the comment via the service
-	comment, err := h.CommentService.GetCommentByID(commentID)
+	comment, err := h.CommentService.GetCommentByID(r.Context(), commentID, requestUserID(r))
 	if err != nil {
-		http.Error(w, "Comment not found", http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
@@ -142,12 +145,12 @@ func (h *CommentHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	commentIDStr, ok := vars["id"]
 	if !ok {
-		http.Error(w, "Comment ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Comment ID is required"))
 		return
 	}
 	commentID, err := strconv.Atoi(commentIDStr)
 	if err != nil {
-		http.Error(w, "Invalid Comment ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Comment ID"))
 		return
 	}
 
@@ -155,16 +158,16 @@ func (h *CommentHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
 	// Decode the JSON request body into the Comment model
 	err = json.NewDecoder(r.Body).Decode(&comment)
 	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
 		return
 	}
 
 	comment.ID = commentID
 
 	// Update the comment via the service
-	err = h.CommentService.UpdateComment(&comment)
+	err = h.CommentService.UpdateComment(r.Context(), &comment)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
@@ -178,19 +181,19 @@ func (h *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	commentIDStr, ok := vars["id"]
 	if !ok {
-		http.Error(w, "Comment ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Comment ID is required"))
 		return
 	}
 	commentID, err := strconv.Atoi(commentIDStr)
 	if err != nil {
-		http.Error(w, "Invalid Comment ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Comment ID"))
 		return
 	}
 
 	// Delete the comment via the service
-	err = h.CommentService.DeleteComment(commentID)
+	err = h.CommentService.DeleteComment(r.Context(), commentID)
 	if err != nil {
-		http.Error(w, "Comment not found or could not be deleted", http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
@@ -199,60 +202,66 @@ func (h *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Comment deleted successfully"})
 }
 
-// GetCommentsByPost retrieves top-level comments for a specific post with pagination.
+// GetCommentsByPost retrieves a page of top-level comments for a specific post.
 func (h *CommentHandler) GetCommentsByPost(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	postIDStr, ok := vars["id"] // post ID from URL
 	if !ok {
-		http.Error(w, "Post ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Post ID is required"))
 		return
 	}
 	postID, err := strconv.Atoi(postIDStr)
 	if err != nil {
-		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Post ID"))
 		return
 	}
 
 	// Parse pagination parameters
-	limit, offset := parsePaginationParams(r)
+	page, err := h.Cursors.parsePage(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
 	// Retrieve the comments via the service
-	comments, err := h.CommentService.GetCommentsByPost(postID, limit, offset)
+	listing, err := h.CommentService.GetCommentsByPost(r.Context(), postID, requestUserID(r), page)
 	if err != nil {
-		http.Error(w, "Failed to retrieve comments", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
 	// Respond with the comments
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(comments)
+	writeListing(w, h.Cursors, listing)
 }
 
-// GetReplies retrieves replies to a specific comment with pagination.
+// GetReplies retrieves a page of replies to a specific comment.
 func (h *CommentHandler) GetReplies(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	parentIDStr, ok := vars["id"] // parent comment ID from URL
 	if !ok {
-		http.Error(w, "Parent Comment ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Parent Comment ID is required"))
 		return
 	}
 	parentID, err := strconv.Atoi(parentIDStr)
 	if err != nil {
-		http.Error(w, "Invalid Parent Comment ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Parent Comment ID"))
 		return
 	}
 
 	// Parse pagination parameters
-	limit, offset := parsePaginationParams(r)
+	page, err := h.Cursors.parsePage(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
 	// Retrieve the replies via the service
-	replies, err := h.CommentService.GetReplies(parentID, limit, offset)
+	listing, err := h.CommentService.GetReplies(r.Context(), parentID, requestUserID(r), page)
 	if err != nil {
-		http.Error(w, "Failed to retrieve replies", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
 	// Respond with the replies
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(replies)
+	writeListing(w, h.Cursors, listing)
 }
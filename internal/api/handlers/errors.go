@@ -0,0 +1,80 @@
+// File: internal/api/handlers/errors.go
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"redditclone/internal/apperr"
+	"redditclone/pkg/logging"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// errorBody is the JSON envelope written for every failed request.
+type errorBody struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Code    string            `json:"code"`             // Machine-readable kind, e.g. "not_found".
+	Message string            `json:"message"`          // Human-readable description.
+	Fields  map[string]string `json:"fields,omitempty"` // Per-field problems for validation errors.
+}
+
+// errorKinds maps each apperr kind to its HTTP status and envelope code.
+var errorKinds = []struct {
+	kind   error
+	status int
+	code   string
+}{
+	{apperr.ErrBadRequest, http.StatusBadRequest, "bad_request"},
+	{apperr.ErrValidation, http.StatusUnprocessableEntity, "validation_failed"},
+	{apperr.ErrUnauthorized, http.StatusUnauthorized, "unauthorized"},
+	{apperr.ErrForbidden, http.StatusForbidden, "forbidden"},
+	{apperr.ErrNotFound, http.StatusNotFound, "not_found"},
+	{apperr.ErrConflict, http.StatusConflict, "conflict"},
+	{apperr.ErrRateLimited, http.StatusTooManyRequests, "rate_limited"},
+}
+
+// WriteError writes the error response for err. It lets middleware outside
+// this package answer with the same envelope as the handlers.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	writeError(w, r, err)
+}
+
+// writeError responds with the status and JSON envelope for err, adding
+// Retry-After to rate-limit errors that say how long to wait. Errors of an
+// unknown kind are logged against the request and reported as a generic 500 so
+// internal details such as SQL errors never reach the client.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, detail := http.StatusInternalServerError, errorDetail{
+		Code:    "internal",
+		Message: "internal server error",
+	}
+	for _, k := range errorKinds {
+		if errors.Is(err, k.kind) {
+			status = k.status
+			detail = errorDetail{Code: k.code, Message: err.Error(), Fields: apperr.FieldsOf(err)}
+			break
+		}
+	}
+	if status == http.StatusInternalServerError {
+		logging.FromContext(r.Context()).Error("internal error", "error", err.Error())
+		tracing.Fail(trace.SpanFromContext(r.Context()), err)
+	}
+
+	if retry := apperr.RetryAfterOf(err); retry > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorBody{Error: detail})
+}
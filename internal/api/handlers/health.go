@@ -0,0 +1,43 @@
+// File: internal/api/handlers/health.go
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"redditclone/internal/models"
+	"redditclone/internal/service"
+)
+
+// HealthHandler handles the health and readiness probes.
+type HealthHandler struct {
+	HealthService service.HealthService
+}
+
+// NewHealthHandler creates a new HealthHandler.
+func NewHealthHandler(healthService service.HealthService) *HealthHandler {
+	return &HealthHandler{HealthService: healthService}
+}
+
+// Healthz reports whether the server and its storage are up.
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, h.HealthService.Liveness(r.Context()))
+}
+
+// Readyz reports whether the server is ready to receive traffic.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, h.HealthService.Readiness(r.Context()))
+}
+
+// writeHealthReport responds 200 when every check passed and 503 otherwise.
+func writeHealthReport(w http.ResponseWriter, report *models.HealthReport) {
+	status := http.StatusOK
+	if report.Status != models.HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"redditclone/internal/apperr"
 	"redditclone/internal/models"
 	"redditclone/internal/service"
 
@@ -16,11 +17,13 @@ import (
 // MessageHandler handles message-related HTTP requests.
 type MessageHandler struct {
 	MessageService service.MessageService
+	Cursors        *CursorCodec
 }
 
 // NewMessageHandler creates a new MessageHandler with the given MessageService.
-func NewMessageHandler(messageService service.MessageService) *MessageHandler {
-	return &MessageHandler{MessageService: messageService}
+// Listing cursors are signed and checked with cursors.
+func NewMessageHandler(messageService service.MessageService, cursors *CursorCodec) *MessageHandler {
+	return &MessageHandler{MessageService: messageService, Cursors: cursors}
 }
 
 // SendMessage handles sending a new direct message.
@@ -29,14 +32,21 @@ func (h *MessageHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	// Decode the JSON request body into the Message model
 	err := json.NewDecoder(r.Body).Decode(&message)
 	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
 		return
 	}
 
+	// The sender is always the signed-in user
+	senderID, ok := signedInUserID(w, r, "send messages")
+	if !ok {
+		return
+	}
+	message.SenderID = senderID
+
 	// Send the message via the service
-	err = h.MessageService.SendMessage(&message)
+	err = h.MessageService.SendMessage(r.Context(), &message)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
@@ -51,12 +61,12 @@ func (h *MessageHandler) ReplyToMessage(w http.ResponseWriter, r *http.Request)
 	vars := mux.Vars(r)
 	parentIDStr, ok := vars["id"] // parent message ID from URL
 	if !ok {
-		http.Error(w, "Parent Message ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Parent Message ID is required"))
 		return
 	}
 	parentID, err := strconv.Atoi(parentIDStr)
 	if err != nil {
-		http.Error(w, "Invalid Parent Message ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Parent Message ID"))
 		return
 	}
 
@@ -64,16 +74,22 @@ func (h *MessageHandler) ReplyToMessage(w http.ResponseWriter, r *http.Request)
 	// Decode the JSON request body into the Message model
 	err = json.NewDecoder(r.Body).Decode(&message)
 	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
 		return
 	}
 
+	// The sender is always the signed-in user
+	senderID, ok := signedInUserID(w, r, "send messages")
+	if !ok {
+		return
+	}
+	message.SenderID = senderID
 	message.ParentID = &parentID
 
 	// Reply to the message via the service
-	err = h.MessageService.ReplyToMessage(&message)
+	err = h.MessageService.ReplyToMessage(r.Context(), &message)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
@@ -88,19 +104,19 @@ func (h *MessageHandler) GetMessage(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	messageIDStr, ok := vars["id"]
 	if !ok {
-		http.Error(w, "Message ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Message ID is required"))
 		return
 	}
 	messageID, err := strconv.Atoi(messageIDStr)
 	if err != nil {
-		http.Error(w, "Invalid Message ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Message ID"))
 		return
 	}
 
 	// Retrieve the message via the service
-	message, err := h.MessageService.GetMessageByID(messageID)
+	message, err := h.MessageService.GetMessageByID(r.Context(), messageID)
 	if err != nil {
-		http.Error(w, "Message not found", http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
@@ -114,12 +130,12 @@ func (h *MessageHandler) UpdateMessage(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	messageIDStr, ok := vars["id"]
 	if !ok {
-		http.Error(w, "Message ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Message ID is required"))
 		return
 	}
 	messageID, err := strconv.Atoi(messageIDStr)
 	if err != nil {
-		http.Error(w, "Invalid Message ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Message ID"))
 		return
 	}
 
@@ -127,16 +143,16 @@ func (h *MessageHandler) UpdateMessage(w http.ResponseWriter, r *http.Request) {
 	// Decode the JSON request body into the Message model
 	err = json.NewDecoder(r.Body).Decode(&message)
 	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
 		return
 	}
 
 	message.ID = messageID
 
 	// Update the message via the service
-	err = h.MessageService.UpdateMessage(&message)
+	err = h.MessageService.UpdateMessage(r.Context(), &message)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
@@ -150,19 +166,19 @@ func (h *MessageHandler) DeleteMessage(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	messageIDStr, ok := vars["id"]
 	if !ok {
-		http.Error(w, "Message ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Message ID is required"))
 		return
 	}
 	messageID, err := strconv.Atoi(messageIDStr)
 	if err != nil {
-		http.Error(w, "Invalid Message ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Message ID"))
 		return
 	}
 
 	// Delete the message via the service
-	err = h.MessageService.DeleteMessage(messageID)
+	err = h.MessageService.DeleteMessage(r.Context(), messageID)
 	if err != nil {
-		http.Error(w, "Message not found or could not be deleted", http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
@@ -171,60 +187,73 @@ func (h *MessageHandler) DeleteMessage(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Message deleted successfully"})
 }
 
-// GetMessagesForUser retrieves direct messages received by a user with pagination.
+// GetMessagesForUser retrieves a page of direct messages received by a user.
+// Only the recipient may view them.
 func (h *MessageHandler) GetMessagesForUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userIDStr, ok := vars["id"] // user ID from URL
 	if !ok {
-		http.Error(w, "User ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("User ID is required"))
 		return
 	}
 	userID, err := strconv.Atoi(userIDStr)
 	if err != nil {
-		http.Error(w, "Invalid User ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid User ID"))
+		return
+	}
+
+	// An inbox is private to its owner
+	if requestUserID(r) != userID {
+		writeError(w, r, apperr.Forbidden("Only the recipient can view their messages"))
 		return
 	}
 
 	// Parse pagination parameters
-	limit, offset := parsePaginationParams(r)
+	page, err := h.Cursors.parsePage(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
 	// Retrieve the messages via the service
-	messages, err := h.MessageService.GetMessagesForUser(userID, limit, offset)
+	listing, err := h.MessageService.GetMessagesForUser(r.Context(), userID, page)
 	if err != nil {
-		http.Error(w, "Failed to retrieve messages", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
 	// Respond with the messages
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(messages)
+	writeListing(w, h.Cursors, listing)
 }
 
-// GetReplies retrieves replies to a specific message with pagination.
+// GetReplies retrieves a page of replies to a specific message.
 func (h *MessageHandler) GetReplies(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	parentIDStr, ok := vars["id"] // parent message ID from URL
 	if !ok {
-		http.Error(w, "Parent Message ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Parent Message ID is required"))
 		return
 	}
 	parentID, err := strconv.Atoi(parentIDStr)
 	if err != nil {
-		http.Error(w, "Invalid Parent Message ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Parent Message ID"))
 		return
 	}
 
 	// Parse pagination parameters
-	limit, offset := parsePaginationParams(r)
+	page, err := h.Cursors.parsePage(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
 	// Retrieve the replies via the service
-	replies, err := h.MessageService.GetReplies(parentID, limit, offset)
+	listing, err := h.MessageService.GetReplies(r.Context(), parentID, page)
 	if err != nil {
-		http.Error(w, "Failed to retrieve replies", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
 	// Respond with the replies
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(replies)
+	writeListing(w, h.Cursors, listing)
 }
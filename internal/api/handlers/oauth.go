@@ -0,0 +1,271 @@
+// File: internal/api/handlers/oauth.go
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// OAuthHandler handles the OAuth2 authorization server: registering clients,
+// the authorization and token endpoints, and reviewing and revoking consents.
+type OAuthHandler struct {
+	OAuthService service.OAuthService
+}
+
+// NewOAuthHandler creates a new OAuthHandler with the given OAuthService.
+func NewOAuthHandler(oauthService service.OAuthService) *OAuthHandler {
+	return &OAuthHandler{OAuthService: oauthService}
+}
+
+// RegisterClient registers a third-party application owned by the caller and
+// returns it, with its secret if it is confidential. The secret is not shown
+// again. It takes a session of the caller's own.
+func (h *OAuthHandler) RegisterClient(w http.ResponseWriter, r *http.Request) {
+	session, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	userID := session.UserID
+
+	var body struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Scopes       []string `json:"scopes"`
+		Confidential bool     `json:"confidential"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+
+	client, err := h.OAuthService.RegisterClient(r.Context(), userID, body.Name, body.RedirectURIs, body.Scopes, body.Confidential)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(client)
+}
+
+// GetClient returns a client's public details.
+func (h *OAuthHandler) GetClient(w http.ResponseWriter, r *http.Request) {
+	client, err := h.OAuthService.GetClient(r.Context(), mux.Vars(r)["clientID"])
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(client)
+}
+
+// ListClients lists the clients the account registered. Only the owner may
+// view them.
+func (h *OAuthHandler) ListClients(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.ownerID(w, r)
+	if !ok {
+		return
+	}
+
+	clients, err := h.OAuthService.ListClients(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if clients == nil {
+		clients = []*models.OAuthClient{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clients)
+}
+
+// DeleteClient deletes one of the account's clients, revoking every token
+// issued to it.
+func (h *OAuthHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.ownerID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.OAuthService.DeleteClient(r.Context(), userID, mux.Vars(r)["clientID"]); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "OAuth client deleted"})
+}
+
+// PrepareAuthorization checks an authorization request, given as the usual
+// OAuth2 query parameters, and describes it so the signed-in user can be
+// asked to approve or deny it.
+func (h *OAuthHandler) PrepareAuthorization(w http.ResponseWriter, r *http.Request) {
+	session, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	userID := session.UserID
+
+	query := r.URL.Query()
+	req := &models.AuthorizationRequest{
+		ResponseType:        query.Get("response_type"),
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+	}
+	prompt, err := h.OAuthService.PrepareAuthorization(r.Context(), userID, req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prompt)
+}
+
+// Authorize records the signed-in user's answer to an authorization request
+// and returns the URI to send the user back to the client with. Consent is
+// only taken from a session the user signed in to themselves: API keys, OAuth
+// tokens and admins' impersonation sessions cannot grant it.
+func (h *OAuthHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	session, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	userID := session.UserID
+
+	var body struct {
+		models.AuthorizationRequest
+		Approve bool `json:"approve"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+
+	redirectTo, err := h.OAuthService.Authorize(r.Context(), userID, &body.AuthorizationRequest, body.Approve)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"redirect_to": redirectTo})
+}
+
+// Token is the OAuth2 token endpoint. It takes a form-encoded request, with
+// confidential clients authenticating through HTTP Basic or the
+// client_secret parameter, and answers errors in the OAuth2 format rather
+// than the usual envelope.
+func (h *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, r, &service.OAuthError{Code: service.OAuthInvalidRequest, Err: apperr.BadRequest("Invalid form body")})
+		return
+	}
+
+	req := &models.TokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		ClientID:     r.PostForm.Get("client_id"),
+		ClientSecret: r.PostForm.Get("client_secret"),
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+		RefreshToken: r.PostForm.Get("refresh_token"),
+		Scope:        r.PostForm.Get("scope"),
+	}
+	if id, secret, ok := r.BasicAuth(); ok {
+		// RFC 6749 form-encodes the credentials before Basic encoding them.
+		req.ClientID, _ = url.QueryUnescape(id)
+		req.ClientSecret, _ = url.QueryUnescape(secret)
+	}
+
+	tokens, err := h.OAuthService.Token(r.Context(), req)
+	if err != nil {
+		writeOAuthError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// writeOAuthError responds to a token request with an OAuth2 error body.
+// Errors that are not *service.OAuthError go through writeError.
+func writeOAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	var oauthErr *service.OAuthError
+	if !errors.As(err, &oauthErr) {
+		writeError(w, r, err)
+		return
+	}
+	status := http.StatusBadRequest
+	if oauthErr.Code == service.OAuthInvalidClient {
+		status = http.StatusUnauthorized
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":             oauthErr.Code,
+		"error_description": oauthErr.Error(),
+	})
+}
+
+// ListConsents lists the clients the account has granted access to, with the
+// scopes of each, revoked grants included. Only the owner may view them.
+func (h *OAuthHandler) ListConsents(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.ownerID(w, r)
+	if !ok {
+		return
+	}
+
+	consents, err := h.OAuthService.ListConsents(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if consents == nil {
+		consents = []*models.OAuthConsent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(consents)
+}
+
+// RevokeConsent revokes the account's grant to a client, signing the client
+// out at once.
+func (h *OAuthHandler) RevokeConsent(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.ownerID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.OAuthService.RevokeConsent(r.Context(), userID, mux.Vars(r)["clientID"]); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Access revoked"})
+}
+
+// ownerID parses the user ID from the path and checks that the request comes
+// from one of that user's sessions.
+func (h *OAuthHandler) ownerID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	return sessionOwner(w, r, "Only the account owner can manage OAuth clients and consents")
+}
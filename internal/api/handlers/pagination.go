@@ -0,0 +1,136 @@
+// File: internal/api/handlers/pagination.go
+
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/repository"
+)
+
+// tokenEncoding rejects stray trailing bits, so each cursor has exactly one token.
+var tokenEncoding = base64.RawURLEncoding.Strict()
+
+// CursorCodec turns listing cursors into opaque tokens and back. Tokens carry
+// an HMAC-SHA256 signature, so clients cannot forge or edit a position.
+type CursorCodec struct {
+	key []byte
+}
+
+// NewCursorCodec creates a CursorCodec signing with secret. An empty secret
+// picks a random key, so tokens stop working when the server restarts.
+func NewCursorCodec(secret string) *CursorCodec {
+	key := []byte(secret)
+	if secret == "" {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic("NewCursorCodec: " + err.Error())
+		}
+	}
+	return &CursorCodec{key: key}
+}
+
+// Encode returns the token for cursor.
+func (c *CursorCodec) Encode(cursor repository.Cursor) string {
+	payload := strconv.FormatInt(cursor.CreatedAt.UnixNano(), 10) + "." + strconv.Itoa(cursor.ID)
+	return tokenEncoding.EncodeToString([]byte(payload)) + "." +
+		tokenEncoding.EncodeToString(c.sign(payload))
+}
+
+// Decode verifies token and returns the cursor it carries.
+func (c *CursorCodec) Decode(token string) (repository.Cursor, error) {
+	invalid := apperr.BadRequest("Invalid pagination cursor")
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return repository.Cursor{}, invalid
+	}
+	payload, err := tokenEncoding.DecodeString(encoded)
+	if err != nil {
+		return repository.Cursor{}, invalid
+	}
+	mac, err := tokenEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, c.sign(string(payload))) {
+		return repository.Cursor{}, invalid
+	}
+
+	nanos, id, _ := strings.Cut(string(payload), ".")
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return repository.Cursor{}, invalid
+	}
+	cursorID, err := strconv.Atoi(id)
+	if err != nil {
+		return repository.Cursor{}, invalid
+	}
+	return repository.Cursor{CreatedAt: time.Unix(0, n).UTC(), ID: cursorID}, nil
+}
+
+func (c *CursorCodec) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// parsePage reads limit and offset like parsePaginationParams, plus the
+// after and before cursor tokens. A cursor takes precedence over offset.
+func (c *CursorCodec) parsePage(r *http.Request) (repository.Page, error) {
+	limit, offset := parsePaginationParams(r)
+	page := repository.Page{Limit: limit, Offset: offset}
+
+	query := r.URL.Query()
+	after, before := query.Get("after"), query.Get("before")
+	if after != "" && before != "" {
+		return page, apperr.BadRequest("Only one of after and before may be given")
+	}
+	if after != "" {
+		cursor, err := c.Decode(after)
+		if err != nil {
+			return page, err
+		}
+		page.After = &cursor
+	}
+	if before != "" {
+		cursor, err := c.Decode(before)
+		if err != nil {
+			return page, err
+		}
+		page.Before = &cursor
+	}
+	return page, nil
+}
+
+// listingBody is the envelope for paginated listings. After and Before are
+// the tokens for the next and previous pages, or null at either end.
+type listingBody[T any] struct {
+	Data   []T     `json:"data"`
+	After  *string `json:"after"`
+	Before *string `json:"before"`
+}
+
+// writeListing responds with listing in the listing envelope.
+func writeListing[T any](w http.ResponseWriter, c *CursorCodec, listing *repository.Listing[T]) {
+	body := listingBody[T]{Data: listing.Items}
+	if body.Data == nil {
+		body.Data = []T{}
+	}
+	if listing.After != nil {
+		token := c.Encode(*listing.After)
+		body.After = &token
+	}
+	if listing.Before != nil {
+		token := c.Encode(*listing.Before)
+		body.Before = &token
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
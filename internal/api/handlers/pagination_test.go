@@ -0,0 +1,103 @@
+// File: internal/api/handlers/pagination_test.go
+
+package handlers
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/repository"
+)
+
+func TestCursorCodecRoundTrip(t *testing.T) {
+	codec := NewCursorCodec("secret")
+	tests := []repository.Cursor{
+		{CreatedAt: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC), ID: 42},
+		{CreatedAt: time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC), ID: 1},
+		{CreatedAt: time.Unix(0, 0).UTC(), ID: 0},
+	}
+	for _, cursor := range tests {
+		token := codec.Encode(cursor)
+		got, err := codec.Decode(token)
+		if err != nil {
+			t.Fatalf("Decode(Encode(%+v)): %v", cursor, err)
+		}
+		if !got.CreatedAt.Equal(cursor.CreatedAt) || got.ID != cursor.ID {
+			t.Errorf("Decode(Encode(%+v)) = %+v", cursor, got)
+		}
+	}
+}
+
+func TestCursorCodecRejectsTampering(t *testing.T) {
+	codec := NewCursorCodec("secret")
+	token := codec.Encode(repository.Cursor{CreatedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), ID: 42})
+	payload, sig, _ := strings.Cut(token, ".")
+
+	// forged carries a different position under the original signature.
+	forged := tokenEncoding.EncodeToString([]byte("1714521600000000000.43")) + "." + sig
+	// flipped changes the first character of the signature.
+	flipped := payload + "." + string(sig[0]^1) + sig[1:]
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"edited payload", forged},
+		{"edited signature", flipped},
+		{"signed with another key", NewCursorCodec("other").Encode(repository.Cursor{ID: 42})},
+		{"random key", NewCursorCodec("").Encode(repository.Cursor{ID: 42})},
+		{"no signature", payload},
+		{"empty", ""},
+		{"not base64", "!!!." + sig},
+		{"trailing bits", payload + "A." + sig},
+	}
+	for _, tt := range tests {
+		_, err := codec.Decode(tt.token)
+		if !errors.Is(err, apperr.ErrBadRequest) {
+			t.Errorf("%s: Decode(%q) error = %v, want a bad request", tt.name, tt.token, err)
+		}
+	}
+}
+
+func TestParsePage(t *testing.T) {
+	codec := NewCursorCodec("secret")
+	cursor := repository.Cursor{CreatedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), ID: 7}
+	token := codec.Encode(cursor)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantAfter  bool
+		wantBefore bool
+		wantErr    bool
+	}{
+		{"offset only", "?limit=5&offset=10", false, false, false},
+		{"after", "?after=" + token, true, false, false},
+		{"before", "?before=" + token, false, true, false},
+		{"both", "?after=" + token + "&before=" + token, false, false, true},
+		{"bad token", "?after=nope", false, false, true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/feed"+tt.query, nil)
+		page, err := codec.parsePage(r)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if (page.After != nil) != tt.wantAfter || (page.Before != nil) != tt.wantBefore {
+			t.Errorf("%s: page = %+v", tt.name, page)
+		}
+		for _, c := range []*repository.Cursor{page.After, page.Before} {
+			if c != nil && (c.ID != cursor.ID || !c.CreatedAt.Equal(cursor.CreatedAt)) {
+				t.Errorf("%s: cursor = %+v, want %+v", tt.name, *c, cursor)
+			}
+		}
+	}
+}
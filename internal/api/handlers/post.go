@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"redditclone/internal/apperr"
 	"redditclone/internal/models"
 	"redditclone/internal/service"
 
@@ -16,11 +17,13 @@ import (
 // PostHandler handles post-related HTTP requests.
 type PostHandler struct {
 	PostService service.PostService
+	Cursors     *CursorCodec
 }
 
 // NewPostHandler creates a new PostHandler with the given PostService.
-func NewPostHandler(postService service.PostService) *PostHandler {
-	return &PostHandler{PostService: postService}
+// Listing cursors are signed and checked with cursors.
+func NewPostHandler(postService service.PostService, cursors *CursorCodec) *PostHandler {
+	return &PostHandler{PostService: postService, Cursors: cursors}
 }
 
 // CreatePost handles the creation of a new post.
@@ -28,12 +31,12 @@ func (h *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	subredditIDStr, ok := vars["id"] // subreddit ID from URL
 	if !ok {
-		http.Error(w, "Subreddit ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Subreddit ID is required"))
 		return
 	}
 	subredditID, err := strconv.Atoi(subredditIDStr)
 	if err != nil {
-		http.Error(w, "Invalid Subreddit ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Subreddit ID"))
 		return
 	}
 
@@ -41,23 +44,23 @@ func (h *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 	// Decode the JSON request body into the Post model
 	err = json.NewDecoder(r.Body).Decode(&post)
 	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
 		return
 	}
 
-	// TODO: Extract the author's user ID from the authenticated context
-	// For simplicity, assume it's provided in the request body
-	if post.AuthorID == 0 {
-		http.Error(w, "Author ID is required", http.StatusBadRequest)
+	// The author is always the signed-in user
+	authorID, ok := signedInUserID(w, r, "post")
+	if !ok {
 		return
 	}
 
+	post.AuthorID = authorID
 	post.SubredditID = subredditID
 
 	// Create the post via the service
-	err = h.PostService.CreatePost(&post)
+	err = h.PostService.CreatePost(r.Context(), &post)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
@@ -72,19 +75,19 @@ func (h *PostHandler) GetPost(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	postIDStr, ok := vars["id"]
 	if !ok {
-		http.Error(w, "Post ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Post ID is required"))
 		return
 	}
 	postID, err := strconv.Atoi(postIDStr)
 	if err != nil {
-		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Post ID"))
 		return
 	}
 
 	// Retrieve the post via the service
-	post, err := h.PostService.GetPostByID(postID)
+	post, err := h.PostService.GetPostByID(r.Context(), postID)
 	if err != nil {
-		http.Error(w, "Post not found", http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
@@ -98,12 +101,12 @@ func (h *PostHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	postIDStr, ok := vars["id"]
 	if !ok {
-		http.Error(w, "Post ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Post ID is required"))
 		return
 	}
 	postID, err := strconv.Atoi(postIDStr)
 	if err != nil {
-		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Post ID"))
 		return
 	}
 
@@ -111,16 +114,16 @@ func (h *PostHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
 	// Decode the JSON request body into the Post model
 	err = json.NewDecoder(r.Body).Decode(&post)
 	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
 		return
 	}
 
 	post.ID = postID
 
 	// Update the post via the service
-	err = h.PostService.UpdatePost(&post)
+	err = h.PostService.UpdatePost(r.Context(), &post)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
@@ -134,19 +137,19 @@ func (h *PostHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	postIDStr, ok := vars["id"]
 	if !ok {
-		http.Error(w, "Post ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Post ID is required"))
 		return
 	}
 	postID, err := strconv.Atoi(postIDStr)
 	if err != nil {
-		http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Post ID"))
 		return
 	}
 
 	// Delete the post via the service
-	err = h.PostService.DeletePost(postID)
+	err = h.PostService.DeletePost(r.Context(), postID)
 	if err != nil {
-		http.Error(w, "Post not found or could not be deleted", http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
@@ -155,32 +158,79 @@ func (h *PostHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Post deleted successfully"})
 }
 
-// GetFeed retrieves a list of posts for the feed with pagination.
+// GetFeed retrieves a page of posts for the feed.
 func (h *PostHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters for pagination
-	limit, offset := parsePaginationParams(r)
+	page, err := h.Cursors.parsePage(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
 	// Retrieve the feed posts via the service
-	posts, err := h.PostService.GetFeedPosts(limit, offset)
+	listing, err := h.PostService.GetFeedPosts(r.Context(), requestUserID(r), page)
 	if err != nil {
-		http.Error(w, "Failed to retrieve feed", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
 	// Respond with the feed posts
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(posts)
+	writeListing(w, h.Cursors, listing)
+}
+
+// GetSubredditPosts retrieves a page of posts from a subreddit.
+func (h *PostHandler) GetSubredditPosts(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditIDStr, ok := vars["id"] // subreddit ID from URL
+	if !ok {
+		writeError(w, r, apperr.BadRequest("Subreddit ID is required"))
+		return
+	}
+	subredditID, err := strconv.Atoi(subredditIDStr)
+	if err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid Subreddit ID"))
+		return
+	}
+
+	// Parse query parameters for pagination
+	page, err := h.Cursors.parsePage(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Retrieve the subreddit's posts via the service
+	listing, err := h.PostService.GetPostsBySubreddit(r.Context(), subredditID, requestUserID(r), page)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Respond with the posts
+	writeListing(w, h.Cursors, listing)
+}
+
+// Page sizes used by parsePaginationParams; see SetPageLimits.
+var (
+	defaultPageLimit = 10
+	maxPageLimit     = 100
+)
+
+// SetPageLimits sets the page size used when a request gives no limit and the
+// largest limit a request may ask for. Call it before serving requests.
+func SetPageLimits(defaultLimit, maxLimit int) {
+	defaultPageLimit, maxPageLimit = defaultLimit, maxLimit
 }
 
 // Helper function to parse pagination query parameters
 func parsePaginationParams(r *http.Request) (limit, offset int) {
 	// Default values
-	limit = 10
+	limit = defaultPageLimit
 	offset = 0
 
 	// Parse 'limit' query parameter
 	if l := r.URL.Query().Get("limit"); l != "" {
-		if val, err := strconv.Atoi(l); err == nil && val > 0 && val <= 100 {
+		if val, err := strconv.Atoi(l); err == nil && val > 0 && val <= maxPageLimit {
 			limit = val
 		}
 	}
@@ -194,3 +244,26 @@ func parsePaginationParams(r *http.Request) (limit, offset int) {
 
 	return
 }
+
+// Helper function to read the caller's user ID from the X-User-ID header,
+// which the router sets from the request's verified credentials only.
+// It returns 0 for anonymous requests.
+func requestUserID(r *http.Request) int {
+	id, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil || id < 0 {
+		return 0
+	}
+	return id
+}
+
+// Helper function to read the caller's user ID like requestUserID, responding
+// with 401 and returning false if the request is anonymous. action completes
+// the message "Sign in to ...".
+func signedInUserID(w http.ResponseWriter, r *http.Request, action string) (int, bool) {
+	userID := requestUserID(r)
+	if userID == 0 {
+		writeError(w, r, apperr.Unauthorized("Sign in to "+action))
+		return 0, false
+	}
+	return userID, true
+}
@@ -0,0 +1,162 @@
+// File: internal/api/handlers/session.go
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// SessionHandler handles refreshing, listing and revoking login sessions.
+type SessionHandler struct {
+	SessionService service.SessionService
+}
+
+// NewSessionHandler creates a new SessionHandler with the given SessionService.
+func NewSessionHandler(sessionService service.SessionService) *SessionHandler {
+	return &SessionHandler{SessionService: sessionService}
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token.
+func (h *SessionHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+
+	tokens, err := h.SessionService.Refresh(r.Context(), body.RefreshToken, clientInfo(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// Logout revokes the session whose access token authenticated the request.
+func (h *SessionHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	session := service.SessionFromContext(r.Context())
+	if session == nil {
+		writeError(w, r, apperr.Unauthorized("An access token is required to log out"))
+		return
+	}
+
+	if err := h.SessionService.RevokeSession(r.Context(), session.UserID, session.ID, models.RevokedLogout); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out"})
+}
+
+// ListSessions lists the account's active sessions with the device, IP and
+// last-seen time of each. Only the owner may view them.
+func (h *SessionHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.ownerID(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.SessionService.ListSessions(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if sessions == nil {
+		sessions = []*models.Session{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// RevokeSession signs one of the account's sessions out.
+func (h *SessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.ownerID(w, r)
+	if !ok {
+		return
+	}
+	sessionID, err := strconv.Atoi(mux.Vars(r)["sessionID"])
+	if err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid session ID"))
+		return
+	}
+
+	if err := h.SessionService.RevokeSession(r.Context(), userID, sessionID, models.RevokedByUser); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Session revoked"})
+}
+
+// RevokeOtherSessions signs out every session of the account except the one
+// making the request.
+func (h *SessionHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.ownerID(w, r)
+	if !ok {
+		return
+	}
+
+	revoked, err := h.SessionService.RevokeOtherSessions(r.Context(), userID, models.RevokedByUser)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"revoked": revoked})
+}
+
+// ownerID parses the user ID from the path and checks that the request comes
+// from one of that user's sessions.
+func (h *SessionHandler) ownerID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	return sessionOwner(w, r, "Only the account owner can manage sessions")
+}
+
+// sessionUser returns the session the request was signed in with. API keys,
+// OAuth2 tokens and requests without credentials get 401, and admins'
+// impersonation sessions 403, so the caller is the user at the keyboard.
+func sessionUser(w http.ResponseWriter, r *http.Request) (*models.Session, bool) {
+	session := service.SessionFromContext(r.Context())
+	if session == nil {
+		writeError(w, r, apperr.Unauthorized("Sign in with a session access token to use this endpoint"))
+		return nil, false
+	}
+	if session.ImpersonatorID != nil {
+		writeError(w, r, apperr.Forbidden("Impersonation sessions cannot be used for this endpoint"))
+		return nil, false
+	}
+	return session, true
+}
+
+// sessionOwner parses the user ID from the path and checks that the request
+// comes from a session of that user, answering 403 with forbidden otherwise.
+func sessionOwner(w http.ResponseWriter, r *http.Request, forbidden string) (int, bool) {
+	userID, ok := parseUserIDParam(w, r)
+	if !ok {
+		return 0, false
+	}
+	session, ok := sessionUser(w, r)
+	if !ok {
+		return 0, false
+	}
+	if session.UserID != userID {
+		writeError(w, r, apperr.Forbidden(forbidden))
+		return 0, false
+	}
+	return userID, true
+}
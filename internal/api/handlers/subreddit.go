@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"redditclone/internal/apperr"
 	"redditclone/internal/models"
 	"redditclone/internal/service"
 
@@ -29,22 +30,21 @@ func (h *SubredditHandler) CreateSubreddit(w http.ResponseWriter, r *http.Reques
 	// Decode the JSON request body into the Subreddit model
 	err := json.NewDecoder(r.Body).Decode(&subreddit)
 	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
 		return
 	}
 
-	// TODO: Extract the creator's user ID from the authenticated context
-	// For simplicity, assume it's provided in the request body
-	// In a real application, retrieve it from the authentication token
-	if subreddit.CreatedBy == 0 {
-		http.Error(w, "Creator ID is required", http.StatusBadRequest)
+	// The creator is always the signed-in user
+	creatorID, ok := signedInUserID(w, r, "create a subreddit")
+	if !ok {
 		return
 	}
+	subreddit.CreatedBy = creatorID
 
 	// Create the subreddit via the service
-	err = h.SubredditService.CreateSubreddit(&subreddit)
+	err = h.SubredditService.CreateSubreddit(r.Context(), &subreddit)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
@@ -59,19 +59,19 @@ func (h *SubredditHandler) GetSubreddit(w http.ResponseWriter, r *http.Request)
 	vars := mux.Vars(r)
 	idStr, ok := vars["id"]
 	if !ok {
-		http.Error(w, "Subreddit ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Subreddit ID is required"))
 		return
 	}
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid Subreddit ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Subreddit ID"))
 		return
 	}
 
 	// Retrieve the subreddit via the service
-	subreddit, err := h.SubredditService.GetSubredditByID(id)
+	subreddit, err := h.SubredditService.GetSubredditByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Subreddit not found", http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
@@ -85,12 +85,12 @@ func (h *SubredditHandler) UpdateSubreddit(w http.ResponseWriter, r *http.Reques
 	vars := mux.Vars(r)
 	idStr, ok := vars["id"]
 	if !ok {
-		http.Error(w, "Subreddit ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Subreddit ID is required"))
 		return
 	}
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid Subreddit ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Subreddit ID"))
 		return
 	}
 
@@ -98,16 +98,16 @@ func (h *SubredditHandler) UpdateSubreddit(w http.ResponseWriter, r *http.Reques
 	// Decode the JSON request body into the Subreddit model
 	err = json.NewDecoder(r.Body).Decode(&subreddit)
 	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
 		return
 	}
 
 	subreddit.ID = id
 
 	// Update the subreddit via the service
-	err = h.SubredditService.UpdateSubreddit(&subreddit)
+	err = h.SubredditService.UpdateSubreddit(r.Context(), &subreddit)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
@@ -121,19 +121,19 @@ func (h *SubredditHandler) DeleteSubreddit(w http.ResponseWriter, r *http.Reques
 	vars := mux.Vars(r)
 	idStr, ok := vars["id"]
 	if !ok {
-		http.Error(w, "Subreddit ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Subreddit ID is required"))
 		return
 	}
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid Subreddit ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Subreddit ID"))
 		return
 	}
 
 	// Delete the subreddit via the service
-	err = h.SubredditService.DeleteSubreddit(id)
+	err = h.SubredditService.DeleteSubreddit(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Subreddit not found or could not be deleted", http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
@@ -147,30 +147,24 @@ func (h *SubredditHandler) JoinSubreddit(w http.ResponseWriter, r *http.Request)
 	vars := mux.Vars(r)
 	idStr, ok := vars["id"] // subreddit ID
 	if !ok {
-		http.Error(w, "Subreddit ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Subreddit ID is required"))
 		return
 	}
 	subredditID, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid Subreddit ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Subreddit ID"))
 		return
 	}
 
-	// TODO: Extract the user's ID from the authenticated context
-	// For simplicity, assume it's provided in the request body
-	var payload struct {
-		UserID int `json:"user_id"`
-	}
-	err = json.NewDecoder(r.Body).Decode(&payload)
-	if err != nil || payload.UserID == 0 {
-		http.Error(w, "Invalid request payload: user_id is required", http.StatusBadRequest)
+	userID, ok := signedInUserID(w, r, "join a subreddit")
+	if !ok {
 		return
 	}
 
 	// Join the subreddit via the service
-	err = h.SubredditService.JoinSubreddit(payload.UserID, subredditID)
+	err = h.SubredditService.JoinSubreddit(r.Context(), userID, subredditID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
@@ -184,30 +178,24 @@ func (h *SubredditHandler) LeaveSubreddit(w http.ResponseWriter, r *http.Request
 	vars := mux.Vars(r)
 	idStr, ok := vars["id"] // subreddit ID
 	if !ok {
-		http.Error(w, "Subreddit ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Subreddit ID is required"))
 		return
 	}
 	subredditID, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid Subreddit ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Subreddit ID"))
 		return
 	}
 
-	// TODO: Extract the user's ID from the authenticated context
-	// For simplicity, assume it's provided in the request body
-	var payload struct {
-		UserID int `json:"user_id"`
-	}
-	err = json.NewDecoder(r.Body).Decode(&payload)
-	if err != nil || payload.UserID == 0 {
-		http.Error(w, "Invalid request payload: user_id is required", http.StatusBadRequest)
+	userID, ok := signedInUserID(w, r, "leave a subreddit")
+	if !ok {
 		return
 	}
 
 	// Leave the subreddit via the service
-	err = h.SubredditService.LeaveSubreddit(payload.UserID, subredditID)
+	err = h.SubredditService.LeaveSubreddit(r.Context(), userID, subredditID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
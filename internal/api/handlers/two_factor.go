@@ -0,0 +1,142 @@
+// File: internal/api/handlers/two_factor.go
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/service"
+)
+
+// TwoFactorHandler handles two-factor authentication settings. Every
+// endpoint is limited to the account owner.
+type TwoFactorHandler struct {
+	TwoFactorService service.TwoFactorService
+}
+
+// NewTwoFactorHandler creates a new TwoFactorHandler with the given TwoFactorService.
+func NewTwoFactorHandler(twoFactorService service.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{TwoFactorService: twoFactorService}
+}
+
+// ownerID parses the user ID from the path and checks that the request comes
+// from one of that user's sessions.
+func (h *TwoFactorHandler) ownerID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	return sessionOwner(w, r, "Only the account owner can manage two-factor authentication")
+}
+
+// decodeCode reads a {"code": ...} request body.
+func decodeCode(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
+		return "", false
+	}
+	return body.Code, true
+}
+
+// GetStatus reports whether two-factor authentication is on and how many
+// recovery codes are left.
+func (h *TwoFactorHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.ownerID(w, r)
+	if !ok {
+		return
+	}
+
+	status, err := h.TwoFactorService.GetStatus(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// BeginEnrollment generates a TOTP secret and returns it with the
+// provisioning URI to add to an authenticator app.
+func (h *TwoFactorHandler) BeginEnrollment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.ownerID(w, r)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.TwoFactorService.BeginEnrollment(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// ConfirmEnrollment turns two-factor authentication on with a code from the
+// authenticator app and returns the recovery codes.
+func (h *TwoFactorHandler) ConfirmEnrollment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.ownerID(w, r)
+	if !ok {
+		return
+	}
+	code, ok := decodeCode(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.TwoFactorService.ConfirmEnrollment(r.Context(), userID, code)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"recovery_codes": codes})
+}
+
+// Disable turns two-factor authentication off, given a current code from the
+// authenticator app.
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.ownerID(w, r)
+	if !ok {
+		return
+	}
+	code, ok := decodeCode(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.TwoFactorService.Disable(r.Context(), userID, code); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes, given a current or
+// recovery code, and returns the new ones.
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.ownerID(w, r)
+	if !ok {
+		return
+	}
+	code, ok := decodeCode(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.TwoFactorService.RegenerateRecoveryCodes(r.Context(), userID, code)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"recovery_codes": codes})
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"redditclone/internal/apperr"
 	"redditclone/internal/models"
 	"redditclone/internal/service"
 
@@ -29,21 +30,21 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 	// Decode the JSON request body into the User model
 	err := json.NewDecoder(r.Body).Decode(&user)
 	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
 		return
 	}
 
 	// Register the user via the service
-	err = h.UserService.RegisterUser(&user)
+	err = h.UserService.RegisterUser(r.Context(), &user)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
 	// Respond with success
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]interface{}{"message": strconv.Itoa(user.ID) + " User registered successfully", "user_id" : user.ID,})
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": strconv.Itoa(user.ID) + " User registered successfully", "user_id": user.ID})
 }
 
 // Login handles user authentication.
@@ -55,108 +56,299 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// Decode the JSON request body into the credentials struct
 	err := json.NewDecoder(r.Body).Decode(&credentials)
 	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
 		return
 	}
 
 	// Authenticate the user via the service
-	user, err := h.UserService.AuthenticateUser(credentials.Username, credentials.Password)
+	result, err := h.UserService.AuthenticateUser(r.Context(), credentials.Username, credentials.Password, clientInfo(r))
 	if err != nil {
-		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		writeError(w, r, err)
 		return
 	}
 
-	// TODO: Generate and return a token (e.g., JWT) for authenticated sessions
+	// With two-factor authentication on, the client must still send a code
+	// to POST /login/2fa along with the challenge
+	if result.Challenge != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			TwoFactorRequired bool `json:"two_factor_required"`
+			*models.TwoFactorChallenge
+		}{true, result.Challenge})
+		return
+	}
+	writeLoginResult(w, result)
+}
+
+// LoginTwoFactor completes a login for a user with two-factor authentication
+// on, given the challenge from Login and a TOTP or recovery code.
+func (h *UserHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Challenge string `json:"challenge"`
+		Code      string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+
+	result, err := h.UserService.CompleteTwoFactorLogin(r.Context(), body.Challenge, body.Code, clientInfo(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeLoginResult(w, result)
+}
+
+// Helper function to respond to a completed login with the user profile and
+// the tokens of the new session.
+func writeLoginResult(w http.ResponseWriter, result *models.LoginResult) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		*models.User
+		Session *models.SessionTokens `json:"session"`
+	}{result.User, result.Session})
+}
+
+// GetLoginHistory lists the recent login attempts on a user's account,
+// successful or not, with the IP address and user agent of each. Only the
+// owner may view it.
+func (h *UserHandler) GetLoginHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseUserIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	// Sign-in history is private to its owner
+	if requestUserID(r) != userID {
+		writeError(w, r, apperr.Forbidden("Only the account owner can view sign-in history"))
+		return
+	}
+
+	// Parse pagination parameters
+	limit, offset := parsePaginationParams(r)
+
+	// Retrieve the login attempts via the service
+	attempts, err := h.UserService.GetLoginHistory(r.Context(), userID, limit, offset)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
-	// For simplicity, respond with the user profile
+	// Respond with the login attempts
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user)
+	json.NewEncoder(w).Encode(attempts)
 }
 
-// GetProfile retrieves a user's profile by ID.
+// GetProfile retrieves a user's profile by ID. The owner, signed in with a
+// session, API key or OAuth token, sees the private profile; everyone else,
+// including anonymous callers, sees the public one.
 func (h *UserHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr, ok := vars["id"]
 	if !ok {
-		http.Error(w, "User ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("User ID is required"))
 		return
 	}
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid User ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid User ID"))
 		return
 	}
 
 	// Retrieve the user profile via the service
-	user, err := h.UserService.GetUserProfile(id)
+	var profile interface{}
+	if requestUserID(r) == id {
+		profile, err = h.UserService.GetPrivateProfile(r.Context(), id)
+	} else {
+		profile, err = h.UserService.GetPublicProfile(r.Context(), id)
+	}
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
 	// Respond with the user profile
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user)
+	json.NewEncoder(w).Encode(profile)
 }
 
-// UpdateProfile updates a user's profile information.
+// UpdateProfile updates a user's profile information. Only the owner, signed
+// in with a session, may change it; a new password or email address also
+// takes the current password as "current_password".
 func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	id, ok := sessionOwner(w, r, "Only the account owner can update the profile")
+	if !ok {
+		return
+	}
+
+	var body struct {
+		models.User
+		CurrentPassword string `json:"current_password"`
+	}
+	// Decode the JSON request body into the User model
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+
+	user := body.User
+	user.ID = id
+
+	// Update the user profile via the service
+	err = h.UserService.UpdateUserProfile(r.Context(), &user, body.CurrentPassword)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Respond with success
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "User profile updated successfully"})
+}
+
+// SetBot marks the account as a bot, run by automation, or as a person's.
+// Only the owner may change it.
+func (h *UserHandler) SetBot(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseUserIDParam(w, r)
+	if !ok {
+		return
+	}
+	if requestUserID(r) != userID {
+		writeError(w, r, apperr.Forbidden("Only the account owner can change the bot flag"))
+		return
+	}
+
+	var body struct {
+		IsBot *bool `json:"is_bot"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+	if body.IsBot == nil {
+		writeError(w, r, apperr.BadRequest("is_bot is required"))
+		return
+	}
+
+	if err := h.UserService.SetBot(r.Context(), userID, *body.IsBot); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"is_bot": *body.IsBot})
+}
+
+// BlockUser adds a user to the block list of the user in the URL. Only the
+// owner may change it.
+func (h *UserHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr, ok := vars["id"]
 	if !ok {
-		http.Error(w, "User ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("User ID is required"))
 		return
 	}
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid User ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid User ID"))
 		return
 	}
 
-	var user models.User
-	// Decode the JSON request body into the User model
-	err = json.NewDecoder(r.Body).Decode(&user)
+	// Only the owner may edit their block list
+	if requestUserID(r) != id {
+		writeError(w, r, apperr.Forbidden("Only the account owner can change the block list"))
+		return
+	}
+
+	var payload struct {
+		BlockedID int `json:"blocked_id"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil || payload.BlockedID == 0 {
+		writeError(w, r, apperr.BadRequest("Invalid request payload: blocked_id is required"))
+		return
+	}
+
+	// Block the user via the service
+	block, err := h.UserService.BlockUser(r.Context(), id, payload.BlockedID)
 	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
-	user.ID = id
+	// Respond with the created block
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(block)
+}
 
-	// Update the user profile via the service
-	err = h.UserService.UpdateUserProfile(&user)
+// UnblockUser removes a user from the block list of the user in the URL. Only
+// the owner may change it.
+func (h *UserHandler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid User ID"))
+		return
+	}
+	blockedID, err := strconv.Atoi(vars["blockedID"])
+	if err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid Blocked User ID"))
+		return
+	}
+
+	// Only the owner may edit their block list
+	if requestUserID(r) != id {
+		writeError(w, r, apperr.Forbidden("Only the account owner can change the block list"))
+		return
+	}
+
+	// Unblock the user via the service
+	err = h.UserService.UnblockUser(r.Context(), id, blockedID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
 	// Respond with success
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "User profile updated successfully"})
+	json.NewEncoder(w).Encode(map[string]string{"message": "User unblocked successfully"})
 }
 
-// DeleteUser removes a user from the system.
-func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+// GetBlockedUsers retrieves the block list of the user in the URL with
+// pagination. Only the owner may view it.
+func (h *UserHandler) GetBlockedUsers(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr, ok := vars["id"]
 	if !ok {
-		http.Error(w, "User ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("User ID is required"))
 		return
 	}
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid User ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid User ID"))
+		return
+	}
+
+	// The block list is private to its owner
+	if requestUserID(r) != id {
+		writeError(w, r, apperr.Forbidden("Only the account owner can view the block list"))
 		return
 	}
 
-	// Delete the user via the service
-	err = h.UserService.DeleteUser(id)
+	// Parse pagination parameters
+	limit, offset := parsePaginationParams(r)
+
+	// Retrieve the block list via the service
+	blocks, err := h.UserService.GetBlockedUsers(r.Context(), id, limit, offset)
 	if err != nil {
-		http.Error(w, "User not found or could not be deleted", http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
-	// Respond with success
+	// Respond with the block list
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "User deleted successfully"})
+	json.NewEncoder(w).Encode(blocks)
 }
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"redditclone/internal/apperr"
 	"redditclone/internal/models"
 	"redditclone/internal/service"
 
@@ -28,12 +29,12 @@ func (h *VoteHandler) CastVote(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	targetIDStr, ok := vars["id"] // post or comment ID from URL
 	if !ok {
-		http.Error(w, "Target ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Target ID is required"))
 		return
 	}
 	_, err := strconv.Atoi(targetIDStr)
 	if err != nil {
-		http.Error(w, "Invalid Target ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Target ID"))
 		return
 	}
 
@@ -42,16 +43,16 @@ func (h *VoteHandler) CastVote(w http.ResponseWriter, r *http.Request) {
 
 	err = json.NewDecoder(r.Body).Decode(&vote)
 	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
 		return
 	}
 
-	// TODO: Extract the user's ID from the authenticated context
-	// For simplicity, assume it's provided in the request body
-	if vote.UserID == 0 {
-		http.Error(w, "User ID is required", http.StatusBadRequest)
+	// The voter is always the signed-in user
+	userID, ok := signedInUserID(w, r, "vote")
+	if !ok {
 		return
 	}
+	vote.UserID = userID
 
 	// Determine if the vote is on a post or comment based on the URL or request body
 	// For simplicity, assume the client specifies the target type
@@ -60,10 +61,10 @@ func (h *VoteHandler) CastVote(w http.ResponseWriter, r *http.Request) {
 	// 	PostID    int    `json:"post_id,omitempty"`
 	// 	CommentID int    `json:"comment_id,omitempty"`
 	// }
-	
+
 	// err = json.NewDecoder(r.Body).Decode(&payload)
 	// if err != nil {
-	// 	http.Error(w, "Invalid request payload to payload", http.StatusBadRequest)
+	// 	writeError(w, r, apperr.BadRequest("Invalid request payload to payload"))
 	// 	return
 	// }
 
@@ -71,7 +72,7 @@ func (h *VoteHandler) CastVote(w http.ResponseWriter, r *http.Request) {
 	// if payload.VoteType == "upvote" || payload.VoteType == "downvote" {
 	// 	vote.VoteType = payload.VoteType //models.VoteType(payload.VoteType)
 	// } else {
-	// 	http.Error(w, "Invalid vote type", http.StatusBadRequest)
+	// 	writeError(w, r, apperr.BadRequest("Invalid vote type"))
 	// 	return
 	// }
 
@@ -80,14 +81,14 @@ func (h *VoteHandler) CastVote(w http.ResponseWriter, r *http.Request) {
 	// } else if payload.CommentID != 0 {
 	// 	vote.CommentID = &payload.CommentID
 	// } else {
-	// 	http.Error(w, "Either post_id or comment_id must be provided", http.StatusBadRequest)
+	// 	writeError(w, r, apperr.BadRequest("Either post_id or comment_id must be provided"))
 	// 	return
 	// }
 
 	// Cast the vote via the service
-	err = h.VoteService.CastVote(&vote)
+	err = h.VoteService.CastVote(r.Context(), &vote)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
@@ -101,29 +102,21 @@ func (h *VoteHandler) ChangeVote(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	targetIDStr, ok := vars["id"] // post or comment ID from URL
 	if !ok {
-		http.Error(w, "Target ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Target ID is required"))
 		return
 	}
 	_, err := strconv.Atoi(targetIDStr)
 	if err != nil {
-		http.Error(w, "Invalid Target ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Target ID"))
 		return
 	}
 
-	var vote models.Vote
-	// Decode the JSON request body into the Vote model
-	err = json.NewDecoder(r.Body).Decode(&vote)
-	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
-		return
-	}
-
-	// TODO: Extract the user's ID from the authenticated context
-	// For simplicity, assume it's provided in the request body
-	if vote.UserID == 0 {
-		http.Error(w, "User ID is required", http.StatusBadRequest)
+	// The voter is always the signed-in user
+	userID, ok := signedInUserID(w, r, "vote")
+	if !ok {
 		return
 	}
+	vote := models.Vote{UserID: userID}
 
 	// Determine if the vote is on a post or comment based on the URL or request body
 	var payload struct {
@@ -133,7 +126,7 @@ func (h *VoteHandler) ChangeVote(w http.ResponseWriter, r *http.Request) {
 	}
 	err = json.NewDecoder(r.Body).Decode(&payload)
 	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
 		return
 	}
 
@@ -141,7 +134,7 @@ func (h *VoteHandler) ChangeVote(w http.ResponseWriter, r *http.Request) {
 	if payload.VoteType == "upvote" || payload.VoteType == "downvote" {
 		vote.VoteType = payload.VoteType //models.VoteType(payload.VoteType)
 	} else {
-		http.Error(w, "Invalid vote type", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid vote type"))
 		return
 	}
 
@@ -150,14 +143,14 @@ func (h *VoteHandler) ChangeVote(w http.ResponseWriter, r *http.Request) {
 	} else if payload.CommentID != 0 {
 		vote.CommentID = &payload.CommentID
 	} else {
-		http.Error(w, "Either post_id or comment_id must be provided", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Either post_id or comment_id must be provided"))
 		return
 	}
 
 	// Change the vote via the service
-	err = h.VoteService.ChangeVote(&vote)
+	err = h.VoteService.ChangeVote(r.Context(), &vote)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
@@ -171,40 +164,43 @@ func (h *VoteHandler) RemoveVote(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	targetIDStr, ok := vars["id"] // post or comment ID from URL
 	if !ok {
-		http.Error(w, "Target ID is required", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Target ID is required"))
 		return
 	}
 	_, err := strconv.Atoi(targetIDStr)
 	if err != nil {
-		http.Error(w, "Invalid Target ID", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Invalid Target ID"))
+		return
+	}
+
+	// The voter is always the signed-in user
+	userID, ok := signedInUserID(w, r, "vote")
+	if !ok {
 		return
 	}
 
-	// TODO: Extract the user's ID from the authenticated context
-	// For simplicity, assume it's provided in the request body
 	var payload struct {
-		UserID    int `json:"user_id"`
 		PostID    int `json:"post_id,omitempty"`
 		CommentID int `json:"comment_id,omitempty"`
 	}
 	err = json.NewDecoder(r.Body).Decode(&payload)
-	if err != nil || payload.UserID == 0 {
-		http.Error(w, "Invalid request payload: user_id is required", http.StatusBadRequest)
+	if err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid request payload"))
 		return
 	}
 
 	// Determine if the vote is on a post or comment
 	if payload.PostID != 0 {
-		err = h.VoteService.RemoveVote(payload.UserID, payload.PostID, 0)
+		err = h.VoteService.RemoveVote(r.Context(), userID, payload.PostID, 0)
 	} else if payload.CommentID != 0 {
-		err = h.VoteService.RemoveVote(payload.UserID, 0, payload.CommentID)
+		err = h.VoteService.RemoveVote(r.Context(), userID, 0, payload.CommentID)
 	} else {
-		http.Error(w, "Either post_id or comment_id must be provided", http.StatusBadRequest)
+		writeError(w, r, apperr.BadRequest("Either post_id or comment_id must be provided"))
 		return
 	}
 
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
@@ -212,3 +208,48 @@ func (h *VoteHandler) RemoveVote(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Vote removed successfully"})
 }
+
+// GetPostVote retrieves the caller's vote on a post.
+func (h *VoteHandler) GetPostVote(w http.ResponseWriter, r *http.Request) {
+	h.getVote(w, r, true)
+}
+
+// GetCommentVote retrieves the caller's vote on a comment.
+func (h *VoteHandler) GetCommentVote(w http.ResponseWriter, r *http.Request) {
+	h.getVote(w, r, false)
+}
+
+func (h *VoteHandler) getVote(w http.ResponseWriter, r *http.Request, onPost bool) {
+	vars := mux.Vars(r)
+	targetIDStr, ok := vars["id"] // post or comment ID from URL
+	if !ok {
+		writeError(w, r, apperr.BadRequest("Target ID is required"))
+		return
+	}
+	targetID, err := strconv.Atoi(targetIDStr)
+	if err != nil {
+		writeError(w, r, apperr.BadRequest("Invalid Target ID"))
+		return
+	}
+
+	userID, ok := signedInUserID(w, r, "view your vote")
+	if !ok {
+		return
+	}
+
+	// Retrieve the vote via the service
+	var vote *models.Vote
+	if onPost {
+		vote, err = h.VoteService.GetVote(r.Context(), userID, targetID, 0)
+	} else {
+		vote, err = h.VoteService.GetVote(r.Context(), userID, 0, targetID)
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Respond with the vote
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(vote)
+}
@@ -0,0 +1,98 @@
+// File: internal/apperr/apperr.go
+
+// Package apperr defines the typed errors shared by the repositories, services
+// and handlers. Each error carries a kind that the handlers map to an HTTP
+// status code; check for a kind with errors.Is and the matching sentinel.
+package apperr
+
+import (
+	"errors"
+	"time"
+)
+
+// Sentinel errors, one per kind. Match them with errors.Is.
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrValidation   = errors.New("validation failed")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrRateLimited  = errors.New("rate limited")
+)
+
+// Error is a domain error of a particular kind.
+type Error struct {
+	Kind    error             // One of the sentinel errors above.
+	Message string            // Human-readable message, prefixed with the operation, e.g. "GetPostByID: post not found".
+	Fields  map[string]string // Per-field problems, for validation errors.
+	// RetryAfter is how long a rate-limited client should wait before trying again.
+	RetryAfter time.Duration
+}
+
+// Error returns the message.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is the error's kind, so errors.Is(err, ErrNotFound) works.
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// BadRequest reports a request that could not be understood, such as a
+// malformed body or path parameter.
+func BadRequest(message string) error {
+	return &Error{Kind: ErrBadRequest, Message: message}
+}
+
+// Validation reports well-formed input that breaks a rule. fields maps each
+// offending field to what is wrong with it and may be nil.
+func Validation(message string, fields map[string]string) error {
+	return &Error{Kind: ErrValidation, Message: message, Fields: fields}
+}
+
+// Unauthorized reports missing or invalid credentials.
+func Unauthorized(message string) error {
+	return &Error{Kind: ErrUnauthorized, Message: message}
+}
+
+// Forbidden reports a caller who is known but not allowed to do something.
+func Forbidden(message string) error {
+	return &Error{Kind: ErrForbidden, Message: message}
+}
+
+// NotFound reports a record that does not exist.
+func NotFound(message string) error {
+	return &Error{Kind: ErrNotFound, Message: message}
+}
+
+// Conflict reports a write that clashes with existing data, such as a
+// duplicate name.
+func Conflict(message string) error {
+	return &Error{Kind: ErrConflict, Message: message}
+}
+
+// RateLimited reports a client that has made too many requests and must wait
+// retryAfter before trying again.
+func RateLimited(message string, retryAfter time.Duration) error {
+	return &Error{Kind: ErrRateLimited, Message: message, RetryAfter: retryAfter}
+}
+
+// FieldsOf returns the per-field details of a validation error, or nil.
+func FieldsOf(err error) map[string]string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Fields
+	}
+	return nil
+}
+
+// RetryAfterOf returns how long a rate-limited client should wait, or 0.
+func RetryAfterOf(err error) time.Duration {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.RetryAfter
+	}
+	return 0
+}
@@ -0,0 +1,10 @@
+// File: internal/models/activity.go
+
+package models
+
+// ActivityItem is one entry in a user's activity listing: either a post or a comment.
+type ActivityItem struct {
+	Kind    string   `json:"kind"`              // "post" or "comment".
+	Post    *Post    `json:"post,omitempty"`    // The post, when Kind is "post".
+	Comment *Comment `json:"comment,omitempty"` // The comment, when Kind is "comment".
+}
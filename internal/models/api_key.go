@@ -0,0 +1,54 @@
+// File: internal/models/api_key.go
+
+package models
+
+import "time"
+
+// Scopes limit what API keys and OAuth2 clients may do. Each route they may
+// call needs one of them.
+const (
+	ScopeRead     = "read"     // Read posts, comments, subreddits, profiles and votes.
+	ScopeSubmit   = "submit"   // Create, edit and delete posts and comments; join and leave subreddits.
+	ScopeVote     = "vote"     // Cast, change and remove votes.
+	ScopeMessage  = "message"  // Read and send private messages.
+	ScopeModerate = "moderate" // Create, edit and delete subreddits.
+)
+
+// Scopes lists every scope, in the order they are documented.
+var Scopes = []string{ScopeRead, ScopeSubmit, ScopeVote, ScopeMessage, ScopeModerate}
+
+// APIKey is a long-lived credential a user creates for a script or bot.
+type APIKey struct {
+	ID         int        `json:"id"`                     // Unique identifier for the key.
+	UserID     int        `json:"user_id"`                // ID of the user the key acts as.
+	Name       string     `json:"name"`                   // Label chosen by the owner.
+	Prefix     string     `json:"prefix"`                 // First characters of the key, to tell keys apart.
+	KeyHash    string     `json:"-"`                      // SHA-256 hash of the key, hex encoded.
+	Scopes     []string   `json:"scopes"`                 // What the key may do; see the Scope* constants.
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`   // When the key stops working; nil if it does not expire.
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"` // When the key was last used, to the minute; nil if never.
+	LastUsedIP string     `json:"last_used_ip,omitempty"` // Client IP address the key was last used from.
+	CreatedAt  time.Time  `json:"created_at"`             // Timestamp of when the key was created.
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`   // When the key was revoked; nil while active.
+}
+
+// HasScope reports whether the key grants scope.
+func (k *APIKey) HasScope(scope string) bool {
+	return hasScope(k.Scopes, scope)
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// NewAPIKey is the response to creating an API key, the only time the key
+// itself is shown.
+type NewAPIKey struct {
+	APIKey
+	Key string `json:"key"` // Sent as "Authorization: Bearer <key>" or "X-API-Key: <key>".
+}
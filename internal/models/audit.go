@@ -0,0 +1,54 @@
+// File: internal/models/audit.go
+
+package models
+
+import "time"
+
+// Audited admin actions, and what the account purge does on its own, which
+// is recorded with actor_id 0.
+const (
+	AuditRoleChanged          = "user.role_changed"       // An admin changed a user's role.
+	AuditUserSuspended        = "user.suspended"          // An admin suspended a user.
+	AuditUserUnsuspended      = "user.unsuspended"        // An admin lifted a suspension.
+	AuditSubredditDeleted     = "subreddit.deleted"       // An admin deleted a subreddit.
+	AuditSubredditQuarantined = "subreddit.quarantined"   // An admin quarantined a subreddit.
+	AuditSubredditReleased    = "subreddit.unquarantined" // An admin lifted a quarantine.
+	AuditSubredditTransferred = "subreddit.transferred"   // An admin gave a subreddit a new owner.
+	AuditImpersonationStarted = "impersonation.started"   // An admin opened a session as a user.
+	AuditImpersonatedRequest  = "impersonation.request"   // A request made in such a session.
+	AuditUserDeleted          = "user.deleted"            // A deleted account was anonymized.
+	AuditVoteReportViewed     = "votes.report_viewed"     // An admin viewed the vote-integrity report.
+)
+
+// Audit target types.
+const (
+	AuditTargetUser      = "user"
+	AuditTargetSubreddit = "subreddit"
+	AuditTargetVotes     = "votes" // The votes site-wide; the target ID is 0.
+)
+
+// Actor identifies who performed an audited action, and from where.
+type Actor struct {
+	UserID int    // ID of the admin.
+	IP     string // Client IP address of the request.
+}
+
+// AuditEntry is one entry in the admin audit log.
+type AuditEntry struct {
+	ID         int       `json:"id"`                // Unique identifier for the entry.
+	ActorID    int       `json:"actor_id"`          // ID of the admin who acted.
+	Action     string    `json:"action"`            // One of the Audit* actions.
+	TargetType string    `json:"target_type"`       // What was acted on: "user", "subreddit" or "votes".
+	TargetID   int       `json:"target_id"`         // ID of the user or subreddit acted on; 0 for votes.
+	Details    string    `json:"details,omitempty"` // Reason given, or what changed.
+	IP         string    `json:"ip"`                // Client IP address of the admin.
+	CreatedAt  time.Time `json:"created_at"`        // Timestamp of the action.
+}
+
+// AuditFilter narrows a search of the audit log. Zero fields match anything.
+type AuditFilter struct {
+	ActorID    int    // Only entries by this admin.
+	Action     string // Only entries with this action.
+	TargetType string // Only entries about this kind of target.
+	TargetID   int    // Only entries about this target; needs TargetType.
+}
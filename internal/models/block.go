@@ -0,0 +1,13 @@
+// File: internal/models/block.go
+
+package models
+
+import "time"
+
+// Block represents one user blocking another.
+type Block struct {
+	ID        int       `json:"id"`         // Unique identifier for the block.
+	BlockerID int       `json:"blocker_id"` // ID of the user who created the block.
+	BlockedID int       `json:"blocked_id"` // ID of the user being blocked.
+	CreatedAt time.Time `json:"created_at"` // Timestamp of when the block was created.
+}
@@ -0,0 +1,15 @@
+// File: internal/models/health.go
+
+package models
+
+// Health statuses reported by the health and readiness checks.
+const (
+	HealthOK          = "ok"
+	HealthUnavailable = "unavailable"
+)
+
+// HealthReport is the result of a health or readiness check.
+type HealthReport struct {
+	Status string            `json:"status"` // "ok" when every check passed, otherwise "unavailable".
+	Checks map[string]string `json:"checks"` // Outcome of each check: "ok" or what went wrong.
+}
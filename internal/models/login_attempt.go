@@ -0,0 +1,37 @@
+// File: internal/models/login_attempt.go
+
+package models
+
+import "time"
+
+// Login attempt results.
+const (
+	LoginSucceeded = "success"             // The password, and the two-factor code if required, was correct.
+	LoginFailed    = "invalid_credentials" // Unknown username, wrong password or wrong two-factor code.
+	LoginThrottled = "throttled"           // Rejected without checking the password while backing off.
+	LoginLocked    = "locked"              // Rejected without checking the password during a lockout.
+	LoginSuspended = "suspended"           // The credentials were correct but the account is suspended.
+)
+
+// LoginAttempt is one entry in the audit trail of login attempts.
+type LoginAttempt struct {
+	ID        int       `json:"id"`                // Unique identifier for the attempt.
+	UserID    *int      `json:"user_id,omitempty"` // Account the username belongs to; nil if it matches none.
+	Username  string    `json:"username"`          // Username as submitted.
+	IP        string    `json:"ip"`                // Client IP address.
+	UserAgent string    `json:"user_agent"`        // Client User-Agent header.
+	Result    string    `json:"result"`            // One of the Login* results.
+	CreatedAt time.Time `json:"created_at"`        // Timestamp of the attempt.
+}
+
+// LoginFailures summarizes recent failed login attempts for a username or IP.
+type LoginFailures struct {
+	Count int       // Number of failed attempts.
+	Last  time.Time // Time of the latest one; zero if Count is 0.
+}
+
+// ClientInfo identifies the client making a request.
+type ClientInfo struct {
+	IP        string // Client IP address.
+	UserAgent string // User-Agent header.
+}
@@ -0,0 +1,124 @@
+// File: internal/models/oauth.go
+
+package models
+
+import "time"
+
+// OAuth token revocation reasons.
+const (
+	RevokedRotated      = "rotated"         // The refresh token was exchanged for a new pair.
+	RevokedConsent      = "consent_revoked" // The user revoked the client's access.
+	RevokedCodeReuse    = "code_reused"     // The authorization code came back, so it was stolen.
+	RevokedRefreshReuse = "refresh_reused"  // A rotated refresh token came back, so it was stolen.
+)
+
+// OAuthClient is a third-party application registered to act on behalf of
+// users through OAuth2.
+type OAuthClient struct {
+	ID           string    `json:"client_id"`     // Public client identifier.
+	OwnerID      int       `json:"owner_id"`      // ID of the user who registered the client.
+	Name         string    `json:"name"`          // Name shown to users when they are asked for consent.
+	SecretHash   string    `json:"-"`             // SHA-256 hash of the client secret; empty for public clients.
+	Confidential bool      `json:"confidential"`  // Whether the client authenticates with a secret.
+	RedirectURIs []string  `json:"redirect_uris"` // Where authorization responses may be sent, matched exactly.
+	Scopes       []string  `json:"scopes"`        // The most the client may ask users for.
+	CreatedAt    time.Time `json:"created_at"`    // Timestamp of when the client was registered.
+}
+
+// NewOAuthClient is the response to registering a client, the only time a
+// confidential client's secret is shown.
+type NewOAuthClient struct {
+	OAuthClient
+	Secret string `json:"client_secret,omitempty"` // Sent with token requests by confidential clients.
+}
+
+// OAuthConsent records the scopes a user granted a client.
+type OAuthConsent struct {
+	ID         int        `json:"-"`                    // Unique identifier for the consent.
+	UserID     int        `json:"user_id"`              // ID of the user who granted access.
+	ClientID   string     `json:"client_id"`            // Client the access was granted to.
+	ClientName string     `json:"client_name"`          // Name of the client, for display.
+	Scopes     []string   `json:"scopes"`               // Scopes granted.
+	CreatedAt  time.Time  `json:"created_at"`           // When access was first granted.
+	UpdatedAt  time.Time  `json:"updated_at"`           // When the grant last changed.
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"` // When the user revoked access; nil while active.
+}
+
+// AuthorizationRequest is an OAuth2 authorization code request, with PKCE.
+type AuthorizationRequest struct {
+	ResponseType        string `json:"response_type"`         // Must be "code".
+	ClientID            string `json:"client_id"`             // Client asking for access.
+	RedirectURI         string `json:"redirect_uri"`          // One of the client's redirect URIs; optional if it has only one.
+	Scope               string `json:"scope"`                 // Space-separated scopes; the client's scopes if empty.
+	State               string `json:"state"`                 // Opaque value echoed back to the client.
+	CodeChallenge       string `json:"code_challenge"`        // base64url SHA-256 of the client's code verifier.
+	CodeChallengeMethod string `json:"code_challenge_method"` // Must be "S256".
+}
+
+// AuthorizationPrompt describes an authorization request for the user to
+// approve or deny.
+type AuthorizationPrompt struct {
+	ClientID          string   `json:"client_id"`          // Client asking for access.
+	ClientName        string   `json:"client_name"`        // Name of the client.
+	Scopes            []string `json:"scopes"`             // Scopes asked for.
+	RedirectURI       string   `json:"redirect_uri"`       // Where the answer will be sent.
+	State             string   `json:"state,omitempty"`    // Echoed back to the client.
+	PreviouslyGranted bool     `json:"previously_granted"` // Whether the user already granted all of these scopes.
+}
+
+// OAuthCode is an authorization code issued to a client. Only a hash of the
+// code is stored.
+type OAuthCode struct {
+	ID            int        // Unique identifier for the code.
+	CodeHash      string     // SHA-256 hash of the code, hex encoded.
+	ClientID      string     // Client the code was issued to.
+	UserID        int        // User who approved the request.
+	RedirectURI   string     // Redirect URI of the request, repeated when exchanging the code.
+	Scopes        []string   // Scopes approved.
+	CodeChallenge string     // PKCE challenge the code verifier must match.
+	ExpiresAt     time.Time  // When the code stops working.
+	UsedAt        *time.Time // When the code was exchanged; nil if it has not been.
+	CreatedAt     time.Time  // Timestamp of when the code was issued.
+}
+
+// OAuthToken is an access and refresh token pair issued to a client. Only
+// hashes of the tokens are stored.
+type OAuthToken struct {
+	ID               int        // Unique identifier for the pair.
+	ClientID         string     // Client the tokens were issued to.
+	UserID           int        // User the client acts for.
+	AccessTokenHash  string     // SHA-256 hash of the access token, hex encoded.
+	AccessExpiresAt  time.Time  // When the access token stops working.
+	RefreshTokenHash string     // SHA-256 hash of the refresh token, hex encoded.
+	RefreshExpiresAt time.Time  // When the refresh token stops working.
+	Scopes           []string   // Scopes the tokens carry.
+	CreatedAt        time.Time  // Timestamp of when the pair was issued.
+	RevokedAt        *time.Time // When the pair was revoked; nil while active.
+	RevokeReason     string     // One of the OAuth Revoked* reasons.
+}
+
+// HasScope reports whether the token grants scope.
+func (t *OAuthToken) HasScope(scope string) bool {
+	return hasScope(t.Scopes, scope)
+}
+
+// TokenRequest is a request to the OAuth2 token endpoint.
+type TokenRequest struct {
+	GrantType    string // "authorization_code" or "refresh_token".
+	ClientID     string // Client making the request.
+	ClientSecret string // Secret of a confidential client.
+	Code         string // Authorization code, for authorization_code.
+	RedirectURI  string // Redirect URI the code was sent to, for authorization_code.
+	CodeVerifier string // PKCE code verifier, for authorization_code.
+	RefreshToken string // Refresh token, for refresh_token.
+	Scope        string // Optional narrower scopes, for refresh_token.
+}
+
+// TokenResponse is the OAuth2 token endpoint's successful response.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`  // Sent as "Authorization: Bearer <token>".
+	TokenType    string `json:"token_type"`    // Always "Bearer".
+	ExpiresIn    int    `json:"expires_in"`    // Seconds until the access token expires.
+	RefreshToken string `json:"refresh_token"` // Exchanged once for a new pair.
+	Scope        string `json:"scope"`         // Space-separated scopes granted.
+}
@@ -0,0 +1,30 @@
+// File: internal/models/profile.go
+
+package models
+
+import "time"
+
+// UserProfile is the public view of a user, safe to show to anyone.
+type UserProfile struct {
+	ID             int       `json:"id"`                // Unique identifier for the user.
+	Username       string    `json:"username"`          // Unique username chosen by the user.
+	IsBot          bool      `json:"is_bot"`            // Whether the account is run by automation.
+	PostKarma      int       `json:"post_karma"`        // Karma earned from votes on the user's posts.
+	CommentKarma   int       `json:"comment_karma"`     // Karma earned from votes on the user's comments.
+	Karma          int       `json:"karma"`             // Total of post and comment karma.
+	PostCount      int       `json:"post_count"`        // Number of posts the user has submitted.
+	CommentCount   int       `json:"comment_count"`     // Number of comments the user has written.
+	AccountAgeDays int       `json:"account_age_days"`  // Whole days since the account was created.
+	CreatedAt      time.Time `json:"created_at"`        // Timestamp of when the user was created.
+	Deleted        bool      `json:"deleted,omitempty"` // Whether the account was deleted; its username is then DeletedUsername.
+}
+
+// PrivateUserProfile is the owner's view of their own profile.
+type PrivateUserProfile struct {
+	UserProfile
+	Email               string     `json:"email"`                           // User's email address.
+	EmailVerified       bool       `json:"email_verified"`                  // Whether the user has confirmed the email address.
+	TwoFactorEnabled    bool       `json:"two_factor_enabled"`              // Whether logging in also takes a TOTP code.
+	UpdatedAt           time.Time  `json:"updated_at"`                      // Timestamp of the last update to the user's information.
+	DeletionRequestedAt *time.Time `json:"deletion_requested_at,omitempty"` // When the owner asked to delete the account; nil if they have not.
+}
@@ -0,0 +1,54 @@
+// File: internal/models/session.go
+
+package models
+
+import "time"
+
+// Session revocation reasons.
+const (
+	RevokedByUser          = "revoked"              // The user signed the session out.
+	RevokedLogout          = "logout"               // The session signed itself out.
+	RevokedPasswordChanged = "password_changed"     // The password changed on another session.
+	RevokedPasswordReset   = "password_reset"       // The password was reset by email.
+	RevokedTokenReuse      = "refresh_token_reused" // A used refresh token came back, so one was stolen.
+	RevokedSuspended       = "suspended"            // An admin suspended the account.
+	RevokedAccountDeleted  = "account_deleted"      // The owner deleted the account.
+)
+
+// Session is a signed-in device or client.
+type Session struct {
+	ID              int        `json:"id"`                        // Unique identifier for the session.
+	UserID          int        `json:"user_id"`                   // ID of the signed-in user.
+	AccessTokenHash string     `json:"-"`                         // SHA-256 hash of the current access token.
+	AccessExpiresAt time.Time  `json:"-"`                         // When the current access token stops working.
+	UserAgent       string     `json:"user_agent"`                // User-Agent of the client at sign-in.
+	IP              string     `json:"ip"`                        // Client IP address last seen.
+	CreatedAt       time.Time  `json:"created_at"`                // When the user signed in.
+	LastSeenAt      time.Time  `json:"last_seen_at"`              // When the session was last used, to the minute.
+	ExpiresAt       time.Time  `json:"expires_at"`                // When the session ends unless refreshed.
+	RevokedAt       *time.Time `json:"revoked_at,omitempty"`      // When the session was revoked; nil while active.
+	RevokeReason    string     `json:"revoke_reason,omitempty"`   // One of the Revoked* reasons.
+	Current         bool       `json:"current"`                   // Whether this is the session making the request.
+	ImpersonatorID  *int       `json:"impersonator_id,omitempty"` // ID of the admin who opened the session as the user; nil for the user's own.
+}
+
+// SessionTokens are the credentials handed to a client when a session is
+// created or refreshed.
+type SessionTokens struct {
+	SessionID             int       `json:"session_id"`               // ID of the session.
+	TokenType             string    `json:"token_type"`               // Always "Bearer".
+	AccessToken           string    `json:"access_token"`             // Sent as "Authorization: Bearer <token>".
+	AccessTokenExpiresAt  time.Time `json:"access_token_expires_at"`  // When to refresh.
+	RefreshToken          string    `json:"refresh_token,omitempty"`  // Exchanged once for new tokens; none for impersonation.
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"` // When the session ends unless refreshed.
+}
+
+// RefreshToken is one link in a session's chain of refresh tokens. Only a
+// hash of the token is stored.
+type RefreshToken struct {
+	ID        int        // Unique identifier for the token.
+	SessionID int        // ID of the session the token refreshes.
+	TokenHash string     // SHA-256 hash of the token, hex encoded.
+	UsedAt    *time.Time // When the token was exchanged; nil if it has not been.
+	CreatedAt time.Time  // Timestamp of when the token was issued.
+}
@@ -0,0 +1,53 @@
+// File: internal/models/two_factor.go
+
+package models
+
+import "time"
+
+// TwoFactor is a user's TOTP two-factor authentication state.
+type TwoFactor struct {
+	UserID    int        // ID of the user.
+	Secret    string     // Encrypted TOTP secret; empty if the user never enrolled.
+	EnabledAt *time.Time // When enrollment was confirmed; nil while pending or off.
+	LastStep  *int64     // Time step of the last accepted code; nil if none.
+}
+
+// TwoFactorEnrollment is what a user needs to add their account to an
+// authenticator app.
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"` // Base32 TOTP secret, for entering by hand.
+	URI    string `json:"uri"`    // otpauth:// provisioning URI, usually shown as a QR code.
+}
+
+// TwoFactorStatus describes a user's two-factor authentication settings.
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`                  // Whether logging in takes a code.
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`     // When it was turned on.
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"` // Unused recovery codes.
+}
+
+// TwoFactorChallenge is handed out when a password was correct but a
+// two-factor code is still needed. The client sends it back with the code.
+type TwoFactorChallenge struct {
+	Token     string    `json:"challenge"`  // Signed token naming the user.
+	ExpiresAt time.Time `json:"expires_at"` // After this the password must be entered again.
+}
+
+// LoginResult is the outcome of a correct password: either the signed-in
+// user with their new session, or a challenge when the user has two-factor
+// authentication on.
+type LoginResult struct {
+	User      *User               // Set when the login is complete.
+	Session   *SessionTokens      // Set with User.
+	Challenge *TwoFactorChallenge // Set when a code is still needed.
+}
+
+// RecoveryCode is a one-time code for signing in without the authenticator
+// app. Only a hash of the code is stored.
+type RecoveryCode struct {
+	ID        int        // Unique identifier for the code.
+	UserID    int        // ID of the user the code belongs to.
+	CodeHash  string     // SHA-256 hash of the normalized code, hex encoded.
+	UsedAt    *time.Time // When the code was used; nil if it has not been.
+	CreatedAt time.Time  // Timestamp of when the code was generated.
+}
@@ -2,14 +2,57 @@
 
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
+// User roles.
+const (
+	RoleUser  = "user"  // An ordinary account.
+	RoleAdmin = "admin" // A site administrator, allowed to use the /admin routes.
+)
+
+// DeletedUsername is shown in place of the username of a deleted account.
+// New usernames may not start with it.
+const DeletedUsername = "[deleted]"
+
+// DeletedAccount returns the username and email address a deleted account is
+// left with, which keep both columns unique without saying who owned it.
+func DeletedAccount(id int) (username, email string) {
+	return fmt.Sprintf("%s-%d", DeletedUsername, id), fmt.Sprintf("deleted-%d@deleted.invalid", id)
+}
 
 // User represents a registered user in the system.
 type User struct {
-	ID        int       `json:"id"`                  // Unique identifier for the user.
-	Username  string    `json:"username"`            // Unique username chosen by the user.
-	Email     string    `json:"email"`               // User's email address.
-	Password  string    `json:"password"`                   // Hashed password. Omitted from JSON responses for security.
-	CreatedAt time.Time `json:"created_at"`          // Timestamp of when the user was created.
-	UpdatedAt time.Time `json:"updated_at"`          // Timestamp of the last update to the user's information.
+	ID                  int        `json:"id"`                              // Unique identifier for the user.
+	Username            string     `json:"username"`                        // Unique username chosen by the user.
+	Email               string     `json:"email"`                           // User's email address.
+	Password            string     `json:"password,omitempty"`              // Hashed password. Omitted from JSON responses for security.
+	CreatedAt           time.Time  `json:"created_at"`                      // Timestamp of when the user was created.
+	UpdatedAt           time.Time  `json:"updated_at"`                      // Timestamp of the last update to the user's information.
+	EmailVerifiedAt     *time.Time `json:"email_verified_at,omitempty"`     // When the current email was verified; nil if it is not.
+	TOTPEnabledAt       *time.Time `json:"-"`                               // When two-factor authentication was turned on; nil if it is off.
+	IsBot               bool       `json:"is_bot"`                          // Whether the account is run by automation.
+	Role                string     `json:"role"`                            // One of the Role* constants.
+	SuspendedAt         *time.Time `json:"suspended_at,omitempty"`          // When an admin suspended the account; nil if it is not suspended.
+	SuspensionReason    string     `json:"suspension_reason,omitempty"`     // Why the account was suspended.
+	DeletionRequestedAt *time.Time `json:"deletion_requested_at,omitempty"` // When the owner asked to delete the account; nil if they have not.
+	DeletedAt           *time.Time `json:"deleted_at,omitempty"`            // When the account was anonymized; nil while it exists.
+}
+
+// AccountDeletion describes a scheduled account deletion.
+type AccountDeletion struct {
+	RequestedAt time.Time `json:"requested_at"` // When the owner asked to delete the account.
+	PurgeAt     time.Time `json:"purge_at"`     // When the account will be anonymized, unless the deletion is cancelled first.
+}
+
+// IsAdmin reports whether the user is a site administrator.
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// IsDeleted reports whether the account was deleted, or is waiting to be.
+func (u *User) IsDeleted() bool {
+	return u.DeletionRequestedAt != nil || u.DeletedAt != nil
 }
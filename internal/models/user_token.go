@@ -0,0 +1,24 @@
+// File: internal/models/user_token.go
+
+package models
+
+import "time"
+
+// User token purposes.
+const (
+	TokenVerifyEmail   = "verify_email"   // Confirms the user owns their email address.
+	TokenResetPassword = "reset_password" // Lets the user choose a new password.
+)
+
+// UserToken is a single-use, expiring token mailed to a user. Only a hash of
+// the token is stored; the token itself exists only in the email.
+type UserToken struct {
+	ID        int        // Unique identifier for the token.
+	UserID    int        // ID of the user the token was issued to.
+	Purpose   string     // One of the Token* purposes.
+	TokenHash string     // SHA-256 hash of the token, hex encoded.
+	Email     string     // Address the token was sent to.
+	ExpiresAt time.Time  // After this the token is no longer accepted.
+	UsedAt    *time.Time // When the token was used; nil if it has not been.
+	CreatedAt time.Time  // Timestamp of when the token was issued.
+}
@@ -0,0 +1,33 @@
+// File: internal/models/vote_flag.go
+
+package models
+
+import "time"
+
+// VoteFlag records why a vote was judged suspicious. Flagged votes stay stored
+// but do not count toward displayed karma.
+type VoteFlag struct {
+	VoteID    int       `json:"vote_id"`              // ID of the flagged vote.
+	UserID    int       `json:"user_id"`              // ID of the user who cast the vote.
+	PostID    *int      `json:"post_id,omitempty"`    // ID of the post voted on (if applicable).
+	CommentID *int      `json:"comment_id,omitempty"` // ID of the comment voted on (if applicable).
+	VoteType  string    `json:"vote_type"`            // Type of vote: upvote or downvote.
+	Score     float64   `json:"score"`                // Suspicion score; votes at or above the threshold are flagged.
+	Reasons   []string  `json:"reasons"`              // Patterns that contributed to the score.
+	CreatedAt time.Time `json:"created_at"`           // Timestamp of when the vote was flagged.
+}
+
+// FlaggedTarget summarizes the flagged votes on a single post or comment.
+type FlaggedTarget struct {
+	PostID       *int `json:"post_id,omitempty"`    // ID of the post (if applicable).
+	CommentID    *int `json:"comment_id,omitempty"` // ID of the comment (if applicable).
+	FlaggedVotes int  `json:"flagged_votes"`        // Number of flagged votes on the target.
+}
+
+// VoteIntegrityReport is the admin view of suspicious voting activity.
+type VoteIntegrityReport struct {
+	TotalFlagged int              `json:"total_flagged"` // Number of flagged votes currently stored.
+	ByReason     map[string]int   `json:"by_reason"`     // Number of flagged votes per reason.
+	TopTargets   []*FlaggedTarget `json:"top_targets"`   // Targets with the most flagged votes.
+	Flags        []*VoteFlag      `json:"flags"`         // Most recent flagged votes, paginated.
+}
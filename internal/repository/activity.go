@@ -0,0 +1,128 @@
+// File: internal/repository/activity.go
+
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"redditclone/internal/models"
+)
+
+// Sort orders supported by the user activity listings.
+const (
+	SortNew           = "new"
+	SortTop           = "top"
+	SortControversial = "controversial"
+)
+
+// controversyExpr ranks items with many votes that are evenly split between
+// upvotes and downvotes highest. It expects ups and downs columns in scope.
+const controversyExpr = `
+	CASE
+		WHEN ups = 0 OR downs = 0 THEN 0
+		WHEN ups > downs THEN (ups + downs) * downs * 1.0 / ups
+		ELSE (ups + downs) * ups * 1.0 / downs
+	END`
+
+// activityOrderBy returns the ORDER BY clause for the given sort order.
+// Unknown sort orders fall back to newest first.
+func activityOrderBy(sort string) string {
+	switch sort {
+	case SortTop:
+		return "karma DESC, created_at DESC, id DESC"
+	case SortControversial:
+		return controversyExpr + " DESC, created_at DESC, id DESC"
+	default:
+		return "created_at DESC, id DESC"
+	}
+}
+
+// activityColumns lists the shared activity column layout read by scanActivityItems.
+const activityColumns = `kind, id, title, content, author_id, subreddit_id,
+	post_id, parent_id, karma, created_at, updated_at, ups, downs`
+
+// activityPostColumns selects a post (aliased p) in the shared activity column
+// layout, including upvote and downvote counts for sorting.
+const activityPostColumns = `
+	'post' AS kind, p.id, p.title, p.content, p.author_id, p.subreddit_id,
+	CAST(NULL AS INTEGER) AS post_id, CAST(NULL AS INTEGER) AS parent_id, p.karma, p.created_at, p.updated_at,
+	(SELECT COUNT(*) FROM votes v WHERE v.post_id = p.id AND v.vote_type = 'upvote') AS ups,
+	(SELECT COUNT(*) FROM votes v WHERE v.post_id = p.id AND v.vote_type = 'downvote') AS downs`
+
+// activityCommentColumns selects a comment (aliased c) in the shared activity
+// column layout, including upvote and downvote counts for sorting.
+const activityCommentColumns = `
+	'comment' AS kind, c.id, CAST(NULL AS TEXT) AS title, c.content, c.author_id, CAST(NULL AS INTEGER) AS subreddit_id,
+	c.post_id, c.parent_id, c.karma, c.created_at, c.updated_at,
+	(SELECT COUNT(*) FROM votes v WHERE v.comment_id = c.id AND v.vote_type = 'upvote') AS ups,
+	(SELECT COUNT(*) FROM votes v WHERE v.comment_id = c.id AND v.vote_type = 'downvote') AS downs`
+
+// scanActivityItems reads rows in the shared activity column layout.
+func scanActivityItems(rows *sql.Rows) ([]*models.ActivityItem, error) {
+	var items []*models.ActivityItem
+	for rows.Next() {
+		var (
+			kind        string
+			id          int
+			title       sql.NullString
+			content     string
+			authorID    int
+			subredditID sql.NullInt64
+			postID      sql.NullInt64
+			parentID    *int
+			karma       int
+			createdAt   time.Time
+			updatedAt   time.Time
+			ups, downs  int
+		)
+		err := rows.Scan(
+			&kind,
+			&id,
+			&title,
+			&content,
+			&authorID,
+			&subredditID,
+			&postID,
+			&parentID,
+			&karma,
+			&createdAt,
+			&updatedAt,
+			&ups,
+			&downs,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		item := &models.ActivityItem{Kind: kind}
+		if kind == "post" {
+			item.Post = &models.Post{
+				ID:          id,
+				Title:       title.String,
+				Content:     content,
+				AuthorID:    authorID,
+				SubredditID: int(subredditID.Int64),
+				Karma:       karma,
+				CreatedAt:   createdAt,
+				UpdatedAt:   updatedAt,
+			}
+		} else {
+			item.Comment = &models.Comment{
+				ID:        id,
+				Content:   content,
+				AuthorID:  authorID,
+				PostID:    int(postID.Int64),
+				ParentID:  parentID,
+				Karma:     karma,
+				CreatedAt: createdAt,
+				UpdatedAt: updatedAt,
+			}
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
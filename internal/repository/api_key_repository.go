@@ -0,0 +1,184 @@
+// File: internal/repository/api_key_repository.go
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/pkg/database"
+	"redditclone/pkg/metrics"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// APIKeyRepository provides access to users' API keys.
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+	GetAPIKeyByHash(ctx context.Context, keyHash string, now time.Time) (*models.APIKey, error)
+	GetAPIKeysByUser(ctx context.Context, userID int) ([]*models.APIKey, error)
+	TouchAPIKey(ctx context.Context, id int, ip string) error
+	RevokeAPIKey(ctx context.Context, userID, id int) error
+}
+
+type apiKeyRepository struct {
+	DB *database.DB
+}
+
+// NewAPIKeyRepository creates a new APIKeyRepository.
+func NewAPIKeyRepository(db *database.DB) APIKeyRepository {
+	return &apiKeyRepository{DB: db}
+}
+
+// apiKeyColumns lists the columns scanned by scanAPIKey, in order.
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at,
+		COALESCE(last_used_ip, ''), created_at, revoked_at`
+
+// scanAPIKey reads a row selected with apiKeyColumns.
+func scanAPIKey(row interface{ Scan(...any) error }) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	var scopes string
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&scopes,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.LastUsedIP,
+		&key.CreatedAt,
+		&key.RevokedAt,
+	)
+	key.Scopes = strings.Fields(scopes)
+	return key, err
+}
+
+// CreateAPIKey inserts a new API key.
+func (r *apiKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	defer metrics.TimeQuery("api_key", "CreateAPIKey")()
+	ctx, span := startSpan(ctx, r.DB, "APIKeyRepository.CreateAPIKey", attribute.Int("user.id", key.UserID))
+	defer span.End()
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP)
+		RETURNING id, created_at
+	`
+	var expiresAt any
+	if key.ExpiresAt != nil {
+		expiresAt = r.DB.TimeArg(*key.ExpiresAt)
+	}
+	err := database.Retry(ctx, func() error {
+		return r.DB.Write.QueryRowContext(ctx, query, key.UserID, key.Name, key.Prefix, key.KeyHash,
+			strings.Join(key.Scopes, " "), expiresAt).
+			Scan(&key.ID, &key.CreatedAt)
+	})
+	if err != nil {
+		return writeError("CreateAPIKey", "API key", err)
+	}
+	return nil
+}
+
+// GetAPIKeyByHash retrieves the active API key with the given hash that has
+// not expired by now and whose owner is not suspended or being deleted.
+func (r *apiKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string, now time.Time) (*models.APIKey, error) {
+	defer metrics.TimeQuery("api_key", "GetAPIKeyByHash")()
+	ctx, span := startSpan(ctx, r.DB, "APIKeyRepository.GetAPIKeyByHash")
+	defer span.End()
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > $2)
+		  AND NOT EXISTS (SELECT 1 FROM users WHERE users.id = api_keys.user_id
+		                  AND (users.suspended_at IS NOT NULL OR users.deletion_requested_at IS NOT NULL))
+	`
+	key, err := scanAPIKey(r.DB.Read.QueryRowContext(ctx, query, keyHash, r.DB.TimeArg(now)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("GetAPIKeyByHash: API key not found")
+		}
+		return nil, fmt.Errorf("GetAPIKeyByHash: %v", err)
+	}
+	return key, nil
+}
+
+// GetAPIKeysByUser retrieves a user's unrevoked API keys, newest first,
+// including expired ones so the owner can see them lapse.
+func (r *apiKeyRepository) GetAPIKeysByUser(ctx context.Context, userID int) ([]*models.APIKey, error) {
+	defer metrics.TimeQuery("api_key", "GetAPIKeysByUser")()
+	ctx, span := startSpan(ctx, r.DB, "APIKeyRepository.GetAPIKeysByUser", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC, id DESC
+	`
+	rows, err := r.DB.Read.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("GetAPIKeysByUser: %v", err)
+	}
+	defer rows.Close()
+
+	var keys []*models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("GetAPIKeysByUser: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetAPIKeysByUser: %v", err)
+	}
+	span.SetAttributes(tracing.Rows(len(keys)))
+	return keys, nil
+}
+
+// TouchAPIKey records that an API key was just used, from the given IP.
+func (r *apiKeyRepository) TouchAPIKey(ctx context.Context, id int, ip string) error {
+	defer metrics.TimeQuery("api_key", "TouchAPIKey")()
+	ctx, span := startSpan(ctx, r.DB, "APIKeyRepository.TouchAPIKey", attribute.Int("api_key.id", id))
+	defer span.End()
+	query := `
+		UPDATE api_keys
+		SET last_used_at = CURRENT_TIMESTAMP, last_used_ip = $2
+		WHERE id = $1
+	`
+	if _, err := r.DB.ExecContext(ctx, query, id, ip); err != nil {
+		return fmt.Errorf("TouchAPIKey: %v", err)
+	}
+	return nil
+}
+
+// RevokeAPIKey revokes one of a user's API keys. It fails with
+// apperr.NotFound if the user has no such unrevoked key.
+func (r *apiKeyRepository) RevokeAPIKey(ctx context.Context, userID, id int) error {
+	defer metrics.TimeQuery("api_key", "RevokeAPIKey")()
+	ctx, span := startSpan(ctx, r.DB, "APIKeyRepository.RevokeAPIKey", attribute.Int("user.id", userID), attribute.Int("api_key.id", id))
+	defer span.End()
+	query := `
+		UPDATE api_keys
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+	result, err := r.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("RevokeAPIKey: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("RevokeAPIKey: %v", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.NotFound("RevokeAPIKey: API key not found")
+	}
+	return nil
+}
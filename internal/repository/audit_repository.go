@@ -0,0 +1,111 @@
+// File: internal/repository/audit_repository.go
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"redditclone/internal/models"
+	"redditclone/pkg/database"
+	"redditclone/pkg/metrics"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// AuditRepository provides access to the admin audit log.
+type AuditRepository interface {
+	CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error
+	GetAuditEntries(ctx context.Context, filter models.AuditFilter, limit, offset int) ([]*models.AuditEntry, error)
+}
+
+type auditRepository struct {
+	DB *database.DB
+}
+
+// NewAuditRepository creates a new AuditRepository.
+func NewAuditRepository(db *database.DB) AuditRepository {
+	return &auditRepository{DB: db}
+}
+
+// CreateAuditEntry records an entry in the audit log.
+func (r *auditRepository) CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	defer metrics.TimeQuery("audit", "CreateAuditEntry")()
+	ctx, span := startSpan(ctx, r.DB, "AuditRepository.CreateAuditEntry", attribute.String("audit.action", entry.Action))
+	defer span.End()
+	query := `
+		INSERT INTO audit_log (actor_id, action, target_type, target_id, details, ip, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP)
+		RETURNING id, created_at
+	`
+	err := database.Retry(ctx, func() error {
+		return r.DB.Write.QueryRowContext(ctx, query, entry.ActorID, entry.Action, entry.TargetType, entry.TargetID, entry.Details, entry.IP).
+			Scan(&entry.ID, &entry.CreatedAt)
+	})
+	if err != nil {
+		return writeError("CreateAuditEntry", "audit entry", err)
+	}
+	return nil
+}
+
+// GetAuditEntries retrieves the audit log entries matching filter, newest
+// first, with pagination.
+func (r *auditRepository) GetAuditEntries(ctx context.Context, filter models.AuditFilter, limit, offset int) ([]*models.AuditEntry, error) {
+	defer metrics.TimeQuery("audit", "GetAuditEntries")()
+	ctx, span := startSpan(ctx, r.DB, "AuditRepository.GetAuditEntries", attribute.Int("page.limit", limit), attribute.Int("page.offset", offset))
+	defer span.End()
+	conds, args := []string{"1 = 1"}, []any{}
+	where := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.ActorID != 0 {
+		where("actor_id = $%d", filter.ActorID)
+	}
+	if filter.Action != "" {
+		where("action = $%d", filter.Action)
+	}
+	if filter.TargetType != "" {
+		where("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		where("target_id = $%d", filter.TargetID)
+	}
+	query := fmt.Sprintf(`
+		SELECT id, actor_id, action, target_type, target_id, details, ip, created_at
+		FROM audit_log
+		WHERE %s
+		ORDER BY id DESC
+		LIMIT $%d OFFSET $%d
+	`, strings.Join(conds, " AND "), len(args)+1, len(args)+2)
+	rows, err := r.DB.Read.QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("GetAuditEntries: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []*models.AuditEntry
+	for rows.Next() {
+		entry := &models.AuditEntry{}
+		err := rows.Scan(
+			&entry.ID,
+			&entry.ActorID,
+			&entry.Action,
+			&entry.TargetType,
+			&entry.TargetID,
+			&entry.Details,
+			&entry.IP,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("GetAuditEntries: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetAuditEntries: %v", err)
+	}
+	return entries, nil
+}
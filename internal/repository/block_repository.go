@@ -0,0 +1,169 @@
+// File: internal/repository/block_repository.go
+
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/pkg/database"
+	"redditclone/pkg/metrics"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// BlockRepository provides access to the user blocks storage.
+type BlockRepository interface {
+	CreateBlock(ctx context.Context, block *models.Block) error
+	DeleteBlock(ctx context.Context, blockerID, blockedID int) error
+	IsBlocked(ctx context.Context, blockerID, blockedID int) (bool, error)
+	GetBlocksByUser(ctx context.Context, blockerID int, limit, offset int) ([]*models.Block, error)
+	GetBlockedUserIDs(ctx context.Context, blockerID int) ([]int, error)
+}
+
+type blockRepository struct {
+	DB *database.DB
+}
+
+// NewBlockRepository creates a new BlockRepository.
+func NewBlockRepository(db *database.DB) BlockRepository {
+	return &blockRepository{DB: db}
+}
+
+// CreateBlock inserts a new block into the database.
+func (r *blockRepository) CreateBlock(ctx context.Context, block *models.Block) error {
+	defer metrics.TimeQuery("block", "CreateBlock")()
+	ctx, span := startSpan(ctx, r.DB, "BlockRepository.CreateBlock")
+	defer span.End()
+	query := `
+		INSERT INTO blocks (blocker_id, blocked_id, created_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		RETURNING id, created_at
+	`
+	err := database.Retry(ctx, func() error {
+		return r.DB.Write.QueryRowContext(ctx, query, block.BlockerID, block.BlockedID).
+			Scan(&block.ID, &block.CreatedAt)
+	})
+	if err != nil {
+		return writeError("CreateBlock", "block", err)
+	}
+	return nil
+}
+
+// DeleteBlock removes a block between two users.
+func (r *blockRepository) DeleteBlock(ctx context.Context, blockerID, blockedID int) error {
+	defer metrics.TimeQuery("block", "DeleteBlock")()
+	ctx, span := startSpan(ctx, r.DB, "BlockRepository.DeleteBlock", attribute.Int("blocker.id", blockerID), attribute.Int("blocked.id", blockedID))
+	defer span.End()
+	query := `
+		DELETE FROM blocks
+		WHERE blocker_id = $1 AND blocked_id = $2
+	`
+	result, err := r.DB.ExecContext(ctx, query, blockerID, blockedID)
+	if err != nil {
+		return fmt.Errorf("DeleteBlock: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("DeleteBlock: %v", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.NotFound("DeleteBlock: no block found to delete")
+	}
+	return nil
+}
+
+// IsBlocked reports whether blockerID has blocked blockedID.
+func (r *blockRepository) IsBlocked(ctx context.Context, blockerID, blockedID int) (bool, error) {
+	defer metrics.TimeQuery("block", "IsBlocked")()
+	ctx, span := startSpan(ctx, r.DB, "BlockRepository.IsBlocked", attribute.Int("blocker.id", blockerID), attribute.Int("blocked.id", blockedID))
+	defer span.End()
+	query := `
+		SELECT COUNT(*)
+		FROM blocks
+		WHERE blocker_id = $1 AND blocked_id = $2
+	`
+	var count int
+	err := r.DB.Read.QueryRowContext(ctx, query, blockerID, blockedID).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("IsBlocked: %v", err)
+	}
+	return count > 0, nil
+}
+
+// GetBlocksByUser retrieves the blocks created by a user with pagination.
+func (r *blockRepository) GetBlocksByUser(ctx context.Context, blockerID int, limit, offset int) ([]*models.Block, error) {
+	defer metrics.TimeQuery("block", "GetBlocksByUser")()
+	ctx, span := startSpan(ctx, r.DB, "BlockRepository.GetBlocksByUser", attribute.Int("blocker.id", blockerID), attribute.Int("page.limit", limit), attribute.Int("page.offset", offset))
+	defer span.End()
+	query := `
+		SELECT id, blocker_id, blocked_id, created_at
+		FROM blocks
+		WHERE blocker_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.DB.Read.QueryContext(ctx, query, blockerID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("GetBlocksByUser: %v", err)
+	}
+	defer rows.Close()
+
+	var blocks []*models.Block
+	for rows.Next() {
+		block := &models.Block{}
+		err := rows.Scan(
+			&block.ID,
+			&block.BlockerID,
+			&block.BlockedID,
+			&block.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("GetBlocksByUser: %v", err)
+		}
+		blocks = append(blocks, block)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetBlocksByUser: %v", err)
+	}
+
+	span.SetAttributes(tracing.Rows(len(blocks)))
+	return blocks, nil
+}
+
+// GetBlockedUserIDs retrieves the IDs of every user blocked by blockerID.
+func (r *blockRepository) GetBlockedUserIDs(ctx context.Context, blockerID int) ([]int, error) {
+	defer metrics.TimeQuery("block", "GetBlockedUserIDs")()
+	ctx, span := startSpan(ctx, r.DB, "BlockRepository.GetBlockedUserIDs", attribute.Int("blocker.id", blockerID))
+	defer span.End()
+	query := `
+		SELECT blocked_id
+		FROM blocks
+		WHERE blocker_id = $1
+	`
+	rows, err := r.DB.Read.QueryContext(ctx, query, blockerID)
+	if err != nil {
+		return nil, fmt.Errorf("GetBlockedUserIDs: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("GetBlockedUserIDs: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetBlockedUserIDs: %v", err)
+	}
+
+	span.SetAttributes(tracing.Rows(len(ids)))
+	return ids, nil
+}
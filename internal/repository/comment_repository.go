@@ -3,60 +3,71 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
-	"errors"
 	"fmt"
+
+	"redditclone/internal/apperr"
 	"redditclone/internal/models"
 	"redditclone/pkg/database"
+	"redditclone/pkg/metrics"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // CommentRepository provides access to the comments storage.
 type CommentRepository interface {
-	CreateComment(comment *models.Comment) error
-	GetCommentByID(id int) (*models.Comment, error)
-	GetCommentsByPost(postID int, limit, offset int) ([]*models.Comment, error)
-	GetReplies(parentID int, limit, offset int) ([]*models.Comment, error)
-	UpdateComment(comment *models.Comment) error
-	DeleteComment(id int) error
+	CreateComment(ctx context.Context, comment *models.Comment) error
+	GetCommentByID(ctx context.Context, id int) (*models.Comment, error)
+	GetCommentsByPost(ctx context.Context, postID int, page Page) ([]*models.Comment, error)
+	GetReplies(ctx context.Context, parentID int, page Page) ([]*models.Comment, error)
+	GetCommentsByAuthor(ctx context.Context, authorID int, sort string, limit, offset int) ([]*models.Comment, error)
+	UpdateComment(ctx context.Context, comment *models.Comment) error
+	DeleteComment(ctx context.Context, id int) error
 }
 
 type commentRepository struct {
-	DB *sql.DB
+	DB *database.DB
 }
 
 // NewCommentRepository creates a new CommentRepository.
-func NewCommentRepository(db *sql.DB) CommentRepository {
+func NewCommentRepository(db *database.DB) CommentRepository {
 	return &commentRepository{DB: db}
 }
 
 // CreateComment inserts a new comment into the database.
-func (r *commentRepository) CreateComment(comment *models.Comment) error {
-	database.DBMu.Lock()
-	defer database.DBMu.Unlock()
+func (r *commentRepository) CreateComment(ctx context.Context, comment *models.Comment) error {
+	defer metrics.TimeQuery("comment", "CreateComment")()
+	ctx, span := startSpan(ctx, r.DB, "CommentRepository.CreateComment")
+	defer span.End()
 	query := `
 		INSERT INTO comments (content, author_id, post_id, parent_id, karma, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 		RETURNING id, created_at, updated_at
 	`
-	err := r.DB.QueryRow(query, comment.Content, comment.AuthorID, comment.PostID, comment.ParentID).
-		Scan(&comment.ID, &comment.CreatedAt, &comment.UpdatedAt)
+	err := database.Retry(ctx, func() error {
+		return r.DB.Write.QueryRowContext(ctx, query, comment.Content, comment.AuthorID, comment.PostID, comment.ParentID).
+			Scan(&comment.ID, &comment.CreatedAt, &comment.UpdatedAt)
+	})
 	if err != nil {
-		return fmt.Errorf("CreateComment: %v", err)
+		return writeError("CreateComment", "comment", err)
 	}
 	return nil
 }
 
 // GetCommentByID retrieves a comment by its ID.
-func (r *commentRepository) GetCommentByID(id int) (*models.Comment, error) {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+func (r *commentRepository) GetCommentByID(ctx context.Context, id int) (*models.Comment, error) {
+	defer metrics.TimeQuery("comment", "GetCommentByID")()
+	ctx, span := startSpan(ctx, r.DB, "CommentRepository.GetCommentByID", attribute.Int("comment.id", id))
+	defer span.End()
 	query := `
 		SELECT id, content, author_id, post_id, parent_id, karma, created_at, updated_at
 		FROM comments
 		WHERE id = $1
 	`
 	comment := &models.Comment{}
-	err := r.DB.QueryRow(query, id).Scan(
+	err := r.DB.Read.QueryRowContext(ctx, query, id).Scan(
 		&comment.ID,
 		&comment.Content,
 		&comment.AuthorID,
@@ -68,25 +79,26 @@ func (r *commentRepository) GetCommentByID(id int) (*models.Comment, error) {
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("GetCommentByID: comment not found")
+			return nil, apperr.NotFound("GetCommentByID: comment not found")
 		}
 		return nil, fmt.Errorf("GetCommentByID: %v", err)
 	}
 	return comment, nil
 }
 
-// GetCommentsByPost retrieves comments for a specific post with pagination.
-func (r *commentRepository) GetCommentsByPost(postID int, limit, offset int) ([]*models.Comment, error) {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+// GetCommentsByPost retrieves a page of top-level comments for a specific post, newest first.
+func (r *commentRepository) GetCommentsByPost(ctx context.Context, postID int, page Page) ([]*models.Comment, error) {
+	defer metrics.TimeQuery("comment", "GetCommentsByPost")()
+	ctx, span := startSpan(ctx, r.DB, "CommentRepository.GetCommentsByPost", attribute.Int("post.id", postID), attribute.Int("page.limit", page.Limit))
+	defer span.End()
+	cond, tail, args := keyset(r.DB, page, true, 2)
 	query := `
 		SELECT id, content, author_id, post_id, parent_id, karma, created_at, updated_at
 		FROM comments
 		WHERE post_id = $1 AND parent_id IS NULL
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
-	`
-	rows, err := r.DB.Query(query, postID, limit, offset)
+		  ` + cond + `
+		` + tail
+	rows, err := r.DB.Read.QueryContext(ctx, query, append([]any{postID}, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("GetCommentsByPost: %v", err)
 	}
@@ -115,21 +127,24 @@ func (r *commentRepository) GetCommentsByPost(postID int, limit, offset int) ([]
 		return nil, fmt.Errorf("GetCommentsByPost: %v", err)
 	}
 
+	reverseRows(page, comments)
+	span.SetAttributes(tracing.Rows(len(comments)))
 	return comments, nil
 }
 
-// GetReplies retrieves replies to a specific comment with pagination.
-func (r *commentRepository) GetReplies(parentID int, limit, offset int) ([]*models.Comment, error) {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+// GetReplies retrieves a page of replies to a specific comment, oldest first.
+func (r *commentRepository) GetReplies(ctx context.Context, parentID int, page Page) ([]*models.Comment, error) {
+	defer metrics.TimeQuery("comment", "GetReplies")()
+	ctx, span := startSpan(ctx, r.DB, "CommentRepository.GetReplies", attribute.Int("parent.id", parentID), attribute.Int("page.limit", page.Limit))
+	defer span.End()
+	cond, tail, args := keyset(r.DB, page, false, 2)
 	query := `
 		SELECT id, content, author_id, post_id, parent_id, karma, created_at, updated_at
 		FROM comments
 		WHERE parent_id = $1
-		ORDER BY created_at ASC
-		LIMIT $2 OFFSET $3
-	`
-	rows, err := r.DB.Query(query, parentID, limit, offset)
+		  ` + cond + `
+		` + tail
+	rows, err := r.DB.Read.QueryContext(ctx, query, append([]any{parentID}, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("GetReplies: %v", err)
 	}
@@ -158,36 +173,72 @@ func (r *commentRepository) GetReplies(parentID int, limit, offset int) ([]*mode
 		return nil, fmt.Errorf("GetReplies: %v", err)
 	}
 
+	reverseRows(page, replies)
+	span.SetAttributes(tracing.Rows(len(replies)))
 	return replies, nil
 }
 
+// GetCommentsByAuthor retrieves the comments written by a user in the given sort order with pagination.
+func (r *commentRepository) GetCommentsByAuthor(ctx context.Context, authorID int, sort string, limit, offset int) ([]*models.Comment, error) {
+	defer metrics.TimeQuery("comment", "GetCommentsByAuthor")()
+	ctx, span := startSpan(ctx, r.DB, "CommentRepository.GetCommentsByAuthor", attribute.Int("author.id", authorID), attribute.String("sort", sort), attribute.Int("page.limit", limit), attribute.Int("page.offset", offset))
+	defer span.End()
+	query := `
+		SELECT ` + activityColumns + `
+		FROM (SELECT ` + activityCommentColumns + ` FROM comments c WHERE c.author_id = $1) t
+		ORDER BY ` + activityOrderBy(sort) + `
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.DB.Read.QueryContext(ctx, query, authorID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("GetCommentsByAuthor: %v", err)
+	}
+	defer rows.Close()
+
+	items, err := scanActivityItems(rows)
+	if err != nil {
+		return nil, fmt.Errorf("GetCommentsByAuthor: %v", err)
+	}
+
+	comments := make([]*models.Comment, 0, len(items))
+	for _, item := range items {
+		comments = append(comments, item.Comment)
+	}
+	span.SetAttributes(tracing.Rows(len(comments)))
+	return comments, nil
+}
+
 // UpdateComment updates an existing comment's content.
-func (r *commentRepository) UpdateComment(comment *models.Comment) error {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+func (r *commentRepository) UpdateComment(ctx context.Context, comment *models.Comment) error {
+	defer metrics.TimeQuery("comment", "UpdateComment")()
+	ctx, span := startSpan(ctx, r.DB, "CommentRepository.UpdateComment", attribute.Int("comment.id", comment.ID))
+	defer span.End()
 	query := `
 		UPDATE comments
 		SET content = $1, updated_at = CURRENT_TIMESTAMP
 		WHERE id = $2
 		RETURNING updated_at
 	`
-	err := r.DB.QueryRow(query, comment.Content, comment.ID).
-		Scan(&comment.UpdatedAt)
+	err := database.Retry(ctx, func() error {
+		return r.DB.Write.QueryRowContext(ctx, query, comment.Content, comment.ID).
+			Scan(&comment.UpdatedAt)
+	})
 	if err != nil {
-		return fmt.Errorf("UpdateComment: %v", err)
+		return writeError("UpdateComment", "comment", err)
 	}
 	return nil
 }
 
 // DeleteComment removes a comment from the database.
-func (r *commentRepository) DeleteComment(id int) error {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+func (r *commentRepository) DeleteComment(ctx context.Context, id int) error {
+	defer metrics.TimeQuery("comment", "DeleteComment")()
+	ctx, span := startSpan(ctx, r.DB, "CommentRepository.DeleteComment", attribute.Int("comment.id", id))
+	defer span.End()
 	query := `
 		DELETE FROM comments
 		WHERE id = $1
 	`
-	result, err := r.DB.Exec(query, id)
+	result, err := r.DB.ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("DeleteComment: %v", err)
 	}
@@ -196,7 +247,7 @@ func (r *commentRepository) DeleteComment(id int) error {
 		return fmt.Errorf("DeleteComment: %v", err)
 	}
 	if rowsAffected == 0 {
-		return errors.New("DeleteComment: no comment found to delete")
+		return apperr.NotFound("DeleteComment: no comment found to delete")
 	}
 	return nil
 }
@@ -0,0 +1,27 @@
+// File: internal/repository/errors.go
+
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"redditclone/internal/apperr"
+	"redditclone/pkg/database"
+)
+
+// writeError wraps an error from an INSERT or UPDATE with the operation name.
+// An UPDATE ... RETURNING that matched no row becomes apperr.NotFound and a
+// unique constraint violation becomes apperr.Conflict; anything else is a
+// storage failure.
+func writeError(op, noun string, err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return apperr.NotFound(fmt.Sprintf("%s: %s not found", op, noun))
+	case database.IsUniqueViolation(err):
+		return apperr.Conflict(fmt.Sprintf("%s: %s already exists", op, noun))
+	default:
+		return fmt.Errorf("%s: %v", op, err)
+	}
+}
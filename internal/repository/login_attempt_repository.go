@@ -0,0 +1,152 @@
+// File: internal/repository/login_attempt_repository.go
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"redditclone/internal/models"
+	"redditclone/pkg/database"
+	"redditclone/pkg/metrics"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// LoginAttemptRepository provides access to the login attempt audit trail.
+type LoginAttemptRepository interface {
+	CreateLoginAttempt(ctx context.Context, attempt *models.LoginAttempt) error
+	GetUsernameFailures(ctx context.Context, username string, since time.Time) (*models.LoginFailures, error)
+	GetIPFailures(ctx context.Context, ip string, since time.Time) (*models.LoginFailures, error)
+	GetLoginAttemptsByUser(ctx context.Context, userID int, limit, offset int) ([]*models.LoginAttempt, error)
+}
+
+type loginAttemptRepository struct {
+	DB *database.DB
+}
+
+// NewLoginAttemptRepository creates a new LoginAttemptRepository.
+func NewLoginAttemptRepository(db *database.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{DB: db}
+}
+
+// CreateLoginAttempt records a login attempt.
+func (r *loginAttemptRepository) CreateLoginAttempt(ctx context.Context, attempt *models.LoginAttempt) error {
+	defer metrics.TimeQuery("login_attempt", "CreateLoginAttempt")()
+	ctx, span := startSpan(ctx, r.DB, "LoginAttemptRepository.CreateLoginAttempt", attribute.String("login.result", attempt.Result))
+	defer span.End()
+	query := `
+		INSERT INTO login_attempts (user_id, username, ip, user_agent, result, created_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
+		RETURNING id, created_at
+	`
+	err := database.Retry(ctx, func() error {
+		return r.DB.Write.QueryRowContext(ctx, query, attempt.UserID, attempt.Username, attempt.IP, attempt.UserAgent, attempt.Result).
+			Scan(&attempt.ID, &attempt.CreatedAt)
+	})
+	if err != nil {
+		return writeError("CreateLoginAttempt", "login attempt", err)
+	}
+	return nil
+}
+
+// GetUsernameFailures summarizes the failed attempts on a username since the
+// given time and since its last successful login.
+func (r *loginAttemptRepository) GetUsernameFailures(ctx context.Context, username string, since time.Time) (*models.LoginFailures, error) {
+	defer metrics.TimeQuery("login_attempt", "GetUsernameFailures")()
+	ctx, span := startSpan(ctx, r.DB, "LoginAttemptRepository.GetUsernameFailures")
+	defer span.End()
+	where := `
+		WHERE username = $1 AND result = 'invalid_credentials' AND created_at >= $2
+		AND id > COALESCE((SELECT MAX(id) FROM login_attempts WHERE username = $1 AND result = 'success'), 0)
+	`
+	failures, err := r.failures(ctx, where, username, r.DB.TimeArg(since))
+	if err != nil {
+		return nil, fmt.Errorf("GetUsernameFailures: %v", err)
+	}
+	return failures, nil
+}
+
+// GetIPFailures summarizes the failed attempts from an IP address on any
+// username since the given time. Successful logins do not reset it, so one
+// account of its own does not let a client keep guessing others.
+func (r *loginAttemptRepository) GetIPFailures(ctx context.Context, ip string, since time.Time) (*models.LoginFailures, error) {
+	defer metrics.TimeQuery("login_attempt", "GetIPFailures")()
+	ctx, span := startSpan(ctx, r.DB, "LoginAttemptRepository.GetIPFailures")
+	defer span.End()
+	where := `
+		WHERE ip = $1 AND result = 'invalid_credentials' AND created_at >= $2
+	`
+	failures, err := r.failures(ctx, where, ip, r.DB.TimeArg(since))
+	if err != nil {
+		return nil, fmt.Errorf("GetIPFailures: %v", err)
+	}
+	return failures, nil
+}
+
+// failures counts the attempts matching where and finds the latest. The time
+// is read from the newest row rather than with MAX, since SQLite returns an
+// aggregate of a DATETIME column as text.
+func (r *loginAttemptRepository) failures(ctx context.Context, where string, args ...any) (*models.LoginFailures, error) {
+	failures := &models.LoginFailures{}
+	err := r.DB.Read.QueryRowContext(ctx, `SELECT COUNT(*) FROM login_attempts `+where, args...).Scan(&failures.Count)
+	if err != nil || failures.Count == 0 {
+		return failures, err
+	}
+	query := `SELECT created_at FROM login_attempts ` + where + ` ORDER BY id DESC LIMIT 1`
+	err = r.DB.Read.QueryRowContext(ctx, query, args...).Scan(&failures.Last)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The attempts were deleted with their user between the queries.
+		return &models.LoginFailures{}, nil
+	}
+	return failures, err
+}
+
+// GetLoginAttemptsByUser retrieves the login attempts on a user's account,
+// newest first, with pagination.
+func (r *loginAttemptRepository) GetLoginAttemptsByUser(ctx context.Context, userID int, limit, offset int) ([]*models.LoginAttempt, error) {
+	defer metrics.TimeQuery("login_attempt", "GetLoginAttemptsByUser")()
+	ctx, span := startSpan(ctx, r.DB, "LoginAttemptRepository.GetLoginAttemptsByUser", attribute.Int("user.id", userID), attribute.Int("page.limit", limit), attribute.Int("page.offset", offset))
+	defer span.End()
+	query := `
+		SELECT id, user_id, username, ip, user_agent, result, created_at
+		FROM login_attempts
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.DB.Read.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("GetLoginAttemptsByUser: %v", err)
+	}
+	defer rows.Close()
+
+	var attempts []*models.LoginAttempt
+	for rows.Next() {
+		attempt := &models.LoginAttempt{}
+		err := rows.Scan(
+			&attempt.ID,
+			&attempt.UserID,
+			&attempt.Username,
+			&attempt.IP,
+			&attempt.UserAgent,
+			&attempt.Result,
+			&attempt.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("GetLoginAttemptsByUser: %v", err)
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetLoginAttemptsByUser: %v", err)
+	}
+
+	span.SetAttributes(tracing.Rows(len(attempts)))
+	return attempts, nil
+}
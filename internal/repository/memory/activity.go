@@ -0,0 +1,124 @@
+// File: internal/repository/memory/activity.go
+
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+)
+
+// activityEntry is a post or comment with the values the activity listings sort on.
+type activityEntry struct {
+	item       *models.ActivityItem
+	id         int
+	karma      int
+	createdAt  time.Time
+	ups, downs int
+	votedAt    time.Time // Only set for voted-item listings.
+}
+
+// postEntry copies a post into an activity entry. Callers must hold s.mu.
+func (s *Store) postEntry(post *models.Post) activityEntry {
+	found := *post
+	ups, downs := s.countVotes(&post.ID, nil)
+	return activityEntry{
+		item:      &models.ActivityItem{Kind: "post", Post: &found},
+		id:        post.ID,
+		karma:     post.Karma,
+		createdAt: post.CreatedAt,
+		ups:       ups,
+		downs:     downs,
+	}
+}
+
+// commentEntry copies a comment into an activity entry. Callers must hold s.mu.
+func (s *Store) commentEntry(comment *models.Comment) activityEntry {
+	found := copyComment(comment)
+	ups, downs := s.countVotes(nil, &comment.ID)
+	return activityEntry{
+		item:      &models.ActivityItem{Kind: "comment", Comment: found},
+		id:        comment.ID,
+		karma:     comment.Karma,
+		createdAt: comment.CreatedAt,
+		ups:       ups,
+		downs:     downs,
+	}
+}
+
+// countVotes counts the upvotes and downvotes on a post or comment.
+func (s *Store) countVotes(postID, commentID *int) (ups, downs int) {
+	for _, vote := range s.votes {
+		if !sameID(vote.PostID, postID) && !sameID(vote.CommentID, commentID) {
+			continue
+		}
+		switch vote.VoteType {
+		case "upvote":
+			ups++
+		case "downvote":
+			downs++
+		}
+	}
+	return ups, downs
+}
+
+// controversy ranks items with many votes that are evenly split between
+// upvotes and downvotes highest, matching the SQL controversy expression.
+func (e activityEntry) controversy() float64 {
+	ups, downs := float64(e.ups), float64(e.downs)
+	switch {
+	case e.ups == 0 || e.downs == 0:
+		return 0
+	case e.ups > e.downs:
+		return (ups + downs) * downs / ups
+	default:
+		return (ups + downs) * ups / downs
+	}
+}
+
+// sortActivity orders entries like the SQL activityOrderBy clause. Unknown sort
+// orders fall back to newest first.
+func sortActivity(entries []activityEntry, order string) {
+	newer := func(a, b activityEntry) bool {
+		if !a.createdAt.Equal(b.createdAt) {
+			return a.createdAt.After(b.createdAt)
+		}
+		return a.id > b.id
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch order {
+		case repository.SortTop:
+			if a.karma != b.karma {
+				return a.karma > b.karma
+			}
+		case repository.SortControversial:
+			if ca, cb := a.controversy(), b.controversy(); ca != cb {
+				return ca > cb
+			}
+		}
+		return newer(a, b)
+	})
+}
+
+// activityItems extracts the items from sorted entries.
+func activityItems(entries []activityEntry) []*models.ActivityItem {
+	var items []*models.ActivityItem
+	for _, entry := range entries {
+		items = append(items, entry.item)
+	}
+	return items
+}
+
+// sortByVotedAt orders voted-item entries most recently voted first.
+func sortByVotedAt(entries []activityEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !a.votedAt.Equal(b.votedAt) {
+			return a.votedAt.After(b.votedAt)
+		}
+		return a.id > b.id
+	})
+}
@@ -0,0 +1,113 @@
+// File: internal/repository/memory/api_key_repository.go
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+)
+
+type apiKeyRepository struct {
+	store *Store
+}
+
+// NewAPIKeyRepository creates an in-memory APIKeyRepository.
+func NewAPIKeyRepository(store *Store) repository.APIKeyRepository {
+	return &apiKeyRepository{store: store}
+}
+
+// CreateAPIKey stores a new API key.
+func (r *apiKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[key.UserID]; !ok {
+		return fmt.Errorf("CreateAPIKey: %v", errForeignKey)
+	}
+	for _, existing := range s.apiKeys {
+		if existing.KeyHash == key.KeyHash {
+			return apperr.Conflict("CreateAPIKey: API key already exists")
+		}
+	}
+	key.ID = s.nextID("api_keys")
+	key.CreatedAt = now()
+	key.LastUsedAt = nil
+	key.LastUsedIP = ""
+	key.RevokedAt = nil
+	s.apiKeys[key.ID] = copyAPIKey(key)
+	return nil
+}
+
+// GetAPIKeyByHash retrieves the active API key with the given hash that has
+// not expired by now and whose owner is not suspended or being deleted.
+func (r *apiKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string, now time.Time) (*models.APIKey, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, key := range s.apiKeys {
+		if key.KeyHash == keyHash && key.RevokedAt == nil && (key.ExpiresAt == nil || key.ExpiresAt.After(now)) && !s.locked(key.UserID) {
+			return copyAPIKey(key), nil
+		}
+	}
+	return nil, apperr.NotFound("GetAPIKeyByHash: API key not found")
+}
+
+// GetAPIKeysByUser retrieves a user's unrevoked API keys, newest first.
+func (r *apiKeyRepository) GetAPIKeysByUser(ctx context.Context, userID int) ([]*models.APIKey, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var keys []*models.APIKey
+	for _, key := range s.apiKeys {
+		if key.UserID == userID && key.RevokedAt == nil {
+			keys = append(keys, copyAPIKey(key))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID > keys[j].ID })
+	return keys, nil
+}
+
+// TouchAPIKey records that an API key was just used, from the given IP.
+func (r *apiKeyRepository) TouchAPIKey(ctx context.Context, id int, ip string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.apiKeys[id]; ok {
+		usedAt := now()
+		key.LastUsedAt = &usedAt
+		key.LastUsedIP = ip
+	}
+	return nil
+}
+
+// RevokeAPIKey revokes one of a user's API keys.
+func (r *apiKeyRepository) RevokeAPIKey(ctx context.Context, userID, id int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.apiKeys[id]
+	if !ok || key.UserID != userID || key.RevokedAt != nil {
+		return apperr.NotFound("RevokeAPIKey: API key not found")
+	}
+	revokedAt := now()
+	key.RevokedAt = &revokedAt
+	return nil
+}
+
+func copyAPIKey(key *models.APIKey) *models.APIKey {
+	found := *key
+	found.Scopes = append([]string(nil), key.Scopes...)
+	for _, t := range []**time.Time{&found.ExpiresAt, &found.LastUsedAt, &found.RevokedAt} {
+		if *t != nil {
+			v := **t
+			*t = &v
+		}
+	}
+	return &found
+}
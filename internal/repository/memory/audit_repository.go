@@ -0,0 +1,52 @@
+// File: internal/repository/memory/audit_repository.go
+
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+)
+
+type auditRepository struct {
+	store *Store
+}
+
+// NewAuditRepository creates an in-memory AuditRepository.
+func NewAuditRepository(store *Store) repository.AuditRepository {
+	return &auditRepository{store: store}
+}
+
+// CreateAuditEntry records an entry in the audit log.
+func (r *auditRepository) CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry.ID = s.nextID("audit_log")
+	entry.CreatedAt = now()
+	stored := *entry
+	s.audit[entry.ID] = &stored
+	return nil
+}
+
+// GetAuditEntries retrieves the audit log entries matching filter, newest
+// first, with pagination.
+func (r *auditRepository) GetAuditEntries(ctx context.Context, filter models.AuditFilter, limit, offset int) ([]*models.AuditEntry, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var entries []*models.AuditEntry
+	for _, entry := range s.audit {
+		if (filter.ActorID == 0 || entry.ActorID == filter.ActorID) &&
+			(filter.Action == "" || entry.Action == filter.Action) &&
+			(filter.TargetType == "" || entry.TargetType == filter.TargetType) &&
+			(filter.TargetID == 0 || entry.TargetID == filter.TargetID) {
+			found := *entry
+			entries = append(entries, &found)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID > entries[j].ID })
+	return paginate(entries, limit, offset), nil
+}
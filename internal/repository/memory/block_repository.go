@@ -0,0 +1,111 @@
+// File: internal/repository/memory/block_repository.go
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+)
+
+type blockRepository struct {
+	store *Store
+}
+
+// NewBlockRepository creates an in-memory BlockRepository.
+func NewBlockRepository(store *Store) repository.BlockRepository {
+	return &blockRepository{store: store}
+}
+
+// CreateBlock stores a new block.
+func (r *blockRepository) CreateBlock(ctx context.Context, block *models.Block) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, blockerOK := s.users[block.BlockerID]
+	_, blockedOK := s.users[block.BlockedID]
+	if !blockerOK || !blockedOK {
+		return fmt.Errorf("CreateBlock: %v", errForeignKey)
+	}
+	for _, other := range s.blocks {
+		if other.BlockerID == block.BlockerID && other.BlockedID == block.BlockedID {
+			return apperr.Conflict("CreateBlock: block already exists")
+		}
+	}
+	block.ID = s.nextID("blocks")
+	block.CreatedAt = now()
+	stored := *block
+	s.blocks[block.ID] = &stored
+	return nil
+}
+
+// DeleteBlock removes a block between two users.
+func (r *blockRepository) DeleteBlock(ctx context.Context, blockerID, blockedID int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, block := range s.blocks {
+		if block.BlockerID == blockerID && block.BlockedID == blockedID {
+			delete(s.blocks, id)
+			return nil
+		}
+	}
+	return apperr.NotFound("DeleteBlock: no block found to delete")
+}
+
+// IsBlocked reports whether blockerID has blocked blockedID.
+func (r *blockRepository) IsBlocked(ctx context.Context, blockerID, blockedID int) (bool, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.blockedBy(blockerID)[blockedID], nil
+}
+
+// GetBlocksByUser retrieves the blocks created by a user, newest first, with pagination.
+func (r *blockRepository) GetBlocksByUser(ctx context.Context, blockerID int, limit, offset int) ([]*models.Block, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var blocks []*models.Block
+	for _, block := range s.blocks {
+		if block.BlockerID == blockerID {
+			found := *block
+			blocks = append(blocks, &found)
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		if !blocks[i].CreatedAt.Equal(blocks[j].CreatedAt) {
+			return blocks[i].CreatedAt.After(blocks[j].CreatedAt)
+		}
+		return blocks[i].ID > blocks[j].ID
+	})
+	return paginate(blocks, limit, offset), nil
+}
+
+// GetBlockedUserIDs retrieves the IDs of every user blocked by blockerID.
+func (r *blockRepository) GetBlockedUserIDs(ctx context.Context, blockerID int) ([]int, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var ids []int
+	for id := range s.blockedBy(blockerID) {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+// blockedBy returns the set of users blocked by blockerID. Callers must hold s.mu.
+func (s *Store) blockedBy(blockerID int) map[int]bool {
+	blocked := make(map[int]bool)
+	for _, block := range s.blocks {
+		if block.BlockerID == blockerID {
+			blocked[block.BlockedID] = true
+		}
+	}
+	return blocked
+}
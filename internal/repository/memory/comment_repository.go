@@ -0,0 +1,155 @@
+// File: internal/repository/memory/comment_repository.go
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+)
+
+type commentRepository struct {
+	store *Store
+}
+
+// NewCommentRepository creates an in-memory CommentRepository.
+func NewCommentRepository(store *Store) repository.CommentRepository {
+	return &commentRepository{store: store}
+}
+
+// CreateComment stores a new comment with zero karma.
+func (r *commentRepository) CreateComment(ctx context.Context, comment *models.Comment) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, authorOK := s.users[comment.AuthorID]
+	_, postOK := s.posts[comment.PostID]
+	parentOK := true
+	if comment.ParentID != nil {
+		_, parentOK = s.comments[*comment.ParentID]
+	}
+	if !authorOK || !postOK || !parentOK {
+		return fmt.Errorf("CreateComment: %v", errForeignKey)
+	}
+	comment.ID = s.nextID("comments")
+	comment.Karma = 0
+	comment.CreatedAt = now()
+	comment.UpdatedAt = comment.CreatedAt
+	s.comments[comment.ID] = copyComment(comment)
+	return nil
+}
+
+// GetCommentByID retrieves a comment by its ID.
+func (r *commentRepository) GetCommentByID(ctx context.Context, id int) (*models.Comment, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	comment, ok := s.comments[id]
+	if !ok {
+		return nil, apperr.NotFound("GetCommentByID: comment not found")
+	}
+	return copyComment(comment), nil
+}
+
+// GetCommentsByPost retrieves a page of top-level comments for a specific post, newest first.
+func (r *commentRepository) GetCommentsByPost(ctx context.Context, postID int, page repository.Page) ([]*models.Comment, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	comments := s.listComments(func(c *models.Comment) bool {
+		return c.PostID == postID && c.ParentID == nil
+	}, true)
+	return window(comments, page, true, repository.CommentCursor), nil
+}
+
+// GetReplies retrieves a page of replies to a specific comment, oldest first.
+func (r *commentRepository) GetReplies(ctx context.Context, parentID int, page repository.Page) ([]*models.Comment, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	replies := s.listComments(func(c *models.Comment) bool {
+		return c.ParentID != nil && *c.ParentID == parentID
+	}, false)
+	return window(replies, page, false, repository.CommentCursor), nil
+}
+
+// GetCommentsByAuthor retrieves the comments written by a user in the given sort order with pagination.
+func (r *commentRepository) GetCommentsByAuthor(ctx context.Context, authorID int, sort string, limit, offset int) ([]*models.Comment, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var entries []activityEntry
+	for _, comment := range s.comments {
+		if comment.AuthorID == authorID {
+			entries = append(entries, s.commentEntry(comment))
+		}
+	}
+	sortActivity(entries, sort)
+	entries = paginate(entries, limit, offset)
+
+	comments := make([]*models.Comment, 0, len(entries))
+	for _, entry := range entries {
+		comments = append(comments, entry.item.Comment)
+	}
+	return comments, nil
+}
+
+// UpdateComment updates an existing comment's content.
+func (r *commentRepository) UpdateComment(ctx context.Context, comment *models.Comment) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.comments[comment.ID]
+	if !ok {
+		return apperr.NotFound("UpdateComment: comment not found")
+	}
+	stored.Content = comment.Content
+	stored.UpdatedAt = now()
+	comment.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+// DeleteComment removes a comment with its replies and votes.
+func (r *commentRepository) DeleteComment(ctx context.Context, id int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.comments[id]; !ok {
+		return apperr.NotFound("DeleteComment: no comment found to delete")
+	}
+	s.deleteComment(id)
+	return nil
+}
+
+// listComments returns copies of the comments matching keep, ordered by
+// creation time. Callers must hold s.mu.
+func (s *Store) listComments(keep func(*models.Comment) bool, newestFirst bool) []*models.Comment {
+	var comments []*models.Comment
+	for _, comment := range s.comments {
+		if keep(comment) {
+			comments = append(comments, copyComment(comment))
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		a, b := comments[i], comments[j]
+		if newestFirst {
+			a, b = b, a
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+	return comments
+}
+
+// copyComment copies a comment so callers cannot modify stored rows.
+func copyComment(comment *models.Comment) *models.Comment {
+	c := *comment
+	c.ParentID = copyIntPtr(comment.ParentID)
+	return &c
+}
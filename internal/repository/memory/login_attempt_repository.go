@@ -0,0 +1,109 @@
+// File: internal/repository/memory/login_attempt_repository.go
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+)
+
+type loginAttemptRepository struct {
+	store *Store
+}
+
+// NewLoginAttemptRepository creates an in-memory LoginAttemptRepository.
+func NewLoginAttemptRepository(store *Store) repository.LoginAttemptRepository {
+	return &loginAttemptRepository{store: store}
+}
+
+// CreateLoginAttempt records a login attempt.
+func (r *loginAttemptRepository) CreateLoginAttempt(ctx context.Context, attempt *models.LoginAttempt) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if attempt.UserID != nil {
+		if _, ok := s.users[*attempt.UserID]; !ok {
+			return fmt.Errorf("CreateLoginAttempt: %v", errForeignKey)
+		}
+	}
+	attempt.ID = s.nextID("login_attempts")
+	attempt.CreatedAt = now()
+	stored := *attempt
+	stored.UserID = copyIntPtr(attempt.UserID)
+	s.logins[attempt.ID] = &stored
+	return nil
+}
+
+// GetUsernameFailures summarizes the failed attempts on a username since the
+// given time and since its last successful login.
+func (r *loginAttemptRepository) GetUsernameFailures(ctx context.Context, username string, since time.Time) (*models.LoginFailures, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	lastSuccess := 0
+	for id, attempt := range s.logins {
+		if attempt.Username == username && attempt.Result == models.LoginSucceeded && id > lastSuccess {
+			lastSuccess = id
+		}
+	}
+	return s.loginFailures(since, func(attempt *models.LoginAttempt) bool {
+		return attempt.Username == username && attempt.ID > lastSuccess
+	}), nil
+}
+
+// GetIPFailures summarizes the failed attempts from an IP address on any
+// username since the given time.
+func (r *loginAttemptRepository) GetIPFailures(ctx context.Context, ip string, since time.Time) (*models.LoginFailures, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.loginFailures(since, func(attempt *models.LoginAttempt) bool {
+		return attempt.IP == ip
+	}), nil
+}
+
+// GetLoginAttemptsByUser retrieves the login attempts on a user's account,
+// newest first, with pagination.
+func (r *loginAttemptRepository) GetLoginAttemptsByUser(ctx context.Context, userID int, limit, offset int) ([]*models.LoginAttempt, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var attempts []*models.LoginAttempt
+	for _, attempt := range s.logins {
+		if attempt.UserID != nil && *attempt.UserID == userID {
+			found := *attempt
+			found.UserID = copyIntPtr(attempt.UserID)
+			attempts = append(attempts, &found)
+		}
+	}
+	sort.Slice(attempts, func(i, j int) bool {
+		if !attempts[i].CreatedAt.Equal(attempts[j].CreatedAt) {
+			return attempts[i].CreatedAt.After(attempts[j].CreatedAt)
+		}
+		return attempts[i].ID > attempts[j].ID
+	})
+	return paginate(attempts, limit, offset), nil
+}
+
+// loginFailures summarizes the failed attempts since the given time that
+// match. Callers must hold s.mu.
+func (s *Store) loginFailures(since time.Time, match func(*models.LoginAttempt) bool) *models.LoginFailures {
+	failures := &models.LoginFailures{}
+	lastID := 0
+	for _, attempt := range s.logins {
+		if attempt.Result != models.LoginFailed || attempt.CreatedAt.Before(since) || !match(attempt) {
+			continue
+		}
+		failures.Count++
+		if attempt.ID > lastID {
+			lastID = attempt.ID
+			failures.Last = attempt.CreatedAt
+		}
+	}
+	return failures
+}
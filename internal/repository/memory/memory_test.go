@@ -0,0 +1,19 @@
+// File: internal/repository/memory/memory_test.go
+
+package memory_test
+
+import (
+	"testing"
+
+	"redditclone/internal/repository"
+	"redditclone/internal/repository/memory"
+	"redditclone/internal/repository/repotest"
+)
+
+// TestRepositories holds the in-memory store to the suite the SQL
+// repositories pass, so the two stay interchangeable.
+func TestRepositories(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.Repositories {
+		return memory.NewRepositories()
+	})
+}
@@ -0,0 +1,133 @@
+// File: internal/repository/memory/message_repository.go
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+)
+
+type messageRepository struct {
+	store *Store
+}
+
+// NewMessageRepository creates an in-memory MessageRepository.
+func NewMessageRepository(store *Store) repository.MessageRepository {
+	return &messageRepository{store: store}
+}
+
+// SendMessage stores a new message.
+func (r *messageRepository) SendMessage(ctx context.Context, message *models.Message) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, senderOK := s.users[message.SenderID]
+	_, receiverOK := s.users[message.ReceiverID]
+	parentOK := true
+	if message.ParentID != nil {
+		_, parentOK = s.messages[*message.ParentID]
+	}
+	if !senderOK || !receiverOK || !parentOK {
+		return fmt.Errorf("SendMessage: %v", errForeignKey)
+	}
+	message.ID = s.nextID("messages")
+	message.CreatedAt = now()
+	message.UpdatedAt = message.CreatedAt
+	s.messages[message.ID] = copyMessage(message)
+	return nil
+}
+
+// GetMessageByID retrieves a message by its ID.
+func (r *messageRepository) GetMessageByID(ctx context.Context, id int) (*models.Message, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	message, ok := s.messages[id]
+	if !ok {
+		return nil, apperr.NotFound("GetMessageByID: message not found")
+	}
+	return copyMessage(message), nil
+}
+
+// GetMessagesForUser retrieves a page of top-level direct messages for a user, newest first.
+func (r *messageRepository) GetMessagesForUser(ctx context.Context, userID int, page repository.Page) ([]*models.Message, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	messages := s.listMessages(func(m *models.Message) bool {
+		return m.ReceiverID == userID && m.ParentID == nil
+	}, true)
+	return window(messages, page, true, repository.MessageCursor), nil
+}
+
+// GetReplies retrieves a page of replies to a specific message, oldest first.
+func (r *messageRepository) GetReplies(ctx context.Context, parentID int, page repository.Page) ([]*models.Message, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	replies := s.listMessages(func(m *models.Message) bool {
+		return m.ParentID != nil && *m.ParentID == parentID
+	}, false)
+	return window(replies, page, false, repository.MessageCursor), nil
+}
+
+// UpdateMessage updates an existing message's content.
+func (r *messageRepository) UpdateMessage(ctx context.Context, message *models.Message) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.messages[message.ID]
+	if !ok {
+		return apperr.NotFound("UpdateMessage: message not found")
+	}
+	stored.Content = message.Content
+	stored.UpdatedAt = now()
+	message.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+// DeleteMessage removes a message and its replies.
+func (r *messageRepository) DeleteMessage(ctx context.Context, id int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.messages[id]; !ok {
+		return apperr.NotFound("DeleteMessage: no message found to delete")
+	}
+	s.deleteMessage(id)
+	return nil
+}
+
+// listMessages returns copies of the messages matching keep, ordered by
+// creation time. Callers must hold s.mu.
+func (s *Store) listMessages(keep func(*models.Message) bool, newestFirst bool) []*models.Message {
+	var messages []*models.Message
+	for _, message := range s.messages {
+		if keep(message) {
+			messages = append(messages, copyMessage(message))
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		a, b := messages[i], messages[j]
+		if newestFirst {
+			a, b = b, a
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+	return messages
+}
+
+// copyMessage copies a message so callers cannot modify stored rows.
+func copyMessage(message *models.Message) *models.Message {
+	m := *message
+	m.ParentID = copyIntPtr(message.ParentID)
+	return &m
+}
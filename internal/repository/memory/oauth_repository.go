@@ -0,0 +1,357 @@
+// File: internal/repository/memory/oauth_repository.go
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+)
+
+type oauthRepository struct {
+	store *Store
+}
+
+// NewOAuthRepository creates an in-memory OAuthRepository.
+func NewOAuthRepository(store *Store) repository.OAuthRepository {
+	return &oauthRepository{store: store}
+}
+
+// CreateClient stores a new OAuth2 client.
+func (r *oauthRepository) CreateClient(ctx context.Context, client *models.OAuthClient) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[client.OwnerID]; !ok {
+		return fmt.Errorf("CreateClient: %v", errForeignKey)
+	}
+	if _, ok := s.oauthClients[client.ID]; ok {
+		return apperr.Conflict("CreateClient: OAuth client already exists")
+	}
+	client.CreatedAt = now()
+	client.Confidential = client.SecretHash != ""
+	s.oauthClients[client.ID] = copyOAuthClient(client)
+	return nil
+}
+
+// GetClient retrieves an OAuth2 client by its client ID.
+func (r *oauthRepository) GetClient(ctx context.Context, id string) (*models.OAuthClient, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	client, ok := s.oauthClients[id]
+	if !ok {
+		return nil, apperr.NotFound("GetClient: OAuth client not found")
+	}
+	return copyOAuthClient(client), nil
+}
+
+// GetClientsByOwner retrieves the OAuth2 clients a user registered, newest first.
+func (r *oauthRepository) GetClientsByOwner(ctx context.Context, ownerID int) ([]*models.OAuthClient, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var clients []*models.OAuthClient
+	for _, client := range s.oauthClients {
+		if client.OwnerID == ownerID {
+			clients = append(clients, copyOAuthClient(client))
+		}
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		if !clients[i].CreatedAt.Equal(clients[j].CreatedAt) {
+			return clients[i].CreatedAt.After(clients[j].CreatedAt)
+		}
+		return clients[i].ID < clients[j].ID
+	})
+	return clients, nil
+}
+
+// DeleteClient removes one of a user's OAuth2 clients, together with its
+// consents, codes and tokens.
+func (r *oauthRepository) DeleteClient(ctx context.Context, ownerID int, id string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, ok := s.oauthClients[id]
+	if !ok || client.OwnerID != ownerID {
+		return apperr.NotFound("DeleteClient: OAuth client not found")
+	}
+	s.deleteOAuthClient(id)
+	return nil
+}
+
+// SaveConsent records that a user granted a client the consent's scopes,
+// replacing any earlier consent, revoked or not.
+func (r *oauthRepository) SaveConsent(ctx context.Context, consent *models.OAuthConsent) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, ok := s.oauthClients[consent.ClientID]
+	if _, userOK := s.users[consent.UserID]; !ok || !userOK {
+		return fmt.Errorf("SaveConsent: %v", errForeignKey)
+	}
+	consent.ClientName = client.Name
+	consent.UpdatedAt = now()
+	consent.RevokedAt = nil
+	for _, existing := range s.oauthConsents {
+		if existing.UserID == consent.UserID && existing.ClientID == consent.ClientID {
+			consent.ID = existing.ID
+			consent.CreatedAt = existing.CreatedAt
+			s.oauthConsents[consent.ID] = copyOAuthConsent(consent)
+			return nil
+		}
+	}
+	consent.ID = s.nextID("oauth_consents")
+	consent.CreatedAt = consent.UpdatedAt
+	s.oauthConsents[consent.ID] = copyOAuthConsent(consent)
+	return nil
+}
+
+// GetConsent retrieves a user's active consent for a client.
+func (r *oauthRepository) GetConsent(ctx context.Context, userID int, clientID string) (*models.OAuthConsent, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, consent := range s.oauthConsents {
+		if consent.UserID == userID && consent.ClientID == clientID && consent.RevokedAt == nil {
+			return copyOAuthConsent(consent), nil
+		}
+	}
+	return nil, apperr.NotFound("GetConsent: consent not found")
+}
+
+// GetConsentsByUser retrieves every consent a user has given, revoked ones
+// included, most recently changed first.
+func (r *oauthRepository) GetConsentsByUser(ctx context.Context, userID int) ([]*models.OAuthConsent, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var consents []*models.OAuthConsent
+	for _, consent := range s.oauthConsents {
+		if consent.UserID == userID {
+			consents = append(consents, copyOAuthConsent(consent))
+		}
+	}
+	sort.Slice(consents, func(i, j int) bool {
+		if !consents[i].UpdatedAt.Equal(consents[j].UpdatedAt) {
+			return consents[i].UpdatedAt.After(consents[j].UpdatedAt)
+		}
+		return consents[i].ID > consents[j].ID
+	})
+	return consents, nil
+}
+
+// RevokeConsent revokes a user's active consent for a client and every token
+// issued under it.
+func (r *oauthRepository) RevokeConsent(ctx context.Context, userID int, clientID string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, consent := range s.oauthConsents {
+		if consent.UserID == userID && consent.ClientID == clientID && consent.RevokedAt == nil {
+			revokedAt := now()
+			consent.RevokedAt = &revokedAt
+			consent.UpdatedAt = revokedAt
+			s.revokeOAuthTokens(userID, clientID, models.RevokedConsent)
+			return nil
+		}
+	}
+	return apperr.NotFound("RevokeConsent: consent not found")
+}
+
+// CreateCode stores a new authorization code.
+func (r *oauthRepository) CreateCode(ctx context.Context, code *models.OAuthCode) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.oauthClients[code.ClientID]; !ok {
+		return fmt.Errorf("CreateCode: %v", errForeignKey)
+	}
+	for _, existing := range s.oauthCodes {
+		if existing.CodeHash == code.CodeHash {
+			return apperr.Conflict("CreateCode: authorization code already exists")
+		}
+	}
+	code.ID = s.nextID("oauth_codes")
+	code.CreatedAt = now()
+	code.UsedAt = nil
+	stored := *code
+	s.oauthCodes[code.ID] = &stored
+	return nil
+}
+
+// UseCode marks an unused authorization code as used and returns it, failing
+// with apperr.Conflict for a code used before.
+func (r *oauthRepository) UseCode(ctx context.Context, codeHash string) (*models.OAuthCode, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, code := range s.oauthCodes {
+		if code.CodeHash != codeHash {
+			continue
+		}
+		found := *code
+		if code.UsedAt != nil {
+			return &found, apperr.Conflict("UseCode: authorization code already used")
+		}
+		usedAt := now()
+		code.UsedAt = &usedAt
+		found.UsedAt = &usedAt
+		return &found, nil
+	}
+	return nil, apperr.NotFound("UseCode: authorization code not found")
+}
+
+// CreateToken stores a new token pair.
+func (r *oauthRepository) CreateToken(ctx context.Context, token *models.OAuthToken) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addOAuthToken("CreateToken", token)
+}
+
+// GetTokenByAccessHash retrieves the active token pair whose access token has
+// the given hash and has not expired by now, for a user who is not suspended
+// or being deleted.
+func (r *oauthRepository) GetTokenByAccessHash(ctx context.Context, accessTokenHash string, now time.Time) (*models.OAuthToken, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, token := range s.oauthTokens {
+		if token.AccessTokenHash == accessTokenHash && token.RevokedAt == nil && token.AccessExpiresAt.After(now) && !s.locked(token.UserID) {
+			return copyOAuthToken(token), nil
+		}
+	}
+	return nil, apperr.NotFound("GetTokenByAccessHash: OAuth token not found")
+}
+
+// GetTokenByRefreshHash retrieves the token pair whose refresh token has the
+// given hash, revoked or not.
+func (r *oauthRepository) GetTokenByRefreshHash(ctx context.Context, refreshTokenHash string) (*models.OAuthToken, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, token := range s.oauthTokens {
+		if token.RefreshTokenHash == refreshTokenHash {
+			return copyOAuthToken(token), nil
+		}
+	}
+	return nil, apperr.NotFound("GetTokenByRefreshHash: OAuth token not found")
+}
+
+// RotateToken revokes the active token pair oldID as rotated and stores its
+// successor, failing with apperr.Conflict if oldID was already revoked.
+func (r *oauthRepository) RotateToken(ctx context.Context, oldID int, next *models.OAuthToken) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.oauthTokens[oldID]
+	if !ok || old.RevokedAt != nil {
+		return apperr.Conflict("RotateToken: OAuth token already revoked")
+	}
+	if err := s.addOAuthToken("RotateToken", next); err != nil {
+		return err
+	}
+	revokedAt := now()
+	old.RevokedAt = &revokedAt
+	old.RevokeReason = models.RevokedRotated
+	return nil
+}
+
+// RevokeGrantTokens revokes every active token pair a client holds for a
+// user and returns how many it revoked.
+func (r *oauthRepository) RevokeGrantTokens(ctx context.Context, userID int, clientID, reason string) (int, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.revokeOAuthTokens(userID, clientID, reason), nil
+}
+
+// addOAuthToken stores token, mirroring the foreign keys and the UNIQUE
+// constraints on the token hashes.
+func (s *Store) addOAuthToken(op string, token *models.OAuthToken) error {
+	if _, ok := s.oauthClients[token.ClientID]; !ok {
+		return fmt.Errorf("%s: %v", op, errForeignKey)
+	}
+	if _, ok := s.users[token.UserID]; !ok {
+		return fmt.Errorf("%s: %v", op, errForeignKey)
+	}
+	for _, existing := range s.oauthTokens {
+		if existing.AccessTokenHash == token.AccessTokenHash || existing.RefreshTokenHash == token.RefreshTokenHash {
+			return apperr.Conflict(op + ": OAuth token already exists")
+		}
+	}
+	token.ID = s.nextID("oauth_tokens")
+	token.CreatedAt = now()
+	token.RevokedAt = nil
+	token.RevokeReason = ""
+	s.oauthTokens[token.ID] = copyOAuthToken(token)
+	return nil
+}
+
+func (s *Store) revokeOAuthTokens(userID int, clientID, reason string) int {
+	revoked := 0
+	revokedAt := now()
+	for _, token := range s.oauthTokens {
+		if token.UserID == userID && token.ClientID == clientID && token.RevokedAt == nil {
+			at := revokedAt
+			token.RevokedAt = &at
+			token.RevokeReason = reason
+			revoked++
+		}
+	}
+	return revoked
+}
+
+// deleteOAuthClient removes a client and everything issued to it, like the
+// ON DELETE CASCADE foreign keys.
+func (s *Store) deleteOAuthClient(id string) {
+	delete(s.oauthClients, id)
+	for cid, consent := range s.oauthConsents {
+		if consent.ClientID == id {
+			delete(s.oauthConsents, cid)
+		}
+	}
+	for cid, code := range s.oauthCodes {
+		if code.ClientID == id {
+			delete(s.oauthCodes, cid)
+		}
+	}
+	for tid, token := range s.oauthTokens {
+		if token.ClientID == id {
+			delete(s.oauthTokens, tid)
+		}
+	}
+}
+
+func copyOAuthClient(client *models.OAuthClient) *models.OAuthClient {
+	found := *client
+	found.RedirectURIs = append([]string(nil), client.RedirectURIs...)
+	found.Scopes = append([]string(nil), client.Scopes...)
+	return &found
+}
+
+func copyOAuthConsent(consent *models.OAuthConsent) *models.OAuthConsent {
+	found := *consent
+	found.Scopes = append([]string(nil), consent.Scopes...)
+	if consent.RevokedAt != nil {
+		revokedAt := *consent.RevokedAt
+		found.RevokedAt = &revokedAt
+	}
+	return &found
+}
+
+func copyOAuthToken(token *models.OAuthToken) *models.OAuthToken {
+	found := *token
+	found.Scopes = append([]string(nil), token.Scopes...)
+	if token.RevokedAt != nil {
+		revokedAt := *token.RevokedAt
+		found.RevokedAt = &revokedAt
+	}
+	return &found
+}
@@ -0,0 +1,146 @@
+// File: internal/repository/memory/post_repository.go
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+)
+
+type postRepository struct {
+	store *Store
+}
+
+// NewPostRepository creates an in-memory PostRepository.
+func NewPostRepository(store *Store) repository.PostRepository {
+	return &postRepository{store: store}
+}
+
+// CreatePost stores a new post with zero karma.
+func (r *postRepository) CreatePost(ctx context.Context, post *models.Post) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, authorOK := s.users[post.AuthorID]
+	_, subredditOK := s.subreddits[post.SubredditID]
+	if !authorOK || !subredditOK {
+		return fmt.Errorf("CreatePost: %v", errForeignKey)
+	}
+	post.ID = s.nextID("posts")
+	post.Karma = 0
+	post.CreatedAt = now()
+	post.UpdatedAt = post.CreatedAt
+	stored := *post
+	s.posts[post.ID] = &stored
+	return nil
+}
+
+// GetPostByID retrieves a post by its ID.
+func (r *postRepository) GetPostByID(ctx context.Context, id int) (*models.Post, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	post, ok := s.posts[id]
+	if !ok {
+		return nil, apperr.NotFound("GetPostByID: post not found")
+	}
+	found := *post
+	return &found, nil
+}
+
+// GetPostsBySubreddit retrieves a page of posts from a specific subreddit, newest
+// first, excluding posts by authors the viewer has blocked.
+func (r *postRepository) GetPostsBySubreddit(ctx context.Context, subredditID, viewerID int, page repository.Page) ([]*models.Post, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.listPosts(viewerID, page, func(post *models.Post) bool {
+		return post.SubredditID == subredditID
+	}), nil
+}
+
+// GetFeedPosts retrieves a page of the feed, newest first, excluding posts by
+// authors the viewer has blocked and posts in quarantined subreddits.
+func (r *postRepository) GetFeedPosts(ctx context.Context, viewerID int, page repository.Page) ([]*models.Post, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.listPosts(viewerID, page, func(post *models.Post) bool {
+		subreddit, ok := s.subreddits[post.SubredditID]
+		return !ok || subreddit.QuarantinedAt == nil
+	}), nil
+}
+
+// GetPostsByAuthor retrieves the posts submitted by a user in the given sort order with pagination.
+func (r *postRepository) GetPostsByAuthor(ctx context.Context, authorID int, sort string, limit, offset int) ([]*models.Post, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var entries []activityEntry
+	for _, post := range s.posts {
+		if post.AuthorID == authorID {
+			entries = append(entries, s.postEntry(post))
+		}
+	}
+	sortActivity(entries, sort)
+	entries = paginate(entries, limit, offset)
+
+	posts := make([]*models.Post, 0, len(entries))
+	for _, entry := range entries {
+		posts = append(posts, entry.item.Post)
+	}
+	return posts, nil
+}
+
+// UpdatePost updates an existing post's information.
+func (r *postRepository) UpdatePost(ctx context.Context, post *models.Post) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.posts[post.ID]
+	if !ok {
+		return apperr.NotFound("UpdatePost: post not found")
+	}
+	stored.Title = post.Title
+	stored.Content = post.Content
+	stored.UpdatedAt = now()
+	post.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+// DeletePost removes a post with its comments and votes.
+func (r *postRepository) DeletePost(ctx context.Context, id int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.posts[id]; !ok {
+		return apperr.NotFound("DeletePost: no post found to delete")
+	}
+	s.deletePost(id)
+	return nil
+}
+
+// listPosts returns a page of the posts matching keep, newest first, skipping
+// authors the viewer has blocked. Callers must hold s.mu.
+func (s *Store) listPosts(viewerID int, page repository.Page, keep func(*models.Post) bool) []*models.Post {
+	blocked := s.blockedBy(viewerID)
+	var posts []*models.Post
+	for _, post := range s.posts {
+		if keep(post) && !blocked[post.AuthorID] {
+			found := *post
+			posts = append(posts, &found)
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].CreatedAt.Equal(posts[j].CreatedAt) {
+			return posts[i].CreatedAt.After(posts[j].CreatedAt)
+		}
+		return posts[i].ID > posts[j].ID
+	})
+	return window(posts, page, true, repository.PostCursor)
+}
@@ -0,0 +1,84 @@
+// File: internal/repository/memory/recovery_code_repository.go
+
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+)
+
+type recoveryCodeRepository struct {
+	store *Store
+}
+
+// NewRecoveryCodeRepository creates an in-memory RecoveryCodeRepository.
+func NewRecoveryCodeRepository(store *Store) repository.RecoveryCodeRepository {
+	return &recoveryCodeRepository{store: store}
+}
+
+// ReplaceRecoveryCodes discards a user's recovery codes and stores new ones.
+func (r *recoveryCodeRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[userID]; !ok {
+		return fmt.Errorf("ReplaceRecoveryCodes: %v", errForeignKey)
+	}
+	for id, code := range s.recovery {
+		if code.UserID == userID {
+			delete(s.recovery, id)
+		}
+	}
+	createdAt := now()
+	for _, hash := range codeHashes {
+		id := s.nextID("recovery_codes")
+		s.recovery[id] = &models.RecoveryCode{ID: id, UserID: userID, CodeHash: hash, CreatedAt: createdAt}
+	}
+	return nil
+}
+
+// UseRecoveryCode marks one of a user's unused recovery codes as used.
+func (r *recoveryCodeRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, code := range s.recovery {
+		if code.UserID == userID && code.CodeHash == codeHash && code.UsedAt == nil {
+			usedAt := now()
+			code.UsedAt = &usedAt
+			return nil
+		}
+	}
+	return apperr.NotFound("UseRecoveryCode: recovery code not found or already used")
+}
+
+// CountRecoveryCodes returns how many unused recovery codes a user has left.
+func (r *recoveryCodeRepository) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	count := 0
+	for _, code := range s.recovery {
+		if code.UserID == userID && code.UsedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// DeleteRecoveryCodes removes all of a user's recovery codes.
+func (r *recoveryCodeRepository) DeleteRecoveryCodes(ctx context.Context, userID int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, code := range s.recovery {
+		if code.UserID == userID {
+			delete(s.recovery, id)
+		}
+	}
+	return nil
+}
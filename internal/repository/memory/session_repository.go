@@ -0,0 +1,227 @@
+// File: internal/repository/memory/session_repository.go
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+)
+
+type sessionRepository struct {
+	store *Store
+}
+
+// NewSessionRepository creates an in-memory SessionRepository.
+func NewSessionRepository(store *Store) repository.SessionRepository {
+	return &sessionRepository{store: store}
+}
+
+// CreateSession stores a new session together with its first refresh token.
+func (r *sessionRepository) CreateSession(ctx context.Context, session *models.Session, refreshTokenHash string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[session.UserID]; !ok {
+		return fmt.Errorf("CreateSession: %v", errForeignKey)
+	}
+	if s.tokenHashTaken(session.AccessTokenHash, refreshTokenHash) {
+		return apperr.Conflict("CreateSession: session already exists")
+	}
+	session.ID = s.nextID("sessions")
+	session.CreatedAt = now()
+	session.LastSeenAt = session.CreatedAt
+	session.RevokedAt = nil
+	session.RevokeReason = ""
+	session.Current = false
+	stored := *session
+	s.sessions[session.ID] = &stored
+	s.addRefreshToken(session.ID, refreshTokenHash)
+	return nil
+}
+
+// GetSessionByID retrieves a session by its ID, revoked or not.
+func (r *sessionRepository) GetSessionByID(ctx context.Context, id int) (*models.Session, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, apperr.NotFound("GetSessionByID: session not found")
+	}
+	return copySession(session), nil
+}
+
+// GetSessionByAccessToken retrieves the active session whose current access
+// token has the given hash and has not expired by now.
+func (r *sessionRepository) GetSessionByAccessToken(ctx context.Context, accessTokenHash string, now time.Time) (*models.Session, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, session := range s.sessions {
+		if session.AccessTokenHash == accessTokenHash && session.RevokedAt == nil && session.AccessExpiresAt.After(now) {
+			return copySession(session), nil
+		}
+	}
+	return nil, apperr.NotFound("GetSessionByAccessToken: session not found")
+}
+
+// GetSessionsByUser retrieves a user's active sessions, most recently used first.
+func (r *sessionRepository) GetSessionsByUser(ctx context.Context, userID int, now time.Time) ([]*models.Session, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var sessions []*models.Session
+	for _, session := range s.sessions {
+		if session.UserID == userID && session.RevokedAt == nil && session.ExpiresAt.After(now) {
+			sessions = append(sessions, copySession(session))
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastSeenAt.Equal(sessions[j].LastSeenAt) {
+			return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+		}
+		return sessions[i].ID > sessions[j].ID
+	})
+	return sessions, nil
+}
+
+// TouchSession records that a session was just used, from the given IP.
+func (r *sessionRepository) TouchSession(ctx context.Context, id int, ip string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if session, ok := s.sessions[id]; ok {
+		session.LastSeenAt = now()
+		session.IP = ip
+	}
+	return nil
+}
+
+// UseRefreshToken marks an unused refresh token as used and returns its
+// session ID, failing with apperr.Conflict for a token used before.
+func (r *sessionRepository) UseRefreshToken(ctx context.Context, tokenHash string) (int, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, token := range s.refreshTokens {
+		if token.TokenHash != tokenHash {
+			continue
+		}
+		if token.UsedAt != nil {
+			return token.SessionID, apperr.Conflict("UseRefreshToken: refresh token already used")
+		}
+		usedAt := now()
+		token.UsedAt = &usedAt
+		return token.SessionID, nil
+	}
+	return 0, apperr.NotFound("UseRefreshToken: refresh token not found")
+}
+
+// RenewSession stores a session's new access token and expiry times together
+// with its next refresh token.
+func (r *sessionRepository) RenewSession(ctx context.Context, session *models.Session, refreshTokenHash string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.sessions[session.ID]
+	if !ok || stored.RevokedAt != nil {
+		return apperr.NotFound("RenewSession: session not found")
+	}
+	if s.tokenHashTaken(session.AccessTokenHash, refreshTokenHash) {
+		return apperr.Conflict("RenewSession: session already exists")
+	}
+	stored.AccessTokenHash = session.AccessTokenHash
+	stored.AccessExpiresAt = session.AccessExpiresAt
+	stored.ExpiresAt = session.ExpiresAt
+	stored.IP = session.IP
+	stored.LastSeenAt = now()
+	session.LastSeenAt = stored.LastSeenAt
+	s.addRefreshToken(session.ID, refreshTokenHash)
+	return nil
+}
+
+// RevokeSession revokes one of a user's active sessions.
+func (r *sessionRepository) RevokeSession(ctx context.Context, userID, id int, reason string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok || session.UserID != userID || session.RevokedAt != nil {
+		return apperr.NotFound("RevokeSession: session not found")
+	}
+	revokedAt := now()
+	session.RevokedAt = &revokedAt
+	session.RevokeReason = reason
+	return nil
+}
+
+// RevokeUserSessions revokes all of a user's active sessions except exceptID
+// and returns how many it revoked.
+func (r *sessionRepository) RevokeUserSessions(ctx context.Context, userID, exceptID int, reason string) (int, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	revoked := 0
+	revokedAt := now()
+	for _, session := range s.sessions {
+		if session.UserID == userID && session.ID != exceptID && session.RevokedAt == nil {
+			at := revokedAt
+			session.RevokedAt = &at
+			session.RevokeReason = reason
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+// CountActiveSessions returns the number of sessions that are neither revoked
+// nor expired.
+func (r *sessionRepository) CountActiveSessions(ctx context.Context, now time.Time) (int, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	count := 0
+	for _, session := range s.sessions {
+		if session.RevokedAt == nil && session.ExpiresAt.After(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// tokenHashTaken reports whether a session already has the access token hash
+// or a refresh token has the refresh token hash, mirroring the UNIQUE
+// constraints.
+func (s *Store) tokenHashTaken(accessTokenHash, refreshTokenHash string) bool {
+	for _, session := range s.sessions {
+		if session.AccessTokenHash == accessTokenHash {
+			return true
+		}
+	}
+	for _, token := range s.refreshTokens {
+		if token.TokenHash == refreshTokenHash {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) addRefreshToken(sessionID int, tokenHash string) {
+	id := s.nextID("refresh_tokens")
+	s.refreshTokens[id] = &models.RefreshToken{ID: id, SessionID: sessionID, TokenHash: tokenHash, CreatedAt: now()}
+}
+
+func copySession(session *models.Session) *models.Session {
+	found := *session
+	if session.RevokedAt != nil {
+		revokedAt := *session.RevokedAt
+		found.RevokedAt = &revokedAt
+	}
+	return &found
+}
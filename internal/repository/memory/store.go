@@ -0,0 +1,312 @@
+// File: internal/repository/memory/store.go
+
+// Package memory implements the repository interfaces on in-process maps. It
+// mirrors the SQL schema's constraints, cascades and error messages so the
+// server can run without a database file, for demos and service-level tests.
+// Nothing is persisted.
+package memory
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+)
+
+// errForeignKey matches what SQLite reports for a foreign key violation.
+var errForeignKey = errors.New("FOREIGN KEY constraint failed")
+
+// userKarma mirrors a row of the user_karma table.
+type userKarma struct {
+	postKarma    int
+	commentKarma int
+}
+
+// Store holds every table. All repositories created from one Store share its
+// data and its lock, so cascading deletes stay consistent across them.
+type Store struct {
+	mu sync.RWMutex
+
+	users         map[int]*models.User
+	subreddits    map[int]*models.Subreddit
+	posts         map[int]*models.Post
+	comments      map[int]*models.Comment
+	votes         map[int]*models.Vote
+	messages      map[int]*models.Message
+	blocks        map[int]*models.Block
+	logins        map[int]*models.LoginAttempt
+	tokens        map[int]*models.UserToken
+	recovery      map[int]*models.RecoveryCode
+	sessions      map[int]*models.Session
+	refreshTokens map[int]*models.RefreshToken
+	apiKeys       map[int]*models.APIKey
+	oauthClients  map[string]*models.OAuthClient // Keyed by client ID.
+	oauthConsents map[int]*models.OAuthConsent
+	oauthCodes    map[int]*models.OAuthCode
+	oauthTokens   map[int]*models.OAuthToken
+	audit         map[int]*models.AuditEntry
+	totp          map[int]*models.TwoFactor // Keyed by user ID; EnabledAt lives on the user.
+	karma         map[int]*userKarma        // Keyed by user ID.
+	flags         map[int]*models.VoteFlag  // Keyed by vote ID.
+
+	lastID map[string]int // Last ID handed out per table, like AUTOINCREMENT.
+}
+
+// NewStore creates an empty Store.
+func NewStore() *Store {
+	return &Store{
+		users:         make(map[int]*models.User),
+		subreddits:    make(map[int]*models.Subreddit),
+		posts:         make(map[int]*models.Post),
+		comments:      make(map[int]*models.Comment),
+		votes:         make(map[int]*models.Vote),
+		messages:      make(map[int]*models.Message),
+		blocks:        make(map[int]*models.Block),
+		logins:        make(map[int]*models.LoginAttempt),
+		tokens:        make(map[int]*models.UserToken),
+		recovery:      make(map[int]*models.RecoveryCode),
+		totp:          make(map[int]*models.TwoFactor),
+		sessions:      make(map[int]*models.Session),
+		refreshTokens: make(map[int]*models.RefreshToken),
+		apiKeys:       make(map[int]*models.APIKey),
+		oauthClients:  make(map[string]*models.OAuthClient),
+		oauthConsents: make(map[int]*models.OAuthConsent),
+		oauthCodes:    make(map[int]*models.OAuthCode),
+		oauthTokens:   make(map[int]*models.OAuthToken),
+		audit:         make(map[int]*models.AuditEntry),
+		karma:         make(map[int]*userKarma),
+		flags:         make(map[int]*models.VoteFlag),
+		lastID:        make(map[string]int),
+	}
+}
+
+// NewRepositories creates in-memory implementations of every repository
+// interface backed by a fresh Store.
+func NewRepositories() repository.Repositories {
+	store := NewStore()
+	return repository.Repositories{
+		Users:         NewUserRepository(store),
+		Subreddits:    NewSubredditRepository(store),
+		Posts:         NewPostRepository(store),
+		Comments:      NewCommentRepository(store),
+		Votes:         NewVoteRepository(store),
+		Messages:      NewMessageRepository(store),
+		Blocks:        NewBlockRepository(store),
+		VoteIntegrity: NewVoteIntegrityRepository(store),
+		LoginAttempts: NewLoginAttemptRepository(store),
+		UserTokens:    NewUserTokenRepository(store),
+		RecoveryCodes: NewRecoveryCodeRepository(store),
+		Sessions:      NewSessionRepository(store),
+		APIKeys:       NewAPIKeyRepository(store),
+		OAuth:         NewOAuthRepository(store),
+		Audit:         NewAuditRepository(store),
+	}
+}
+
+// nextID returns the next ID for a table. IDs are never reused.
+func (s *Store) nextID(table string) int {
+	s.lastID[table]++
+	return s.lastID[table]
+}
+
+// now returns the timestamp stored for CURRENT_TIMESTAMP, truncated to whole
+// seconds as SQLite stores it so listings break ties the same way.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// paginate applies LIMIT and OFFSET to a result set. A negative limit means no
+// limit, as in SQLite.
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit >= 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// window applies page to rows already in listing order, ordered by creation
+// time and ID, newest first when newestFirst is set. It mirrors the keyset
+// queries of the SQL repositories.
+func window[T any](rows []T, page repository.Page, newestFirst bool, cursorOf func(T) repository.Cursor) []T {
+	// precedes reports whether a comes before b in listing order
+	precedes := func(a, b repository.Cursor) bool {
+		if newestFirst {
+			a, b = b, a
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	}
+	switch {
+	case page.Before != nil:
+		end := 0
+		for end < len(rows) && precedes(cursorOf(rows[end]), *page.Before) {
+			end++
+		}
+		return rows[max(0, end-page.Limit):end]
+	case page.After != nil:
+		start := 0
+		for start < len(rows) && !precedes(*page.After, cursorOf(rows[start])) {
+			start++
+		}
+		return paginate(rows[start:], page.Limit, 0)
+	default:
+		return paginate(rows, page.Limit, page.Offset)
+	}
+}
+
+// copyIntPtr copies a nullable ID so callers cannot modify stored rows.
+func copyIntPtr(p *int) *int {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// sameID reports whether two nullable IDs are equal, treating NULL as equal to
+// nothing, as SQL comparisons do.
+func sameID(a, b *int) bool {
+	return a != nil && b != nil && *a == *b
+}
+
+// locked reports whether a user's account is suspended or being deleted,
+// which keeps its API keys and OAuth2 tokens from working.
+func (s *Store) locked(userID int) bool {
+	user, ok := s.users[userID]
+	return ok && (user.SuspendedAt != nil || user.DeletionRequestedAt != nil)
+}
+
+// deleteUserData removes what is private to an account when it is
+// anonymized, as UserRepository.AnonymizeUser does in SQL: its sign-ins,
+// credentials, grants, OAuth2 clients, login history, email tokens and
+// blocks either way. Its posts, comments, votes, messages and subreddits
+// stay. Callers must hold s.mu for writing.
+func (s *Store) deleteUserData(id int) {
+	for bid, block := range s.blocks {
+		if block.BlockerID == id || block.BlockedID == id {
+			delete(s.blocks, bid)
+		}
+	}
+	for lid, attempt := range s.logins {
+		if attempt.UserID != nil && *attempt.UserID == id {
+			delete(s.logins, lid)
+		}
+	}
+	for tid, token := range s.tokens {
+		if token.UserID == id {
+			delete(s.tokens, tid)
+		}
+	}
+	for cid, code := range s.recovery {
+		if code.UserID == id {
+			delete(s.recovery, cid)
+		}
+	}
+	delete(s.totp, id)
+	for sid, session := range s.sessions {
+		if session.UserID == id {
+			delete(s.sessions, sid)
+			for rid, token := range s.refreshTokens {
+				if token.SessionID == sid {
+					delete(s.refreshTokens, rid)
+				}
+			}
+		}
+	}
+	for kid, key := range s.apiKeys {
+		if key.UserID == id {
+			delete(s.apiKeys, kid)
+		}
+	}
+	for cid, client := range s.oauthClients {
+		if client.OwnerID == id {
+			s.deleteOAuthClient(cid)
+		}
+	}
+	for cid, consent := range s.oauthConsents {
+		if consent.UserID == id {
+			delete(s.oauthConsents, cid)
+		}
+	}
+	for cid, code := range s.oauthCodes {
+		if code.UserID == id {
+			delete(s.oauthCodes, cid)
+		}
+	}
+	for tid, token := range s.oauthTokens {
+		if token.UserID == id {
+			delete(s.oauthTokens, tid)
+		}
+	}
+}
+
+// The delete* helpers remove a row and everything that references it with ON
+// DELETE CASCADE. Callers must hold s.mu for writing.
+
+func (s *Store) deleteSubreddit(id int) {
+	delete(s.subreddits, id)
+	for pid, post := range s.posts {
+		if post.SubredditID == id {
+			s.deletePost(pid)
+		}
+	}
+}
+
+func (s *Store) deletePost(id int) {
+	delete(s.posts, id)
+	for cid, comment := range s.comments {
+		if comment.PostID == id {
+			s.deleteComment(cid)
+		}
+	}
+	for vid, vote := range s.votes {
+		if vote.PostID != nil && *vote.PostID == id {
+			s.deleteVote(vid)
+		}
+	}
+}
+
+func (s *Store) deleteComment(id int) {
+	if _, ok := s.comments[id]; !ok {
+		return
+	}
+	delete(s.comments, id)
+	for cid, comment := range s.comments {
+		if comment.ParentID != nil && *comment.ParentID == id {
+			s.deleteComment(cid)
+		}
+	}
+	for vid, vote := range s.votes {
+		if vote.CommentID != nil && *vote.CommentID == id {
+			s.deleteVote(vid)
+		}
+	}
+}
+
+func (s *Store) deleteVote(id int) {
+	delete(s.votes, id)
+	delete(s.flags, id)
+}
+
+func (s *Store) deleteMessage(id int) {
+	if _, ok := s.messages[id]; !ok {
+		return
+	}
+	delete(s.messages, id)
+	for mid, message := range s.messages {
+		if message.ParentID != nil && *message.ParentID == id {
+			s.deleteMessage(mid)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// File: internal/repository/memory/subreddit_repository.go
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+)
+
+type subredditRepository struct {
+	store *Store
+}
+
+// NewSubredditRepository creates an in-memory SubredditRepository.
+func NewSubredditRepository(store *Store) repository.SubredditRepository {
+	return &subredditRepository{store: store}
+}
+
+// CreateSubreddit stores a new subreddit.
+func (r *subredditRepository) CreateSubreddit(ctx context.Context, subreddit *models.Subreddit) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subredditNameTaken(0, subreddit.Name) {
+		return apperr.Conflict("CreateSubreddit: subreddit already exists")
+	}
+	if _, ok := s.users[subreddit.CreatedBy]; !ok {
+		return fmt.Errorf("CreateSubreddit: %v", errForeignKey)
+	}
+	subreddit.ID = s.nextID("subreddits")
+	subreddit.QuarantinedAt = nil
+	subreddit.QuarantineReason = ""
+	subreddit.CreatedAt = now()
+	subreddit.UpdatedAt = subreddit.CreatedAt
+	stored := *subreddit
+	s.subreddits[subreddit.ID] = &stored
+	return nil
+}
+
+// GetSubredditByID retrieves a subreddit by its ID.
+func (r *subredditRepository) GetSubredditByID(ctx context.Context, id int) (*models.Subreddit, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	subreddit, ok := s.subreddits[id]
+	if !ok {
+		return nil, apperr.NotFound("GetSubredditByID: subreddit not found")
+	}
+	found := *subreddit
+	return &found, nil
+}
+
+// GetSubredditByName retrieves a subreddit by its unique name.
+func (r *subredditRepository) GetSubredditByName(ctx context.Context, name string) (*models.Subreddit, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, subreddit := range s.subreddits {
+		if subreddit.Name == name {
+			found := *subreddit
+			return &found, nil
+		}
+	}
+	return nil, apperr.NotFound("GetSubredditByName: subreddit not found")
+}
+
+// UpdateSubreddit updates an existing subreddit's information.
+func (r *subredditRepository) UpdateSubreddit(ctx context.Context, subreddit *models.Subreddit) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.subreddits[subreddit.ID]
+	if !ok {
+		return apperr.NotFound("UpdateSubreddit: subreddit not found")
+	}
+	if s.subredditNameTaken(subreddit.ID, subreddit.Name) {
+		return apperr.Conflict("UpdateSubreddit: subreddit already exists")
+	}
+	stored.Name = subreddit.Name
+	stored.Description = subreddit.Description
+	stored.UpdatedAt = now()
+	subreddit.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+// DeleteSubreddit removes a subreddit and its posts.
+func (r *subredditRepository) DeleteSubreddit(ctx context.Context, id int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subreddits[id]; !ok {
+		return apperr.NotFound("DeleteSubreddit: no subreddit found to delete")
+	}
+	s.deleteSubreddit(id)
+	return nil
+}
+
+// Quarantine quarantines a subreddit, or updates the reason if it already is.
+func (r *subredditRepository) Quarantine(ctx context.Context, id int, reason string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.subreddits[id]
+	if !ok {
+		return apperr.NotFound("Quarantine: subreddit not found")
+	}
+	stored.UpdatedAt = now()
+	if stored.QuarantinedAt == nil {
+		quarantinedAt := stored.UpdatedAt
+		stored.QuarantinedAt = &quarantinedAt
+	}
+	stored.QuarantineReason = reason
+	return nil
+}
+
+// Unquarantine lifts a subreddit's quarantine.
+func (r *subredditRepository) Unquarantine(ctx context.Context, id int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.subreddits[id]
+	if !ok {
+		return apperr.NotFound("Unquarantine: subreddit not found")
+	}
+	stored.QuarantinedAt = nil
+	stored.QuarantineReason = ""
+	stored.UpdatedAt = now()
+	return nil
+}
+
+// TransferOwnership makes another user the subreddit's owner.
+func (r *subredditRepository) TransferOwnership(ctx context.Context, id, ownerID int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.subreddits[id]
+	if !ok {
+		return apperr.NotFound("TransferOwnership: subreddit not found")
+	}
+	if _, ok := s.users[ownerID]; !ok {
+		return fmt.Errorf("TransferOwnership: %v", errForeignKey)
+	}
+	stored.CreatedBy = ownerID
+	stored.UpdatedAt = now()
+	return nil
+}
+
+// GetSubredditIDsByCreator retrieves the IDs of the subreddits a user owns.
+func (r *subredditRepository) GetSubredditIDsByCreator(ctx context.Context, userID int) ([]int, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var ids []int
+	for _, subreddit := range s.subreddits {
+		if subreddit.CreatedBy == userID {
+			ids = append(ids, subreddit.ID)
+		}
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+// GetTopContributor finds the user other than excludeUserID with the most
+// posts and comments in a subreddit, ties going to the lowest ID. Suspended
+// accounts and accounts being deleted, or already deleted, are passed over.
+// It fails with apperr.NotFound if nobody qualifies.
+func (r *subredditRepository) GetTopContributor(ctx context.Context, id, excludeUserID int) (int, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	counts := make(map[int]int)
+	for _, post := range s.posts {
+		if post.SubredditID == id {
+			counts[post.AuthorID]++
+		}
+	}
+	for _, comment := range s.comments {
+		if post, ok := s.posts[comment.PostID]; ok && post.SubredditID == id {
+			counts[comment.AuthorID]++
+		}
+	}
+	top, topCount := 0, 0
+	for userID, count := range counts {
+		user, ok := s.users[userID]
+		if !ok || userID == excludeUserID || user.SuspendedAt != nil || user.DeletionRequestedAt != nil {
+			continue
+		}
+		if count > topCount || (count == topCount && userID < top) {
+			top, topCount = userID, count
+		}
+	}
+	if top == 0 {
+		return 0, apperr.NotFound("GetTopContributor: no contributor found")
+	}
+	return top, nil
+}
+
+// subredditNameTaken reports whether another subreddit already has the name,
+// mirroring the UNIQUE constraint and ignoring the subreddit being updated.
+func (s *Store) subredditNameTaken(id int, name string) bool {
+	for _, other := range s.subreddits {
+		if other.ID != id && other.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,423 @@
+// File: internal/repository/memory/user_repository.go
+
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+)
+
+type userRepository struct {
+	store *Store
+}
+
+// NewUserRepository creates an in-memory UserRepository.
+func NewUserRepository(store *Store) repository.UserRepository {
+	return &userRepository{store: store}
+}
+
+// CreateUser stores a new user.
+func (r *userRepository) CreateUser(ctx context.Context, user *models.User) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.userTaken(0, user.Username, user.Email) {
+		return apperr.Conflict("CreateUser: user already exists")
+	}
+	user.ID = s.nextID("users")
+	user.EmailVerifiedAt = nil
+	user.TOTPEnabledAt = nil
+	user.Role = models.RoleUser
+	user.SuspendedAt = nil
+	user.SuspensionReason = ""
+	user.DeletionRequestedAt = nil
+	user.DeletedAt = nil
+	user.CreatedAt = now()
+	user.UpdatedAt = user.CreatedAt
+	stored := *user
+	s.users[user.ID] = &stored
+	return nil
+}
+
+// GetUserByID retrieves a user by their ID.
+func (r *userRepository) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	user, ok := s.users[id]
+	if !ok {
+		return nil, apperr.NotFound("GetUserByID: user not found")
+	}
+	found := *user
+	return &found, nil
+}
+
+// GetUserByUsername retrieves a user by their username. Deleted accounts
+// are not found.
+func (r *userRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, user := range s.users {
+		if user.Username == username && user.DeletedAt == nil {
+			found := *user
+			return &found, nil
+		}
+	}
+	return nil, apperr.NotFound("GetUserByUsername: user not found")
+}
+
+// GetUserByEmail retrieves a user by their email address. Deleted accounts
+// are not found.
+func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, user := range s.users {
+		if user.Email == email && user.DeletedAt == nil {
+			found := *user
+			return &found, nil
+		}
+	}
+	return nil, apperr.NotFound("GetUserByEmail: user not found")
+}
+
+// UpdateUser updates an existing user's information. Changing the email
+// address clears its verification.
+func (r *userRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.users[user.ID]
+	if !ok {
+		return apperr.NotFound("UpdateUser: user not found")
+	}
+	if s.userTaken(user.ID, user.Username, user.Email) {
+		return apperr.Conflict("UpdateUser: user already exists")
+	}
+	if stored.Email != user.Email {
+		stored.EmailVerifiedAt = nil
+	}
+	stored.Username = user.Username
+	stored.Email = user.Email
+	stored.Password = user.Password
+	stored.UpdatedAt = now()
+	user.UpdatedAt = stored.UpdatedAt
+	user.EmailVerifiedAt = stored.EmailVerifiedAt
+	return nil
+}
+
+// MarkEmailVerified records that a user verified their email address. It
+// fails with apperr.NotFound if the user no longer has that address.
+func (r *userRepository) MarkEmailVerified(ctx context.Context, userID int, email string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.users[userID]
+	if !ok || stored.Email != email {
+		return apperr.NotFound("MarkEmailVerified: user not found or email changed")
+	}
+	if stored.EmailVerifiedAt == nil {
+		verifiedAt := now()
+		stored.EmailVerifiedAt = &verifiedAt
+	}
+	return nil
+}
+
+// SetBot marks a user's account as run by automation, or not.
+func (r *userRepository) SetBot(ctx context.Context, userID int, isBot bool) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.users[userID]
+	if !ok {
+		return apperr.NotFound("SetBot: user not found")
+	}
+	stored.IsBot = isBot
+	stored.UpdatedAt = now()
+	return nil
+}
+
+// SetRole changes a user's role.
+func (r *userRepository) SetRole(ctx context.Context, userID int, role string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.users[userID]
+	if !ok {
+		return apperr.NotFound("SetRole: user not found")
+	}
+	stored.Role = role
+	stored.UpdatedAt = now()
+	return nil
+}
+
+// Suspend suspends a user's account site-wide, failing with apperr.Conflict
+// if it is missing or already suspended.
+func (r *userRepository) Suspend(ctx context.Context, userID int, reason string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.users[userID]
+	if !ok || stored.SuspendedAt != nil {
+		return apperr.Conflict("Suspend: user not found or already suspended")
+	}
+	suspendedAt := now()
+	stored.SuspendedAt = &suspendedAt
+	stored.SuspensionReason = reason
+	stored.UpdatedAt = suspendedAt
+	return nil
+}
+
+// Unsuspend lifts a user's suspension, failing with apperr.Conflict if the
+// account is missing or not suspended.
+func (r *userRepository) Unsuspend(ctx context.Context, userID int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.users[userID]
+	if !ok || stored.SuspendedAt == nil {
+		return apperr.Conflict("Unsuspend: user not found or not suspended")
+	}
+	stored.SuspendedAt = nil
+	stored.SuspensionReason = ""
+	stored.UpdatedAt = now()
+	return nil
+}
+
+// GetTwoFactor retrieves a user's two-factor authentication state.
+func (r *userRepository) GetTwoFactor(ctx context.Context, userID int) (*models.TwoFactor, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	user, ok := s.users[userID]
+	if !ok {
+		return nil, apperr.NotFound("GetTwoFactor: user not found")
+	}
+	tf := &models.TwoFactor{UserID: userID, EnabledAt: user.TOTPEnabledAt}
+	if stored, ok := s.totp[userID]; ok {
+		tf.Secret = stored.Secret
+		if stored.LastStep != nil {
+			step := *stored.LastStep
+			tf.LastStep = &step
+		}
+	}
+	return tf, nil
+}
+
+// SetTOTPSecret stores a new, not yet confirmed, TOTP secret for a user who
+// does not have two-factor authentication on.
+func (r *userRepository) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[userID]
+	if !ok || user.TOTPEnabledAt != nil {
+		return apperr.Conflict("SetTOTPSecret: two-factor authentication is already enabled")
+	}
+	s.totp[userID] = &models.TwoFactor{UserID: userID, Secret: secret}
+	return nil
+}
+
+// EnableTOTP turns two-factor authentication on with the pending secret,
+// recording step as used.
+func (r *userRepository) EnableTOTP(ctx context.Context, userID int, step int64) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[userID]
+	tf, pending := s.totp[userID]
+	if !ok || !pending || user.TOTPEnabledAt != nil {
+		return apperr.Conflict("EnableTOTP: no pending two-factor enrollment")
+	}
+	enabledAt := now()
+	user.TOTPEnabledAt = &enabledAt
+	tf.LastStep = &step
+	return nil
+}
+
+// DisableTOTP turns two-factor authentication off and forgets the secret.
+func (r *userRepository) DisableTOTP(ctx context.Context, userID int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[userID]
+	if !ok {
+		return apperr.NotFound("DisableTOTP: user not found")
+	}
+	user.TOTPEnabledAt = nil
+	delete(s.totp, userID)
+	return nil
+}
+
+// UseTOTPStep records that the code for step was accepted, failing for a
+// step at or before the last one used.
+func (r *userRepository) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tf, ok := s.totp[userID]
+	if !ok || (tf.LastStep != nil && *tf.LastStep >= step) {
+		return apperr.Conflict("UseTOTPStep: code already used")
+	}
+	tf.LastStep = &step
+	return nil
+}
+
+// RequestDeletion schedules a user's account for deletion, failing with
+// apperr.Conflict if it is missing or already being deleted.
+func (r *userRepository) RequestDeletion(ctx context.Context, userID int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.users[userID]
+	if !ok || stored.DeletionRequestedAt != nil || stored.DeletedAt != nil {
+		return apperr.Conflict("RequestDeletion: user not found or already being deleted")
+	}
+	requestedAt := now()
+	stored.DeletionRequestedAt = &requestedAt
+	stored.UpdatedAt = requestedAt
+	return nil
+}
+
+// CancelDeletion takes back a scheduled deletion that has not happened yet,
+// failing with apperr.Conflict if there is none.
+func (r *userRepository) CancelDeletion(ctx context.Context, userID int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.users[userID]
+	if !ok || stored.DeletionRequestedAt == nil || stored.DeletedAt != nil {
+		return apperr.Conflict("CancelDeletion: user not found or not being deleted")
+	}
+	stored.DeletionRequestedAt = nil
+	stored.UpdatedAt = now()
+	return nil
+}
+
+// GetUsersDueForDeletion retrieves the IDs of up to limit accounts whose
+// deletion was requested before the given time and has not happened yet,
+// oldest request first.
+func (r *userRepository) GetUsersDueForDeletion(ctx context.Context, requestedBefore time.Time, limit int) ([]int, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var due []*models.User
+	for _, user := range s.users {
+		if user.DeletionRequestedAt != nil && !user.DeletionRequestedAt.After(requestedBefore) && user.DeletedAt == nil {
+			due = append(due, user)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		a, b := due[i], due[j]
+		if !a.DeletionRequestedAt.Equal(*b.DeletionRequestedAt) {
+			return a.DeletionRequestedAt.Before(*b.DeletionRequestedAt)
+		}
+		return a.ID < b.ID
+	})
+	var ids []int
+	for _, user := range paginate(due, limit, 0) {
+		ids = append(ids, user.ID)
+	}
+	return ids, nil
+}
+
+// AnonymizeUser carries out a scheduled deletion. The account stays, so the
+// user's posts, comments, votes, messages and subreddits keep their author,
+// but its username and email are replaced with placeholders, its password,
+// two-factor and moderation state are cleared, and its private data is
+// removed. It fails with apperr.Conflict if the account is missing, not
+// scheduled for deletion or already anonymized.
+func (r *userRepository) AnonymizeUser(ctx context.Context, userID int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.users[userID]
+	if !ok || stored.DeletionRequestedAt == nil || stored.DeletedAt != nil {
+		return apperr.Conflict("AnonymizeUser: user not found or not scheduled for deletion")
+	}
+	deletedAt := now()
+	stored.Username, stored.Email = models.DeletedAccount(userID)
+	stored.Password = ""
+	stored.EmailVerifiedAt = nil
+	stored.TOTPEnabledAt = nil
+	stored.IsBot = false
+	stored.Role = models.RoleUser
+	stored.SuspendedAt = nil
+	stored.SuspensionReason = ""
+	stored.DeletedAt = &deletedAt
+	stored.UpdatedAt = deletedAt
+	s.deleteUserData(userID)
+	return nil
+}
+
+// GetUserProfile retrieves a user's public profile with karma and activity counts.
+func (r *userRepository) GetUserProfile(ctx context.Context, id int) (*models.UserProfile, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	user, ok := s.users[id]
+	if !ok {
+		return nil, apperr.NotFound("GetUserProfile: user not found")
+	}
+	profile := &models.UserProfile{
+		ID:        user.ID,
+		Username:  user.Username,
+		IsBot:     user.IsBot,
+		CreatedAt: user.CreatedAt,
+		Deleted:   user.DeletedAt != nil,
+	}
+	if k, ok := s.karma[id]; ok {
+		profile.PostKarma = k.postKarma
+		profile.CommentKarma = k.commentKarma
+	}
+	for _, post := range s.posts {
+		if post.AuthorID == id {
+			profile.PostCount++
+		}
+	}
+	for _, comment := range s.comments {
+		if comment.AuthorID == id {
+			profile.CommentCount++
+		}
+	}
+	profile.Karma = profile.PostKarma + profile.CommentKarma
+	return profile, nil
+}
+
+// GetUserOverview retrieves a user's posts and comments interleaved in the given
+// sort order with pagination.
+func (r *userRepository) GetUserOverview(ctx context.Context, userID int, sort string, limit, offset int) ([]*models.ActivityItem, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var entries []activityEntry
+	for _, post := range s.posts {
+		if post.AuthorID == userID {
+			entries = append(entries, s.postEntry(post))
+		}
+	}
+	for _, comment := range s.comments {
+		if comment.AuthorID == userID {
+			entries = append(entries, s.commentEntry(comment))
+		}
+	}
+	sortActivity(entries, sort)
+	return activityItems(paginate(entries, limit, offset)), nil
+}
+
+// userTaken reports whether another user already holds the username or email,
+// mirroring the UNIQUE constraints and ignoring the user being updated.
+func (s *Store) userTaken(id int, username, email string) bool {
+	for _, other := range s.users {
+		if other.ID != id && (other.Username == username || other.Email == email) {
+			return true
+		}
+	}
+	return false
+}
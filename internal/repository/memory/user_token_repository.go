@@ -0,0 +1,72 @@
+// File: internal/repository/memory/user_token_repository.go
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+)
+
+type userTokenRepository struct {
+	store *Store
+}
+
+// NewUserTokenRepository creates an in-memory UserTokenRepository.
+func NewUserTokenRepository(store *Store) repository.UserTokenRepository {
+	return &userTokenRepository{store: store}
+}
+
+// CreateUserToken stores a new token.
+func (r *userTokenRepository) CreateUserToken(ctx context.Context, token *models.UserToken) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[token.UserID]; !ok {
+		return fmt.Errorf("CreateUserToken: %v", errForeignKey)
+	}
+	for _, other := range s.tokens {
+		if other.TokenHash == token.TokenHash {
+			return apperr.Conflict("CreateUserToken: token already exists")
+		}
+	}
+	token.ID = s.nextID("user_tokens")
+	token.UsedAt = nil
+	token.CreatedAt = now()
+	stored := *token
+	s.tokens[token.ID] = &stored
+	return nil
+}
+
+// ConsumeUserToken marks an unused, unexpired token as used and returns it.
+func (r *userTokenRepository) ConsumeUserToken(ctx context.Context, purpose, tokenHash string, now time.Time) (*models.UserToken, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, token := range s.tokens {
+		if token.TokenHash == tokenHash && token.Purpose == purpose && token.UsedAt == nil && token.ExpiresAt.After(now) {
+			usedAt := now.UTC().Truncate(time.Second)
+			token.UsedAt = &usedAt
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, apperr.NotFound("ConsumeUserToken: token is invalid, expired or already used")
+}
+
+// DeleteUserTokens removes a user's outstanding tokens for a purpose.
+func (r *userTokenRepository) DeleteUserTokens(ctx context.Context, userID int, purpose string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, token := range s.tokens {
+		if token.UserID == userID && token.Purpose == purpose {
+			delete(s.tokens, id)
+		}
+	}
+	return nil
+}
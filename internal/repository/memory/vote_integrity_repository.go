@@ -0,0 +1,200 @@
+// File: internal/repository/memory/vote_integrity_repository.go
+
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+)
+
+type voteIntegrityRepository struct {
+	store *Store
+}
+
+// NewVoteIntegrityRepository creates an in-memory VoteIntegrityRepository.
+func NewVoteIntegrityRepository(store *Store) repository.VoteIntegrityRepository {
+	return &voteIntegrityRepository{store: store}
+}
+
+// CountRecentVoters counts the distinct users who voted on a post or comment since the given time.
+func (r *voteIntegrityRepository) CountRecentVoters(ctx context.Context, postID, commentID *int, since time.Time) (int, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	voters := make(map[int]bool)
+	for _, vote := range s.votes {
+		if (sameID(vote.PostID, postID) || sameID(vote.CommentID, commentID)) && !vote.CreatedAt.Before(since) {
+			voters[vote.UserID] = true
+		}
+	}
+	return len(voters), nil
+}
+
+// GetLockstepStats looks at a user's most recent votes and reports how many
+// there are and the largest number of them any single other user matched with
+// the same vote on the same target.
+func (r *voteIntegrityRepository) GetLockstepStats(ctx context.Context, userID int, recent int) (int, int, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var mine []*models.Vote
+	for _, vote := range s.votes {
+		if vote.UserID == userID {
+			mine = append(mine, vote)
+		}
+	}
+	sort.Slice(mine, func(i, j int) bool {
+		if !mine[i].CreatedAt.Equal(mine[j].CreatedAt) {
+			return mine[i].CreatedAt.After(mine[j].CreatedAt)
+		}
+		return mine[i].ID > mine[j].ID
+	})
+	mine = paginate(mine, recent, 0)
+
+	shared := make(map[int]int)
+	maxShared := 0
+	for _, m := range mine {
+		for _, o := range s.votes {
+			if o.UserID == userID || o.VoteType != m.VoteType {
+				continue
+			}
+			if sameID(o.PostID, m.PostID) || sameID(o.CommentID, m.CommentID) {
+				shared[o.UserID]++
+				maxShared = max(maxShared, shared[o.UserID])
+			}
+		}
+	}
+	return len(mine), maxShared, nil
+}
+
+// CreateVoteFlag records a suspicious vote and takes it back out of karma.
+func (r *voteIntegrityRepository) CreateVoteFlag(ctx context.Context, flag *models.VoteFlag) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vote, ok := s.votes[flag.VoteID]
+	if !ok {
+		return apperr.NotFound("CreateVoteFlag: vote not found")
+	}
+	if _, ok := s.flags[flag.VoteID]; ok {
+		return apperr.Conflict("CreateVoteFlag: vote flag already exists")
+	}
+	flag.CreatedAt = now()
+	stored := *flag
+	stored.Reasons = slices.Clone(flag.Reasons)
+	s.flags[flag.VoteID] = &stored
+	s.addVoteKarma(vote, -vote.Value())
+	return nil
+}
+
+// IsVoteFlagged reports whether a vote has been flagged as suspicious.
+func (r *voteIntegrityRepository) IsVoteFlagged(ctx context.Context, voteID int) (bool, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.flags[voteID]
+	return ok, nil
+}
+
+// GetVoteFlags retrieves flagged votes, most recent first, with pagination.
+// The voter and target are read from the vote, as the SQL join does.
+func (r *voteIntegrityRepository) GetVoteFlags(ctx context.Context, limit, offset int) ([]*models.VoteFlag, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var flags []*models.VoteFlag
+	for voteID, flag := range s.flags {
+		vote := s.votes[voteID]
+		found := *flag
+		found.UserID = vote.UserID
+		found.PostID = copyIntPtr(vote.PostID)
+		found.CommentID = copyIntPtr(vote.CommentID)
+		found.VoteType = vote.VoteType
+		found.Reasons = slices.Clone(flag.Reasons)
+		flags = append(flags, &found)
+	}
+	sort.Slice(flags, func(i, j int) bool {
+		if !flags[i].CreatedAt.Equal(flags[j].CreatedAt) {
+			return flags[i].CreatedAt.After(flags[j].CreatedAt)
+		}
+		return flags[i].VoteID > flags[j].VoteID
+	})
+	return paginate(flags, limit, offset), nil
+}
+
+// CountVoteFlags counts all flagged votes.
+func (r *voteIntegrityRepository) CountVoteFlags(ctx context.Context) (int, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.flags), nil
+}
+
+// CountVoteFlagsByReason counts the flagged votes that list the given reason.
+func (r *voteIntegrityRepository) CountVoteFlagsByReason(ctx context.Context, reason string) (int, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	count := 0
+	for _, flag := range s.flags {
+		if slices.Contains(flag.Reasons, reason) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetTopFlaggedTargets retrieves the posts and comments with the most flagged votes.
+func (r *voteIntegrityRepository) GetTopFlaggedTargets(ctx context.Context, limit int) ([]*models.FlaggedTarget, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	type key struct{ postID, commentID int } // Zero stands in for NULL.
+	byTarget := make(map[key]*models.FlaggedTarget)
+	for voteID := range s.flags {
+		vote := s.votes[voteID]
+		var k key
+		if vote.PostID != nil {
+			k.postID = *vote.PostID
+		}
+		if vote.CommentID != nil {
+			k.commentID = *vote.CommentID
+		}
+		target, ok := byTarget[k]
+		if !ok {
+			target = &models.FlaggedTarget{
+				PostID:    copyIntPtr(vote.PostID),
+				CommentID: copyIntPtr(vote.CommentID),
+			}
+			byTarget[k] = target
+		}
+		target.FlaggedVotes++
+	}
+
+	keys := make([]key, 0, len(byTarget))
+	for k := range byTarget {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := byTarget[keys[i]], byTarget[keys[j]]
+		if a.FlaggedVotes != b.FlaggedVotes {
+			return a.FlaggedVotes > b.FlaggedVotes
+		}
+		if keys[i].postID != keys[j].postID {
+			return keys[i].postID < keys[j].postID
+		}
+		return keys[i].commentID < keys[j].commentID
+	})
+
+	var targets []*models.FlaggedTarget
+	for _, k := range paginate(keys, limit, 0) {
+		targets = append(targets, byTarget[k])
+	}
+	return targets, nil
+}
@@ -0,0 +1,228 @@
+// File: internal/repository/memory/vote_repository.go
+
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+)
+
+type voteRepository struct {
+	store *Store
+}
+
+// NewVoteRepository creates an in-memory VoteRepository.
+func NewVoteRepository(store *Store) repository.VoteRepository {
+	return &voteRepository{store: store}
+}
+
+// CreateVote stores a new vote and adds it to the karma of the post or comment
+// and of its author.
+func (r *voteRepository) CreateVote(ctx context.Context, vote *models.Vote) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if vote.VoteType != models.Upvote && vote.VoteType != models.Downvote {
+		return fmt.Errorf("CreateVote: CHECK constraint failed: vote_type IN ('upvote', 'downvote')")
+	}
+	_, userOK := s.users[vote.UserID]
+	postOK, commentOK := true, true
+	if vote.PostID != nil {
+		_, postOK = s.posts[*vote.PostID]
+	}
+	if vote.CommentID != nil {
+		_, commentOK = s.comments[*vote.CommentID]
+	}
+	if !userOK || !postOK || !commentOK {
+		return fmt.Errorf("CreateVote: %v", errForeignKey)
+	}
+	vote.ID = s.nextID("votes")
+	vote.CreatedAt = now()
+	vote.UpdatedAt = vote.CreatedAt
+	s.votes[vote.ID] = copyVote(vote)
+	s.addVoteKarma(vote, vote.Value())
+	return nil
+}
+
+// GetVoteByUserAndPost retrieves a vote by a user on a specific post.
+func (r *voteRepository) GetVoteByUserAndPost(ctx context.Context, userID, postID int) (*models.Vote, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if vote := s.findVote(userID, &postID, nil); vote != nil {
+		return vote, nil
+	}
+	return nil, apperr.NotFound("GetVoteByUserAndPost: vote not found")
+}
+
+// GetVoteByUserAndComment retrieves a vote by a user on a specific comment.
+func (r *voteRepository) GetVoteByUserAndComment(ctx context.Context, userID, commentID int) (*models.Vote, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if vote := s.findVote(userID, nil, &commentID); vote != nil {
+		return vote, nil
+	}
+	return nil, apperr.NotFound("GetVoteByUserAndComment: vote not found")
+}
+
+// UpdateVote updates an existing vote's type and, unless the vote is flagged,
+// moves the karma it contributes.
+func (r *voteRepository) UpdateVote(ctx context.Context, vote *models.Vote) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.votes[vote.ID]
+	if !ok {
+		return apperr.NotFound("UpdateVote: vote not found")
+	}
+	if _, flagged := s.flags[vote.ID]; !flagged {
+		s.addVoteKarma(stored, vote.Value()-stored.Value())
+	}
+	stored.VoteType = vote.VoteType
+	stored.UpdatedAt = now()
+	vote.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+// DeleteVote removes a vote and, unless it is flagged, takes it back out of karma.
+func (r *voteRepository) DeleteVote(ctx context.Context, vote *models.Vote) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.votes[vote.ID]
+	if !ok {
+		return apperr.NotFound("DeleteVote: no vote found to delete")
+	}
+	if _, flagged := s.flags[vote.ID]; !flagged {
+		s.addVoteKarma(stored, -stored.Value())
+	}
+	s.deleteVote(vote.ID)
+	return nil
+}
+
+// GetVotedItemsByUser retrieves the posts and comments a user voted on with the
+// given vote type, most recently voted first, with pagination.
+func (r *voteRepository) GetVotedItemsByUser(ctx context.Context, userID int, voteType string, limit, offset int) ([]*models.ActivityItem, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var entries []activityEntry
+	for _, vote := range s.votes {
+		if vote.UserID != userID || vote.VoteType != voteType {
+			continue
+		}
+		var entry activityEntry
+		switch {
+		case vote.PostID != nil && vote.CommentID == nil:
+			post, ok := s.posts[*vote.PostID]
+			if !ok {
+				continue
+			}
+			entry = s.postEntry(post)
+		case vote.CommentID != nil && vote.PostID == nil:
+			comment, ok := s.comments[*vote.CommentID]
+			if !ok {
+				continue
+			}
+			entry = s.commentEntry(comment)
+		default:
+			continue
+		}
+		entry.votedAt = vote.UpdatedAt
+		entries = append(entries, entry)
+	}
+	sortByVotedAt(entries)
+	return activityItems(paginate(entries, limit, offset)), nil
+}
+
+// GetUserVotesOnPosts retrieves a user's votes on the given posts, keyed by
+// post ID. Posts the user has not voted on are absent from the map.
+func (r *voteRepository) GetUserVotesOnPosts(ctx context.Context, userID int, postIDs []int) (map[int]string, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	votes := make(map[int]string, len(postIDs))
+	for _, id := range postIDs {
+		if vote := s.findVote(userID, &id, nil); vote != nil {
+			votes[id] = vote.VoteType
+		}
+	}
+	return votes, nil
+}
+
+// GetUserVotesOnComments retrieves a user's votes on the given comments, keyed
+// by comment ID. Comments the user has not voted on are absent from the map.
+func (r *voteRepository) GetUserVotesOnComments(ctx context.Context, userID int, commentIDs []int) (map[int]string, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	votes := make(map[int]string, len(commentIDs))
+	for _, id := range commentIDs {
+		if vote := s.findVote(userID, nil, &id); vote != nil {
+			votes[id] = vote.VoteType
+		}
+	}
+	return votes, nil
+}
+
+// findVote returns a copy of the user's vote on exactly the given post or
+// comment, or nil. Callers must hold s.mu.
+func (s *Store) findVote(userID int, postID, commentID *int) *models.Vote {
+	for _, vote := range s.votes {
+		if vote.UserID != userID {
+			continue
+		}
+		if (postID == nil) != (vote.PostID == nil) || (commentID == nil) != (vote.CommentID == nil) {
+			continue
+		}
+		if (postID == nil || *postID == *vote.PostID) && (commentID == nil || *commentID == *vote.CommentID) {
+			return copyVote(vote)
+		}
+	}
+	return nil
+}
+
+// copyVote copies a vote so callers cannot modify stored rows.
+func copyVote(vote *models.Vote) *models.Vote {
+	v := *vote
+	v.PostID = copyIntPtr(vote.PostID)
+	v.CommentID = copyIntPtr(vote.CommentID)
+	return &v
+}
+
+// addVoteKarma adds delta to the karma of the post or comment a vote is on and
+// to the post or comment karma of its author. Callers must hold s.mu.
+func (s *Store) addVoteKarma(vote *models.Vote, delta int) {
+	var authorID int
+	var postDelta, commentDelta int
+	switch {
+	case vote.CommentID != nil:
+		comment, ok := s.comments[*vote.CommentID]
+		if !ok {
+			return
+		}
+		comment.Karma += delta
+		authorID, commentDelta = comment.AuthorID, delta
+	case vote.PostID != nil:
+		post, ok := s.posts[*vote.PostID]
+		if !ok {
+			return
+		}
+		post.Karma += delta
+		authorID, postDelta = post.AuthorID, delta
+	default:
+		return
+	}
+	k, ok := s.karma[authorID]
+	if !ok {
+		k = &userKarma{}
+		s.karma[authorID] = k
+	}
+	k.postKarma += postDelta
+	k.commentKarma += commentDelta
+}
@@ -3,44 +3,52 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
-	"errors"
 	"fmt"
 
+	"redditclone/internal/apperr"
 	"redditclone/internal/models"
 	"redditclone/pkg/database"
+	"redditclone/pkg/metrics"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // MessageRepository provides access to the messages storage.
 type MessageRepository interface {
-	SendMessage(message *models.Message) error
-	GetMessageByID(id int) (*models.Message, error)
-	GetMessagesForUser(userID int, limit, offset int) ([]*models.Message, error)
-	GetReplies(parentID int, limit, offset int) ([]*models.Message, error)
-	UpdateMessage(message *models.Message) error
-	DeleteMessage(id int) error
+	SendMessage(ctx context.Context, message *models.Message) error
+	GetMessageByID(ctx context.Context, id int) (*models.Message, error)
+	GetMessagesForUser(ctx context.Context, userID int, page Page) ([]*models.Message, error)
+	GetReplies(ctx context.Context, parentID int, page Page) ([]*models.Message, error)
+	UpdateMessage(ctx context.Context, message *models.Message) error
+	DeleteMessage(ctx context.Context, id int) error
 }
 
 type messageRepository struct {
-	DB *sql.DB
+	DB *database.DB
 }
 
 // NewMessageRepository creates a new MessageRepository.
-func NewMessageRepository(db *sql.DB) MessageRepository {
+func NewMessageRepository(db *database.DB) MessageRepository {
 	return &messageRepository{DB: db}
 }
 
 // SendMessage inserts a new message into the database.
-func (r *messageRepository) SendMessage(message *models.Message) error {
-	database.DBMu.Lock()
-	defer database.DBMu.Unlock()
+func (r *messageRepository) SendMessage(ctx context.Context, message *models.Message) error {
+	defer metrics.TimeQuery("message", "SendMessage")()
+	ctx, span := startSpan(ctx, r.DB, "MessageRepository.SendMessage")
+	defer span.End()
 	query := `
 		INSERT INTO messages (sender_id, receiver_id, content, parent_id, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 		RETURNING id, created_at, updated_at
 	`
-	err := r.DB.QueryRow(query, message.SenderID, message.ReceiverID, message.Content, message.ParentID).
-		Scan(&message.ID, &message.CreatedAt, &message.UpdatedAt)
+	err := database.Retry(ctx, func() error {
+		return r.DB.Write.QueryRowContext(ctx, query, message.SenderID, message.ReceiverID, message.Content, message.ParentID).
+			Scan(&message.ID, &message.CreatedAt, &message.UpdatedAt)
+	})
 	if err != nil {
 		return fmt.Errorf("SendMessage: %v", err)
 	}
@@ -48,16 +56,17 @@ func (r *messageRepository) SendMessage(message *models.Message) error {
 }
 
 // GetMessageByID retrieves a message by its ID.
-func (r *messageRepository) GetMessageByID(id int) (*models.Message, error) {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+func (r *messageRepository) GetMessageByID(ctx context.Context, id int) (*models.Message, error) {
+	defer metrics.TimeQuery("message", "GetMessageByID")()
+	ctx, span := startSpan(ctx, r.DB, "MessageRepository.GetMessageByID", attribute.Int("message.id", id))
+	defer span.End()
 	query := `
 		SELECT id, sender_id, receiver_id, content, parent_id, created_at, updated_at
 		FROM messages
 		WHERE id = $1
 	`
 	message := &models.Message{}
-	err := r.DB.QueryRow(query, id).Scan(
+	err := r.DB.Read.QueryRowContext(ctx, query, id).Scan(
 		&message.ID,
 		&message.SenderID,
 		&message.ReceiverID,
@@ -68,25 +77,26 @@ func (r *messageRepository) GetMessageByID(id int) (*models.Message, error) {
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("GetMessageByID: message not found")
+			return nil, apperr.NotFound("GetMessageByID: message not found")
 		}
 		return nil, fmt.Errorf("GetMessageByID: %v", err)
 	}
 	return message, nil
 }
 
-// GetMessagesForUser retrieves direct messages for a user with pagination.
-func (r *messageRepository) GetMessagesForUser(userID int, limit, offset int) ([]*models.Message, error) {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+// GetMessagesForUser retrieves a page of top-level direct messages for a user, newest first.
+func (r *messageRepository) GetMessagesForUser(ctx context.Context, userID int, page Page) ([]*models.Message, error) {
+	defer metrics.TimeQuery("message", "GetMessagesForUser")()
+	ctx, span := startSpan(ctx, r.DB, "MessageRepository.GetMessagesForUser", attribute.Int("user.id", userID), attribute.Int("page.limit", page.Limit))
+	defer span.End()
+	cond, tail, args := keyset(r.DB, page, true, 2)
 	query := `
 		SELECT id, sender_id, receiver_id, content, parent_id, created_at, updated_at
 		FROM messages
 		WHERE receiver_id = $1 AND parent_id IS NULL
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
-	`
-	rows, err := r.DB.Query(query, userID, limit, offset)
+		  ` + cond + `
+		` + tail
+	rows, err := r.DB.Read.QueryContext(ctx, query, append([]any{userID}, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("GetMessagesForUser: %v", err)
 	}
@@ -114,21 +124,24 @@ func (r *messageRepository) GetMessagesForUser(userID int, limit, offset int) ([
 		return nil, fmt.Errorf("GetMessagesForUser: %v", err)
 	}
 
+	reverseRows(page, messages)
+	span.SetAttributes(tracing.Rows(len(messages)))
 	return messages, nil
 }
 
-// GetReplies retrieves replies to a specific message with pagination.
-func (r *messageRepository) GetReplies(parentID int, limit, offset int) ([]*models.Message, error) {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+// GetReplies retrieves a page of replies to a specific message, oldest first.
+func (r *messageRepository) GetReplies(ctx context.Context, parentID int, page Page) ([]*models.Message, error) {
+	defer metrics.TimeQuery("message", "GetReplies")()
+	ctx, span := startSpan(ctx, r.DB, "MessageRepository.GetReplies", attribute.Int("parent.id", parentID), attribute.Int("page.limit", page.Limit))
+	defer span.End()
+	cond, tail, args := keyset(r.DB, page, false, 2)
 	query := `
 		SELECT id, sender_id, receiver_id, content, parent_id, created_at, updated_at
 		FROM messages
 		WHERE parent_id = $1
-		ORDER BY created_at ASC
-		LIMIT $2 OFFSET $3
-	`
-	rows, err := r.DB.Query(query, parentID, limit, offset)
+		  ` + cond + `
+		` + tail
+	rows, err := r.DB.Read.QueryContext(ctx, query, append([]any{parentID}, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("GetReplies: %v", err)
 	}
@@ -156,36 +169,42 @@ func (r *messageRepository) GetReplies(parentID int, limit, offset int) ([]*mode
 		return nil, fmt.Errorf("GetReplies: %v", err)
 	}
 
+	reverseRows(page, replies)
+	span.SetAttributes(tracing.Rows(len(replies)))
 	return replies, nil
 }
 
 // UpdateMessage updates an existing message's content.
-func (r *messageRepository) UpdateMessage(message *models.Message) error {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+func (r *messageRepository) UpdateMessage(ctx context.Context, message *models.Message) error {
+	defer metrics.TimeQuery("message", "UpdateMessage")()
+	ctx, span := startSpan(ctx, r.DB, "MessageRepository.UpdateMessage", attribute.Int("message.id", message.ID))
+	defer span.End()
 	query := `
 		UPDATE messages
 		SET content = $1, updated_at = CURRENT_TIMESTAMP
 		WHERE id = $2
 		RETURNING updated_at
 	`
-	err := r.DB.QueryRow(query, message.Content, message.ID).
-		Scan(&message.UpdatedAt)
+	err := database.Retry(ctx, func() error {
+		return r.DB.Write.QueryRowContext(ctx, query, message.Content, message.ID).
+			Scan(&message.UpdatedAt)
+	})
 	if err != nil {
-		return fmt.Errorf("UpdateMessage: %v", err)
+		return writeError("UpdateMessage", "message", err)
 	}
 	return nil
 }
 
 // DeleteMessage removes a message from the database.
-func (r *messageRepository) DeleteMessage(id int) error {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+func (r *messageRepository) DeleteMessage(ctx context.Context, id int) error {
+	defer metrics.TimeQuery("message", "DeleteMessage")()
+	ctx, span := startSpan(ctx, r.DB, "MessageRepository.DeleteMessage", attribute.Int("message.id", id))
+	defer span.End()
 	query := `
 		DELETE FROM messages
 		WHERE id = $1
 	`
-	result, err := r.DB.Exec(query, id)
+	result, err := r.DB.ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("DeleteMessage: %v", err)
 	}
@@ -194,7 +213,7 @@ func (r *messageRepository) DeleteMessage(id int) error {
 		return fmt.Errorf("DeleteMessage: %v", err)
 	}
 	if rowsAffected == 0 {
-		return errors.New("DeleteMessage: no message found to delete")
+		return apperr.NotFound("DeleteMessage: no message found to delete")
 	}
 	return nil
 }
@@ -0,0 +1,518 @@
+// File: internal/repository/oauth_repository.go
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/pkg/database"
+	"redditclone/pkg/metrics"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// OAuthRepository provides access to OAuth2 clients, the consents users give
+// them, and the authorization codes and tokens issued to them.
+type OAuthRepository interface {
+	CreateClient(ctx context.Context, client *models.OAuthClient) error
+	GetClient(ctx context.Context, id string) (*models.OAuthClient, error)
+	GetClientsByOwner(ctx context.Context, ownerID int) ([]*models.OAuthClient, error)
+	DeleteClient(ctx context.Context, ownerID int, id string) error
+	SaveConsent(ctx context.Context, consent *models.OAuthConsent) error
+	GetConsent(ctx context.Context, userID int, clientID string) (*models.OAuthConsent, error)
+	GetConsentsByUser(ctx context.Context, userID int) ([]*models.OAuthConsent, error)
+	RevokeConsent(ctx context.Context, userID int, clientID string) error
+	CreateCode(ctx context.Context, code *models.OAuthCode) error
+	UseCode(ctx context.Context, codeHash string) (*models.OAuthCode, error)
+	CreateToken(ctx context.Context, token *models.OAuthToken) error
+	GetTokenByAccessHash(ctx context.Context, accessTokenHash string, now time.Time) (*models.OAuthToken, error)
+	GetTokenByRefreshHash(ctx context.Context, refreshTokenHash string) (*models.OAuthToken, error)
+	RotateToken(ctx context.Context, oldID int, next *models.OAuthToken) error
+	RevokeGrantTokens(ctx context.Context, userID int, clientID, reason string) (int, error)
+}
+
+type oauthRepository struct {
+	DB *database.DB
+}
+
+// NewOAuthRepository creates a new OAuthRepository.
+func NewOAuthRepository(db *database.DB) OAuthRepository {
+	return &oauthRepository{DB: db}
+}
+
+// oauthClientColumns lists the columns scanned by scanOAuthClient, in order.
+const oauthClientColumns = `id, owner_id, name, COALESCE(secret_hash, ''), redirect_uris, scopes, created_at`
+
+// scanOAuthClient reads a row selected with oauthClientColumns.
+func scanOAuthClient(row interface{ Scan(...any) error }) (*models.OAuthClient, error) {
+	client := &models.OAuthClient{}
+	var redirectURIs, scopes string
+	err := row.Scan(&client.ID, &client.OwnerID, &client.Name, &client.SecretHash, &redirectURIs, &scopes, &client.CreatedAt)
+	client.Confidential = client.SecretHash != ""
+	client.RedirectURIs = strings.Fields(redirectURIs)
+	client.Scopes = strings.Fields(scopes)
+	return client, err
+}
+
+// oauthConsentColumns lists the columns scanned by scanOAuthConsent, in order.
+// Queries using it join oauth_clients as c.
+const oauthConsentColumns = `o.id, o.user_id, o.client_id, c.name, o.scopes, o.created_at, o.updated_at, o.revoked_at`
+
+// scanOAuthConsent reads a row selected with oauthConsentColumns.
+func scanOAuthConsent(row interface{ Scan(...any) error }) (*models.OAuthConsent, error) {
+	consent := &models.OAuthConsent{}
+	var scopes string
+	err := row.Scan(&consent.ID, &consent.UserID, &consent.ClientID, &consent.ClientName, &scopes,
+		&consent.CreatedAt, &consent.UpdatedAt, &consent.RevokedAt)
+	consent.Scopes = strings.Fields(scopes)
+	return consent, err
+}
+
+// oauthCodeColumns lists the columns scanned by scanOAuthCode, in order.
+const oauthCodeColumns = `id, code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at, used_at, created_at`
+
+// scanOAuthCode reads a row selected with oauthCodeColumns.
+func scanOAuthCode(row interface{ Scan(...any) error }) (*models.OAuthCode, error) {
+	code := &models.OAuthCode{}
+	var scopes string
+	err := row.Scan(&code.ID, &code.CodeHash, &code.ClientID, &code.UserID, &code.RedirectURI, &scopes,
+		&code.CodeChallenge, &code.ExpiresAt, &code.UsedAt, &code.CreatedAt)
+	code.Scopes = strings.Fields(scopes)
+	return code, err
+}
+
+// oauthTokenColumns lists the columns scanned by scanOAuthToken, in order.
+const oauthTokenColumns = `id, client_id, user_id, access_token_hash, access_expires_at, refresh_token_hash,
+		refresh_expires_at, scopes, created_at, revoked_at, COALESCE(revoke_reason, '')`
+
+// scanOAuthToken reads a row selected with oauthTokenColumns.
+func scanOAuthToken(row interface{ Scan(...any) error }) (*models.OAuthToken, error) {
+	token := &models.OAuthToken{}
+	var scopes string
+	err := row.Scan(&token.ID, &token.ClientID, &token.UserID, &token.AccessTokenHash, &token.AccessExpiresAt,
+		&token.RefreshTokenHash, &token.RefreshExpiresAt, &scopes, &token.CreatedAt, &token.RevokedAt, &token.RevokeReason)
+	token.Scopes = strings.Fields(scopes)
+	return token, err
+}
+
+// CreateClient inserts a new OAuth2 client.
+func (r *oauthRepository) CreateClient(ctx context.Context, client *models.OAuthClient) error {
+	defer metrics.TimeQuery("oauth", "CreateClient")()
+	ctx, span := startSpan(ctx, r.DB, "OAuthRepository.CreateClient", attribute.Int("user.id", client.OwnerID))
+	defer span.End()
+	query := `
+		INSERT INTO oauth_clients (id, owner_id, name, secret_hash, redirect_uris, scopes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP)
+		RETURNING created_at
+	`
+	var secretHash any
+	if client.SecretHash != "" {
+		secretHash = client.SecretHash
+	}
+	err := database.Retry(ctx, func() error {
+		return r.DB.Write.QueryRowContext(ctx, query, client.ID, client.OwnerID, client.Name, secretHash,
+			strings.Join(client.RedirectURIs, " "), strings.Join(client.Scopes, " ")).
+			Scan(&client.CreatedAt)
+	})
+	if err != nil {
+		return writeError("CreateClient", "OAuth client", err)
+	}
+	return nil
+}
+
+// GetClient retrieves an OAuth2 client by its client ID.
+func (r *oauthRepository) GetClient(ctx context.Context, id string) (*models.OAuthClient, error) {
+	defer metrics.TimeQuery("oauth", "GetClient")()
+	ctx, span := startSpan(ctx, r.DB, "OAuthRepository.GetClient", attribute.String("oauth.client_id", id))
+	defer span.End()
+	query := `SELECT ` + oauthClientColumns + ` FROM oauth_clients WHERE id = $1`
+	client, err := scanOAuthClient(r.DB.Read.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("GetClient: OAuth client not found")
+		}
+		return nil, fmt.Errorf("GetClient: %v", err)
+	}
+	return client, nil
+}
+
+// GetClientsByOwner retrieves the OAuth2 clients a user registered, newest first.
+func (r *oauthRepository) GetClientsByOwner(ctx context.Context, ownerID int) ([]*models.OAuthClient, error) {
+	defer metrics.TimeQuery("oauth", "GetClientsByOwner")()
+	ctx, span := startSpan(ctx, r.DB, "OAuthRepository.GetClientsByOwner", attribute.Int("user.id", ownerID))
+	defer span.End()
+	query := `
+		SELECT ` + oauthClientColumns + `
+		FROM oauth_clients
+		WHERE owner_id = $1
+		ORDER BY created_at DESC, id
+	`
+	rows, err := r.DB.Read.QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("GetClientsByOwner: %v", err)
+	}
+	defer rows.Close()
+
+	var clients []*models.OAuthClient
+	for rows.Next() {
+		client, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, fmt.Errorf("GetClientsByOwner: %v", err)
+		}
+		clients = append(clients, client)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetClientsByOwner: %v", err)
+	}
+	span.SetAttributes(tracing.Rows(len(clients)))
+	return clients, nil
+}
+
+// DeleteClient removes one of a user's OAuth2 clients, together with its
+// consents, codes and tokens. It fails with apperr.NotFound if the user has
+// no such client.
+func (r *oauthRepository) DeleteClient(ctx context.Context, ownerID int, id string) error {
+	defer metrics.TimeQuery("oauth", "DeleteClient")()
+	ctx, span := startSpan(ctx, r.DB, "OAuthRepository.DeleteClient", attribute.Int("user.id", ownerID), attribute.String("oauth.client_id", id))
+	defer span.End()
+	query := `
+		DELETE FROM oauth_clients
+		WHERE id = $1 AND owner_id = $2
+	`
+	result, err := r.DB.ExecContext(ctx, query, id, ownerID)
+	if err != nil {
+		return fmt.Errorf("DeleteClient: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("DeleteClient: %v", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.NotFound("DeleteClient: OAuth client not found")
+	}
+	return nil
+}
+
+// SaveConsent records that a user granted a client the consent's scopes,
+// replacing any earlier consent, revoked or not.
+func (r *oauthRepository) SaveConsent(ctx context.Context, consent *models.OAuthConsent) error {
+	defer metrics.TimeQuery("oauth", "SaveConsent")()
+	ctx, span := startSpan(ctx, r.DB, "OAuthRepository.SaveConsent", attribute.Int("user.id", consent.UserID), attribute.String("oauth.client_id", consent.ClientID))
+	defer span.End()
+	query := `
+		INSERT INTO oauth_consents (user_id, client_id, scopes, created_at, updated_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id, client_id) DO UPDATE
+		SET scopes = excluded.scopes, updated_at = CURRENT_TIMESTAMP, revoked_at = NULL
+		RETURNING id, created_at, updated_at
+	`
+	err := database.Retry(ctx, func() error {
+		return r.DB.Write.QueryRowContext(ctx, query, consent.UserID, consent.ClientID, strings.Join(consent.Scopes, " ")).
+			Scan(&consent.ID, &consent.CreatedAt, &consent.UpdatedAt)
+	})
+	if err != nil {
+		return writeError("SaveConsent", "consent", err)
+	}
+	consent.RevokedAt = nil
+	return nil
+}
+
+// GetConsent retrieves a user's active consent for a client.
+func (r *oauthRepository) GetConsent(ctx context.Context, userID int, clientID string) (*models.OAuthConsent, error) {
+	defer metrics.TimeQuery("oauth", "GetConsent")()
+	ctx, span := startSpan(ctx, r.DB, "OAuthRepository.GetConsent", attribute.Int("user.id", userID), attribute.String("oauth.client_id", clientID))
+	defer span.End()
+	query := `
+		SELECT ` + oauthConsentColumns + `
+		FROM oauth_consents o
+		JOIN oauth_clients c ON c.id = o.client_id
+		WHERE o.user_id = $1 AND o.client_id = $2 AND o.revoked_at IS NULL
+	`
+	consent, err := scanOAuthConsent(r.DB.Read.QueryRowContext(ctx, query, userID, clientID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("GetConsent: consent not found")
+		}
+		return nil, fmt.Errorf("GetConsent: %v", err)
+	}
+	return consent, nil
+}
+
+// GetConsentsByUser retrieves every consent a user has given, revoked ones
+// included, most recently changed first.
+func (r *oauthRepository) GetConsentsByUser(ctx context.Context, userID int) ([]*models.OAuthConsent, error) {
+	defer metrics.TimeQuery("oauth", "GetConsentsByUser")()
+	ctx, span := startSpan(ctx, r.DB, "OAuthRepository.GetConsentsByUser", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		SELECT ` + oauthConsentColumns + `
+		FROM oauth_consents o
+		JOIN oauth_clients c ON c.id = o.client_id
+		WHERE o.user_id = $1
+		ORDER BY o.updated_at DESC, o.id DESC
+	`
+	rows, err := r.DB.Read.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("GetConsentsByUser: %v", err)
+	}
+	defer rows.Close()
+
+	var consents []*models.OAuthConsent
+	for rows.Next() {
+		consent, err := scanOAuthConsent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("GetConsentsByUser: %v", err)
+		}
+		consents = append(consents, consent)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetConsentsByUser: %v", err)
+	}
+	span.SetAttributes(tracing.Rows(len(consents)))
+	return consents, nil
+}
+
+// RevokeConsent revokes a user's active consent for a client and, in the same
+// transaction, every token issued under it. It fails with apperr.NotFound if
+// there is no such active consent.
+func (r *oauthRepository) RevokeConsent(ctx context.Context, userID int, clientID string) error {
+	defer metrics.TimeQuery("oauth", "RevokeConsent")()
+	ctx, span := startSpan(ctx, r.DB, "OAuthRepository.RevokeConsent", attribute.Int("user.id", userID), attribute.String("oauth.client_id", clientID))
+	defer span.End()
+	errNoConsent := apperr.NotFound("RevokeConsent: consent not found")
+	err := database.Retry(ctx, func() error {
+		tx, err := r.DB.Write.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		result, err := tx.ExecContext(ctx, `
+			UPDATE oauth_consents
+			SET revoked_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			WHERE user_id = $1 AND client_id = $2 AND revoked_at IS NULL
+		`, userID, clientID)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return errNoConsent
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE oauth_tokens
+			SET revoked_at = CURRENT_TIMESTAMP, revoke_reason = $3
+			WHERE user_id = $1 AND client_id = $2 AND revoked_at IS NULL
+		`, userID, clientID, models.RevokedConsent); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	if errors.Is(err, apperr.ErrNotFound) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("RevokeConsent: %v", err)
+	}
+	return nil
+}
+
+// CreateCode inserts a new authorization code.
+func (r *oauthRepository) CreateCode(ctx context.Context, code *models.OAuthCode) error {
+	defer metrics.TimeQuery("oauth", "CreateCode")()
+	ctx, span := startSpan(ctx, r.DB, "OAuthRepository.CreateCode", attribute.Int("user.id", code.UserID), attribute.String("oauth.client_id", code.ClientID))
+	defer span.End()
+	query := `
+		INSERT INTO oauth_codes (code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP)
+		RETURNING id, created_at
+	`
+	err := database.Retry(ctx, func() error {
+		return r.DB.Write.QueryRowContext(ctx, query, code.CodeHash, code.ClientID, code.UserID, code.RedirectURI,
+			strings.Join(code.Scopes, " "), code.CodeChallenge, r.DB.TimeArg(code.ExpiresAt)).
+			Scan(&code.ID, &code.CreatedAt)
+	})
+	if err != nil {
+		return writeError("CreateCode", "authorization code", err)
+	}
+	return nil
+}
+
+// UseCode marks an unused authorization code as used and returns it. A code
+// that was already used fails with apperr.Conflict, still returning the code
+// so the caller can revoke what it was exchanged for; an unknown code is
+// apperr.NotFound. Expiry is left to the caller.
+func (r *oauthRepository) UseCode(ctx context.Context, codeHash string) (*models.OAuthCode, error) {
+	defer metrics.TimeQuery("oauth", "UseCode")()
+	ctx, span := startSpan(ctx, r.DB, "OAuthRepository.UseCode")
+	defer span.End()
+	query := `
+		UPDATE oauth_codes
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE code_hash = $1 AND used_at IS NULL
+		RETURNING ` + oauthCodeColumns
+	var code *models.OAuthCode
+	err := database.Retry(ctx, func() error {
+		var err error
+		code, err = scanOAuthCode(r.DB.Write.QueryRowContext(ctx, query, codeHash))
+		return err
+	})
+	if err == nil {
+		return code, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("UseCode: %v", err)
+	}
+
+	// Tell a replayed code apart from one that never existed
+	code, err = scanOAuthCode(r.DB.Write.QueryRowContext(ctx, `SELECT `+oauthCodeColumns+` FROM oauth_codes WHERE code_hash = $1`, codeHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("UseCode: authorization code not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("UseCode: %v", err)
+	}
+	return code, apperr.Conflict("UseCode: authorization code already used")
+}
+
+// CreateToken inserts a new token pair.
+func (r *oauthRepository) CreateToken(ctx context.Context, token *models.OAuthToken) error {
+	defer metrics.TimeQuery("oauth", "CreateToken")()
+	ctx, span := startSpan(ctx, r.DB, "OAuthRepository.CreateToken", attribute.Int("user.id", token.UserID), attribute.String("oauth.client_id", token.ClientID))
+	defer span.End()
+	err := database.Retry(ctx, func() error {
+		return r.insertToken(ctx, r.DB.Write, token)
+	})
+	if err != nil {
+		return writeError("CreateToken", "OAuth token", err)
+	}
+	return nil
+}
+
+// insertToken inserts token through q, a connection or a transaction.
+func (r *oauthRepository) insertToken(ctx context.Context, q interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}, token *models.OAuthToken) error {
+	query := `
+		INSERT INTO oauth_tokens (client_id, user_id, access_token_hash, access_expires_at, refresh_token_hash,
+			refresh_expires_at, scopes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP)
+		RETURNING id, created_at
+	`
+	return q.QueryRowContext(ctx, query, token.ClientID, token.UserID, token.AccessTokenHash, r.DB.TimeArg(token.AccessExpiresAt),
+		token.RefreshTokenHash, r.DB.TimeArg(token.RefreshExpiresAt), strings.Join(token.Scopes, " ")).
+		Scan(&token.ID, &token.CreatedAt)
+}
+
+// GetTokenByAccessHash retrieves the active token pair whose access token has
+// the given hash and has not expired by now, for a user who is not suspended
+// or being deleted.
+func (r *oauthRepository) GetTokenByAccessHash(ctx context.Context, accessTokenHash string, now time.Time) (*models.OAuthToken, error) {
+	defer metrics.TimeQuery("oauth", "GetTokenByAccessHash")()
+	ctx, span := startSpan(ctx, r.DB, "OAuthRepository.GetTokenByAccessHash")
+	defer span.End()
+	query := `
+		SELECT ` + oauthTokenColumns + `
+		FROM oauth_tokens
+		WHERE access_token_hash = $1 AND revoked_at IS NULL AND access_expires_at > $2
+		  AND NOT EXISTS (SELECT 1 FROM users WHERE users.id = oauth_tokens.user_id
+		                  AND (users.suspended_at IS NOT NULL OR users.deletion_requested_at IS NOT NULL))
+	`
+	token, err := scanOAuthToken(r.DB.Read.QueryRowContext(ctx, query, accessTokenHash, r.DB.TimeArg(now)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("GetTokenByAccessHash: OAuth token not found")
+		}
+		return nil, fmt.Errorf("GetTokenByAccessHash: %v", err)
+	}
+	return token, nil
+}
+
+// GetTokenByRefreshHash retrieves the token pair whose refresh token has the
+// given hash, revoked or not.
+func (r *oauthRepository) GetTokenByRefreshHash(ctx context.Context, refreshTokenHash string) (*models.OAuthToken, error) {
+	defer metrics.TimeQuery("oauth", "GetTokenByRefreshHash")()
+	ctx, span := startSpan(ctx, r.DB, "OAuthRepository.GetTokenByRefreshHash")
+	defer span.End()
+	query := `SELECT ` + oauthTokenColumns + ` FROM oauth_tokens WHERE refresh_token_hash = $1`
+	token, err := scanOAuthToken(r.DB.Write.QueryRowContext(ctx, query, refreshTokenHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("GetTokenByRefreshHash: OAuth token not found")
+		}
+		return nil, fmt.Errorf("GetTokenByRefreshHash: %v", err)
+	}
+	return token, nil
+}
+
+// RotateToken revokes the active token pair oldID as rotated and inserts its
+// successor, in one transaction. It fails with apperr.Conflict if oldID was
+// revoked in the meantime, such as by a concurrent refresh.
+func (r *oauthRepository) RotateToken(ctx context.Context, oldID int, next *models.OAuthToken) error {
+	defer metrics.TimeQuery("oauth", "RotateToken")()
+	ctx, span := startSpan(ctx, r.DB, "OAuthRepository.RotateToken", attribute.Int("user.id", next.UserID), attribute.String("oauth.client_id", next.ClientID))
+	defer span.End()
+	errRevoked := apperr.Conflict("RotateToken: OAuth token already revoked")
+	err := database.Retry(ctx, func() error {
+		tx, err := r.DB.Write.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		result, err := tx.ExecContext(ctx, `
+			UPDATE oauth_tokens
+			SET revoked_at = CURRENT_TIMESTAMP, revoke_reason = $2
+			WHERE id = $1 AND revoked_at IS NULL
+		`, oldID, models.RevokedRotated)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return errRevoked
+		}
+		if err := r.insertToken(ctx, tx, next); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	if errors.Is(err, apperr.ErrConflict) {
+		return err
+	}
+	if err != nil {
+		return writeError("RotateToken", "OAuth token", err)
+	}
+	return nil
+}
+
+// RevokeGrantTokens revokes every active token pair a client holds for a
+// user and returns how many it revoked.
+func (r *oauthRepository) RevokeGrantTokens(ctx context.Context, userID int, clientID, reason string) (int, error) {
+	defer metrics.TimeQuery("oauth", "RevokeGrantTokens")()
+	ctx, span := startSpan(ctx, r.DB, "OAuthRepository.RevokeGrantTokens", attribute.Int("user.id", userID), attribute.String("oauth.client_id", clientID))
+	defer span.End()
+	query := `
+		UPDATE oauth_tokens
+		SET revoked_at = CURRENT_TIMESTAMP, revoke_reason = $3
+		WHERE user_id = $1 AND client_id = $2 AND revoked_at IS NULL
+	`
+	result, err := r.DB.ExecContext(ctx, query, userID, clientID, reason)
+	if err != nil {
+		return 0, fmt.Errorf("RevokeGrantTokens: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("RevokeGrantTokens: %v", err)
+	}
+	span.SetAttributes(tracing.Rows(int(rowsAffected)))
+	return int(rowsAffected), nil
+}
@@ -0,0 +1,93 @@
+// File: internal/repository/pagination.go
+
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"redditclone/internal/models"
+	"redditclone/pkg/database"
+)
+
+// Cursor marks a row's position in a listing ordered by creation time, with
+// the ID breaking ties between rows created in the same second.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+// PostCursor returns the position of post in a post listing.
+func PostCursor(post *models.Post) Cursor {
+	return Cursor{CreatedAt: post.CreatedAt, ID: post.ID}
+}
+
+// CommentCursor returns the position of comment in a comment listing.
+func CommentCursor(comment *models.Comment) Cursor {
+	return Cursor{CreatedAt: comment.CreatedAt, ID: comment.ID}
+}
+
+// MessageCursor returns the position of message in a message listing.
+func MessageCursor(message *models.Message) Cursor {
+	return Cursor{CreatedAt: message.CreatedAt, ID: message.ID}
+}
+
+// Page selects a window of a listing. After continues the listing past the
+// given row and Before returns the rows just ahead of it; with neither set the
+// window starts Offset rows in. Rows are always returned in listing order.
+type Page struct {
+	Limit  int
+	Offset int
+	After  *Cursor
+	Before *Cursor
+}
+
+// Backward reports whether the page reads towards the start of the listing.
+func (p Page) Backward() bool {
+	return p.Before != nil
+}
+
+// Listing is one page of a listing together with the cursors that continue it.
+// After is nil on the last page and Before is nil on the first.
+type Listing[T any] struct {
+	Items  []T
+	After  *Cursor
+	Before *Cursor
+}
+
+// keyset builds the SQL that selects page from a listing ordered by
+// created_at and id, newest first when newestFirst is set. It returns a
+// condition to append to the WHERE clause (empty without a cursor), the ORDER
+// BY and LIMIT/OFFSET clauses, and their arguments numbered from argN.
+// Backward pages are read in reverse order; callers restore it with reverseRows.
+func keyset(db *database.DB, page Page, newestFirst bool, argN int) (cond, tail string, args []any) {
+	cursor, offset := page.After, page.Offset
+	if page.Backward() {
+		cursor = page.Before
+	}
+	desc := newestFirst != page.Backward()
+
+	op, dir := ">", "ASC"
+	if desc {
+		op, dir = "<", "DESC"
+	}
+	if cursor != nil {
+		cond = fmt.Sprintf("AND (created_at, id) %s ($%d, $%d)", op, argN, argN+1)
+		args = append(args, db.TimeArg(cursor.CreatedAt), cursor.ID)
+		argN += 2
+		offset = 0
+	}
+	tail = fmt.Sprintf("ORDER BY created_at %s, id %s LIMIT $%d OFFSET $%d", dir, dir, argN, argN+1)
+	args = append(args, page.Limit, offset)
+	return cond, tail, args
+}
+
+// reverseRows restores listing order for rows read by a backward page.
+func reverseRows[T any](page Page, rows []T) {
+	if !page.Backward() {
+		return
+	}
+	for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+		rows[i], rows[j] = rows[j], rows[i]
+	}
+}
@@ -3,61 +3,71 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
-	"errors"
 	"fmt"
 
+	"redditclone/internal/apperr"
 	"redditclone/internal/models"
 	"redditclone/pkg/database"
+	"redditclone/pkg/metrics"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // PostRepository provides access to the posts storage.
 type PostRepository interface {
-	CreatePost(post *models.Post) error
-	GetPostByID(id int) (*models.Post, error)
-	GetPostsBySubreddit(subredditID int, limit, offset int) ([]*models.Post, error)
-	GetFeedPosts(limit, offset int) ([]*models.Post, error)
-	UpdatePost(post *models.Post) error
-	DeletePost(id int) error
+	CreatePost(ctx context.Context, post *models.Post) error
+	GetPostByID(ctx context.Context, id int) (*models.Post, error)
+	GetPostsBySubreddit(ctx context.Context, subredditID, viewerID int, page Page) ([]*models.Post, error)
+	GetFeedPosts(ctx context.Context, viewerID int, page Page) ([]*models.Post, error)
+	GetPostsByAuthor(ctx context.Context, authorID int, sort string, limit, offset int) ([]*models.Post, error)
+	UpdatePost(ctx context.Context, post *models.Post) error
+	DeletePost(ctx context.Context, id int) error
 }
 
 type postRepository struct {
-	DB *sql.DB
+	DB *database.DB
 }
 
 // NewPostRepository creates a new PostRepository.
-func NewPostRepository(db *sql.DB) PostRepository {
+func NewPostRepository(db *database.DB) PostRepository {
 	return &postRepository{DB: db}
 }
 
 // CreatePost inserts a new post into the database.
-func (r *postRepository) CreatePost(post *models.Post) error {
-	database.DBMu.Lock()
-	defer database.DBMu.Unlock()
+func (r *postRepository) CreatePost(ctx context.Context, post *models.Post) error {
+	defer metrics.TimeQuery("post", "CreatePost")()
+	ctx, span := startSpan(ctx, r.DB, "PostRepository.CreatePost")
+	defer span.End()
 	query := `
 		INSERT INTO posts (title, content, author_id, subreddit_id, karma, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 		RETURNING id, created_at, updated_at
 	`
-	err := r.DB.QueryRow(query, post.Title, post.Content, post.AuthorID, post.SubredditID).
-		Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt)
+	err := database.Retry(ctx, func() error {
+		return r.DB.Write.QueryRowContext(ctx, query, post.Title, post.Content, post.AuthorID, post.SubredditID).
+			Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt)
+	})
 	if err != nil {
-		return fmt.Errorf("CreatePost: %v", err)
+		return writeError("CreatePost", "post", err)
 	}
 	return nil
 }
 
 // GetPostByID retrieves a post by its ID.
-func (r *postRepository) GetPostByID(id int) (*models.Post, error) {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+func (r *postRepository) GetPostByID(ctx context.Context, id int) (*models.Post, error) {
+	defer metrics.TimeQuery("post", "GetPostByID")()
+	ctx, span := startSpan(ctx, r.DB, "PostRepository.GetPostByID", attribute.Int("post.id", id))
+	defer span.End()
 	query := `
 		SELECT id, title, content, author_id, subreddit_id, karma, created_at, updated_at
 		FROM posts
 		WHERE id = $1
 	`
 	post := &models.Post{}
-	err := r.DB.QueryRow(query, id).Scan(
+	err := r.DB.Read.QueryRowContext(ctx, query, id).Scan(
 		&post.ID,
 		&post.Title,
 		&post.Content,
@@ -69,25 +79,28 @@ func (r *postRepository) GetPostByID(id int) (*models.Post, error) {
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("GetPostByID: post not found")
+			return nil, apperr.NotFound("GetPostByID: post not found")
 		}
 		return nil, fmt.Errorf("GetPostByID: %v", err)
 	}
 	return post, nil
 }
 
-// GetPostsBySubreddit retrieves posts from a specific subreddit with pagination.
-func (r *postRepository) GetPostsBySubreddit(subredditID int, limit, offset int) ([]*models.Post, error) {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+// GetPostsBySubreddit retrieves a page of posts from a specific subreddit, newest
+// first, excluding posts by authors the viewer has blocked.
+func (r *postRepository) GetPostsBySubreddit(ctx context.Context, subredditID, viewerID int, page Page) ([]*models.Post, error) {
+	defer metrics.TimeQuery("post", "GetPostsBySubreddit")()
+	ctx, span := startSpan(ctx, r.DB, "PostRepository.GetPostsBySubreddit", attribute.Int("subreddit.id", subredditID), attribute.Int("viewer.id", viewerID), attribute.Int("page.limit", page.Limit))
+	defer span.End()
+	cond, tail, args := keyset(r.DB, page, true, 3)
 	query := `
 		SELECT id, title, content, author_id, subreddit_id, karma, created_at, updated_at
 		FROM posts
 		WHERE subreddit_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
-	`
-	rows, err := r.DB.Query(query, subredditID, limit, offset)
+		  AND author_id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = $2)
+		  ` + cond + `
+		` + tail
+	rows, err := r.DB.Read.QueryContext(ctx, query, append([]any{subredditID, viewerID}, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("GetPostsBySubreddit: %v", err)
 	}
@@ -116,20 +129,26 @@ func (r *postRepository) GetPostsBySubreddit(subredditID int, limit, offset int)
 		return nil, fmt.Errorf("GetPostsBySubreddit: %v", err)
 	}
 
+	reverseRows(page, posts)
+	span.SetAttributes(tracing.Rows(len(posts)))
 	return posts, nil
 }
 
-// GetFeedPosts retrieves posts for the feed with pagination.
-func (r *postRepository) GetFeedPosts(limit, offset int) ([]*models.Post, error) {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+// GetFeedPosts retrieves a page of the feed, newest first, excluding posts by
+// authors the viewer has blocked and posts in quarantined subreddits.
+func (r *postRepository) GetFeedPosts(ctx context.Context, viewerID int, page Page) ([]*models.Post, error) {
+	defer metrics.TimeQuery("post", "GetFeedPosts")()
+	ctx, span := startSpan(ctx, r.DB, "PostRepository.GetFeedPosts", attribute.Int("viewer.id", viewerID), attribute.Int("page.limit", page.Limit))
+	defer span.End()
+	cond, tail, args := keyset(r.DB, page, true, 2)
 	query := `
 		SELECT id, title, content, author_id, subreddit_id, karma, created_at, updated_at
 		FROM posts
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`
-	rows, err := r.DB.Query(query, limit, offset)
+		WHERE author_id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = $1)
+		  AND subreddit_id NOT IN (SELECT id FROM subreddits WHERE quarantined_at IS NOT NULL)
+		  ` + cond + `
+		` + tail
+	rows, err := r.DB.Read.QueryContext(ctx, query, append([]any{viewerID}, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("GetFeedPosts: %v", err)
 	}
@@ -158,36 +177,72 @@ func (r *postRepository) GetFeedPosts(limit, offset int) ([]*models.Post, error)
 		return nil, fmt.Errorf("GetFeedPosts: %v", err)
 	}
 
+	reverseRows(page, posts)
+	span.SetAttributes(tracing.Rows(len(posts)))
+	return posts, nil
+}
+
+// GetPostsByAuthor retrieves the posts submitted by a user in the given sort order with pagination.
+func (r *postRepository) GetPostsByAuthor(ctx context.Context, authorID int, sort string, limit, offset int) ([]*models.Post, error) {
+	defer metrics.TimeQuery("post", "GetPostsByAuthor")()
+	ctx, span := startSpan(ctx, r.DB, "PostRepository.GetPostsByAuthor", attribute.Int("author.id", authorID), attribute.String("sort", sort), attribute.Int("page.limit", limit), attribute.Int("page.offset", offset))
+	defer span.End()
+	query := `
+		SELECT ` + activityColumns + `
+		FROM (SELECT ` + activityPostColumns + ` FROM posts p WHERE p.author_id = $1) t
+		ORDER BY ` + activityOrderBy(sort) + `
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.DB.Read.QueryContext(ctx, query, authorID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("GetPostsByAuthor: %v", err)
+	}
+	defer rows.Close()
+
+	items, err := scanActivityItems(rows)
+	if err != nil {
+		return nil, fmt.Errorf("GetPostsByAuthor: %v", err)
+	}
+
+	posts := make([]*models.Post, 0, len(items))
+	for _, item := range items {
+		posts = append(posts, item.Post)
+	}
+	span.SetAttributes(tracing.Rows(len(posts)))
 	return posts, nil
 }
 
 // UpdatePost updates an existing post's information.
-func (r *postRepository) UpdatePost(post *models.Post) error {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+func (r *postRepository) UpdatePost(ctx context.Context, post *models.Post) error {
+	defer metrics.TimeQuery("post", "UpdatePost")()
+	ctx, span := startSpan(ctx, r.DB, "PostRepository.UpdatePost", attribute.Int("post.id", post.ID))
+	defer span.End()
 	query := `
 		UPDATE posts
 		SET title = $1, content = $2, updated_at = CURRENT_TIMESTAMP
 		WHERE id = $3
 		RETURNING updated_at
 	`
-	err := r.DB.QueryRow(query, post.Title, post.Content, post.ID).
-		Scan(&post.UpdatedAt)
+	err := database.Retry(ctx, func() error {
+		return r.DB.Write.QueryRowContext(ctx, query, post.Title, post.Content, post.ID).
+			Scan(&post.UpdatedAt)
+	})
 	if err != nil {
-		return fmt.Errorf("UpdatePost: %v", err)
+		return writeError("UpdatePost", "post", err)
 	}
 	return nil
 }
 
 // DeletePost removes a post from the database.
-func (r *postRepository) DeletePost(id int) error {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+func (r *postRepository) DeletePost(ctx context.Context, id int) error {
+	defer metrics.TimeQuery("post", "DeletePost")()
+	ctx, span := startSpan(ctx, r.DB, "PostRepository.DeletePost", attribute.Int("post.id", id))
+	defer span.End()
 	query := `
 		DELETE FROM posts
 		WHERE id = $1
 	`
-	result, err := r.DB.Exec(query, id)
+	result, err := r.DB.ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("DeletePost: %v", err)
 	}
@@ -196,7 +251,7 @@ func (r *postRepository) DeletePost(id int) error {
 		return fmt.Errorf("DeletePost: %v", err)
 	}
 	if rowsAffected == 0 {
-		return errors.New("DeletePost: no post found to delete")
+		return apperr.NotFound("DeletePost: no post found to delete")
 	}
 	return nil
 }
@@ -0,0 +1,125 @@
+// File: internal/repository/recovery_code_repository.go
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"redditclone/internal/apperr"
+	"redditclone/pkg/database"
+	"redditclone/pkg/metrics"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// RecoveryCodeRepository provides access to users' two-factor recovery codes.
+type RecoveryCodeRepository interface {
+	ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) error
+	CountRecoveryCodes(ctx context.Context, userID int) (int, error)
+	DeleteRecoveryCodes(ctx context.Context, userID int) error
+}
+
+type recoveryCodeRepository struct {
+	DB *database.DB
+}
+
+// NewRecoveryCodeRepository creates a new RecoveryCodeRepository.
+func NewRecoveryCodeRepository(db *database.DB) RecoveryCodeRepository {
+	return &recoveryCodeRepository{DB: db}
+}
+
+// ReplaceRecoveryCodes discards a user's recovery codes and stores new ones,
+// in one transaction so the old codes never outlive the new ones.
+func (r *recoveryCodeRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	defer metrics.TimeQuery("recovery_code", "ReplaceRecoveryCodes")()
+	ctx, span := startSpan(ctx, r.DB, "RecoveryCodeRepository.ReplaceRecoveryCodes", attribute.Int("user.id", userID))
+	defer span.End()
+	values := make([]string, len(codeHashes))
+	args := []any{userID}
+	for i, hash := range codeHashes {
+		values[i] = fmt.Sprintf("($1, $%d, CURRENT_TIMESTAMP)", i+2)
+		args = append(args, hash)
+	}
+	insert := `INSERT INTO recovery_codes (user_id, code_hash, created_at) VALUES ` + strings.Join(values, ", ")
+
+	err := database.Retry(ctx, func() error {
+		tx, err := r.DB.Write.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+		if len(codeHashes) > 0 {
+			if _, err := tx.ExecContext(ctx, insert, args...); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return writeError("ReplaceRecoveryCodes", "user", err)
+	}
+	return nil
+}
+
+// UseRecoveryCode marks one of a user's unused recovery codes as used. It
+// fails with apperr.NotFound if the user has no such unused code.
+func (r *recoveryCodeRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	defer metrics.TimeQuery("recovery_code", "UseRecoveryCode")()
+	ctx, span := startSpan(ctx, r.DB, "RecoveryCodeRepository.UseRecoveryCode", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		UPDATE recovery_codes
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	result, err := r.DB.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return fmt.Errorf("UseRecoveryCode: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("UseRecoveryCode: %v", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.NotFound("UseRecoveryCode: recovery code not found or already used")
+	}
+	return nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes a user has left.
+func (r *recoveryCodeRepository) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	defer metrics.TimeQuery("recovery_code", "CountRecoveryCodes")()
+	ctx, span := startSpan(ctx, r.DB, "RecoveryCodeRepository.CountRecoveryCodes", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		SELECT COUNT(*)
+		FROM recovery_codes
+		WHERE user_id = $1 AND used_at IS NULL
+	`
+	var count int
+	if err := r.DB.Read.QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("CountRecoveryCodes: %v", err)
+	}
+	return count, nil
+}
+
+// DeleteRecoveryCodes removes all of a user's recovery codes.
+func (r *recoveryCodeRepository) DeleteRecoveryCodes(ctx context.Context, userID int) error {
+	defer metrics.TimeQuery("recovery_code", "DeleteRecoveryCodes")()
+	ctx, span := startSpan(ctx, r.DB, "RecoveryCodeRepository.DeleteRecoveryCodes", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		DELETE FROM recovery_codes
+		WHERE user_id = $1
+	`
+	if _, err := r.DB.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("DeleteRecoveryCodes: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+// File: internal/repository/repositories.go
+
+package repository
+
+import "redditclone/pkg/database"
+
+// Repositories bundles one implementation of every repository interface so a
+// storage backend can be swapped as a whole.
+type Repositories struct {
+	Users         UserRepository
+	Subreddits    SubredditRepository
+	Posts         PostRepository
+	Comments      CommentRepository
+	Votes         VoteRepository
+	Messages      MessageRepository
+	Blocks        BlockRepository
+	VoteIntegrity VoteIntegrityRepository
+	LoginAttempts LoginAttemptRepository
+	UserTokens    UserTokenRepository
+	RecoveryCodes RecoveryCodeRepository
+	Sessions      SessionRepository
+	APIKeys       APIKeyRepository
+	OAuth         OAuthRepository
+	Audit         AuditRepository
+}
+
+// NewSQLRepositories creates the SQL-backed repositories on a database connection.
+func NewSQLRepositories(db *database.DB) Repositories {
+	return Repositories{
+		Users:         NewUserRepository(db),
+		Subreddits:    NewSubredditRepository(db),
+		Posts:         NewPostRepository(db),
+		Comments:      NewCommentRepository(db),
+		Votes:         NewVoteRepository(db),
+		Messages:      NewMessageRepository(db),
+		Blocks:        NewBlockRepository(db),
+		VoteIntegrity: NewVoteIntegrityRepository(db),
+		LoginAttempts: NewLoginAttemptRepository(db),
+		UserTokens:    NewUserTokenRepository(db),
+		RecoveryCodes: NewRecoveryCodeRepository(db),
+		Sessions:      NewSessionRepository(db),
+		APIKeys:       NewAPIKeyRepository(db),
+		OAuth:         NewOAuthRepository(db),
+		Audit:         NewAuditRepository(db),
+	}
+}
@@ -0,0 +1,71 @@
+// File: internal/repository/repository_test.go
+
+package repository_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"redditclone/internal/repository"
+	"redditclone/internal/repository/repotest"
+	"redditclone/pkg/database"
+)
+
+func TestSQLiteRepositories(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.Repositories {
+		db := connect(t, database.Config{
+			Driver:      database.DriverSQLite,
+			DSN:         filepath.Join(t.TempDir(), "test.db"),
+			BusyTimeout: 5 * time.Second,
+		})
+		return repository.NewSQLRepositories(db)
+	})
+}
+
+// TestPostgresRepositories runs the suite against the database named by
+// TEST_POSTGRES_DSN, which is emptied before every test.
+func TestPostgresRepositories(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+	repotest.Run(t, func(t *testing.T) repository.Repositories {
+		db := connect(t, database.Config{Driver: database.DriverPostgres, DSN: dsn})
+		_, err := db.Write.Exec(`
+			DO $$
+			DECLARE t text;
+			BEGIN
+				FOR t IN SELECT tablename FROM pg_tables
+					WHERE schemaname = current_schema() AND tablename <> 'schema_migrations'
+				LOOP
+					EXECUTE format('TRUNCATE %I RESTART IDENTITY CASCADE', t);
+				END LOOP;
+			END $$
+		`)
+		if err != nil {
+			t.Fatalf("truncating tables: %v", err)
+		}
+		return repository.NewSQLRepositories(db)
+	})
+}
+
+// connect opens cfg and applies every migration.
+func connect(t *testing.T, cfg database.Config) *database.DB {
+	t.Helper()
+	db, err := database.Connect(cfg)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	migrator, err := database.NewMigrator(db.Write, cfg.Driver, io.Discard)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	return db
+}
@@ -0,0 +1,550 @@
+// File: internal/repository/repotest/repotest.go
+
+// Package repotest is a conformance suite for the repository interfaces. Each
+// storage backend runs it against its own implementations, so SQLite,
+// PostgreSQL and the in-memory store are held to the same behaviour and the
+// same error messages.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+)
+
+// Run runs the suite. open must return repositories on an empty store; it is
+// called once per test.
+func Run(t *testing.T, open func(t *testing.T) repository.Repositories) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repos repository.Repositories)
+	}{
+		{"Users", testUsers},
+		{"Votes", testVotes},
+		{"VoteKarma", testVoteKarma},
+		{"RecentVoters", testRecentVoters},
+		{"Lockstep", testLockstep},
+		{"LoginFailures", testLoginFailures},
+		{"UserTokens", testUserTokens},
+		{"Blocks", testBlocks},
+		{"Anonymize", testAnonymize},
+		{"PostListing", testPostListing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, open(t))
+		})
+	}
+}
+
+// wantErr fails the test unless err is of the given kind with the given message.
+func wantErr(t *testing.T, err error, kind error, message string) {
+	t.Helper()
+	if !errors.Is(err, kind) {
+		t.Fatalf("error = %v, want kind %v", err, kind)
+	}
+	if err.Error() != message {
+		t.Fatalf("error = %q, want %q", err.Error(), message)
+	}
+}
+
+// mustUser creates a user with the given name.
+func mustUser(t *testing.T, repos repository.Repositories, name string) *models.User {
+	t.Helper()
+	user := &models.User{Username: name, Email: name + "@example.com", Password: "hash"}
+	if err := repos.Users.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("CreateUser(%s): %v", name, err)
+	}
+	return user
+}
+
+// mustPost creates a subreddit owned by author and a post in it.
+func mustPost(t *testing.T, repos repository.Repositories, author *models.User, subreddit string) *models.Post {
+	t.Helper()
+	ctx := context.Background()
+	sub := &models.Subreddit{Name: subreddit, Description: "d", CreatedBy: author.ID}
+	if err := repos.Subreddits.CreateSubreddit(ctx, sub); err != nil {
+		t.Fatalf("CreateSubreddit(%s): %v", subreddit, err)
+	}
+	post := &models.Post{Title: "t", Content: "c", AuthorID: author.ID, SubredditID: sub.ID}
+	if err := repos.Posts.CreatePost(ctx, post); err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	return post
+}
+
+func testUsers(t *testing.T, repos repository.Repositories) {
+	ctx := context.Background()
+	ann := mustUser(t, repos, "ann")
+	if ann.ID == 0 || ann.CreatedAt.IsZero() {
+		t.Fatalf("CreateUser did not fill in the ID and timestamps: %+v", ann)
+	}
+
+	lookups := []struct {
+		name string
+		get  func() (*models.User, error)
+	}{
+		{"ByID", func() (*models.User, error) { return repos.Users.GetUserByID(ctx, ann.ID) }},
+		{"ByUsername", func() (*models.User, error) { return repos.Users.GetUserByUsername(ctx, "ann") }},
+		{"ByEmail", func() (*models.User, error) { return repos.Users.GetUserByEmail(ctx, "ann@example.com") }},
+	}
+	for _, tt := range lookups {
+		user, err := tt.get()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if user.ID != ann.ID || user.Username != "ann" || user.Role != models.RoleUser {
+			t.Fatalf("%s = %+v, want ann", tt.name, user)
+		}
+	}
+
+	_, err := repos.Users.GetUserByID(ctx, ann.ID+100)
+	wantErr(t, err, apperr.ErrNotFound, "GetUserByID: user not found")
+	_, err = repos.Users.GetUserByUsername(ctx, "nobody")
+	wantErr(t, err, apperr.ErrNotFound, "GetUserByUsername: user not found")
+
+	duplicate := &models.User{Username: "ann", Email: "other@example.com", Password: "hash"}
+	err = repos.Users.CreateUser(ctx, duplicate)
+	wantErr(t, err, apperr.ErrConflict, "CreateUser: user already exists")
+}
+
+func testVotes(t *testing.T, repos repository.Repositories) {
+	ctx := context.Background()
+	ann := mustUser(t, repos, "ann")
+	bob := mustUser(t, repos, "bob")
+	post := mustPost(t, repos, ann, "golang")
+
+	vote := &models.Vote{UserID: bob.ID, PostID: &post.ID, VoteType: "upvote"}
+	if err := repos.Votes.CreateVote(ctx, vote); err != nil {
+		t.Fatalf("CreateVote: %v", err)
+	}
+	got, err := repos.Votes.GetVoteByUserAndPost(ctx, bob.ID, post.ID)
+	if err != nil {
+		t.Fatalf("GetVoteByUserAndPost: %v", err)
+	}
+	if got.ID != vote.ID || got.VoteType != "upvote" {
+		t.Fatalf("GetVoteByUserAndPost = %+v, want %+v", got, vote)
+	}
+
+	_, err = repos.Votes.GetVoteByUserAndPost(ctx, ann.ID, post.ID)
+	wantErr(t, err, apperr.ErrNotFound, "GetVoteByUserAndPost: vote not found")
+
+	got.VoteType = "downvote"
+	if err := repos.Votes.UpdateVote(ctx, got); err != nil {
+		t.Fatalf("UpdateVote: %v", err)
+	}
+	votes, err := repos.Votes.GetUserVotesOnPosts(ctx, bob.ID, []int{post.ID})
+	if err != nil {
+		t.Fatalf("GetUserVotesOnPosts: %v", err)
+	}
+	if votes[post.ID] != "downvote" {
+		t.Fatalf("GetUserVotesOnPosts = %v, want a downvote on post %d", votes, post.ID)
+	}
+
+	if err := repos.Votes.DeleteVote(ctx, got); err != nil {
+		t.Fatalf("DeleteVote: %v", err)
+	}
+	_, err = repos.Votes.GetVoteByUserAndPost(ctx, bob.ID, post.ID)
+	wantErr(t, err, apperr.ErrNotFound, "GetVoteByUserAndPost: vote not found")
+}
+
+// testVoteKarma checks that every vote write keeps the karma of the target and
+// of its author equal to the sum of the target's unflagged votes.
+func testVoteKarma(t *testing.T, repos repository.Repositories) {
+	ctx := context.Background()
+	ann := mustUser(t, repos, "ann")
+	bob := mustUser(t, repos, "bob")
+	cid := mustUser(t, repos, "cid")
+	post := mustPost(t, repos, ann, "golang")
+	comment := &models.Comment{Content: "c", AuthorID: ann.ID, PostID: post.ID}
+	if err := repos.Comments.CreateComment(ctx, comment); err != nil {
+		t.Fatalf("CreateComment: %v", err)
+	}
+	postVote := &models.Vote{UserID: bob.ID, PostID: &post.ID, VoteType: "upvote"}
+	commentVote := &models.Vote{UserID: cid.ID, CommentID: &comment.ID, VoteType: "downvote"}
+
+	steps := []struct {
+		name                  string
+		write                 func() error
+		wantPost, wantComment int // Karma of the post and the comment, and so of ann.
+	}{
+		{"upvote the post", func() error { return repos.Votes.CreateVote(ctx, postVote) }, 1, 0},
+		{"downvote the comment", func() error { return repos.Votes.CreateVote(ctx, commentVote) }, 1, -1},
+		{"change to a downvote", func() error {
+			postVote.VoteType = "downvote"
+			return repos.Votes.UpdateVote(ctx, postVote)
+		}, -1, -1},
+		{"flag the post vote", func() error {
+			return repos.VoteIntegrity.CreateVoteFlag(ctx, &models.VoteFlag{VoteID: postVote.ID, Score: 1, Reasons: []string{"burst"}})
+		}, 0, -1},
+		{"change the flagged vote", func() error {
+			postVote.VoteType = "upvote"
+			return repos.Votes.UpdateVote(ctx, postVote)
+		}, 0, -1},
+		{"remove the flagged vote", func() error { return repos.Votes.DeleteVote(ctx, postVote) }, 0, -1},
+		{"remove the comment vote", func() error { return repos.Votes.DeleteVote(ctx, commentVote) }, 0, 0},
+	}
+	for _, step := range steps {
+		if err := step.write(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		gotPost, err := repos.Posts.GetPostByID(ctx, post.ID)
+		if err != nil {
+			t.Fatalf("GetPostByID: %v", err)
+		}
+		gotComment, err := repos.Comments.GetCommentByID(ctx, comment.ID)
+		if err != nil {
+			t.Fatalf("GetCommentByID: %v", err)
+		}
+		profile, err := repos.Users.GetUserProfile(ctx, ann.ID)
+		if err != nil {
+			t.Fatalf("GetUserProfile: %v", err)
+		}
+		if gotPost.Karma != step.wantPost || profile.PostKarma != step.wantPost ||
+			gotComment.Karma != step.wantComment || profile.CommentKarma != step.wantComment {
+			t.Fatalf("after %s: post %d, comment %d, author %d/%d; want %d and %d",
+				step.name, gotPost.Karma, gotComment.Karma, profile.PostKarma, profile.CommentKarma, step.wantPost, step.wantComment)
+		}
+	}
+
+	err := repos.VoteIntegrity.CreateVoteFlag(ctx, &models.VoteFlag{VoteID: postVote.ID, Score: 1, Reasons: []string{"burst"}})
+	wantErr(t, err, apperr.ErrNotFound, "CreateVoteFlag: vote not found")
+	vote := &models.Vote{UserID: bob.ID, PostID: &post.ID, VoteType: "upvote"}
+	if err := repos.Votes.CreateVote(ctx, vote); err != nil {
+		t.Fatalf("CreateVote: %v", err)
+	}
+	flag := &models.VoteFlag{VoteID: vote.ID, Score: 1, Reasons: []string{"burst"}}
+	if err := repos.VoteIntegrity.CreateVoteFlag(ctx, flag); err != nil {
+		t.Fatalf("CreateVoteFlag: %v", err)
+	}
+	err = repos.VoteIntegrity.CreateVoteFlag(ctx, flag)
+	wantErr(t, err, apperr.ErrConflict, "CreateVoteFlag: vote flag already exists")
+	err = repos.Votes.DeleteVote(ctx, postVote)
+	wantErr(t, err, apperr.ErrNotFound, "DeleteVote: no vote found to delete")
+	if gotPost, err := repos.Posts.GetPostByID(ctx, post.ID); err != nil || gotPost.Karma != 0 {
+		t.Fatalf("post = %+v, %v; want karma 0 after the failed writes", gotPost, err)
+	}
+}
+
+// testRecentVoters checks the time window of CountRecentVoters, which compares
+// a Go time against a column filled in by the database.
+func testRecentVoters(t *testing.T, repos repository.Repositories) {
+	ctx := context.Background()
+	ann := mustUser(t, repos, "ann")
+	post := mustPost(t, repos, ann, "golang")
+	var first *models.Vote
+	for _, name := range []string{"bob", "cal"} {
+		voter := mustUser(t, repos, name)
+		vote := &models.Vote{UserID: voter.ID, PostID: &post.ID, VoteType: "upvote"}
+		if err := repos.Votes.CreateVote(ctx, vote); err != nil {
+			t.Fatalf("CreateVote: %v", err)
+		}
+		if first == nil {
+			first = vote
+		}
+	}
+
+	tests := []struct {
+		name  string
+		since time.Time
+		want  int
+	}{
+		{"hour ago", time.Now().Add(-time.Hour), 2},
+		// SQLite compares timestamps as text, so this catches a since value
+		// formatted differently from the stored column.
+		{"first vote", first.CreatedAt, 2},
+		{"in an hour", time.Now().Add(time.Hour), 0},
+	}
+	for _, tt := range tests {
+		got, err := repos.VoteIntegrity.CountRecentVoters(ctx, &post.ID, nil, tt.since)
+		if err != nil {
+			t.Fatalf("%s: CountRecentVoters: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: CountRecentVoters = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// testLockstep checks that GetLockstepStats matches votes on posts and on
+// comments, and only votes of the same type.
+func testLockstep(t *testing.T, repos repository.Repositories) {
+	ctx := context.Background()
+	ann := mustUser(t, repos, "ann")
+	bob := mustUser(t, repos, "bob")
+	cid := mustUser(t, repos, "cid")
+	first := mustPost(t, repos, ann, "golang")
+	second := mustPost(t, repos, ann, "rust")
+	comment := &models.Comment{Content: "c", AuthorID: ann.ID, PostID: first.ID}
+	if err := repos.Comments.CreateComment(ctx, comment); err != nil {
+		t.Fatalf("CreateComment: %v", err)
+	}
+
+	votes := []*models.Vote{
+		{UserID: ann.ID, PostID: &first.ID, VoteType: models.Upvote},
+		{UserID: ann.ID, PostID: &second.ID, VoteType: models.Upvote},
+		{UserID: ann.ID, CommentID: &comment.ID, VoteType: models.Downvote},
+		{UserID: bob.ID, PostID: &first.ID, VoteType: models.Upvote},
+		{UserID: bob.ID, PostID: &second.ID, VoteType: models.Downvote},
+		{UserID: bob.ID, CommentID: &comment.ID, VoteType: models.Downvote},
+		{UserID: cid.ID, PostID: &second.ID, VoteType: models.Upvote},
+	}
+	for _, vote := range votes {
+		if err := repos.Votes.CreateVote(ctx, vote); err != nil {
+			t.Fatalf("CreateVote: %v", err)
+		}
+	}
+
+	tests := []struct {
+		user, recent         int
+		wantTotal, wantShare int
+	}{
+		{ann.ID, 20, 3, 2}, // bob matches the first post and the comment.
+		{ann.ID, 1, 1, 1},  // Only the comment vote is recent enough.
+		{cid.ID, 20, 1, 1},
+	}
+	for _, tt := range tests {
+		total, shared, err := repos.VoteIntegrity.GetLockstepStats(ctx, tt.user, tt.recent)
+		if err != nil {
+			t.Fatalf("GetLockstepStats: %v", err)
+		}
+		if total != tt.wantTotal || shared != tt.wantShare {
+			t.Errorf("GetLockstepStats(%d, %d) = %d, %d; want %d, %d", tt.user, tt.recent, total, shared, tt.wantTotal, tt.wantShare)
+		}
+	}
+}
+
+func testLoginFailures(t *testing.T, repos repository.Repositories) {
+	ctx := context.Background()
+	attempts := []struct {
+		username string
+		ip       string
+		result   string
+	}{
+		{"ann", "10.0.0.1", models.LoginFailed},
+		{"ann", "10.0.0.1", models.LoginSucceeded},
+		{"ann", "10.0.0.1", models.LoginFailed},
+		{"ann", "10.0.0.2", models.LoginFailed},
+		{"bob", "10.0.0.1", models.LoginFailed},
+	}
+	for _, a := range attempts {
+		attempt := &models.LoginAttempt{Username: a.username, IP: a.ip, UserAgent: "test", Result: a.result}
+		if err := repos.LoginAttempts.CreateLoginAttempt(ctx, attempt); err != nil {
+			t.Fatalf("CreateLoginAttempt: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		get   func(since time.Time) (*models.LoginFailures, error)
+		since time.Duration
+		want  int
+	}{
+		// The failure before ann's successful login no longer counts
+		// against the username, but still counts against the IP.
+		{"username", func(since time.Time) (*models.LoginFailures, error) {
+			return repos.LoginAttempts.GetUsernameFailures(ctx, "ann", since)
+		}, -time.Hour, 2},
+		{"ip", func(since time.Time) (*models.LoginFailures, error) {
+			return repos.LoginAttempts.GetIPFailures(ctx, "10.0.0.1", since)
+		}, -time.Hour, 3},
+		{"username outside window", func(since time.Time) (*models.LoginFailures, error) {
+			return repos.LoginAttempts.GetUsernameFailures(ctx, "ann", since)
+		}, time.Hour, 0},
+	}
+	for _, tt := range tests {
+		failures, err := tt.get(time.Now().Add(tt.since))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if failures.Count != tt.want {
+			t.Errorf("%s: Count = %d, want %d", tt.name, failures.Count, tt.want)
+		}
+		if (failures.Count > 0) == failures.Last.IsZero() {
+			t.Errorf("%s: Last = %v with Count %d", tt.name, failures.Last, failures.Count)
+		}
+	}
+}
+
+// testUserTokens checks that ConsumeUserToken accepts a token once and only
+// before it expires.
+func testUserTokens(t *testing.T, repos repository.Repositories) {
+	ctx := context.Background()
+	ann := mustUser(t, repos, "ann")
+	now := time.Now()
+	tokens := []*models.UserToken{
+		{UserID: ann.ID, Purpose: models.TokenVerifyEmail, TokenHash: "live", Email: ann.Email, ExpiresAt: now.Add(time.Hour)},
+		{UserID: ann.ID, Purpose: models.TokenVerifyEmail, TokenHash: "expired", Email: ann.Email, ExpiresAt: now.Add(-time.Minute)},
+	}
+	for _, token := range tokens {
+		if err := repos.UserTokens.CreateUserToken(ctx, token); err != nil {
+			t.Fatalf("CreateUserToken: %v", err)
+		}
+	}
+
+	got, err := repos.UserTokens.ConsumeUserToken(ctx, models.TokenVerifyEmail, "live", now)
+	if err != nil {
+		t.Fatalf("ConsumeUserToken: %v", err)
+	}
+	if got.ID != tokens[0].ID || got.UsedAt == nil || got.ExpiresAt.Sub(tokens[0].ExpiresAt).Abs() >= time.Second {
+		t.Fatalf("ConsumeUserToken = %+v, want %+v marked used", got, tokens[0])
+	}
+
+	tests := []struct {
+		name, purpose, hash string
+	}{
+		{"used token", models.TokenVerifyEmail, "live"},
+		{"expired token", models.TokenVerifyEmail, "expired"},
+		{"other purpose", models.TokenResetPassword, "live"},
+		{"unknown token", models.TokenVerifyEmail, "nope"},
+	}
+	for _, tt := range tests {
+		_, err := repos.UserTokens.ConsumeUserToken(ctx, tt.purpose, tt.hash, now)
+		if !errors.Is(err, apperr.ErrNotFound) {
+			t.Fatalf("%s: ConsumeUserToken error = %v, want not found", tt.name, err)
+		}
+	}
+}
+
+func testBlocks(t *testing.T, repos repository.Repositories) {
+	ctx := context.Background()
+	ann := mustUser(t, repos, "ann")
+	bob := mustUser(t, repos, "bob")
+
+	if err := repos.Blocks.CreateBlock(ctx, &models.Block{BlockerID: ann.ID, BlockedID: bob.ID}); err != nil {
+		t.Fatalf("CreateBlock: %v", err)
+	}
+	err := repos.Blocks.CreateBlock(ctx, &models.Block{BlockerID: ann.ID, BlockedID: bob.ID})
+	wantErr(t, err, apperr.ErrConflict, "CreateBlock: block already exists")
+
+	tests := []struct {
+		blocker, blocked int
+		want             bool
+	}{
+		{ann.ID, bob.ID, true},
+		{bob.ID, ann.ID, false},
+	}
+	for _, tt := range tests {
+		got, err := repos.Blocks.IsBlocked(ctx, tt.blocker, tt.blocked)
+		if err != nil {
+			t.Fatalf("IsBlocked: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("IsBlocked(%d, %d) = %v, want %v", tt.blocker, tt.blocked, got, tt.want)
+		}
+	}
+
+	if err := repos.Blocks.DeleteBlock(ctx, ann.ID, bob.ID); err != nil {
+		t.Fatalf("DeleteBlock: %v", err)
+	}
+	err = repos.Blocks.DeleteBlock(ctx, ann.ID, bob.ID)
+	wantErr(t, err, apperr.ErrNotFound, "DeleteBlock: no block found to delete")
+}
+
+// testAnonymize deletes an account the way the purge does: the row and its
+// content stay, under placeholder names.
+func testAnonymize(t *testing.T, repos repository.Repositories) {
+	ctx := context.Background()
+	ann := mustUser(t, repos, "ann")
+	bob := mustUser(t, repos, "bob")
+	post := mustPost(t, repos, ann, "golang")
+	if err := repos.Blocks.CreateBlock(ctx, &models.Block{BlockerID: ann.ID, BlockedID: bob.ID}); err != nil {
+		t.Fatalf("CreateBlock: %v", err)
+	}
+
+	const notScheduled = "AnonymizeUser: user not found or not scheduled for deletion"
+	err := repos.Users.AnonymizeUser(ctx, ann.ID)
+	wantErr(t, err, apperr.ErrConflict, notScheduled)
+
+	if err := repos.Users.RequestDeletion(ctx, ann.ID); err != nil {
+		t.Fatalf("RequestDeletion: %v", err)
+	}
+	if err := repos.Users.AnonymizeUser(ctx, ann.ID); err != nil {
+		t.Fatalf("AnonymizeUser: %v", err)
+	}
+	err = repos.Users.AnonymizeUser(ctx, ann.ID)
+	wantErr(t, err, apperr.ErrConflict, notScheduled)
+
+	user, err := repos.Users.GetUserByID(ctx, ann.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	username, email := models.DeletedAccount(ann.ID)
+	if user.Username != username || user.Email != email || user.Password != "" || user.DeletedAt == nil {
+		t.Errorf("anonymized user = %+v, want %s <%s> with no password", user, username, email)
+	}
+	if blocked, err := repos.Blocks.IsBlocked(ctx, ann.ID, bob.ID); err != nil || blocked {
+		t.Errorf("IsBlocked = %v, %v; want the block removed", blocked, err)
+	}
+	got, err := repos.Posts.GetPostByID(ctx, post.ID)
+	if err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+	if got.AuthorID != ann.ID {
+		t.Errorf("post author = %d, want %d", got.AuthorID, ann.ID)
+	}
+}
+
+// testPostListing pages through a subreddit with cursors in both directions.
+func testPostListing(t *testing.T, repos repository.Repositories) {
+	ctx := context.Background()
+	ann := mustUser(t, repos, "ann")
+	first := mustPost(t, repos, ann, "golang")
+	ids := []int{first.ID}
+	for i := 0; i < 4; i++ {
+		post := &models.Post{Title: "t", Content: "c", AuthorID: ann.ID, SubredditID: first.SubredditID}
+		if err := repos.Posts.CreatePost(ctx, post); err != nil {
+			t.Fatalf("CreatePost: %v", err)
+		}
+		ids = append(ids, post.ID)
+	}
+
+	// Newest first.
+	all, err := repos.Posts.GetPostsBySubreddit(ctx, first.SubredditID, 0, repository.Page{Limit: 10})
+	if err != nil {
+		t.Fatalf("GetPostsBySubreddit: %v", err)
+	}
+	if len(all) != len(ids) {
+		t.Fatalf("GetPostsBySubreddit returned %d posts, want %d", len(all), len(ids))
+	}
+	for i, post := range all {
+		if want := ids[len(ids)-1-i]; post.ID != want {
+			t.Fatalf("post %d has ID %d, want %d", i, post.ID, want)
+		}
+	}
+
+	after := repository.PostCursor(all[1])
+	before := repository.PostCursor(all[3])
+	tests := []struct {
+		name string
+		page repository.Page
+		want []int
+	}{
+		{"after", repository.Page{Limit: 2, After: &after}, []int{all[2].ID, all[3].ID}},
+		{"before", repository.Page{Limit: 2, Before: &before}, []int{all[1].ID, all[2].ID}},
+		{"offset", repository.Page{Limit: 2, Offset: 4}, []int{all[4].ID}},
+	}
+	for _, tt := range tests {
+		posts, err := repos.Posts.GetPostsBySubreddit(ctx, first.SubredditID, 0, tt.page)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []int
+		for _, post := range posts {
+			got = append(got, post.ID)
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Fatalf("%s: got %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	}
+}
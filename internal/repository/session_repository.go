@@ -0,0 +1,323 @@
+// File: internal/repository/session_repository.go
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/pkg/database"
+	"redditclone/pkg/metrics"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SessionRepository provides access to login sessions and their refresh tokens.
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *models.Session, refreshTokenHash string) error
+	GetSessionByID(ctx context.Context, id int) (*models.Session, error)
+	GetSessionByAccessToken(ctx context.Context, accessTokenHash string, now time.Time) (*models.Session, error)
+	GetSessionsByUser(ctx context.Context, userID int, now time.Time) ([]*models.Session, error)
+	TouchSession(ctx context.Context, id int, ip string) error
+	UseRefreshToken(ctx context.Context, tokenHash string) (int, error)
+	RenewSession(ctx context.Context, session *models.Session, refreshTokenHash string) error
+	RevokeSession(ctx context.Context, userID, id int, reason string) error
+	RevokeUserSessions(ctx context.Context, userID, exceptID int, reason string) (int, error)
+	CountActiveSessions(ctx context.Context, now time.Time) (int, error)
+}
+
+type sessionRepository struct {
+	DB *database.DB
+}
+
+// NewSessionRepository creates a new SessionRepository.
+func NewSessionRepository(db *database.DB) SessionRepository {
+	return &sessionRepository{DB: db}
+}
+
+// sessionColumns lists the columns scanned by scanSession, in order.
+const sessionColumns = `id, user_id, access_token_hash, access_expires_at, user_agent, ip,
+		created_at, last_seen_at, expires_at, revoked_at, COALESCE(revoke_reason, ''), impersonator_id`
+
+// scanSession reads a row selected with sessionColumns.
+func scanSession(row interface{ Scan(...any) error }) (*models.Session, error) {
+	session := &models.Session{}
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.AccessTokenHash,
+		&session.AccessExpiresAt,
+		&session.UserAgent,
+		&session.IP,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+		&session.RevokeReason,
+		&session.ImpersonatorID,
+	)
+	return session, err
+}
+
+// CreateSession inserts a new session together with its first refresh token.
+func (r *sessionRepository) CreateSession(ctx context.Context, session *models.Session, refreshTokenHash string) error {
+	defer metrics.TimeQuery("session", "CreateSession")()
+	ctx, span := startSpan(ctx, r.DB, "SessionRepository.CreateSession", attribute.Int("user.id", session.UserID))
+	defer span.End()
+	query := `
+		INSERT INTO sessions (user_id, access_token_hash, access_expires_at, user_agent, ip, created_at, last_seen_at, expires_at, impersonator_id)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, $6, $7)
+		RETURNING id, created_at, last_seen_at
+	`
+	err := database.Retry(ctx, func() error {
+		tx, err := r.DB.Write.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		err = tx.QueryRowContext(ctx, query, session.UserID, session.AccessTokenHash, r.DB.TimeArg(session.AccessExpiresAt),
+			session.UserAgent, session.IP, r.DB.TimeArg(session.ExpiresAt), session.ImpersonatorID).
+			Scan(&session.ID, &session.CreatedAt, &session.LastSeenAt)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO refresh_tokens (session_id, token_hash, created_at) VALUES ($1, $2, CURRENT_TIMESTAMP)`,
+			session.ID, refreshTokenHash); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return writeError("CreateSession", "session", err)
+	}
+	return nil
+}
+
+// GetSessionByID retrieves a session by its ID, revoked or not.
+func (r *sessionRepository) GetSessionByID(ctx context.Context, id int) (*models.Session, error) {
+	defer metrics.TimeQuery("session", "GetSessionByID")()
+	ctx, span := startSpan(ctx, r.DB, "SessionRepository.GetSessionByID", attribute.Int("session.id", id))
+	defer span.End()
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id = $1`
+	session, err := scanSession(r.DB.Read.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("GetSessionByID: session not found")
+		}
+		return nil, fmt.Errorf("GetSessionByID: %v", err)
+	}
+	return session, nil
+}
+
+// GetSessionByAccessToken retrieves the active session whose current access
+// token has the given hash and has not expired by now.
+func (r *sessionRepository) GetSessionByAccessToken(ctx context.Context, accessTokenHash string, now time.Time) (*models.Session, error) {
+	defer metrics.TimeQuery("session", "GetSessionByAccessToken")()
+	ctx, span := startSpan(ctx, r.DB, "SessionRepository.GetSessionByAccessToken")
+	defer span.End()
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE access_token_hash = $1 AND revoked_at IS NULL AND access_expires_at > $2
+	`
+	session, err := scanSession(r.DB.Read.QueryRowContext(ctx, query, accessTokenHash, r.DB.TimeArg(now)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("GetSessionByAccessToken: session not found")
+		}
+		return nil, fmt.Errorf("GetSessionByAccessToken: %v", err)
+	}
+	return session, nil
+}
+
+// GetSessionsByUser retrieves a user's active sessions, most recently used first.
+func (r *sessionRepository) GetSessionsByUser(ctx context.Context, userID int, now time.Time) ([]*models.Session, error) {
+	defer metrics.TimeQuery("session", "GetSessionsByUser")()
+	ctx, span := startSpan(ctx, r.DB, "SessionRepository.GetSessionsByUser", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY last_seen_at DESC, id DESC
+	`
+	rows, err := r.DB.Read.QueryContext(ctx, query, userID, r.DB.TimeArg(now))
+	if err != nil {
+		return nil, fmt.Errorf("GetSessionsByUser: %v", err)
+	}
+	defer rows.Close()
+
+	var sessions []*models.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("GetSessionsByUser: %v", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetSessionsByUser: %v", err)
+	}
+	span.SetAttributes(tracing.Rows(len(sessions)))
+	return sessions, nil
+}
+
+// TouchSession records that a session was just used, from the given IP.
+func (r *sessionRepository) TouchSession(ctx context.Context, id int, ip string) error {
+	defer metrics.TimeQuery("session", "TouchSession")()
+	ctx, span := startSpan(ctx, r.DB, "SessionRepository.TouchSession", attribute.Int("session.id", id))
+	defer span.End()
+	query := `
+		UPDATE sessions
+		SET last_seen_at = CURRENT_TIMESTAMP, ip = $2
+		WHERE id = $1
+	`
+	if _, err := r.DB.ExecContext(ctx, query, id, ip); err != nil {
+		return fmt.Errorf("TouchSession: %v", err)
+	}
+	return nil
+}
+
+// UseRefreshToken marks an unused refresh token as used and returns its
+// session ID. A token that was already used fails with apperr.Conflict, still
+// returning its session ID so the caller can revoke the session; an unknown
+// token is apperr.NotFound.
+func (r *sessionRepository) UseRefreshToken(ctx context.Context, tokenHash string) (int, error) {
+	defer metrics.TimeQuery("session", "UseRefreshToken")()
+	ctx, span := startSpan(ctx, r.DB, "SessionRepository.UseRefreshToken")
+	defer span.End()
+	query := `
+		UPDATE refresh_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND used_at IS NULL
+		RETURNING session_id
+	`
+	var sessionID int
+	err := database.Retry(ctx, func() error {
+		return r.DB.Write.QueryRowContext(ctx, query, tokenHash).Scan(&sessionID)
+	})
+	if err == nil {
+		return sessionID, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("UseRefreshToken: %v", err)
+	}
+
+	// Tell a replayed token apart from one that never existed
+	err = r.DB.Write.QueryRowContext(ctx, `SELECT session_id FROM refresh_tokens WHERE token_hash = $1`, tokenHash).Scan(&sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, apperr.NotFound("UseRefreshToken: refresh token not found")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("UseRefreshToken: %v", err)
+	}
+	return sessionID, apperr.Conflict("UseRefreshToken: refresh token already used")
+}
+
+// RenewSession stores a session's new access token and expiry times together
+// with its next refresh token. It fails with apperr.NotFound if the session
+// was revoked in the meantime.
+func (r *sessionRepository) RenewSession(ctx context.Context, session *models.Session, refreshTokenHash string) error {
+	defer metrics.TimeQuery("session", "RenewSession")()
+	ctx, span := startSpan(ctx, r.DB, "SessionRepository.RenewSession", attribute.Int("session.id", session.ID))
+	defer span.End()
+	query := `
+		UPDATE sessions
+		SET access_token_hash = $2, access_expires_at = $3, expires_at = $4, ip = $5, last_seen_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING last_seen_at
+	`
+	err := database.Retry(ctx, func() error {
+		tx, err := r.DB.Write.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		err = tx.QueryRowContext(ctx, query, session.ID, session.AccessTokenHash, r.DB.TimeArg(session.AccessExpiresAt),
+			r.DB.TimeArg(session.ExpiresAt), session.IP).Scan(&session.LastSeenAt)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO refresh_tokens (session_id, token_hash, created_at) VALUES ($1, $2, CURRENT_TIMESTAMP)`,
+			session.ID, refreshTokenHash); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return writeError("RenewSession", "session", err)
+	}
+	return nil
+}
+
+// RevokeSession revokes one of a user's active sessions. It fails with
+// apperr.NotFound if the user has no such active session.
+func (r *sessionRepository) RevokeSession(ctx context.Context, userID, id int, reason string) error {
+	defer metrics.TimeQuery("session", "RevokeSession")()
+	ctx, span := startSpan(ctx, r.DB, "SessionRepository.RevokeSession", attribute.Int("user.id", userID), attribute.Int("session.id", id))
+	defer span.End()
+	query := `
+		UPDATE sessions
+		SET revoked_at = CURRENT_TIMESTAMP, revoke_reason = $3
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+	result, err := r.DB.ExecContext(ctx, query, id, userID, reason)
+	if err != nil {
+		return fmt.Errorf("RevokeSession: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("RevokeSession: %v", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.NotFound("RevokeSession: session not found")
+	}
+	return nil
+}
+
+// RevokeUserSessions revokes all of a user's active sessions except exceptID,
+// which may be 0 to revoke them all, and returns how many it revoked.
+func (r *sessionRepository) RevokeUserSessions(ctx context.Context, userID, exceptID int, reason string) (int, error) {
+	defer metrics.TimeQuery("session", "RevokeUserSessions")()
+	ctx, span := startSpan(ctx, r.DB, "SessionRepository.RevokeUserSessions", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		UPDATE sessions
+		SET revoked_at = CURRENT_TIMESTAMP, revoke_reason = $3
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+	`
+	result, err := r.DB.ExecContext(ctx, query, userID, exceptID, reason)
+	if err != nil {
+		return 0, fmt.Errorf("RevokeUserSessions: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("RevokeUserSessions: %v", err)
+	}
+	span.SetAttributes(tracing.Rows(int(rowsAffected)))
+	return int(rowsAffected), nil
+}
+
+// CountActiveSessions returns the number of sessions, across all users, that
+// are neither revoked nor expired.
+func (r *sessionRepository) CountActiveSessions(ctx context.Context, now time.Time) (int, error) {
+	defer metrics.TimeQuery("session", "CountActiveSessions")()
+	ctx, span := startSpan(ctx, r.DB, "SessionRepository.CountActiveSessions")
+	defer span.End()
+	query := `
+		SELECT COUNT(*)
+		FROM sessions
+		WHERE revoked_at IS NULL AND expires_at > $1
+	`
+	var count int
+	if err := r.DB.Read.QueryRowContext(ctx, query, r.DB.TimeArg(now)).Scan(&count); err != nil {
+		return 0, fmt.Errorf("CountActiveSessions: %v", err)
+	}
+	return count, nil
+}
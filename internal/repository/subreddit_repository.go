@@ -3,70 +3,85 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
-	"errors"
 	"fmt"
 
+	"redditclone/internal/apperr"
 	"redditclone/internal/models"
 	"redditclone/pkg/database"
+	"redditclone/pkg/metrics"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // SubredditRepository provides access to the subreddits storage.
 type SubredditRepository interface {
-	CreateSubreddit(subreddit *models.Subreddit) error
-	GetSubredditByID(id int) (*models.Subreddit, error)
-	GetSubredditByName(name string) (*models.Subreddit, error)
-	UpdateSubreddit(subreddit *models.Subreddit) error
-	DeleteSubreddit(id int) error
+	CreateSubreddit(ctx context.Context, subreddit *models.Subreddit) error
+	GetSubredditByID(ctx context.Context, id int) (*models.Subreddit, error)
+	GetSubredditByName(ctx context.Context, name string) (*models.Subreddit, error)
+	UpdateSubreddit(ctx context.Context, subreddit *models.Subreddit) error
+	DeleteSubreddit(ctx context.Context, id int) error
+	Quarantine(ctx context.Context, id int, reason string) error
+	Unquarantine(ctx context.Context, id int) error
+	TransferOwnership(ctx context.Context, id, ownerID int) error
+	GetSubredditIDsByCreator(ctx context.Context, userID int) ([]int, error)
+	GetTopContributor(ctx context.Context, id, excludeUserID int) (int, error)
 }
 
 type subredditRepository struct {
-	DB *sql.DB
+	DB *database.DB
 }
 
 // NewSubredditRepository creates a new SubredditRepository.
-func NewSubredditRepository(db *sql.DB) SubredditRepository {
+func NewSubredditRepository(db *database.DB) SubredditRepository {
 	return &subredditRepository{DB: db}
 }
 
 // CreateSubreddit inserts a new subreddit into the database.
-func (r *subredditRepository) CreateSubreddit(subreddit *models.Subreddit) error {
-	database.DBMu.Lock()
-	defer database.DBMu.Unlock()
+func (r *subredditRepository) CreateSubreddit(ctx context.Context, subreddit *models.Subreddit) error {
+	defer metrics.TimeQuery("subreddit", "CreateSubreddit")()
+	ctx, span := startSpan(ctx, r.DB, "SubredditRepository.CreateSubreddit")
+	defer span.End()
 	query := `
 		INSERT INTO subreddits (name, description, created_by, created_at, updated_at)
 		VALUES ($1, $2, $3, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-		RETURNING id, created_at, updated_at
+		RETURNING id, created_at, updated_at, quarantined_at, quarantine_reason
 	`
-	err := r.DB.QueryRow(query, subreddit.Name, subreddit.Description, subreddit.CreatedBy).
-		Scan(&subreddit.ID, &subreddit.CreatedAt, &subreddit.UpdatedAt)
+	err := database.Retry(ctx, func() error {
+		return r.DB.Write.QueryRowContext(ctx, query, subreddit.Name, subreddit.Description, subreddit.CreatedBy).
+			Scan(&subreddit.ID, &subreddit.CreatedAt, &subreddit.UpdatedAt, &subreddit.QuarantinedAt, &subreddit.QuarantineReason)
+	})
 	if err != nil {
-		return fmt.Errorf("CreateSubreddit: %v", err)
+		return writeError("CreateSubreddit", "subreddit", err)
 	}
 	return nil
 }
 
 // GetSubredditByID retrieves a subreddit by its ID.
-func (r *subredditRepository) GetSubredditByID(id int) (*models.Subreddit, error) {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+func (r *subredditRepository) GetSubredditByID(ctx context.Context, id int) (*models.Subreddit, error) {
+	defer metrics.TimeQuery("subreddit", "GetSubredditByID")()
+	ctx, span := startSpan(ctx, r.DB, "SubredditRepository.GetSubredditByID", attribute.Int("subreddit.id", id))
+	defer span.End()
 	query := `
-		SELECT id, name, description, created_by, created_at, updated_at
+		SELECT id, name, description, created_by, created_at, updated_at, quarantined_at, quarantine_reason
 		FROM subreddits
 		WHERE id = $1
 	`
 	subreddit := &models.Subreddit{}
-	err := r.DB.QueryRow(query, id).Scan(
+	err := r.DB.Read.QueryRowContext(ctx, query, id).Scan(
 		&subreddit.ID,
 		&subreddit.Name,
 		&subreddit.Description,
 		&subreddit.CreatedBy,
 		&subreddit.CreatedAt,
 		&subreddit.UpdatedAt,
+		&subreddit.QuarantinedAt,
+		&subreddit.QuarantineReason,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("GetSubredditByID: subreddit not found")
+			return nil, apperr.NotFound("GetSubredditByID: subreddit not found")
 		}
 		return nil, fmt.Errorf("GetSubredditByID: %v", err)
 	}
@@ -74,26 +89,29 @@ func (r *subredditRepository) GetSubredditByID(id int) (*models.Subreddit, error
 }
 
 // GetSubredditByName retrieves a subreddit by its unique name.
-func (r *subredditRepository) GetSubredditByName(name string) (*models.Subreddit, error) {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+func (r *subredditRepository) GetSubredditByName(ctx context.Context, name string) (*models.Subreddit, error) {
+	defer metrics.TimeQuery("subreddit", "GetSubredditByName")()
+	ctx, span := startSpan(ctx, r.DB, "SubredditRepository.GetSubredditByName")
+	defer span.End()
 	query := `
-		SELECT id, name, description, created_by, created_at, updated_at
+		SELECT id, name, description, created_by, created_at, updated_at, quarantined_at, quarantine_reason
 		FROM subreddits
 		WHERE name = $1
 	`
 	subreddit := &models.Subreddit{}
-	err := r.DB.QueryRow(query, name).Scan(
+	err := r.DB.Read.QueryRowContext(ctx, query, name).Scan(
 		&subreddit.ID,
 		&subreddit.Name,
 		&subreddit.Description,
 		&subreddit.CreatedBy,
 		&subreddit.CreatedAt,
 		&subreddit.UpdatedAt,
+		&subreddit.QuarantinedAt,
+		&subreddit.QuarantineReason,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("GetSubredditByName: subreddit not found")
+			return nil, apperr.NotFound("GetSubredditByName: subreddit not found")
 		}
 		return nil, fmt.Errorf("GetSubredditByName: %v", err)
 	}
@@ -101,32 +119,36 @@ func (r *subredditRepository) GetSubredditByName(name string) (*models.Subreddit
 }
 
 // UpdateSubreddit updates an existing subreddit's information.
-func (r *subredditRepository) UpdateSubreddit(subreddit *models.Subreddit) error {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+func (r *subredditRepository) UpdateSubreddit(ctx context.Context, subreddit *models.Subreddit) error {
+	defer metrics.TimeQuery("subreddit", "UpdateSubreddit")()
+	ctx, span := startSpan(ctx, r.DB, "SubredditRepository.UpdateSubreddit", attribute.Int("subreddit.id", subreddit.ID))
+	defer span.End()
 	query := `
 		UPDATE subreddits
 		SET name = $1, description = $2, updated_at = CURRENT_TIMESTAMP
 		WHERE id = $3
 		RETURNING updated_at
 	`
-	err := r.DB.QueryRow(query, subreddit.Name, subreddit.Description, subreddit.ID).
-		Scan(&subreddit.UpdatedAt)
+	err := database.Retry(ctx, func() error {
+		return r.DB.Write.QueryRowContext(ctx, query, subreddit.Name, subreddit.Description, subreddit.ID).
+			Scan(&subreddit.UpdatedAt)
+	})
 	if err != nil {
-		return fmt.Errorf("UpdateSubreddit: %v", err)
+		return writeError("UpdateSubreddit", "subreddit", err)
 	}
 	return nil
 }
 
 // DeleteSubreddit removes a subreddit from the database.
-func (r *subredditRepository) DeleteSubreddit(id int) error {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+func (r *subredditRepository) DeleteSubreddit(ctx context.Context, id int) error {
+	defer metrics.TimeQuery("subreddit", "DeleteSubreddit")()
+	ctx, span := startSpan(ctx, r.DB, "SubredditRepository.DeleteSubreddit", attribute.Int("subreddit.id", id))
+	defer span.End()
 	query := `
 		DELETE FROM subreddits
 		WHERE id = $1
 	`
-	result, err := r.DB.Exec(query, id)
+	result, err := r.DB.ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("DeleteSubreddit: %v", err)
 	}
@@ -135,7 +157,126 @@ func (r *subredditRepository) DeleteSubreddit(id int) error {
 		return fmt.Errorf("DeleteSubreddit: %v", err)
 	}
 	if rowsAffected == 0 {
-		return errors.New("DeleteSubreddit: no subreddit found to delete")
+		return apperr.NotFound("DeleteSubreddit: no subreddit found to delete")
+	}
+	return nil
+}
+
+// Quarantine quarantines a subreddit, or updates the reason if it already is.
+func (r *subredditRepository) Quarantine(ctx context.Context, id int, reason string) error {
+	defer metrics.TimeQuery("subreddit", "Quarantine")()
+	ctx, span := startSpan(ctx, r.DB, "SubredditRepository.Quarantine", attribute.Int("subreddit.id", id))
+	defer span.End()
+	query := `
+		UPDATE subreddits
+		SET quarantined_at = COALESCE(quarantined_at, CURRENT_TIMESTAMP), quarantine_reason = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	return r.update(ctx, "Quarantine", query, id, reason)
+}
+
+// Unquarantine lifts a subreddit's quarantine.
+func (r *subredditRepository) Unquarantine(ctx context.Context, id int) error {
+	defer metrics.TimeQuery("subreddit", "Unquarantine")()
+	ctx, span := startSpan(ctx, r.DB, "SubredditRepository.Unquarantine", attribute.Int("subreddit.id", id))
+	defer span.End()
+	query := `
+		UPDATE subreddits
+		SET quarantined_at = NULL, quarantine_reason = '', updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	return r.update(ctx, "Unquarantine", query, id)
+}
+
+// TransferOwnership makes another user the subreddit's owner.
+func (r *subredditRepository) TransferOwnership(ctx context.Context, id, ownerID int) error {
+	defer metrics.TimeQuery("subreddit", "TransferOwnership")()
+	ctx, span := startSpan(ctx, r.DB, "SubredditRepository.TransferOwnership", attribute.Int("subreddit.id", id), attribute.Int("user.id", ownerID))
+	defer span.End()
+	query := `
+		UPDATE subreddits
+		SET created_by = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	return r.update(ctx, "TransferOwnership", query, id, ownerID)
+}
+
+// GetSubredditIDsByCreator retrieves the IDs of the subreddits a user owns.
+func (r *subredditRepository) GetSubredditIDsByCreator(ctx context.Context, userID int) ([]int, error) {
+	defer metrics.TimeQuery("subreddit", "GetSubredditIDsByCreator")()
+	ctx, span := startSpan(ctx, r.DB, "SubredditRepository.GetSubredditIDsByCreator", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		SELECT id
+		FROM subreddits
+		WHERE created_by = $1
+		ORDER BY id
+	`
+	rows, err := r.DB.Read.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("GetSubredditIDsByCreator: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("GetSubredditIDsByCreator: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetSubredditIDsByCreator: %v", err)
+	}
+	return ids, nil
+}
+
+// GetTopContributor finds the user other than excludeUserID with the most
+// posts and comments in a subreddit, ties going to the lowest ID. Suspended
+// accounts and accounts being deleted, or already deleted, are passed over.
+// It fails with apperr.NotFound if nobody qualifies.
+func (r *subredditRepository) GetTopContributor(ctx context.Context, id, excludeUserID int) (int, error) {
+	defer metrics.TimeQuery("subreddit", "GetTopContributor")()
+	ctx, span := startSpan(ctx, r.DB, "SubredditRepository.GetTopContributor", attribute.Int("subreddit.id", id))
+	defer span.End()
+	query := `
+		SELECT t.author_id
+		FROM (
+			SELECT author_id FROM posts WHERE subreddit_id = $1
+			UNION ALL
+			SELECT c.author_id FROM comments c JOIN posts p ON p.id = c.post_id WHERE p.subreddit_id = $1
+		) t
+		JOIN users u ON u.id = t.author_id
+		WHERE u.id <> $2 AND u.suspended_at IS NULL AND u.deletion_requested_at IS NULL
+		GROUP BY t.author_id
+		ORDER BY COUNT(*) DESC, t.author_id
+		LIMIT 1
+	`
+	var userID int
+	err := r.DB.Read.QueryRowContext(ctx, query, id, excludeUserID).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, apperr.NotFound("GetTopContributor: no contributor found")
+		}
+		return 0, fmt.Errorf("GetTopContributor: %v", err)
+	}
+	return userID, nil
+}
+
+// update runs an update of one subreddit, returning apperr.NotFound if it
+// changed no row.
+func (r *subredditRepository) update(ctx context.Context, op, query string, args ...any) error {
+	result, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if rowsAffected == 0 {
+		return apperr.NotFound(op + ": subreddit not found")
 	}
 	return nil
 }
@@ -0,0 +1,19 @@
+// File: internal/repository/tracing.go
+
+package repository
+
+import (
+	"context"
+
+	"redditclone/pkg/database"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan starts the span for a repository method, tagged with the database
+// system it queries.
+func startSpan(ctx context.Context, db *database.DB, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Start(ctx, name, append(attrs, attribute.String("db.system", db.Driver))...)
+}
@@ -3,139 +3,600 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
+	"redditclone/internal/apperr"
 	"redditclone/internal/models"
 	"redditclone/pkg/database"
+	"redditclone/pkg/metrics"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // UserRepository provides access to the users storage.
 type UserRepository interface {
-	CreateUser(user *models.User) error
-	GetUserByID(id int) (*models.User, error)
-	GetUserByUsername(username string) (*models.User, error)
-	UpdateUser(user *models.User) error
-	DeleteUser(id int) error
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUserByID(ctx context.Context, id int) (*models.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	UpdateUser(ctx context.Context, user *models.User) error
+	MarkEmailVerified(ctx context.Context, userID int, email string) error
+	SetBot(ctx context.Context, userID int, isBot bool) error
+	SetRole(ctx context.Context, userID int, role string) error
+	Suspend(ctx context.Context, userID int, reason string) error
+	Unsuspend(ctx context.Context, userID int) error
+	GetTwoFactor(ctx context.Context, userID int) (*models.TwoFactor, error)
+	SetTOTPSecret(ctx context.Context, userID int, secret string) error
+	EnableTOTP(ctx context.Context, userID int, step int64) error
+	DisableTOTP(ctx context.Context, userID int) error
+	UseTOTPStep(ctx context.Context, userID int, step int64) error
+	RequestDeletion(ctx context.Context, userID int) error
+	CancelDeletion(ctx context.Context, userID int) error
+	GetUsersDueForDeletion(ctx context.Context, requestedBefore time.Time, limit int) ([]int, error)
+	AnonymizeUser(ctx context.Context, userID int) error
+	GetUserProfile(ctx context.Context, id int) (*models.UserProfile, error)
+	GetUserOverview(ctx context.Context, userID int, sort string, limit, offset int) ([]*models.ActivityItem, error)
 }
 
 type userRepository struct {
-	DB *sql.DB
+	DB *database.DB
 }
 
 // NewUserRepository creates a new UserRepository.
-func NewUserRepository(db *sql.DB) UserRepository {
+func NewUserRepository(db *database.DB) UserRepository {
 	return &userRepository{DB: db}
 }
 
 // CreateUser inserts a new user into the database.
-func (r *userRepository) CreateUser(user *models.User) error {
-	database.DBMu.Lock()
-	defer database.DBMu.Unlock()
+func (r *userRepository) CreateUser(ctx context.Context, user *models.User) error {
+	defer metrics.TimeQuery("user", "CreateUser")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.CreateUser")
+	defer span.End()
 	query := `
-		INSERT INTO users (username, email, password, created_at, updated_at)
-		VALUES ($1, $2, $3, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-		RETURNING id, created_at, updated_at
+		INSERT INTO users (username, email, password, is_bot, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING id, created_at, updated_at, role, suspended_at, suspension_reason
 	`
-	err := r.DB.QueryRow(query, user.Username, user.Email, user.Password).
-		Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	err := database.Retry(ctx, func() error {
+		return r.DB.Write.QueryRowContext(ctx, query, user.Username, user.Email, user.Password, user.IsBot).
+			Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt, &user.Role, &user.SuspendedAt, &user.SuspensionReason)
+	})
 	if err != nil {
-		return fmt.Errorf("CreateUser: %v", err)
+		return writeError("CreateUser", "user", err)
 	}
 	return nil
 }
 
 // GetUserByID retrieves a user by their ID.
-func (r *userRepository) GetUserByID(id int) (*models.User, error) {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+func (r *userRepository) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	defer metrics.TimeQuery("user", "GetUserByID")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.GetUserByID", attribute.Int("user.id", id))
+	defer span.End()
 	query := `
-		SELECT id, username, email, password, created_at, updated_at
+		SELECT id, username, email, password, created_at, updated_at, email_verified_at, totp_enabled_at, is_bot,
+		       role, suspended_at, suspension_reason, deletion_requested_at, deleted_at
 		FROM users
 		WHERE id = $1
 	`
 	user := &models.User{}
-	err := r.DB.QueryRow(query, id).Scan(
+	err := r.DB.Read.QueryRowContext(ctx, query, id).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.Password,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.EmailVerifiedAt,
+		&user.TOTPEnabledAt,
+		&user.IsBot,
+		&user.Role,
+		&user.SuspendedAt,
+		&user.SuspensionReason,
+		&user.DeletionRequestedAt,
+		&user.DeletedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("GetUserByID: user not found")
+			return nil, apperr.NotFound("GetUserByID: user not found")
 		}
 		return nil, fmt.Errorf("GetUserByID: %v", err)
 	}
 	return user, nil
 }
 
-// GetUserByUsername retrieves a user by their username.
-func (r *userRepository) GetUserByUsername(username string) (*models.User, error) {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+// GetUserByUsername retrieves a user by their username. Deleted accounts
+// are not found.
+func (r *userRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	defer metrics.TimeQuery("user", "GetUserByUsername")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.GetUserByUsername")
+	defer span.End()
 	query := `
-		SELECT id, username, email, password, created_at, updated_at
+		SELECT id, username, email, password, created_at, updated_at, email_verified_at, totp_enabled_at, is_bot,
+		       role, suspended_at, suspension_reason, deletion_requested_at, deleted_at
 		FROM users
-		WHERE username = $1
+		WHERE username = $1 AND deleted_at IS NULL
 	`
 	user := &models.User{}
-	err := r.DB.QueryRow(query, username).Scan(
+	err := r.DB.Read.QueryRowContext(ctx, query, username).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.Password,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.EmailVerifiedAt,
+		&user.TOTPEnabledAt,
+		&user.IsBot,
+		&user.Role,
+		&user.SuspendedAt,
+		&user.SuspensionReason,
+		&user.DeletionRequestedAt,
+		&user.DeletedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("GetUserByUsername: user not found")
+			return nil, apperr.NotFound("GetUserByUsername: user not found")
 		}
 		return nil, fmt.Errorf("GetUserByUsername: %v", err)
 	}
 	return user, nil
 }
 
-// UpdateUser updates an existing user's information.
-func (r *userRepository) UpdateUser(user *models.User) error {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+// GetUserByEmail retrieves a user by their email address. Deleted accounts
+// are not found.
+func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	defer metrics.TimeQuery("user", "GetUserByEmail")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.GetUserByEmail")
+	defer span.End()
+	query := `
+		SELECT id, username, email, password, created_at, updated_at, email_verified_at, totp_enabled_at, is_bot,
+		       role, suspended_at, suspension_reason, deletion_requested_at, deleted_at
+		FROM users
+		WHERE email = $1 AND deleted_at IS NULL
+	`
+	user := &models.User{}
+	err := r.DB.Read.QueryRowContext(ctx, query, email).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.Password,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.EmailVerifiedAt,
+		&user.TOTPEnabledAt,
+		&user.IsBot,
+		&user.Role,
+		&user.SuspendedAt,
+		&user.SuspensionReason,
+		&user.DeletionRequestedAt,
+		&user.DeletedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("GetUserByEmail: user not found")
+		}
+		return nil, fmt.Errorf("GetUserByEmail: %v", err)
+	}
+	return user, nil
+}
+
+// UpdateUser updates an existing user's information. Changing the email
+// address clears its verification.
+func (r *userRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	defer metrics.TimeQuery("user", "UpdateUser")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.UpdateUser", attribute.Int("user.id", user.ID))
+	defer span.End()
 	query := `
 		UPDATE users
-		SET username = $1, email = $2, password = $3, updated_at = CURRENT_TIMESTAMP
+		SET username = $1, email = $2, password = $3, updated_at = CURRENT_TIMESTAMP,
+			email_verified_at = CASE WHEN email = $2 THEN email_verified_at END
 		WHERE id = $4
-		RETURNING updated_at
+		RETURNING updated_at, email_verified_at
+	`
+	err := database.Retry(ctx, func() error {
+		return r.DB.Write.QueryRowContext(ctx, query, user.Username, user.Email, user.Password, user.ID).
+			Scan(&user.UpdatedAt, &user.EmailVerifiedAt)
+	})
+	if err != nil {
+		return writeError("UpdateUser", "user", err)
+	}
+	return nil
+}
+
+// MarkEmailVerified records that a user verified their email address. It
+// fails with apperr.NotFound if the user no longer has that address.
+func (r *userRepository) MarkEmailVerified(ctx context.Context, userID int, email string) error {
+	defer metrics.TimeQuery("user", "MarkEmailVerified")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.MarkEmailVerified", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP)
+		WHERE id = $1 AND email = $2
+	`
+	result, err := r.DB.ExecContext(ctx, query, userID, email)
+	if err != nil {
+		return fmt.Errorf("MarkEmailVerified: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("MarkEmailVerified: %v", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.NotFound("MarkEmailVerified: user not found or email changed")
+	}
+	return nil
+}
+
+// SetBot marks a user's account as run by automation, or not.
+func (r *userRepository) SetBot(ctx context.Context, userID int, isBot bool) error {
+	defer metrics.TimeQuery("user", "SetBot")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.SetBot", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		UPDATE users
+		SET is_bot = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
 	`
-	err := r.DB.QueryRow(query, user.Username, user.Email, user.Password, user.ID).
-		Scan(&user.UpdatedAt)
+	result, err := r.DB.ExecContext(ctx, query, userID, isBot)
+	if err != nil {
+		return fmt.Errorf("SetBot: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("SetBot: %v", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.NotFound("SetBot: user not found")
+	}
+	return nil
+}
+
+// SetRole changes a user's role.
+func (r *userRepository) SetRole(ctx context.Context, userID int, role string) error {
+	defer metrics.TimeQuery("user", "SetRole")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.SetRole", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		UPDATE users
+		SET role = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	result, err := r.DB.ExecContext(ctx, query, userID, role)
+	if err != nil {
+		return fmt.Errorf("SetRole: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("UpdateUser: %v", err)
+		return fmt.Errorf("SetRole: %v", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.NotFound("SetRole: user not found")
 	}
 	return nil
 }
 
-// DeleteUser removes a user from the database.
-func (r *userRepository) DeleteUser(id int) error {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+// Suspend suspends a user's account site-wide, failing with apperr.Conflict
+// if it is missing or already suspended.
+func (r *userRepository) Suspend(ctx context.Context, userID int, reason string) error {
+	defer metrics.TimeQuery("user", "Suspend")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.Suspend", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		UPDATE users
+		SET suspended_at = CURRENT_TIMESTAMP, suspension_reason = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND suspended_at IS NULL
+	`
+	return r.updateUser(ctx, "Suspend", query, apperr.Conflict("Suspend: user not found or already suspended"), userID, reason)
+}
+
+// Unsuspend lifts a user's suspension, failing with apperr.Conflict if the
+// account is missing or not suspended.
+func (r *userRepository) Unsuspend(ctx context.Context, userID int) error {
+	defer metrics.TimeQuery("user", "Unsuspend")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.Unsuspend", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		UPDATE users
+		SET suspended_at = NULL, suspension_reason = '', updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND suspended_at IS NOT NULL
+	`
+	return r.updateUser(ctx, "Unsuspend", query, apperr.Conflict("Unsuspend: user not found or not suspended"), userID)
+}
+
+// GetTwoFactor retrieves a user's two-factor authentication state.
+func (r *userRepository) GetTwoFactor(ctx context.Context, userID int) (*models.TwoFactor, error) {
+	defer metrics.TimeQuery("user", "GetTwoFactor")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.GetTwoFactor", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		SELECT id, COALESCE(totp_secret, ''), totp_enabled_at, totp_last_step
+		FROM users
+		WHERE id = $1
+	`
+	tf := &models.TwoFactor{}
+	err := r.DB.Read.QueryRowContext(ctx, query, userID).Scan(&tf.UserID, &tf.Secret, &tf.EnabledAt, &tf.LastStep)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("GetTwoFactor: user not found")
+		}
+		return nil, fmt.Errorf("GetTwoFactor: %v", err)
+	}
+	return tf, nil
+}
+
+// SetTOTPSecret stores a new, not yet confirmed, TOTP secret for a user who
+// does not have two-factor authentication on. It fails with apperr.Conflict
+// if they do.
+func (r *userRepository) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
+	defer metrics.TimeQuery("user", "SetTOTPSecret")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.SetTOTPSecret", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		UPDATE users
+		SET totp_secret = $2, totp_last_step = NULL
+		WHERE id = $1 AND totp_enabled_at IS NULL
+	`
+	return r.updateUser(ctx, "SetTOTPSecret", query, apperr.Conflict("SetTOTPSecret: two-factor authentication is already enabled"), userID, secret)
+}
+
+// EnableTOTP turns two-factor authentication on with the pending secret,
+// recording step as used. It fails with apperr.Conflict if there is no
+// pending secret.
+func (r *userRepository) EnableTOTP(ctx context.Context, userID int, step int64) error {
+	defer metrics.TimeQuery("user", "EnableTOTP")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.EnableTOTP", attribute.Int("user.id", userID))
+	defer span.End()
 	query := `
-		DELETE FROM users
+		UPDATE users
+		SET totp_enabled_at = CURRENT_TIMESTAMP, totp_last_step = $2
+		WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL
+	`
+	return r.updateUser(ctx, "EnableTOTP", query, apperr.Conflict("EnableTOTP: no pending two-factor enrollment"), userID, step)
+}
+
+// DisableTOTP turns two-factor authentication off and forgets the secret.
+func (r *userRepository) DisableTOTP(ctx context.Context, userID int) error {
+	defer metrics.TimeQuery("user", "DisableTOTP")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.DisableTOTP", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		UPDATE users
+		SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL
 		WHERE id = $1
 	`
-	result, err := r.DB.Exec(query, id)
+	return r.updateUser(ctx, "DisableTOTP", query, apperr.NotFound("DisableTOTP: user not found"), userID)
+}
+
+// UseTOTPStep records that the code for step was accepted. Steps only move
+// forward, so it fails with apperr.Conflict for a step at or before the last
+// one used, which keeps a code from being replayed.
+func (r *userRepository) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	defer metrics.TimeQuery("user", "UseTOTPStep")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.UseTOTPStep", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		UPDATE users
+		SET totp_last_step = $2
+		WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)
+	`
+	return r.updateUser(ctx, "UseTOTPStep", query, apperr.Conflict("UseTOTPStep: code already used"), userID, step)
+}
+
+// updateUser runs a conditional update of a user's row, returning notMatched
+// if it changed no row.
+func (r *userRepository) updateUser(ctx context.Context, op, query string, notMatched error, args ...any) error {
+	result, err := r.DB.ExecContext(ctx, query, args...)
 	if err != nil {
-		return fmt.Errorf("DeleteUser: %v", err)
+		return fmt.Errorf("%s: %v", op, err)
 	}
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("DeleteUser: %v", err)
+		return fmt.Errorf("%s: %v", op, err)
 	}
 	if rowsAffected == 0 {
-		return errors.New("DeleteUser: no user found to delete")
+		return notMatched
+	}
+	return nil
+}
+
+// RequestDeletion schedules a user's account for deletion, failing with
+// apperr.Conflict if it is missing or already being deleted.
+func (r *userRepository) RequestDeletion(ctx context.Context, userID int) error {
+	defer metrics.TimeQuery("user", "RequestDeletion")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.RequestDeletion", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		UPDATE users
+		SET deletion_requested_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deletion_requested_at IS NULL AND deleted_at IS NULL
+	`
+	return r.updateUser(ctx, "RequestDeletion", query, apperr.Conflict("RequestDeletion: user not found or already being deleted"), userID)
+}
+
+// CancelDeletion takes back a scheduled deletion that has not happened yet,
+// failing with apperr.Conflict if there is none.
+func (r *userRepository) CancelDeletion(ctx context.Context, userID int) error {
+	defer metrics.TimeQuery("user", "CancelDeletion")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.CancelDeletion", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		UPDATE users
+		SET deletion_requested_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deletion_requested_at IS NOT NULL AND deleted_at IS NULL
+	`
+	return r.updateUser(ctx, "CancelDeletion", query, apperr.Conflict("CancelDeletion: user not found or not being deleted"), userID)
+}
+
+// GetUsersDueForDeletion retrieves the IDs of up to limit accounts whose
+// deletion was requested before the given time and has not happened yet,
+// oldest request first.
+func (r *userRepository) GetUsersDueForDeletion(ctx context.Context, requestedBefore time.Time, limit int) ([]int, error) {
+	defer metrics.TimeQuery("user", "GetUsersDueForDeletion")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.GetUsersDueForDeletion", attribute.Int("page.limit", limit))
+	defer span.End()
+	query := `
+		SELECT id
+		FROM users
+		WHERE deletion_requested_at <= $1 AND deleted_at IS NULL
+		ORDER BY deletion_requested_at, id
+		LIMIT $2
+	`
+	rows, err := r.DB.Read.QueryContext(ctx, query, r.DB.TimeArg(requestedBefore), limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetUsersDueForDeletion: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("GetUsersDueForDeletion: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetUsersDueForDeletion: %v", err)
+	}
+	span.SetAttributes(tracing.Rows(len(ids)))
+	return ids, nil
+}
+
+// anonymizeQueries remove what is private to an account when it is
+// anonymized: its sign-ins, credentials, grants, OAuth2 clients, login
+// history, email tokens and blocks either way.
+var anonymizeQueries = []string{
+	`DELETE FROM sessions WHERE user_id = $1`,
+	`DELETE FROM api_keys WHERE user_id = $1`,
+	`DELETE FROM oauth_tokens WHERE user_id = $1`,
+	`DELETE FROM oauth_codes WHERE user_id = $1`,
+	`DELETE FROM oauth_consents WHERE user_id = $1`,
+	`DELETE FROM oauth_clients WHERE owner_id = $1`,
+	`DELETE FROM login_attempts WHERE user_id = $1`,
+	`DELETE FROM user_tokens WHERE user_id = $1`,
+	`DELETE FROM recovery_codes WHERE user_id = $1`,
+	`DELETE FROM blocks WHERE blocker_id = $1 OR blocked_id = $1`,
+}
+
+// AnonymizeUser carries out a scheduled deletion. The account row stays, so
+// the user's posts, comments, votes, messages and subreddits keep their
+// author, but its username and email are replaced with placeholders, its
+// password, two-factor and moderation state are cleared, and everything in
+// anonymizeQueries is removed. It fails with apperr.Conflict if the account
+// is missing, not scheduled for deletion or already anonymized.
+func (r *userRepository) AnonymizeUser(ctx context.Context, userID int) error {
+	defer metrics.TimeQuery("user", "AnonymizeUser")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.AnonymizeUser", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		UPDATE users
+		SET username = $2, email = $3, password = '', email_verified_at = NULL,
+			totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL,
+			is_bot = FALSE, role = 'user', suspended_at = NULL, suspension_reason = '',
+			deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deletion_requested_at IS NOT NULL AND deleted_at IS NULL
+	`
+	username, email := models.DeletedAccount(userID)
+	errNotScheduled := apperr.Conflict("AnonymizeUser: user not found or not scheduled for deletion")
+	err := database.Retry(ctx, func() error {
+		tx, err := r.DB.Write.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		result, err := tx.ExecContext(ctx, query, userID, username, email)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return errNotScheduled
+		}
+		for _, q := range anonymizeQueries {
+			if _, err := tx.ExecContext(ctx, q, userID); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+	if errors.Is(err, apperr.ErrConflict) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("AnonymizeUser: %v", err)
 	}
 	return nil
 }
+
+// GetUserProfile retrieves a user's public profile with karma and activity counts.
+func (r *userRepository) GetUserProfile(ctx context.Context, id int) (*models.UserProfile, error) {
+	defer metrics.TimeQuery("user", "GetUserProfile")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.GetUserProfile", attribute.Int("user.id", id))
+	defer span.End()
+	query := `
+		SELECT u.id, u.username, u.is_bot, u.created_at, u.deleted_at IS NOT NULL,
+			COALESCE(k.post_karma, 0), COALESCE(k.comment_karma, 0),
+			(SELECT COUNT(*) FROM posts WHERE author_id = u.id),
+			(SELECT COUNT(*) FROM comments WHERE author_id = u.id)
+		FROM users u
+		LEFT JOIN user_karma k ON k.user_id = u.id
+		WHERE u.id = $1
+	`
+	profile := &models.UserProfile{}
+	err := r.DB.Read.QueryRowContext(ctx, query, id).Scan(
+		&profile.ID,
+		&profile.Username,
+		&profile.IsBot,
+		&profile.CreatedAt,
+		&profile.Deleted,
+		&profile.PostKarma,
+		&profile.CommentKarma,
+		&profile.PostCount,
+		&profile.CommentCount,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("GetUserProfile: user not found")
+		}
+		return nil, fmt.Errorf("GetUserProfile: %v", err)
+	}
+	profile.Karma = profile.PostKarma + profile.CommentKarma
+	return profile, nil
+}
+
+// GetUserOverview retrieves a user's posts and comments interleaved in the given
+// sort order with pagination.
+func (r *userRepository) GetUserOverview(ctx context.Context, userID int, sort string, limit, offset int) ([]*models.ActivityItem, error) {
+	defer metrics.TimeQuery("user", "GetUserOverview")()
+	ctx, span := startSpan(ctx, r.DB, "UserRepository.GetUserOverview", attribute.Int("user.id", userID), attribute.String("sort", sort), attribute.Int("page.limit", limit), attribute.Int("page.offset", offset))
+	defer span.End()
+	query := `
+		SELECT ` + activityColumns + `
+		FROM (
+			SELECT ` + activityPostColumns + ` FROM posts p WHERE p.author_id = $1
+			UNION ALL
+			SELECT ` + activityCommentColumns + ` FROM comments c WHERE c.author_id = $1
+		) t
+		ORDER BY ` + activityOrderBy(sort) + `
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.DB.Read.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("GetUserOverview: %v", err)
+	}
+	defer rows.Close()
+
+	items, err := scanActivityItems(rows)
+	if err != nil {
+		return nil, fmt.Errorf("GetUserOverview: %v", err)
+	}
+	span.SetAttributes(tracing.Rows(len(items)))
+	return items, nil
+}
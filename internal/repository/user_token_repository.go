@@ -0,0 +1,106 @@
+// File: internal/repository/user_token_repository.go
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/pkg/database"
+	"redditclone/pkg/metrics"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// UserTokenRepository provides access to the single-use tokens mailed to users.
+type UserTokenRepository interface {
+	CreateUserToken(ctx context.Context, token *models.UserToken) error
+	ConsumeUserToken(ctx context.Context, purpose, tokenHash string, now time.Time) (*models.UserToken, error)
+	DeleteUserTokens(ctx context.Context, userID int, purpose string) error
+}
+
+type userTokenRepository struct {
+	DB *database.DB
+}
+
+// NewUserTokenRepository creates a new UserTokenRepository.
+func NewUserTokenRepository(db *database.DB) UserTokenRepository {
+	return &userTokenRepository{DB: db}
+}
+
+// CreateUserToken inserts a new token into the database.
+func (r *userTokenRepository) CreateUserToken(ctx context.Context, token *models.UserToken) error {
+	defer metrics.TimeQuery("user_token", "CreateUserToken")()
+	ctx, span := startSpan(ctx, r.DB, "UserTokenRepository.CreateUserToken", attribute.Int("user.id", token.UserID), attribute.String("token.purpose", token.Purpose))
+	defer span.End()
+	query := `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, email, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
+		RETURNING id, created_at
+	`
+	err := database.Retry(ctx, func() error {
+		return r.DB.Write.QueryRowContext(ctx, query, token.UserID, token.Purpose, token.TokenHash, token.Email, r.DB.TimeArg(token.ExpiresAt)).
+			Scan(&token.ID, &token.CreatedAt)
+	})
+	if err != nil {
+		return writeError("CreateUserToken", "token", err)
+	}
+	return nil
+}
+
+// ConsumeUserToken marks an unused, unexpired token as used and returns it.
+// Marking and checking happen in one statement, so a token is accepted once
+// even under concurrent requests. Unknown, used and expired tokens are
+// apperr.NotFound.
+func (r *userTokenRepository) ConsumeUserToken(ctx context.Context, purpose, tokenHash string, now time.Time) (*models.UserToken, error) {
+	defer metrics.TimeQuery("user_token", "ConsumeUserToken")()
+	ctx, span := startSpan(ctx, r.DB, "UserTokenRepository.ConsumeUserToken", attribute.String("token.purpose", purpose))
+	defer span.End()
+	query := `
+		UPDATE user_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+		RETURNING id, user_id, purpose, token_hash, email, expires_at, used_at, created_at
+	`
+	token := &models.UserToken{}
+	err := database.Retry(ctx, func() error {
+		return r.DB.Write.QueryRowContext(ctx, query, tokenHash, purpose, r.DB.TimeArg(now)).Scan(
+			&token.ID,
+			&token.UserID,
+			&token.Purpose,
+			&token.TokenHash,
+			&token.Email,
+			&token.ExpiresAt,
+			&token.UsedAt,
+			&token.CreatedAt,
+		)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("ConsumeUserToken: token is invalid, expired or already used")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ConsumeUserToken: %v", err)
+	}
+	return token, nil
+}
+
+// DeleteUserTokens removes a user's outstanding tokens for a purpose, so
+// links in earlier emails stop working.
+func (r *userTokenRepository) DeleteUserTokens(ctx context.Context, userID int, purpose string) error {
+	defer metrics.TimeQuery("user_token", "DeleteUserTokens")()
+	ctx, span := startSpan(ctx, r.DB, "UserTokenRepository.DeleteUserTokens", attribute.Int("user.id", userID), attribute.String("token.purpose", purpose))
+	defer span.End()
+	query := `
+		DELETE FROM user_tokens
+		WHERE user_id = $1 AND purpose = $2
+	`
+	if _, err := r.DB.ExecContext(ctx, query, userID, purpose); err != nil {
+		return fmt.Errorf("DeleteUserTokens: %v", err)
+	}
+	return nil
+}
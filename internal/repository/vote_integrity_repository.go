@@ -0,0 +1,286 @@
+// File: internal/repository/vote_integrity_repository.go
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/pkg/database"
+	"redditclone/pkg/metrics"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// VoteIntegrityRepository provides access to the signals and flags used to
+// detect vote manipulation.
+type VoteIntegrityRepository interface {
+	CountRecentVoters(ctx context.Context, postID, commentID *int, since time.Time) (int, error)
+	GetLockstepStats(ctx context.Context, userID int, recent int) (total int, maxShared int, err error)
+	CreateVoteFlag(ctx context.Context, flag *models.VoteFlag) error
+	IsVoteFlagged(ctx context.Context, voteID int) (bool, error)
+	GetVoteFlags(ctx context.Context, limit, offset int) ([]*models.VoteFlag, error)
+	CountVoteFlags(ctx context.Context) (int, error)
+	CountVoteFlagsByReason(ctx context.Context, reason string) (int, error)
+	GetTopFlaggedTargets(ctx context.Context, limit int) ([]*models.FlaggedTarget, error)
+}
+
+type voteIntegrityRepository struct {
+	DB *database.DB
+}
+
+// NewVoteIntegrityRepository creates a new VoteIntegrityRepository.
+func NewVoteIntegrityRepository(db *database.DB) VoteIntegrityRepository {
+	return &voteIntegrityRepository{DB: db}
+}
+
+// CountRecentVoters counts the distinct users who voted on a post or comment since the given time.
+func (r *voteIntegrityRepository) CountRecentVoters(ctx context.Context, postID, commentID *int, since time.Time) (int, error) {
+	defer metrics.TimeQuery("vote_integrity", "CountRecentVoters")()
+	ctx, span := startSpan(ctx, r.DB, "VoteIntegrityRepository.CountRecentVoters")
+	defer span.End()
+	query := `
+		SELECT COUNT(DISTINCT user_id)
+		FROM votes
+		WHERE (post_id = $1 OR comment_id = $2) AND created_at >= $3
+	`
+	var count int
+	err := r.DB.Read.QueryRowContext(ctx, query, postID, commentID, r.DB.TimeArg(since)).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("CountRecentVoters: %v", err)
+	}
+	return count, nil
+}
+
+// GetLockstepStats looks at a user's most recent votes and reports how many
+// there are and the largest number of them any single other user matched with
+// the same vote on the same target. Posts and comments are matched in separate
+// branches so each can look up its index on votes; CROSS JOIN keeps SQLite
+// from scanning votes in the outer loop.
+func (r *voteIntegrityRepository) GetLockstepStats(ctx context.Context, userID int, recent int) (int, int, error) {
+	defer metrics.TimeQuery("vote_integrity", "GetLockstepStats")()
+	ctx, span := startSpan(ctx, r.DB, "VoteIntegrityRepository.GetLockstepStats", attribute.Int("user.id", userID))
+	defer span.End()
+	query := `
+		WITH mine AS (
+			SELECT post_id, comment_id, vote_type
+			FROM votes
+			WHERE user_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2
+		),
+		matches AS (
+			SELECT o.user_id
+			FROM mine m
+			CROSS JOIN votes o
+			WHERE o.post_id = m.post_id AND o.vote_type = m.vote_type AND o.user_id <> $1
+			UNION ALL
+			SELECT o.user_id
+			FROM mine m
+			CROSS JOIN votes o
+			WHERE o.comment_id = m.comment_id AND o.vote_type = m.vote_type AND o.user_id <> $1
+		)
+		SELECT
+			(SELECT COUNT(*) FROM mine),
+			COALESCE((
+				SELECT MAX(shared) FROM (
+					SELECT user_id, COUNT(*) AS shared
+					FROM matches
+					GROUP BY user_id
+				) s
+			), 0)
+	`
+	var total, maxShared int
+	err := r.DB.Read.QueryRowContext(ctx, query, userID, recent).Scan(&total, &maxShared)
+	if err != nil {
+		return 0, 0, fmt.Errorf("GetLockstepStats: %v", err)
+	}
+	return total, maxShared, nil
+}
+
+// CreateVoteFlag records a suspicious vote and takes it back out of karma, in
+// one transaction.
+func (r *voteIntegrityRepository) CreateVoteFlag(ctx context.Context, flag *models.VoteFlag) error {
+	defer metrics.TimeQuery("vote_integrity", "CreateVoteFlag")()
+	ctx, span := startSpan(ctx, r.DB, "VoteIntegrityRepository.CreateVoteFlag", attribute.Int("vote.id", flag.VoteID))
+	defer span.End()
+	query := `
+		INSERT INTO vote_flags (vote_id, score, reasons, created_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		RETURNING created_at
+	`
+	errNotFound := apperr.NotFound("CreateVoteFlag: vote not found")
+	errExists := apperr.Conflict("CreateVoteFlag: vote flag already exists")
+	err := database.Retry(ctx, func() error {
+		tx, err := r.DB.Write.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		vote, flagged, err := lockVote(ctx, r.DB, tx, flag.VoteID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errNotFound
+		} else if err != nil {
+			return err
+		}
+		if flagged {
+			return errExists
+		}
+		err = tx.QueryRowContext(ctx, query, flag.VoteID, flag.Score, strings.Join(flag.Reasons, ",")).
+			Scan(&flag.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if err := addVoteKarma(ctx, tx, vote, -vote.Value()); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	if errors.Is(err, apperr.ErrNotFound) || errors.Is(err, apperr.ErrConflict) {
+		return err
+	}
+	if err != nil {
+		return writeError("CreateVoteFlag", "vote flag", err)
+	}
+	return nil
+}
+
+// IsVoteFlagged reports whether a vote has been flagged as suspicious.
+func (r *voteIntegrityRepository) IsVoteFlagged(ctx context.Context, voteID int) (bool, error) {
+	defer metrics.TimeQuery("vote_integrity", "IsVoteFlagged")()
+	ctx, span := startSpan(ctx, r.DB, "VoteIntegrityRepository.IsVoteFlagged", attribute.Int("vote.id", voteID))
+	defer span.End()
+	query := `
+		SELECT COUNT(*)
+		FROM vote_flags
+		WHERE vote_id = $1
+	`
+	var count int
+	err := r.DB.Read.QueryRowContext(ctx, query, voteID).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("IsVoteFlagged: %v", err)
+	}
+	return count > 0, nil
+}
+
+// GetVoteFlags retrieves flagged votes, most recent first, with pagination.
+func (r *voteIntegrityRepository) GetVoteFlags(ctx context.Context, limit, offset int) ([]*models.VoteFlag, error) {
+	defer metrics.TimeQuery("vote_integrity", "GetVoteFlags")()
+	ctx, span := startSpan(ctx, r.DB, "VoteIntegrityRepository.GetVoteFlags", attribute.Int("page.limit", limit), attribute.Int("page.offset", offset))
+	defer span.End()
+	query := `
+		SELECT f.vote_id, v.user_id, v.post_id, v.comment_id, v.vote_type, f.score, f.reasons, f.created_at
+		FROM vote_flags f
+		JOIN votes v ON v.id = f.vote_id
+		ORDER BY f.created_at DESC, f.vote_id DESC
+		LIMIT $1 OFFSET $2
+	`
+	rows, err := r.DB.Read.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("GetVoteFlags: %v", err)
+	}
+	defer rows.Close()
+
+	var flags []*models.VoteFlag
+	for rows.Next() {
+		flag := &models.VoteFlag{}
+		var reasons string
+		err := rows.Scan(
+			&flag.VoteID,
+			&flag.UserID,
+			&flag.PostID,
+			&flag.CommentID,
+			&flag.VoteType,
+			&flag.Score,
+			&reasons,
+			&flag.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("GetVoteFlags: %v", err)
+		}
+		flag.Reasons = strings.Split(reasons, ",")
+		flags = append(flags, flag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetVoteFlags: %v", err)
+	}
+
+	span.SetAttributes(tracing.Rows(len(flags)))
+	return flags, nil
+}
+
+// CountVoteFlags counts all flagged votes.
+func (r *voteIntegrityRepository) CountVoteFlags(ctx context.Context) (int, error) {
+	defer metrics.TimeQuery("vote_integrity", "CountVoteFlags")()
+	ctx, span := startSpan(ctx, r.DB, "VoteIntegrityRepository.CountVoteFlags")
+	defer span.End()
+	var count int
+	err := r.DB.Read.QueryRowContext(ctx, `SELECT COUNT(*) FROM vote_flags`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("CountVoteFlags: %v", err)
+	}
+	return count, nil
+}
+
+// CountVoteFlagsByReason counts the flagged votes that list the given reason.
+func (r *voteIntegrityRepository) CountVoteFlagsByReason(ctx context.Context, reason string) (int, error) {
+	defer metrics.TimeQuery("vote_integrity", "CountVoteFlagsByReason")()
+	ctx, span := startSpan(ctx, r.DB, "VoteIntegrityRepository.CountVoteFlagsByReason", attribute.String("flag.reason", reason))
+	defer span.End()
+	query := `
+		SELECT COUNT(*)
+		FROM vote_flags
+		WHERE ',' || reasons || ',' LIKE $1
+	`
+	var count int
+	err := r.DB.Read.QueryRowContext(ctx, query, "%,"+reason+",%").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("CountVoteFlagsByReason: %v", err)
+	}
+	return count, nil
+}
+
+// GetTopFlaggedTargets retrieves the posts and comments with the most flagged votes.
+func (r *voteIntegrityRepository) GetTopFlaggedTargets(ctx context.Context, limit int) ([]*models.FlaggedTarget, error) {
+	defer metrics.TimeQuery("vote_integrity", "GetTopFlaggedTargets")()
+	ctx, span := startSpan(ctx, r.DB, "VoteIntegrityRepository.GetTopFlaggedTargets", attribute.Int("page.limit", limit))
+	defer span.End()
+	query := `
+		SELECT v.post_id, v.comment_id, COUNT(*) AS flagged
+		FROM vote_flags f
+		JOIN votes v ON v.id = f.vote_id
+		GROUP BY v.post_id, v.comment_id
+		ORDER BY flagged DESC
+		LIMIT $1
+	`
+	rows, err := r.DB.Read.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetTopFlaggedTargets: %v", err)
+	}
+	defer rows.Close()
+
+	var targets []*models.FlaggedTarget
+	for rows.Next() {
+		target := &models.FlaggedTarget{}
+		err := rows.Scan(&target.PostID, &target.CommentID, &target.FlaggedVotes)
+		if err != nil {
+			return nil, fmt.Errorf("GetTopFlaggedTargets: %v", err)
+		}
+		targets = append(targets, target)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetTopFlaggedTargets: %v", err)
+	}
+
+	span.SetAttributes(tracing.Rows(len(targets)))
+	return targets, nil
+}
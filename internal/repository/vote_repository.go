@@ -3,60 +3,87 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 
+	"redditclone/internal/apperr"
 	"redditclone/internal/models"
 	"redditclone/pkg/database"
+	"redditclone/pkg/metrics"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // VoteRepository provides access to the votes storage.
 type VoteRepository interface {
-	CreateVote(vote *models.Vote) error
-	GetVoteByUserAndPost(userID, postID int) (*models.Vote, error)
-	GetVoteByUserAndComment(userID, commentID int) (*models.Vote, error)
-	UpdateVote(vote *models.Vote) error
-	DeleteVote(vote *models.Vote) error
+	CreateVote(ctx context.Context, vote *models.Vote) error
+	GetVoteByUserAndPost(ctx context.Context, userID, postID int) (*models.Vote, error)
+	GetVoteByUserAndComment(ctx context.Context, userID, commentID int) (*models.Vote, error)
+	UpdateVote(ctx context.Context, vote *models.Vote) error
+	DeleteVote(ctx context.Context, vote *models.Vote) error
+	GetVotedItemsByUser(ctx context.Context, userID int, voteType string, limit, offset int) ([]*models.ActivityItem, error)
+	GetUserVotesOnPosts(ctx context.Context, userID int, postIDs []int) (map[int]string, error)
+	GetUserVotesOnComments(ctx context.Context, userID int, commentIDs []int) (map[int]string, error)
 }
 
 type voteRepository struct {
-	DB *sql.DB
+	DB *database.DB
 }
 
 // NewVoteRepository creates a new VoteRepository.
-func NewVoteRepository(db *sql.DB) VoteRepository {
+func NewVoteRepository(db *database.DB) VoteRepository {
 	return &voteRepository{DB: db}
 }
 
-// CreateVote inserts a new vote into the database.
-func (r *voteRepository) CreateVote(vote *models.Vote) error {
-	database.DBMu.Lock()
-	defer database.DBMu.Unlock()
+// CreateVote inserts a new vote into the database and adds it to the karma of
+// the post or comment and of its author, in one transaction.
+func (r *voteRepository) CreateVote(ctx context.Context, vote *models.Vote) error {
+	defer metrics.TimeQuery("vote", "CreateVote")()
+	ctx, span := startSpan(ctx, r.DB, "VoteRepository.CreateVote")
+	defer span.End()
 	query := `
 		INSERT INTO votes (user_id, post_id, comment_id, vote_type, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 		RETURNING id, created_at, updated_at
 	`
-	err := r.DB.QueryRow(query, vote.UserID, vote.PostID, vote.CommentID, vote.VoteType).
-		Scan(&vote.ID, &vote.CreatedAt, &vote.UpdatedAt)
+	err := database.Retry(ctx, func() error {
+		tx, err := r.DB.Write.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		err = tx.QueryRowContext(ctx, query, vote.UserID, vote.PostID, vote.CommentID, vote.VoteType).
+			Scan(&vote.ID, &vote.CreatedAt, &vote.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		if err := addVoteKarma(ctx, tx, vote, vote.Value()); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
 	if err != nil {
-		return fmt.Errorf("CreateVote: %v", err)
+		return writeError("CreateVote", "vote", err)
 	}
 	return nil
 }
 
 // GetVoteByUserAndPost retrieves a vote by a user on a specific post.
-func (r *voteRepository) GetVoteByUserAndPost(userID, postID int) (*models.Vote, error) {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+func (r *voteRepository) GetVoteByUserAndPost(ctx context.Context, userID, postID int) (*models.Vote, error) {
+	defer metrics.TimeQuery("vote", "GetVoteByUserAndPost")()
+	ctx, span := startSpan(ctx, r.DB, "VoteRepository.GetVoteByUserAndPost", attribute.Int("user.id", userID), attribute.Int("post.id", postID))
+	defer span.End()
 	query := `
 		SELECT id, user_id, post_id, comment_id, vote_type, created_at, updated_at
 		FROM votes
 		WHERE user_id = $1 AND post_id = $2 AND comment_id IS NULL
 	`
 	vote := &models.Vote{}
-	err := r.DB.QueryRow(query, userID, postID).Scan(
+	err := r.DB.Read.QueryRowContext(ctx, query, userID, postID).Scan(
 		&vote.ID,
 		&vote.UserID,
 		&vote.PostID,
@@ -67,7 +94,7 @@ func (r *voteRepository) GetVoteByUserAndPost(userID, postID int) (*models.Vote,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("GetVoteByUserAndPost: vote not found")
+			return nil, apperr.NotFound("GetVoteByUserAndPost: vote not found")
 		}
 		return nil, fmt.Errorf("GetVoteByUserAndPost: %v", err)
 	}
@@ -75,16 +102,17 @@ func (r *voteRepository) GetVoteByUserAndPost(userID, postID int) (*models.Vote,
 }
 
 // GetVoteByUserAndComment retrieves a vote by a user on a specific comment.
-func (r *voteRepository) GetVoteByUserAndComment(userID, commentID int) (*models.Vote, error) {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+func (r *voteRepository) GetVoteByUserAndComment(ctx context.Context, userID, commentID int) (*models.Vote, error) {
+	defer metrics.TimeQuery("vote", "GetVoteByUserAndComment")()
+	ctx, span := startSpan(ctx, r.DB, "VoteRepository.GetVoteByUserAndComment", attribute.Int("user.id", userID), attribute.Int("comment.id", commentID))
+	defer span.End()
 	query := `
 		SELECT id, user_id, post_id, comment_id, vote_type, created_at, updated_at
 		FROM votes
 		WHERE user_id = $1 AND comment_id = $2 AND post_id IS NULL
 	`
 	vote := &models.Vote{}
-	err := r.DB.QueryRow(query, userID, commentID).Scan(
+	err := r.DB.Read.QueryRowContext(ctx, query, userID, commentID).Scan(
 		&vote.ID,
 		&vote.UserID,
 		&vote.PostID,
@@ -95,49 +123,261 @@ func (r *voteRepository) GetVoteByUserAndComment(userID, commentID int) (*models
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("GetVoteByUserAndComment: vote not found")
+			return nil, apperr.NotFound("GetVoteByUserAndComment: vote not found")
 		}
 		return nil, fmt.Errorf("GetVoteByUserAndComment: %v", err)
 	}
 	return vote, nil
 }
 
-// UpdateVote updates an existing vote's type.
-func (r *voteRepository) UpdateVote(vote *models.Vote) error {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+// UpdateVote updates an existing vote's type and, unless the vote is flagged,
+// moves the karma it contributes, in one transaction.
+func (r *voteRepository) UpdateVote(ctx context.Context, vote *models.Vote) error {
+	defer metrics.TimeQuery("vote", "UpdateVote")()
+	ctx, span := startSpan(ctx, r.DB, "VoteRepository.UpdateVote", attribute.Int("vote.id", vote.ID))
+	defer span.End()
 	query := `
 		UPDATE votes
 		SET vote_type = $1, updated_at = CURRENT_TIMESTAMP
 		WHERE id = $2
 		RETURNING updated_at
 	`
-	err := r.DB.QueryRow(query, vote.VoteType, vote.ID).
-		Scan(&vote.UpdatedAt)
+	err := database.Retry(ctx, func() error {
+		tx, err := r.DB.Write.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		stored, flagged, err := lockVote(ctx, r.DB, tx, vote.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.QueryRowContext(ctx, query, vote.VoteType, vote.ID).Scan(&vote.UpdatedAt); err != nil {
+			return err
+		}
+		if !flagged {
+			if err := addVoteKarma(ctx, tx, stored, vote.Value()-stored.Value()); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
 	if err != nil {
-		return fmt.Errorf("UpdateVote: %v", err)
+		return writeError("UpdateVote", "vote", err)
 	}
 	return nil
 }
 
-// DeleteVote removes a vote from the database.
-func (r *voteRepository) DeleteVote(vote *models.Vote) error {
-	database.DBMu.Lock()
-    defer database.DBMu.Unlock()
+// DeleteVote removes a vote from the database and, unless it is flagged, takes
+// it back out of karma, in one transaction.
+func (r *voteRepository) DeleteVote(ctx context.Context, vote *models.Vote) error {
+	defer metrics.TimeQuery("vote", "DeleteVote")()
+	ctx, span := startSpan(ctx, r.DB, "VoteRepository.DeleteVote", attribute.Int("vote.id", vote.ID))
+	defer span.End()
 	query := `
 		DELETE FROM votes
 		WHERE id = $1
 	`
-	result, err := r.DB.Exec(query, vote.ID)
+	errNotFound := apperr.NotFound("DeleteVote: no vote found to delete")
+	err := database.Retry(ctx, func() error {
+		tx, err := r.DB.Write.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		stored, flagged, err := lockVote(ctx, r.DB, tx, vote.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errNotFound
+		} else if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query, vote.ID); err != nil {
+			return err
+		}
+		if !flagged {
+			if err := addVoteKarma(ctx, tx, stored, -stored.Value()); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+	if errors.Is(err, apperr.ErrNotFound) {
+		return err
+	}
 	if err != nil {
 		return fmt.Errorf("DeleteVote: %v", err)
 	}
-	rowsAffected, err := result.RowsAffected()
+	return nil
+}
+
+// lockVote reads a vote and whether it is flagged as part of tx, locking the
+// vote so that no flag can be added or removed until tx ends. Flagged votes
+// do not count toward karma.
+func lockVote(ctx context.Context, db *database.DB, tx *sql.Tx, voteID int) (*models.Vote, bool, error) {
+	query := `
+		SELECT id, user_id, post_id, comment_id, vote_type, created_at, updated_at
+		FROM votes
+		WHERE id = $1` + db.ForUpdate()
+	vote := &models.Vote{}
+	err := tx.QueryRowContext(ctx, query, voteID).Scan(
+		&vote.ID,
+		&vote.UserID,
+		&vote.PostID,
+		&vote.CommentID,
+		&vote.VoteType,
+		&vote.CreatedAt,
+		&vote.UpdatedAt,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	var flags int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM vote_flags WHERE vote_id = $1`, voteID).Scan(&flags)
 	if err != nil {
-		return fmt.Errorf("DeleteVote: %v", err)
+		return nil, false, err
 	}
-	if rowsAffected == 0 {
-		return errors.New("DeleteVote: no vote found to delete")
+	return vote, flags > 0, nil
+}
+
+// addVoteKarma adds delta to the karma of the post or comment a vote is on and
+// to the post or comment karma of its author, as part of tx.
+func addVoteKarma(ctx context.Context, tx *sql.Tx, vote *models.Vote, delta int) error {
+	if delta == 0 {
+		return nil
 	}
-	return nil
+	table, targetID := "posts", vote.PostID
+	postDelta, commentDelta := delta, 0
+	if vote.CommentID != nil {
+		table, targetID = "comments", vote.CommentID
+		postDelta, commentDelta = 0, delta
+	}
+	if targetID == nil {
+		return errors.New("vote is on neither a post nor a comment")
+	}
+	var authorID int
+	query := `UPDATE ` + table + ` SET karma = karma + $1 WHERE id = $2 RETURNING author_id`
+	if err := tx.QueryRowContext(ctx, query, delta, *targetID).Scan(&authorID); err != nil {
+		return err
+	}
+	query = `
+		INSERT INTO user_karma (user_id, post_karma, comment_karma)
+		VALUES ($1, $2, $3)
+		ON CONFLICT(user_id) DO UPDATE
+		SET post_karma = user_karma.post_karma + excluded.post_karma,
+			comment_karma = user_karma.comment_karma + excluded.comment_karma
+	`
+	_, err := tx.ExecContext(ctx, query, authorID, postDelta, commentDelta)
+	return err
+}
+
+// GetVotedItemsByUser retrieves the posts and comments a user voted on with the
+// given vote type, most recently voted first, with pagination.
+func (r *voteRepository) GetVotedItemsByUser(ctx context.Context, userID int, voteType string, limit, offset int) ([]*models.ActivityItem, error) {
+	defer metrics.TimeQuery("vote", "GetVotedItemsByUser")()
+	ctx, span := startSpan(ctx, r.DB, "VoteRepository.GetVotedItemsByUser", attribute.Int("user.id", userID), attribute.String("vote.type", voteType), attribute.Int("page.limit", limit), attribute.Int("page.offset", offset))
+	defer span.End()
+	query := `
+		SELECT ` + activityColumns + `
+		FROM (
+			SELECT ` + activityPostColumns + `, uv.updated_at AS voted_at
+			FROM posts p
+			JOIN votes uv ON uv.post_id = p.id AND uv.comment_id IS NULL
+			WHERE uv.user_id = $1 AND uv.vote_type = $2
+			UNION ALL
+			SELECT ` + activityCommentColumns + `, uv.updated_at AS voted_at
+			FROM comments c
+			JOIN votes uv ON uv.comment_id = c.id AND uv.post_id IS NULL
+			WHERE uv.user_id = $1 AND uv.vote_type = $2
+		) t
+		ORDER BY voted_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.DB.Read.QueryContext(ctx, query, userID, voteType, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("GetVotedItemsByUser: %v", err)
+	}
+	defer rows.Close()
+
+	items, err := scanActivityItems(rows)
+	if err != nil {
+		return nil, fmt.Errorf("GetVotedItemsByUser: %v", err)
+	}
+	span.SetAttributes(tracing.Rows(len(items)))
+	return items, nil
+}
+
+// GetUserVotesOnPosts retrieves a user's votes on the given posts in one query,
+// keyed by post ID. Posts the user has not voted on are absent from the map.
+func (r *voteRepository) GetUserVotesOnPosts(ctx context.Context, userID int, postIDs []int) (map[int]string, error) {
+	defer metrics.TimeQuery("vote", "GetUserVotesOnPosts")()
+	ctx, span := startSpan(ctx, r.DB, "VoteRepository.GetUserVotesOnPosts", attribute.Int("user.id", userID))
+	defer span.End()
+	votes, err := r.getUserVotesOn(ctx, "post_id", "comment_id", userID, postIDs)
+	if err != nil {
+		return nil, fmt.Errorf("GetUserVotesOnPosts: %v", err)
+	}
+	span.SetAttributes(tracing.Rows(len(votes)))
+	return votes, nil
+}
+
+// GetUserVotesOnComments retrieves a user's votes on the given comments in one
+// query, keyed by comment ID. Comments the user has not voted on are absent from the map.
+func (r *voteRepository) GetUserVotesOnComments(ctx context.Context, userID int, commentIDs []int) (map[int]string, error) {
+	defer metrics.TimeQuery("vote", "GetUserVotesOnComments")()
+	ctx, span := startSpan(ctx, r.DB, "VoteRepository.GetUserVotesOnComments", attribute.Int("user.id", userID))
+	defer span.End()
+	votes, err := r.getUserVotesOn(ctx, "comment_id", "post_id", userID, commentIDs)
+	if err != nil {
+		return nil, fmt.Errorf("GetUserVotesOnComments: %v", err)
+	}
+	span.SetAttributes(tracing.Rows(len(votes)))
+	return votes, nil
+}
+
+// getUserVotesOn looks up a user's votes where targetColumn is one of ids and
+// otherColumn is NULL.
+func (r *voteRepository) getUserVotesOn(ctx context.Context, targetColumn, otherColumn string, userID int, ids []int) (map[int]string, error) {
+	votes := make(map[int]string, len(ids))
+	if len(ids) == 0 {
+		return votes, nil
+	}
+
+	args := make([]interface{}, 0, len(ids)+1)
+	args = append(args, userID)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	query := `
+		SELECT ` + targetColumn + `, vote_type
+		FROM votes
+		WHERE user_id = $1 AND ` + otherColumn + ` IS NULL
+		  AND ` + targetColumn + ` IN (` + placeholders(2, len(ids)) + `)
+	`
+	rows, err := r.DB.Read.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var voteType string
+		if err := rows.Scan(&id, &voteType); err != nil {
+			return nil, err
+		}
+		votes[id] = voteType
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return votes, nil
+}
+
+// placeholders returns n comma-separated positional placeholders starting at $start.
+func placeholders(start, n int) string {
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = fmt.Sprintf("$%d", start+i)
+	}
+	return strings.Join(parts, ", ")
 }
@@ -0,0 +1,207 @@
+// File: internal/service/account_deletion_service.go
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+	"redditclone/pkg/logging"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// purgeBatchSize is the most accounts one purge run anonymizes; the rest
+// wait for the next run.
+const purgeBatchSize = 100
+
+// AccountDeletionService defines deleting accounts. A deletion is only
+// scheduled at first, and can be cancelled during a grace period; after it
+// the account is anonymized rather than removed, so discussions it took part
+// in stay whole.
+type AccountDeletionService interface {
+	RequestDeletion(ctx context.Context, userID int, password string) (*models.AccountDeletion, error)
+	CancelDeletion(ctx context.Context, userID int) error
+	PurgeDueAccounts(ctx context.Context) (int, error)
+}
+
+// AccountDeletionConfig configures account deletion.
+type AccountDeletionConfig struct {
+	Grace time.Duration // How long a deletion can be cancelled before the account is anonymized.
+}
+
+type accountDeletionService struct {
+	UserRepo      repository.UserRepository
+	SubredditRepo repository.SubredditRepository
+	AuditRepo     repository.AuditRepository
+	Sessions      SessionService
+	Config        AccountDeletionConfig
+}
+
+// NewAccountDeletionService creates a new AccountDeletionService.
+func NewAccountDeletionService(userRepo repository.UserRepository, subredditRepo repository.SubredditRepository, auditRepo repository.AuditRepository, sessions SessionService, cfg AccountDeletionConfig) AccountDeletionService {
+	return &accountDeletionService{
+		UserRepo:      userRepo,
+		SubredditRepo: subredditRepo,
+		AuditRepo:     auditRepo,
+		Sessions:      sessions,
+		Config:        cfg,
+	}
+}
+
+// RequestDeletion schedules the user's account for deletion, once the user
+// confirms it with their password, and signs out every other session. Until
+// the grace period is over the user can still sign in, and cancel, but their
+// API keys and OAuth grants stop working.
+func (s *accountDeletionService) RequestDeletion(ctx context.Context, userID int, password string) (*models.AccountDeletion, error) {
+	ctx, span := tracing.Start(ctx, "AccountDeletionService.RequestDeletion", attribute.Int("user.id", userID))
+	defer span.End()
+	user, err := activeUser(ctx, s.UserRepo, "RequestDeletion", userID)
+	if err != nil {
+		return nil, ifNotFound(err, apperr.NotFound("RequestDeletion: user does not exist"))
+	}
+	if err := checkPassword("RequestDeletion", user, password); err != nil {
+		return nil, err
+	}
+	if err := s.UserRepo.RequestDeletion(ctx, userID); err != nil {
+		return nil, ifConflict(err, apperr.Conflict("RequestDeletion: account is already scheduled for deletion"))
+	}
+	if _, err := s.Sessions.RevokeOtherSessions(ctx, userID, models.RevokedAccountDeleted); err != nil {
+		return nil, err
+	}
+
+	user, err = s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	deletion := &models.AccountDeletion{
+		RequestedAt: *user.DeletionRequestedAt,
+		PurgeAt:     user.DeletionRequestedAt.Add(s.Config.Grace),
+	}
+	logging.FromContext(ctx).Info("account deletion requested", "user_id", userID, "purge_at", deletion.PurgeAt)
+	return deletion, nil
+}
+
+// CancelDeletion takes back a scheduled deletion during its grace period.
+// Sessions signed out by the request stay signed out.
+func (s *accountDeletionService) CancelDeletion(ctx context.Context, userID int) error {
+	ctx, span := tracing.Start(ctx, "AccountDeletionService.CancelDeletion", attribute.Int("user.id", userID))
+	defer span.End()
+	if err := s.UserRepo.CancelDeletion(ctx, userID); err != nil {
+		return ifConflict(err, apperr.Conflict("CancelDeletion: account is not scheduled for deletion"))
+	}
+	logging.FromContext(ctx).Info("account deletion cancelled", "user_id", userID)
+	return nil
+}
+
+// PurgeDueAccounts anonymizes the accounts whose grace period is over and
+// returns how many it anonymized. An account that fails is logged and
+// retried on the next run.
+func (s *accountDeletionService) PurgeDueAccounts(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "AccountDeletionService.PurgeDueAccounts")
+	defer span.End()
+	userIDs, err := s.UserRepo.GetUsersDueForDeletion(ctx, time.Now().Add(-s.Config.Grace), purgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, userID := range userIDs {
+		done, err := s.purgeAccount(ctx, userID)
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to purge account", "user_id", userID, "error", err.Error())
+			continue
+		}
+		if done {
+			purged++
+		}
+	}
+	if purged > 0 {
+		// The accounts' sessions went with them; keep the gauge in step.
+		if _, err := s.Sessions.CountActiveSessions(ctx); err != nil {
+			logging.FromContext(ctx).Warn("failed to count active sessions", "error", err.Error())
+		}
+	}
+	return purged, nil
+}
+
+// purgeAccount hands each subreddit an account owns to the subreddit's most
+// active remaining contributor, then anonymizes the account. A subreddit
+// nobody else has posted or commented in keeps the anonymized owner, so it
+// is not lost, and an admin can reassign it later. Subreddits are handed on
+// first so that a failure leaves the account due, to be tried again. It
+// reports false if the deletion was cancelled since the account was listed.
+func (s *accountDeletionService) purgeAccount(ctx context.Context, userID int) (bool, error) {
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if user.DeletionRequestedAt == nil || user.DeletedAt != nil {
+		return false, nil
+	}
+
+	subredditIDs, err := s.SubredditRepo.GetSubredditIDsByCreator(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, subredditID := range subredditIDs {
+		ownerID, err := s.SubredditRepo.GetTopContributor(ctx, subredditID, userID)
+		if errors.Is(err, apperr.ErrNotFound) {
+			logging.FromContext(ctx).Info("subreddit kept by deleted owner", "subreddit_id", subredditID, "user_id", userID)
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if err := s.SubredditRepo.TransferOwnership(ctx, subredditID, ownerID); err != nil {
+			return false, err
+		}
+		details := fmt.Sprintf("user %d -> user %d (owner's account deleted)", userID, ownerID)
+		if err := s.audit(ctx, models.AuditSubredditTransferred, models.AuditTargetSubreddit, subredditID, details); err != nil {
+			return false, err
+		}
+	}
+
+	err = s.UserRepo.AnonymizeUser(ctx, userID)
+	if errors.Is(err, apperr.ErrConflict) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := s.audit(ctx, models.AuditUserDeleted, models.AuditTargetUser, userID, ""); err != nil {
+		return false, err
+	}
+	logging.FromContext(ctx).Info("account anonymized", "user_id", userID)
+	return true, nil
+}
+
+// audit records what the purge did in the audit log, with actor_id 0.
+func (s *accountDeletionService) audit(ctx context.Context, action, targetType string, targetID int, details string) error {
+	return s.AuditRepo.CreateAuditEntry(ctx, &models.AuditEntry{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    details,
+	})
+}
+
+// activeUser retrieves a user about to act or be acted on. Anonymized
+// accounts count as missing, as they did when deleting an account removed
+// it: their row only stays to keep their old content attributed.
+func activeUser(ctx context.Context, userRepo repository.UserRepository, op string, userID int) (*models.User, error) {
+	user, err := userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt != nil {
+		return nil, apperr.NotFound(op + ": user was deleted")
+	}
+	return user, nil
+}
@@ -0,0 +1,126 @@
+// File: internal/service/account_deletion_service_test.go
+
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository/memory"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestPurgeAnonymizes deletes an account that owns two subreddits: one with
+// another contributor, who takes it over, and one without, which the
+// anonymized account keeps.
+func TestPurgeAnonymizes(t *testing.T) {
+	ctx := context.Background()
+	repos := memory.NewRepositories()
+	sessions := NewSessionService(repos.Sessions, SessionConfig{AccessTTL: time.Hour, RefreshTTL: 24 * time.Hour})
+	deletions := NewAccountDeletionService(repos.Users, repos.Subreddits, repos.Audit, sessions, AccountDeletionConfig{Grace: 0})
+
+	hash, err := hashPassword("secret123", bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hashPassword: %v", err)
+	}
+	users := map[string]*models.User{}
+	for _, name := range []string{"ann", "bob"} {
+		user := &models.User{Username: name, Email: name + "@example.com", Password: hash}
+		if err := repos.Users.CreateUser(ctx, user); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		users[name] = user
+	}
+	ann, bob := users["ann"], users["bob"]
+
+	subreddits := map[string]*models.Subreddit{}
+	for _, name := range []string{"golang", "solo"} {
+		sub := &models.Subreddit{Name: name, Description: "d", CreatedBy: ann.ID}
+		if err := repos.Subreddits.CreateSubreddit(ctx, sub); err != nil {
+			t.Fatalf("CreateSubreddit: %v", err)
+		}
+		subreddits[name] = sub
+	}
+	posts := []*models.Post{
+		{Title: "t", Content: "by ann", AuthorID: ann.ID, SubredditID: subreddits["solo"].ID},
+		{Title: "t", Content: "by bob", AuthorID: bob.ID, SubredditID: subreddits["golang"].ID},
+	}
+	for _, post := range posts {
+		if err := repos.Posts.CreatePost(ctx, post); err != nil {
+			t.Fatalf("CreatePost: %v", err)
+		}
+	}
+
+	for _, password := range []string{"", "wrong"} {
+		_, err := deletions.RequestDeletion(ctx, ann.ID, password)
+		if !errors.Is(err, apperr.ErrValidation) {
+			t.Errorf("RequestDeletion with password %q: error = %v, want a validation error", password, err)
+		}
+	}
+	if _, err := deletions.RequestDeletion(ctx, ann.ID, "secret123"); err != nil {
+		t.Fatalf("RequestDeletion: %v", err)
+	}
+	purged, err := deletions.PurgeDueAccounts(ctx)
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeDueAccounts = %d, %v; want 1", purged, err)
+	}
+	if purged, err := deletions.PurgeDueAccounts(ctx); err != nil || purged != 0 {
+		t.Errorf("second PurgeDueAccounts = %d, %v; want 0", purged, err)
+	}
+
+	user, err := repos.Users.GetUserByID(ctx, ann.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	username, email := models.DeletedAccount(ann.ID)
+	if user.Username != username || user.Email != email || user.Password != "" || user.DeletedAt == nil {
+		t.Errorf("purged user = %+v, want %s <%s> with no password", user, username, email)
+	}
+	if err := comparePasswords(user.Password, "secret123"); err == nil {
+		t.Error("the old password still matches")
+	}
+
+	owners := []struct {
+		subreddit string
+		want      int
+	}{
+		{"golang", bob.ID},
+		{"solo", ann.ID},
+	}
+	for _, tt := range owners {
+		sub, err := repos.Subreddits.GetSubredditByID(ctx, subreddits[tt.subreddit].ID)
+		if err != nil {
+			t.Fatalf("GetSubredditByID: %v", err)
+		}
+		if sub.CreatedBy != tt.want {
+			t.Errorf("%s is owned by %d, want %d", tt.subreddit, sub.CreatedBy, tt.want)
+		}
+	}
+
+	post, err := repos.Posts.GetPostByID(ctx, posts[0].ID)
+	if err != nil || post.AuthorID != ann.ID || post.Content != "by ann" {
+		t.Errorf("ann's post = %+v, %v; want it kept", post, err)
+	}
+
+	audits := []struct {
+		action   string
+		targetID int
+	}{
+		{models.AuditUserDeleted, ann.ID},
+		{models.AuditSubredditTransferred, subreddits["golang"].ID},
+	}
+	for _, tt := range audits {
+		entries, err := repos.Audit.GetAuditEntries(ctx, models.AuditFilter{Action: tt.action}, 10, 0)
+		if err != nil {
+			t.Fatalf("GetAuditEntries: %v", err)
+		}
+		if len(entries) != 1 || entries[0].TargetID != tt.targetID {
+			t.Errorf("%s entries = %+v, want one for %d", tt.action, entries, tt.targetID)
+		}
+	}
+}
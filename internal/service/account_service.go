@@ -0,0 +1,246 @@
+// File: internal/service/account_service.go
+
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+	"redditclone/pkg/logging"
+	"redditclone/pkg/mail"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// AccountService defines the email verification and password reset flows.
+type AccountService interface {
+	SendVerificationEmail(ctx context.Context, userID int) error
+	VerifyEmail(ctx context.Context, token string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+}
+
+// AccountConfig configures the links and tokens mailed to users.
+type AccountConfig struct {
+	BaseURL         string        // Public URL the links in emails point at, e.g. https://example.com.
+	VerificationTTL time.Duration // How long an email verification link stays valid.
+	ResetTTL        time.Duration // How long a password reset link stays valid.
+	BcryptCost      int           // Cost used to hash reset passwords.
+}
+
+type accountService struct {
+	UserRepo  repository.UserRepository
+	TokenRepo repository.UserTokenRepository
+	Sessions  SessionService
+	Mailer    mail.Mailer
+	Config    AccountConfig
+}
+
+// NewAccountService creates a new AccountService that delivers its emails
+// through mailer and signs users out through sessions when their password is
+// reset.
+func NewAccountService(userRepo repository.UserRepository, tokenRepo repository.UserTokenRepository, sessions SessionService, mailer mail.Mailer, cfg AccountConfig) AccountService {
+	return &accountService{
+		UserRepo:  userRepo,
+		TokenRepo: tokenRepo,
+		Sessions:  sessions,
+		Mailer:    mailer,
+		Config:    cfg,
+	}
+}
+
+// emailData fills in the email templates.
+type emailData struct {
+	Username  string
+	Email     string
+	Link      string // Link carrying the token; empty for notifications.
+	ExpiresIn string // How long the link stays valid, e.g. "24 hours".
+}
+
+// SendVerificationEmail mails a user a link confirming their current email
+// address. Links sent earlier stop working.
+func (s *accountService) SendVerificationEmail(ctx context.Context, userID int) error {
+	ctx, span := tracing.Start(ctx, "AccountService.SendVerificationEmail", attribute.Int("user.id", userID))
+	defer span.End()
+	user, err := activeUser(ctx, s.UserRepo, "SendVerificationEmail", userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return apperr.Conflict("SendVerificationEmail: email is already verified")
+	}
+	return s.sendToken(ctx, user, models.TokenVerifyEmail, mail.TemplateVerifyEmail, "/verify-email", s.Config.VerificationTTL)
+}
+
+// VerifyEmail consumes a verification token and marks the address it was sent
+// to as verified.
+func (s *accountService) VerifyEmail(ctx context.Context, token string) error {
+	ctx, span := tracing.Start(ctx, "AccountService.VerifyEmail")
+	defer span.End()
+	if token == "" {
+		return apperr.Validation("VerifyEmail: token is required", map[string]string{"token": "is required"})
+	}
+	consumed, err := s.TokenRepo.ConsumeUserToken(ctx, models.TokenVerifyEmail, hashToken(token), time.Now())
+	if err != nil {
+		return ifNotFound(err, apperr.BadRequest("VerifyEmail: verification link is invalid or has expired"))
+	}
+	if err := s.UserRepo.MarkEmailVerified(ctx, consumed.UserID, consumed.Email); err != nil {
+		return ifNotFound(err, apperr.BadRequest("VerifyEmail: verification link is for an old email address"))
+	}
+	logging.FromContext(ctx).Info("email verified", "user_id", consumed.UserID)
+	return nil
+}
+
+// RequestPasswordReset mails a reset link to the account with the given email
+// address. It succeeds whether or not such an account exists, so callers
+// cannot use it to find out which addresses are registered.
+func (s *accountService) RequestPasswordReset(ctx context.Context, email string) error {
+	ctx, span := tracing.Start(ctx, "AccountService.RequestPasswordReset")
+	defer span.End()
+	if email == "" {
+		return apperr.Validation("RequestPasswordReset: email is required", map[string]string{"email": "is required"})
+	}
+	user, err := s.UserRepo.GetUserByEmail(ctx, email)
+	if errors.Is(err, apperr.ErrNotFound) {
+		logging.FromContext(ctx).Info("password reset requested for unknown email")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Report delivery failures only in the log, for the same reason.
+	if err := s.sendToken(ctx, user, models.TokenResetPassword, mail.TemplateResetPassword, "/reset-password", s.Config.ResetTTL); err != nil {
+		logging.FromContext(ctx).Error("failed to send password reset email", "user_id", user.ID, "error", err.Error())
+	}
+	return nil
+}
+
+// ResetPassword consumes a reset token and sets the user's new password.
+// Other outstanding reset links stop working, every session is signed out,
+// and the user is told by email.
+func (s *accountService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	ctx, span := tracing.Start(ctx, "AccountService.ResetPassword")
+	defer span.End()
+	fields := map[string]string{}
+	if token == "" {
+		fields["token"] = "is required"
+	}
+	if newPassword == "" {
+		fields["password"] = "is required"
+	}
+	if len(fields) > 0 {
+		return apperr.Validation("ResetPassword: token and password are required", fields)
+	}
+
+	consumed, err := s.TokenRepo.ConsumeUserToken(ctx, models.TokenResetPassword, hashToken(token), time.Now())
+	if err != nil {
+		return ifNotFound(err, apperr.BadRequest("ResetPassword: reset link is invalid or has expired"))
+	}
+	user, err := activeUser(ctx, s.UserRepo, "ResetPassword", consumed.UserID)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := hashPassword(newPassword, s.Config.BcryptCost)
+	if err != nil {
+		return err
+	}
+	user.Password = hashedPassword
+	if err := s.UserRepo.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+	if err := s.TokenRepo.DeleteUserTokens(ctx, user.ID, models.TokenResetPassword); err != nil {
+		return err
+	}
+	if _, err := s.Sessions.RevokeOtherSessions(ctx, user.ID, models.RevokedPasswordReset); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("password reset", "user_id", user.ID)
+
+	// The password has changed either way, so a failed notice is only logged
+	msg, err := mail.Compose(mail.TemplatePasswordChanged, user.Email, emailData{Username: user.Username, Email: user.Email})
+	if err == nil {
+		err = s.Mailer.Send(ctx, msg)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to send password change notice", "user_id", user.ID, "error", err.Error())
+	}
+	return nil
+}
+
+// sendToken issues a new token of the given purpose to user, replacing any
+// outstanding ones, and mails it as a link to path on the base URL.
+func (s *accountService) sendToken(ctx context.Context, user *models.User, purpose, template, path string, ttl time.Duration) error {
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+	if err := s.TokenRepo.DeleteUserTokens(ctx, user.ID, purpose); err != nil {
+		return err
+	}
+	err = s.TokenRepo.CreateUserToken(ctx, &models.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return err
+	}
+
+	msg, err := mail.Compose(template, user.Email, emailData{
+		Username:  user.Username,
+		Email:     user.Email,
+		Link:      strings.TrimRight(s.Config.BaseURL, "/") + path + "?token=" + url.QueryEscape(token),
+		ExpiresIn: describeDuration(ttl),
+	})
+	if err != nil {
+		return err
+	}
+	return s.Mailer.Send(ctx, msg)
+}
+
+// describeDuration renders d for people, in the largest whole unit that
+// fits, e.g. "24 hours" or "90 minutes".
+func describeDuration(d time.Duration) string {
+	n, unit := int64(d/time.Second), "second"
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		n, unit = int64(d/time.Hour), "hour"
+	case d >= time.Minute && d%time.Minute == 0:
+		n, unit = int64(d/time.Minute), "minute"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", n, unit)
+}
+
+// newToken returns a random 256-bit token, URL-safe base64 encoded.
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hex SHA-256 hash under which a token is stored. The
+// tokens are random, so an unsalted fast hash is enough to keep a database
+// leak from exposing usable links.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,146 @@
+// File: internal/service/activity_service.go
+
+package service
+
+import (
+	"context"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ActivityService defines the methods for listing what a user has submitted and voted on.
+type ActivityService interface {
+	GetUserPosts(ctx context.Context, userID, viewerID int, sort string, limit, offset int) ([]*models.Post, error)
+	GetUserComments(ctx context.Context, userID, viewerID int, sort string, limit, offset int) ([]*models.Comment, error)
+	GetUserOverview(ctx context.Context, userID, viewerID int, sort string, limit, offset int) ([]*models.ActivityItem, error)
+	GetVotedItems(ctx context.Context, userID int, voteType string, limit, offset int) ([]*models.ActivityItem, error)
+}
+
+type activityService struct {
+	UserRepo    repository.UserRepository
+	PostRepo    repository.PostRepository
+	CommentRepo repository.CommentRepository
+	VoteRepo    repository.VoteRepository
+}
+
+// NewActivityService creates a new ActivityService.
+func NewActivityService(userRepo repository.UserRepository, postRepo repository.PostRepository, commentRepo repository.CommentRepository, voteRepo repository.VoteRepository) ActivityService {
+	return &activityService{
+		UserRepo:    userRepo,
+		PostRepo:    postRepo,
+		CommentRepo: commentRepo,
+		VoteRepo:    voteRepo,
+	}
+}
+
+// GetUserPosts retrieves the posts submitted by a user with pagination.
+func (s *activityService) GetUserPosts(ctx context.Context, userID, viewerID int, sort string, limit, offset int) ([]*models.Post, error) {
+	ctx, span := tracing.Start(ctx, "ActivityService.GetUserPosts", attribute.Int("user.id", userID), attribute.Int("viewer.id", viewerID), attribute.String("sort", sort), attribute.Int("page.limit", limit), attribute.Int("page.offset", offset))
+	defer span.End()
+	sort, err := s.validate(ctx, "GetUserPosts", userID, sort)
+	if err != nil {
+		return nil, err
+	}
+
+	posts, err := s.PostRepo.GetPostsByAuthor(ctx, userID, sort, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if err := annotatePostVotes(ctx, s.VoteRepo, viewerID, posts); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// GetUserComments retrieves the comments written by a user with pagination.
+func (s *activityService) GetUserComments(ctx context.Context, userID, viewerID int, sort string, limit, offset int) ([]*models.Comment, error) {
+	ctx, span := tracing.Start(ctx, "ActivityService.GetUserComments", attribute.Int("user.id", userID), attribute.Int("viewer.id", viewerID), attribute.String("sort", sort), attribute.Int("page.limit", limit), attribute.Int("page.offset", offset))
+	defer span.End()
+	sort, err := s.validate(ctx, "GetUserComments", userID, sort)
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := s.CommentRepo.GetCommentsByAuthor(ctx, userID, sort, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if err := annotateCommentVotes(ctx, s.VoteRepo, viewerID, comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// GetUserOverview retrieves a user's posts and comments together with pagination.
+func (s *activityService) GetUserOverview(ctx context.Context, userID, viewerID int, sort string, limit, offset int) ([]*models.ActivityItem, error) {
+	ctx, span := tracing.Start(ctx, "ActivityService.GetUserOverview", attribute.Int("user.id", userID), attribute.Int("viewer.id", viewerID), attribute.String("sort", sort), attribute.Int("page.limit", limit), attribute.Int("page.offset", offset))
+	defer span.End()
+	sort, err := s.validate(ctx, "GetUserOverview", userID, sort)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.UserRepo.GetUserOverview(ctx, userID, sort, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if err := annotateActivityVotes(ctx, s.VoteRepo, viewerID, items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetVotedItems retrieves the posts and comments a user upvoted or downvoted with pagination.
+func (s *activityService) GetVotedItems(ctx context.Context, userID int, voteType string, limit, offset int) ([]*models.ActivityItem, error) {
+	ctx, span := tracing.Start(ctx, "ActivityService.GetVotedItems", attribute.Int("user.id", userID), attribute.String("vote.type", voteType), attribute.Int("page.limit", limit), attribute.Int("page.offset", offset))
+	defer span.End()
+	// Validate input
+	if voteType != models.Upvote && voteType != models.Downvote {
+		return nil, apperr.Validation("GetVotedItems: invalid vote type", map[string]string{"vote_type": "must be upvote or downvote"})
+	}
+
+	// Check if user exists
+	_, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, ifNotFound(err, apperr.NotFound("GetVotedItems: user does not exist"))
+	}
+
+	items, err := s.VoteRepo.GetVotedItemsByUser(ctx, userID, voteType, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every item in this listing carries the owner's vote by construction
+	for _, item := range items {
+		if item.Post != nil {
+			item.Post.MyVote = voteType
+		}
+		if item.Comment != nil {
+			item.Comment.MyVote = voteType
+		}
+	}
+	return items, nil
+}
+
+// validate checks that the user exists and that the sort order is supported.
+// An empty sort order defaults to newest first. Errors are prefixed with op.
+func (s *activityService) validate(ctx context.Context, op string, userID int, sort string) (string, error) {
+	switch sort {
+	case "":
+		sort = repository.SortNew
+	case repository.SortNew, repository.SortTop, repository.SortControversial:
+	default:
+		return "", apperr.Validation(op+": invalid sort order: must be new, top or controversial", map[string]string{"sort": "must be new, top or controversial"})
+	}
+
+	_, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", ifNotFound(err, apperr.NotFound(op+": user does not exist"))
+	}
+	return sort, nil
+}
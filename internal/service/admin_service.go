@@ -0,0 +1,379 @@
+// File: internal/service/admin_service.go
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+	"redditclone/pkg/logging"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxAdminReason is the longest reason an admin may give, in bytes.
+const maxAdminReason = 500
+
+// AdminService defines site administration: roles, site-wide suspensions,
+// deleting, quarantining and reassigning subreddits, impersonating users for
+// support, the vote-integrity report, and the audit log every one of these
+// actions is recorded in.
+type AdminService interface {
+	CheckAdmin(ctx context.Context, userID int) error
+	SetRole(ctx context.Context, admin models.Actor, userID int, role string) (*models.User, error)
+	SuspendUser(ctx context.Context, admin models.Actor, userID int, reason string) (*models.User, error)
+	UnsuspendUser(ctx context.Context, admin models.Actor, userID int) (*models.User, error)
+	DeleteSubreddit(ctx context.Context, admin models.Actor, subredditID int, reason string) error
+	QuarantineSubreddit(ctx context.Context, admin models.Actor, subredditID int, reason string) (*models.Subreddit, error)
+	UnquarantineSubreddit(ctx context.Context, admin models.Actor, subredditID int) (*models.Subreddit, error)
+	TransferSubreddit(ctx context.Context, admin models.Actor, subredditID, ownerID int) (*models.Subreddit, error)
+	Impersonate(ctx context.Context, admin models.Actor, userID int, reason string, client models.ClientInfo) (*models.SessionTokens, error)
+	RecordImpersonatedRequest(ctx context.Context, session *models.Session, ip, request string) error
+	GetAuditLog(ctx context.Context, filter models.AuditFilter, limit, offset int) ([]*models.AuditEntry, error)
+	GetVoteReport(ctx context.Context, admin models.Actor, limit, offset int) (*models.VoteIntegrityReport, error)
+}
+
+type adminService struct {
+	UserRepo      repository.UserRepository
+	SubredditRepo repository.SubredditRepository
+	AuditRepo     repository.AuditRepository
+	Sessions      SessionService
+	VoteIntegrity VoteIntegrityService
+}
+
+// NewAdminService creates a new AdminService.
+func NewAdminService(userRepo repository.UserRepository, subredditRepo repository.SubredditRepository, auditRepo repository.AuditRepository, sessions SessionService, voteIntegrity VoteIntegrityService) AdminService {
+	return &adminService{
+		UserRepo:      userRepo,
+		SubredditRepo: subredditRepo,
+		AuditRepo:     auditRepo,
+		Sessions:      sessions,
+		VoteIntegrity: voteIntegrity,
+	}
+}
+
+// CheckAdmin returns apperr.Forbidden unless the user is a site admin.
+func (s *adminService) CheckAdmin(ctx context.Context, userID int) error {
+	ctx, span := tracing.Start(ctx, "AdminService.CheckAdmin", attribute.Int("user.id", userID))
+	defer span.End()
+	forbidden := apperr.Forbidden("CheckAdmin: only site admins can use this endpoint")
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return ifNotFound(err, forbidden)
+	}
+	if !user.IsAdmin() || user.SuspendedAt != nil {
+		return forbidden
+	}
+	return nil
+}
+
+// SetRole makes a user an admin or an ordinary user. Admins cannot change
+// their own role, so the site cannot be left without one by accident.
+func (s *adminService) SetRole(ctx context.Context, admin models.Actor, userID int, role string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.SetRole", attribute.Int("admin.id", admin.UserID), attribute.Int("user.id", userID))
+	defer span.End()
+	if role != models.RoleUser && role != models.RoleAdmin {
+		return nil, apperr.Validation("SetRole: invalid role", map[string]string{"role": "must be user or admin"})
+	}
+	if userID == admin.UserID {
+		return nil, apperr.Forbidden("SetRole: admins cannot change their own role")
+	}
+	user, err := activeUser(ctx, s.UserRepo, "SetRole", userID)
+	if err != nil {
+		return nil, ifNotFound(err, apperr.NotFound("SetRole: user does not exist"))
+	}
+	if user.Role == role {
+		return sanitizeUser(user), nil
+	}
+
+	details := fmt.Sprintf("%s -> %s", user.Role, role)
+	if err := s.audit(ctx, admin, models.AuditRoleChanged, models.AuditTargetUser, userID, details); err != nil {
+		return nil, err
+	}
+	if err := s.UserRepo.SetRole(ctx, userID, role); err != nil {
+		return nil, err
+	}
+	return s.getUser(ctx, userID)
+}
+
+// SuspendUser suspends a user site-wide and signs out all of their sessions.
+// While suspended they cannot sign in, use their API keys or OAuth grants, or
+// post, comment, vote, message or create subreddits.
+func (s *adminService) SuspendUser(ctx context.Context, admin models.Actor, userID int, reason string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.SuspendUser", attribute.Int("admin.id", admin.UserID), attribute.Int("user.id", userID))
+	defer span.End()
+	reason, err := checkReason("SuspendUser", reason)
+	if err != nil {
+		return nil, err
+	}
+	if userID == admin.UserID {
+		return nil, apperr.Forbidden("SuspendUser: admins cannot suspend themselves")
+	}
+	user, err := activeUser(ctx, s.UserRepo, "SuspendUser", userID)
+	if err != nil {
+		return nil, ifNotFound(err, apperr.NotFound("SuspendUser: user does not exist"))
+	}
+	if user.IsAdmin() {
+		return nil, apperr.Forbidden("SuspendUser: remove the user's admin role first")
+	}
+	errSuspended := apperr.Conflict("SuspendUser: user is already suspended")
+	if user.SuspendedAt != nil {
+		return nil, errSuspended
+	}
+
+	if err := s.audit(ctx, admin, models.AuditUserSuspended, models.AuditTargetUser, userID, reason); err != nil {
+		return nil, err
+	}
+	if err := s.UserRepo.Suspend(ctx, userID, reason); err != nil {
+		return nil, ifConflict(err, errSuspended)
+	}
+	if _, err := s.Sessions.RevokeOtherSessions(ctx, userID, models.RevokedSuspended); err != nil {
+		return nil, err
+	}
+	return s.getUser(ctx, userID)
+}
+
+// UnsuspendUser lifts a suspension. Sessions stay signed out, but API keys
+// and OAuth grants work again.
+func (s *adminService) UnsuspendUser(ctx context.Context, admin models.Actor, userID int) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.UnsuspendUser", attribute.Int("admin.id", admin.UserID), attribute.Int("user.id", userID))
+	defer span.End()
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, ifNotFound(err, apperr.NotFound("UnsuspendUser: user does not exist"))
+	}
+	errNotSuspended := apperr.Conflict("UnsuspendUser: user is not suspended")
+	if user.SuspendedAt == nil {
+		return nil, errNotSuspended
+	}
+
+	if err := s.audit(ctx, admin, models.AuditUserUnsuspended, models.AuditTargetUser, userID, ""); err != nil {
+		return nil, err
+	}
+	if err := s.UserRepo.Unsuspend(ctx, userID); err != nil {
+		return nil, ifConflict(err, errNotSuspended)
+	}
+	return s.getUser(ctx, userID)
+}
+
+// DeleteSubreddit deletes a subreddit with its posts and comments. The audit
+// entry keeps its name, since the subreddit itself is gone.
+func (s *adminService) DeleteSubreddit(ctx context.Context, admin models.Actor, subredditID int, reason string) error {
+	ctx, span := tracing.Start(ctx, "AdminService.DeleteSubreddit", attribute.Int("admin.id", admin.UserID), attribute.Int("subreddit.id", subredditID))
+	defer span.End()
+	reason, err := checkReason("DeleteSubreddit", reason)
+	if err != nil {
+		return err
+	}
+	subreddit, err := s.SubredditRepo.GetSubredditByID(ctx, subredditID)
+	if err != nil {
+		return ifNotFound(err, apperr.NotFound("DeleteSubreddit: subreddit does not exist"))
+	}
+	details := fmt.Sprintf("r/%s: %s", subreddit.Name, reason)
+	if err := s.audit(ctx, admin, models.AuditSubredditDeleted, models.AuditTargetSubreddit, subredditID, details); err != nil {
+		return err
+	}
+	return s.SubredditRepo.DeleteSubreddit(ctx, subredditID)
+}
+
+// QuarantineSubreddit quarantines a subreddit: it stays reachable directly
+// but leaves the feed and takes no new posts or comments. Quarantining one
+// again updates the reason.
+func (s *adminService) QuarantineSubreddit(ctx context.Context, admin models.Actor, subredditID int, reason string) (*models.Subreddit, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.QuarantineSubreddit", attribute.Int("admin.id", admin.UserID), attribute.Int("subreddit.id", subredditID))
+	defer span.End()
+	reason, err := checkReason("QuarantineSubreddit", reason)
+	if err != nil {
+		return nil, err
+	}
+	errNotFound := apperr.NotFound("QuarantineSubreddit: subreddit does not exist")
+	if _, err := s.SubredditRepo.GetSubredditByID(ctx, subredditID); err != nil {
+		return nil, ifNotFound(err, errNotFound)
+	}
+	if err := s.audit(ctx, admin, models.AuditSubredditQuarantined, models.AuditTargetSubreddit, subredditID, reason); err != nil {
+		return nil, err
+	}
+	if err := s.SubredditRepo.Quarantine(ctx, subredditID, reason); err != nil {
+		return nil, ifNotFound(err, errNotFound)
+	}
+	return s.SubredditRepo.GetSubredditByID(ctx, subredditID)
+}
+
+// UnquarantineSubreddit lifts a subreddit's quarantine.
+func (s *adminService) UnquarantineSubreddit(ctx context.Context, admin models.Actor, subredditID int) (*models.Subreddit, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.UnquarantineSubreddit", attribute.Int("admin.id", admin.UserID), attribute.Int("subreddit.id", subredditID))
+	defer span.End()
+	subreddit, err := s.SubredditRepo.GetSubredditByID(ctx, subredditID)
+	if err != nil {
+		return nil, ifNotFound(err, apperr.NotFound("UnquarantineSubreddit: subreddit does not exist"))
+	}
+	if subreddit.QuarantinedAt == nil {
+		return nil, apperr.Conflict("UnquarantineSubreddit: subreddit is not quarantined")
+	}
+	if err := s.audit(ctx, admin, models.AuditSubredditReleased, models.AuditTargetSubreddit, subredditID, ""); err != nil {
+		return nil, err
+	}
+	if err := s.SubredditRepo.Unquarantine(ctx, subredditID); err != nil {
+		return nil, err
+	}
+	return s.SubredditRepo.GetSubredditByID(ctx, subredditID)
+}
+
+// TransferSubreddit makes another user the owner of a subreddit, for one
+// whose creator has left or been suspended.
+func (s *adminService) TransferSubreddit(ctx context.Context, admin models.Actor, subredditID, ownerID int) (*models.Subreddit, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.TransferSubreddit", attribute.Int("admin.id", admin.UserID), attribute.Int("subreddit.id", subredditID), attribute.Int("owner.id", ownerID))
+	defer span.End()
+	subreddit, err := s.SubredditRepo.GetSubredditByID(ctx, subredditID)
+	if err != nil {
+		return nil, ifNotFound(err, apperr.NotFound("TransferSubreddit: subreddit does not exist"))
+	}
+	owner, err := activeUser(ctx, s.UserRepo, "TransferSubreddit", ownerID)
+	if err != nil {
+		return nil, ifNotFound(err, apperr.Validation("TransferSubreddit: new owner does not exist", map[string]string{"owner_id": "does not exist"}))
+	}
+	if owner.SuspendedAt != nil {
+		return nil, apperr.Validation("TransferSubreddit: new owner is suspended", map[string]string{"owner_id": "is suspended"})
+	}
+	if owner.DeletionRequestedAt != nil {
+		return nil, apperr.Validation("TransferSubreddit: new owner is deleting their account", map[string]string{"owner_id": "is being deleted"})
+	}
+	if subreddit.CreatedBy == ownerID {
+		return subreddit, nil
+	}
+
+	details := fmt.Sprintf("user %d -> user %d", subreddit.CreatedBy, ownerID)
+	if err := s.audit(ctx, admin, models.AuditSubredditTransferred, models.AuditTargetSubreddit, subredditID, details); err != nil {
+		return nil, err
+	}
+	if err := s.SubredditRepo.TransferOwnership(ctx, subredditID, ownerID); err != nil {
+		return nil, err
+	}
+	return s.SubredditRepo.GetSubredditByID(ctx, subredditID)
+}
+
+// Impersonate opens a session in which the admin acts as a user, to see what
+// they see when helping with a support request. The session cannot be
+// refreshed and lasts as long as one access token, it shows up in the user's
+// session list, and every request made with it is audited. Other admins and
+// suspended users cannot be impersonated.
+func (s *adminService) Impersonate(ctx context.Context, admin models.Actor, userID int, reason string, client models.ClientInfo) (*models.SessionTokens, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.Impersonate", attribute.Int("admin.id", admin.UserID), attribute.Int("user.id", userID))
+	defer span.End()
+	reason, err := checkReason("Impersonate", reason)
+	if err != nil {
+		return nil, err
+	}
+	user, err := activeUser(ctx, s.UserRepo, "Impersonate", userID)
+	if err != nil {
+		return nil, ifNotFound(err, apperr.NotFound("Impersonate: user does not exist"))
+	}
+	if user.IsAdmin() {
+		return nil, apperr.Forbidden("Impersonate: admins cannot be impersonated")
+	}
+	if user.SuspendedAt != nil {
+		return nil, apperr.Forbidden("Impersonate: suspended users cannot be impersonated")
+	}
+
+	// Record the impersonation before any token exists
+	if err := s.audit(ctx, admin, models.AuditImpersonationStarted, models.AuditTargetUser, userID, reason); err != nil {
+		return nil, err
+	}
+	tokens, err := s.Sessions.CreateImpersonationSession(ctx, userID, admin.UserID, client)
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Warn("admin impersonating user", "admin_id", admin.UserID, "user_id", userID, "session_id", tokens.SessionID)
+	return tokens, nil
+}
+
+// RecordImpersonatedRequest audits a request made in an impersonation
+// session, described by request, such as "DELETE /posts/7 -> 200".
+func (s *adminService) RecordImpersonatedRequest(ctx context.Context, session *models.Session, ip, request string) error {
+	if session.ImpersonatorID == nil {
+		return nil
+	}
+	admin := models.Actor{UserID: *session.ImpersonatorID, IP: ip}
+	return s.audit(ctx, admin, models.AuditImpersonatedRequest, models.AuditTargetUser, session.UserID, request)
+}
+
+// GetAuditLog retrieves audit log entries matching filter, newest first,
+// with pagination.
+func (s *adminService) GetAuditLog(ctx context.Context, filter models.AuditFilter, limit, offset int) ([]*models.AuditEntry, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.GetAuditLog", attribute.Int("page.limit", limit), attribute.Int("page.offset", offset))
+	defer span.End()
+	if filter.TargetID != 0 && filter.TargetType == "" {
+		return nil, apperr.Validation("GetAuditLog: target_id needs target_type", map[string]string{"target_type": "is required with target_id"})
+	}
+	return s.AuditRepo.GetAuditEntries(ctx, filter, limit, offset)
+}
+
+// GetVoteReport summarizes flagged votes, with the list of flags paginated.
+// The report names the accounts suspected of manipulating votes, so each
+// viewing is audited.
+func (s *adminService) GetVoteReport(ctx context.Context, admin models.Actor, limit, offset int) (*models.VoteIntegrityReport, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.GetVoteReport", attribute.Int("admin.id", admin.UserID))
+	defer span.End()
+	report, err := s.VoteIntegrity.GetReport(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	details := fmt.Sprintf("limit=%d offset=%d", limit, offset)
+	if err := s.audit(ctx, admin, models.AuditVoteReportViewed, models.AuditTargetVotes, 0, details); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// audit records an admin action in the audit log. Actions are recorded before
+// they are applied, once every check has passed, so that no change is made
+// without an entry; if the change then fails, the entry records the attempt.
+func (s *adminService) audit(ctx context.Context, admin models.Actor, action, targetType string, targetID int, details string) error {
+	entry := &models.AuditEntry{
+		ActorID:    admin.UserID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    details,
+		IP:         admin.IP,
+	}
+	if err := s.AuditRepo.CreateAuditEntry(ctx, entry); err != nil {
+		return err
+	}
+	if action != models.AuditImpersonatedRequest {
+		logging.FromContext(ctx).Info("admin action", "admin_id", admin.UserID, "action", action, "target_type", targetType, "target_id", targetID)
+	}
+	return nil
+}
+
+// getUser reloads a user after a change, without the password hash.
+func (s *adminService) getUser(ctx context.Context, userID int) (*models.User, error) {
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return sanitizeUser(user), nil
+}
+
+// sanitizeUser clears the password hash from a user about to be returned.
+func sanitizeUser(user *models.User) *models.User {
+	user.Password = ""
+	return user
+}
+
+// checkReason trims a reason an admin gave and checks that there is one.
+func checkReason(op, reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return "", apperr.Validation(op+": reason is required", map[string]string{"reason": "is required"})
+	}
+	if len(reason) > maxAdminReason {
+		return "", apperr.Validation(op+": reason is too long", map[string]string{"reason": fmt.Sprintf("must be at most %d bytes", maxAdminReason)})
+	}
+	return reason, nil
+}
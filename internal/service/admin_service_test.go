@@ -0,0 +1,111 @@
+// File: internal/service/admin_service_test.go
+
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+	"redditclone/internal/repository/memory"
+)
+
+// TestGetVoteReportAudited checks that every viewing of the vote report is
+// written to the audit log.
+func TestGetVoteReportAudited(t *testing.T) {
+	ctx := context.Background()
+	repos := memory.NewRepositories()
+	sessions := NewSessionService(repos.Sessions, SessionConfig{AccessTTL: time.Hour, RefreshTTL: 24 * time.Hour})
+	integrity := NewVoteIntegrityService(repos.VoteIntegrity, repos.Users, DefaultVoteIntegrityConfig())
+	admins := NewAdminService(repos.Users, repos.Subreddits, repos.Audit, sessions, integrity)
+	admin := models.Actor{UserID: 7, IP: "10.0.0.1"}
+
+	pages := []struct {
+		limit, offset int
+		wantDetails   string
+	}{
+		{10, 0, "limit=10 offset=0"},
+		{5, 20, "limit=5 offset=20"},
+	}
+	for _, page := range pages {
+		if _, err := admins.GetVoteReport(ctx, admin, page.limit, page.offset); err != nil {
+			t.Fatalf("GetVoteReport: %v", err)
+		}
+	}
+
+	entries, err := admins.GetAuditLog(ctx, models.AuditFilter{Action: models.AuditVoteReportViewed}, 10, 0)
+	if err != nil {
+		t.Fatalf("GetAuditLog: %v", err)
+	}
+	if len(entries) != len(pages) {
+		t.Fatalf("got %d audit entries, want %d", len(entries), len(pages))
+	}
+	for i, entry := range entries {
+		want := pages[len(pages)-1-i] // Newest first.
+		if entry.ActorID != admin.UserID || entry.IP != admin.IP || entry.TargetType != models.AuditTargetVotes ||
+			entry.TargetID != 0 || entry.Details != want.wantDetails {
+			t.Errorf("entry %d = %+v, want %q by admin %d", i, entry, want.wantDetails, admin.UserID)
+		}
+	}
+}
+
+// failingAudit is an audit repository whose writes always fail.
+type failingAudit struct {
+	repository.AuditRepository
+}
+
+var errAuditDown = errors.New("audit log unavailable")
+
+func (failingAudit) CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	return errAuditDown
+}
+
+// TestAuditFailureBlocksChange checks that an admin action is not applied
+// when its audit entry cannot be written.
+func TestAuditFailureBlocksChange(t *testing.T) {
+	ctx := context.Background()
+	repos := memory.NewRepositories()
+	sessions := NewSessionService(repos.Sessions, SessionConfig{AccessTTL: time.Hour, RefreshTTL: 24 * time.Hour})
+	admins := NewAdminService(repos.Users, repos.Subreddits, failingAudit{repos.Audit}, sessions, nil)
+	admin := models.Actor{UserID: 7, IP: "10.0.0.1"}
+
+	user := &models.User{Username: "ann", Email: "ann@example.com", Password: "x"}
+	if err := repos.Users.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	subreddit := &models.Subreddit{Name: "golang", CreatedBy: user.ID}
+	if err := repos.Subreddits.CreateSubreddit(ctx, subreddit); err != nil {
+		t.Fatalf("CreateSubreddit: %v", err)
+	}
+
+	if _, err := admins.SetRole(ctx, admin, user.ID, models.RoleAdmin); !errors.Is(err, errAuditDown) {
+		t.Errorf("SetRole error = %v, want %v", err, errAuditDown)
+	}
+	if _, err := admins.SuspendUser(ctx, admin, user.ID, "spam"); !errors.Is(err, errAuditDown) {
+		t.Errorf("SuspendUser error = %v, want %v", err, errAuditDown)
+	}
+	if _, err := admins.QuarantineSubreddit(ctx, admin, subreddit.ID, "spam"); !errors.Is(err, errAuditDown) {
+		t.Errorf("QuarantineSubreddit error = %v, want %v", err, errAuditDown)
+	}
+	if err := admins.DeleteSubreddit(ctx, admin, subreddit.ID, "spam"); !errors.Is(err, errAuditDown) {
+		t.Errorf("DeleteSubreddit error = %v, want %v", err, errAuditDown)
+	}
+
+	got, err := repos.Users.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if got.Role != models.RoleUser || got.SuspendedAt != nil {
+		t.Errorf("user role = %q, suspended at %v; want an unchanged user", got.Role, got.SuspendedAt)
+	}
+	sub, err := repos.Subreddits.GetSubredditByID(ctx, subreddit.ID)
+	if err != nil {
+		t.Fatalf("GetSubredditByID: %v", err)
+	}
+	if sub.QuarantinedAt != nil {
+		t.Errorf("subreddit quarantined at %v, want nil", sub.QuarantinedAt)
+	}
+}
@@ -0,0 +1,175 @@
+// File: internal/service/api_key_service.go
+
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+	"redditclone/pkg/logging"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// APIKeyPrefix starts every API key, which tells keys apart from session
+// access tokens and makes leaked keys easy to search for.
+const APIKeyPrefix = "rck_"
+
+const (
+	maxAPIKeysPerUser = 25  // Unrevoked keys a user may hold at once.
+	maxAPIKeyName     = 100 // Longest key name, in bytes.
+	apiKeyShownPrefix = 12  // Characters of the key kept to identify it.
+)
+
+// APIKeyService defines API keys: creating, listing and revoking them, and
+// resolving a presented key to its owner and scopes.
+type APIKeyService interface {
+	CreateKey(ctx context.Context, userID int, name string, scopes []string, expiresAt *time.Time) (*models.NewAPIKey, error)
+	ListKeys(ctx context.Context, userID int) ([]*models.APIKey, error)
+	RevokeKey(ctx context.Context, userID, keyID int) error
+	Authenticate(ctx context.Context, key string, client models.ClientInfo) (*models.APIKey, error)
+}
+
+type apiKeyService struct {
+	APIKeyRepo repository.APIKeyRepository
+}
+
+// NewAPIKeyService creates a new APIKeyService.
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository) APIKeyService {
+	return &apiKeyService{APIKeyRepo: apiKeyRepo}
+}
+
+type apiKeyKey struct{}
+
+// WithAPIKey returns a copy of ctx carrying the API key the request was
+// authenticated with.
+func WithAPIKey(ctx context.Context, key *models.APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyKey{}, key)
+}
+
+// APIKeyFromContext returns the API key stored by WithAPIKey, or nil for
+// requests that did not present one.
+func APIKeyFromContext(ctx context.Context) *models.APIKey {
+	key, _ := ctx.Value(apiKeyKey{}).(*models.APIKey)
+	return key
+}
+
+// IsAPIKey reports whether a bearer token is an API key rather than a
+// session access token.
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// CreateKey creates an API key with the given scopes for a user. The key
+// itself is returned only here; afterwards only its prefix is shown.
+func (s *apiKeyService) CreateKey(ctx context.Context, userID int, name string, scopes []string, expiresAt *time.Time) (*models.NewAPIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.CreateKey", attribute.Int("user.id", userID))
+	defer span.End()
+	name = strings.TrimSpace(name)
+	fields := map[string]string{}
+	if name == "" {
+		fields["name"] = "is required"
+	} else if len(name) > maxAPIKeyName {
+		fields["name"] = "must be at most 100 characters"
+	}
+	scopes, ok := normalizeScopes(scopes)
+	if !ok {
+		fields["scopes"] = "must list one or more of " + strings.Join(models.Scopes, ", ")
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		fields["expires_at"] = "must be in the future"
+	}
+	if len(fields) > 0 {
+		return nil, apperr.Validation("CreateKey: invalid API key", fields)
+	}
+
+	existing, err := s.APIKeyRepo.GetAPIKeysByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxAPIKeysPerUser {
+		return nil, apperr.Conflict("CreateKey: too many API keys; revoke one first")
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	secret := APIKeyPrefix + token
+	key := &models.APIKey{
+		UserID:  userID,
+		Name:    name,
+		Prefix:  secret[:apiKeyShownPrefix],
+		KeyHash: hashToken(secret),
+		Scopes:  scopes,
+	}
+	if expiresAt != nil {
+		t := expiresAt.UTC().Truncate(time.Second)
+		key.ExpiresAt = &t
+	}
+	if err := s.APIKeyRepo.CreateAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("API key created", "user_id", userID, "api_key_id", key.ID, "scopes", strings.Join(scopes, " "))
+	return &models.NewAPIKey{APIKey: *key, Key: secret}, nil
+}
+
+// ListKeys retrieves a user's unrevoked API keys, newest first.
+func (s *apiKeyService) ListKeys(ctx context.Context, userID int) ([]*models.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.ListKeys", attribute.Int("user.id", userID))
+	defer span.End()
+	return s.APIKeyRepo.GetAPIKeysByUser(ctx, userID)
+}
+
+// RevokeKey revokes one of a user's API keys; it stops working immediately.
+func (s *apiKeyService) RevokeKey(ctx context.Context, userID, keyID int) error {
+	ctx, span := tracing.Start(ctx, "APIKeyService.RevokeKey", attribute.Int("user.id", userID), attribute.Int("api_key.id", keyID))
+	defer span.End()
+	if err := s.APIKeyRepo.RevokeAPIKey(ctx, userID, keyID); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("API key revoked", "user_id", userID, "api_key_id", keyID)
+	return nil
+}
+
+// Authenticate resolves an API key to the key's record, updating its
+// last-used time. Unknown, expired and revoked keys are apperr.Unauthorized.
+func (s *apiKeyService) Authenticate(ctx context.Context, secret string, client models.ClientInfo) (*models.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.Authenticate")
+	defer span.End()
+	now := time.Now()
+	key, err := s.APIKeyRepo.GetAPIKeyByHash(ctx, hashToken(secret), now)
+	if err != nil {
+		return nil, ifNotFound(err, apperr.Unauthorized("Authenticate: API key is invalid, revoked or expired"))
+	}
+	span.SetAttributes(attribute.Int("api_key.id", key.ID), attribute.Int("user.id", key.UserID))
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= touchInterval || key.LastUsedIP != client.IP {
+		if err := s.APIKeyRepo.TouchAPIKey(ctx, key.ID, client.IP); err != nil {
+			logging.FromContext(ctx).Warn("failed to update API key last used", "api_key_id", key.ID, "error", err.Error())
+		}
+	}
+	return key, nil
+}
+
+// normalizeScopes checks that scopes is a non-empty list of known scopes and
+// returns it without duplicates, in the order of models.Scopes.
+func normalizeScopes(scopes []string) ([]string, bool) {
+	requested := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		requested[scope] = true
+	}
+	var normalized []string
+	for _, scope := range models.Scopes {
+		if requested[scope] {
+			normalized = append(normalized, scope)
+			delete(requested, scope)
+		}
+	}
+	return normalized, len(normalized) > 0 && len(requested) == 0
+}
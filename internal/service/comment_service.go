@@ -3,21 +3,25 @@
 package service
 
 import (
-	"errors"
+	"context"
 
+	"redditclone/internal/apperr"
 	"redditclone/internal/models"
 	"redditclone/internal/repository"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // CommentService defines the methods for comment-related business logic.
 type CommentService interface {
-	AddComment(comment *models.Comment) error
-	ReplyToComment(comment *models.Comment) error
-	GetCommentByID(id int) (*models.Comment, error)
-	GetCommentsByPost(postID int, limit, offset int) ([]*models.Comment, error)
-	GetReplies(parentID int, limit, offset int) ([]*models.Comment, error)
-	UpdateComment(comment *models.Comment) error
-	DeleteComment(id int) error
+	AddComment(ctx context.Context, comment *models.Comment) error
+	ReplyToComment(ctx context.Context, comment *models.Comment) error
+	GetCommentByID(ctx context.Context, id, viewerID int) (*models.Comment, error)
+	GetCommentsByPost(ctx context.Context, postID, viewerID int, page repository.Page) (*repository.Listing[*models.Comment], error)
+	GetReplies(ctx context.Context, parentID, viewerID int, page repository.Page) (*repository.Listing[*models.Comment], error)
+	UpdateComment(ctx context.Context, comment *models.Comment) error
+	DeleteComment(ctx context.Context, id int) error
 }
 
 type commentService struct {
@@ -25,43 +29,54 @@ type commentService struct {
 	PostRepo      repository.PostRepository
 	UserRepo      repository.UserRepository
 	SubredditRepo repository.SubredditRepository
+	BlockRepo     repository.BlockRepository
+	VoteRepo      repository.VoteRepository
 	// Add additional repositories if necessary
 }
 
 // NewCommentService creates a new CommentService.
-func NewCommentService(commentRepo repository.CommentRepository, postRepo repository.PostRepository, userRepo repository.UserRepository, subredditRepo repository.SubredditRepository) CommentService {
+func NewCommentService(commentRepo repository.CommentRepository, postRepo repository.PostRepository, userRepo repository.UserRepository, subredditRepo repository.SubredditRepository, blockRepo repository.BlockRepository, voteRepo repository.VoteRepository) CommentService {
 	return &commentService{
 		CommentRepo:   commentRepo,
 		PostRepo:      postRepo,
 		UserRepo:      userRepo,
 		SubredditRepo: subredditRepo,
+		BlockRepo:     blockRepo,
+		VoteRepo:      voteRepo,
 	}
 }
 
 // AddComment adds a new comment to a post.
-func (s *commentService) AddComment(comment *models.Comment) error {
+func (s *commentService) AddComment(ctx context.Context, comment *models.Comment) error {
+	ctx, span := tracing.Start(ctx, "CommentService.AddComment", attribute.Int("author.id", comment.AuthorID), attribute.Int("post.id", comment.PostID))
+	defer span.End()
 	// Validate input
 	if comment.Content == "" {
-		return errors.New("AddComment: content is required")
+		return apperr.Validation("AddComment: content is required", map[string]string{"content": "is required"})
 	}
 
 	// Check if author exists
-	_, err := s.UserRepo.GetUserByID(comment.AuthorID)
+	author, err := activeUser(ctx, s.UserRepo, "AddComment", comment.AuthorID)
 	if err != nil {
-		return errors.New("AddComment: author does not exist")
+		return ifNotFound(err, apperr.Validation("AddComment: author does not exist", map[string]string{"author_id": "does not exist"}))
+	}
+	if author.SuspendedAt != nil {
+		return apperr.Forbidden("AddComment: author is suspended")
 	}
 
 	// Check if post exists
-	post, err := s.PostRepo.GetPostByID(comment.PostID)
+	post, err := s.PostRepo.GetPostByID(ctx, comment.PostID)
 	if err != nil {
-		return errors.New("AddComment: post does not exist")
+		return ifNotFound(err, apperr.NotFound("AddComment: post does not exist"))
+	}
+	if err := s.checkQuarantine(ctx, "AddComment", post.SubredditID); err != nil {
+		return err
 	}
-	_=post
 
 	// Optionally, check if the user has joined the subreddit's post
 
 	// Create the comment via the repository
-	err = s.CommentRepo.CreateComment(comment)
+	err = s.CommentRepo.CreateComment(ctx, comment)
 	if err != nil {
 		return err
 	}
@@ -70,29 +85,50 @@ func (s *commentService) AddComment(comment *models.Comment) error {
 }
 
 // ReplyToComment adds a reply to an existing comment.
-func (s *commentService) ReplyToComment(comment *models.Comment) error {
+func (s *commentService) ReplyToComment(ctx context.Context, comment *models.Comment) error {
+	ctx, span := tracing.Start(ctx, "CommentService.ReplyToComment", attribute.Int("author.id", comment.AuthorID))
+	defer span.End()
 	// Validate input
 	if comment.Content == "" {
-		return errors.New("ReplyToComment: content is required")
+		return apperr.Validation("ReplyToComment: content is required", map[string]string{"content": "is required"})
 	}
 
 	// Check if author exists
-	_, err := s.UserRepo.GetUserByID(comment.AuthorID)
+	author, err := activeUser(ctx, s.UserRepo, "ReplyToComment", comment.AuthorID)
 	if err != nil {
-		return errors.New("ReplyToComment: author does not exist")
+		return ifNotFound(err, apperr.Validation("ReplyToComment: author does not exist", map[string]string{"author_id": "does not exist"}))
+	}
+	if author.SuspendedAt != nil {
+		return apperr.Forbidden("ReplyToComment: author is suspended")
 	}
 
 	// Check if parent comment exists
-	parentComment, err := s.CommentRepo.GetCommentByID(*comment.ParentID)
+	parentComment, err := s.CommentRepo.GetCommentByID(ctx, *comment.ParentID)
 	if err != nil {
-		return errors.New("ReplyToComment: parent comment does not exist")
+		return ifNotFound(err, apperr.NotFound("ReplyToComment: parent comment does not exist"))
+	}
+
+	// Blocked users cannot reply directly to the blocker's comments
+	blocked, err := s.BlockRepo.IsBlocked(ctx, parentComment.AuthorID, comment.AuthorID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return apperr.Forbidden("ReplyToComment: author of the parent comment has blocked this user")
 	}
 
 	// The reply should belong to the same post as the parent comment
 	comment.PostID = parentComment.PostID
+	post, err := s.PostRepo.GetPostByID(ctx, comment.PostID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkQuarantine(ctx, "ReplyToComment", post.SubredditID); err != nil {
+		return err
+	}
 
 	// Create the reply via the repository
-	err = s.CommentRepo.CreateComment(comment)
+	err = s.CommentRepo.CreateComment(ctx, comment)
 	if err != nil {
 		return err
 	}
@@ -101,55 +137,85 @@ func (s *commentService) ReplyToComment(comment *models.Comment) error {
 }
 
 // GetCommentByID retrieves a comment by its ID.
-func (s *commentService) GetCommentByID(id int) (*models.Comment, error) {
-	comment, err := s.CommentRepo.GetCommentByID(id)
+// A comment by a user the viewer has blocked is collapsed, as in listings.
+func (s *commentService) GetCommentByID(ctx context.Context, id, viewerID int) (*models.Comment, error) {
+	ctx, span := tracing.Start(ctx, "CommentService.GetCommentByID", attribute.Int("comment.id", id), attribute.Int("viewer.id", viewerID))
+	defer span.End()
+	comment, err := s.CommentRepo.GetCommentByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.collapseBlocked(ctx, viewerID, []*models.Comment{comment}); err != nil {
+		return nil, err
+	}
 	return comment, nil
 }
 
-// GetCommentsByPost retrieves top-level comments for a specific post with pagination.
-func (s *commentService) GetCommentsByPost(postID int, limit, offset int) ([]*models.Comment, error) {
+// GetCommentsByPost retrieves a page of top-level comments for a specific post.
+// Comments by users the viewer has blocked are collapsed, and each comment carries the viewer's vote.
+func (s *commentService) GetCommentsByPost(ctx context.Context, postID, viewerID int, page repository.Page) (*repository.Listing[*models.Comment], error) {
+	ctx, span := tracing.Start(ctx, "CommentService.GetCommentsByPost", attribute.Int("post.id", postID), attribute.Int("viewer.id", viewerID), attribute.Int("page.limit", page.Limit))
+	defer span.End()
 	// Check if post exists
-	_, err := s.PostRepo.GetPostByID(postID)
+	_, err := s.PostRepo.GetPostByID(ctx, postID)
 	if err != nil {
-		return nil, errors.New("GetCommentsByPost: post does not exist")
+		return nil, ifNotFound(err, apperr.NotFound("GetCommentsByPost: post does not exist"))
 	}
 
-	comments, err := s.CommentRepo.GetCommentsByPost(postID, limit, offset)
+	listing, err := listPage(page, repository.CommentCursor, func(page repository.Page) ([]*models.Comment, error) {
+		return s.CommentRepo.GetCommentsByPost(ctx, postID, page)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return comments, nil
+	if err := s.collapseBlocked(ctx, viewerID, listing.Items); err != nil {
+		return nil, err
+	}
+	if err := annotateCommentVotes(ctx, s.VoteRepo, viewerID, listing.Items); err != nil {
+		return nil, err
+	}
+	return listing, nil
 }
 
-// GetReplies retrieves replies to a specific comment with pagination.
-func (s *commentService) GetReplies(parentID int, limit, offset int) ([]*models.Comment, error) {
+// GetReplies retrieves a page of replies to a specific comment.
+// Replies by users the viewer has blocked are collapsed, and each reply carries the viewer's vote.
+func (s *commentService) GetReplies(ctx context.Context, parentID, viewerID int, page repository.Page) (*repository.Listing[*models.Comment], error) {
+	ctx, span := tracing.Start(ctx, "CommentService.GetReplies", attribute.Int("parent.id", parentID), attribute.Int("viewer.id", viewerID), attribute.Int("page.limit", page.Limit))
+	defer span.End()
 	// Check if parent comment exists
-	_, err := s.CommentRepo.GetCommentByID(parentID)
+	_, err := s.CommentRepo.GetCommentByID(ctx, parentID)
 	if err != nil {
-		return nil, errors.New("GetReplies: parent comment does not exist")
+		return nil, ifNotFound(err, apperr.NotFound("GetReplies: parent comment does not exist"))
 	}
 
-	replies, err := s.CommentRepo.GetReplies(parentID, limit, offset)
+	listing, err := listPage(page, repository.CommentCursor, func(page repository.Page) ([]*models.Comment, error) {
+		return s.CommentRepo.GetReplies(ctx, parentID, page)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return replies, nil
+	if err := s.collapseBlocked(ctx, viewerID, listing.Items); err != nil {
+		return nil, err
+	}
+	if err := annotateCommentVotes(ctx, s.VoteRepo, viewerID, listing.Items); err != nil {
+		return nil, err
+	}
+	return listing, nil
 }
 
 // UpdateComment updates a comment's content.
-func (s *commentService) UpdateComment(comment *models.Comment) error {
+func (s *commentService) UpdateComment(ctx context.Context, comment *models.Comment) error {
+	ctx, span := tracing.Start(ctx, "CommentService.UpdateComment", attribute.Int("comment.id", comment.ID))
+	defer span.End()
 	// Validate input
 	if comment.Content == "" {
-		return errors.New("UpdateComment: content is required")
+		return apperr.Validation("UpdateComment: content is required", map[string]string{"content": "is required"})
 	}
 
 	// Check if comment exists
-	existingComment, err := s.CommentRepo.GetCommentByID(comment.ID)
+	existingComment, err := s.CommentRepo.GetCommentByID(ctx, comment.ID)
 	if err != nil {
-		return errors.New("UpdateComment: comment does not exist")
+		return ifNotFound(err, apperr.NotFound("UpdateComment: comment does not exist"))
 	}
 
 	// Optionally, check if the user is authorized to update the comment (e.g., the author)
@@ -158,7 +224,7 @@ func (s *commentService) UpdateComment(comment *models.Comment) error {
 	existingComment.Content = comment.Content
 
 	// Update the comment via the repository
-	err = s.CommentRepo.UpdateComment(existingComment)
+	err = s.CommentRepo.UpdateComment(ctx, existingComment)
 	if err != nil {
 		return err
 	}
@@ -167,12 +233,51 @@ func (s *commentService) UpdateComment(comment *models.Comment) error {
 }
 
 // DeleteComment removes a comment from the system.
-func (s *commentService) DeleteComment(id int) error {
+func (s *commentService) DeleteComment(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "CommentService.DeleteComment", attribute.Int("comment.id", id))
+	defer span.End()
 	// Optionally, check if the user is authorized to delete the comment
 
-	err := s.CommentRepo.DeleteComment(id)
+	err := s.CommentRepo.DeleteComment(ctx, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// collapseBlocked hides the content of comments written by users the viewer has blocked.
+func (s *commentService) collapseBlocked(ctx context.Context, viewerID int, comments []*models.Comment) error {
+	if viewerID == 0 || len(comments) == 0 {
+		return nil
+	}
+	blockedIDs, err := s.BlockRepo.GetBlockedUserIDs(ctx, viewerID)
 	if err != nil {
 		return err
 	}
+	if len(blockedIDs) == 0 {
+		return nil
+	}
+	blocked := make(map[int]bool, len(blockedIDs))
+	for _, id := range blockedIDs {
+		blocked[id] = true
+	}
+	for _, comment := range comments {
+		if blocked[comment.AuthorID] {
+			comment.Collapsed = true
+			comment.Content = ""
+		}
+	}
+	return nil
+}
+
+// checkQuarantine refuses new comments in a quarantined subreddit.
+func (s *commentService) checkQuarantine(ctx context.Context, op string, subredditID int) error {
+	subreddit, err := s.SubredditRepo.GetSubredditByID(ctx, subredditID)
+	if err != nil {
+		return err
+	}
+	if subreddit.QuarantinedAt != nil {
+		return apperr.Forbidden(op + ": subreddit is quarantined")
+	}
 	return nil
 }
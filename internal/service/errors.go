@@ -0,0 +1,27 @@
+// File: internal/service/errors.go
+
+package service
+
+import (
+	"errors"
+
+	"redditclone/internal/apperr"
+)
+
+// ifNotFound returns replacement when err reports a missing row and err
+// itself otherwise, so existence checks do not mask storage failures.
+func ifNotFound(err, replacement error) error {
+	if errors.Is(err, apperr.ErrNotFound) {
+		return replacement
+	}
+	return err
+}
+
+// ifConflict returns replacement when err reports a conflict and err itself
+// otherwise.
+func ifConflict(err, replacement error) error {
+	if errors.Is(err, apperr.ErrConflict) {
+		return replacement
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+// File: internal/service/health_service.go
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"redditclone/internal/models"
+	"redditclone/pkg/database"
+)
+
+// healthCheckTimeout bounds how long a single check may wait on the database.
+const healthCheckTimeout = 2 * time.Second
+
+// HealthService defines the checks behind the health and readiness endpoints.
+type HealthService interface {
+	// Liveness reports whether the storage backend is reachable.
+	Liveness(ctx context.Context) *models.HealthReport
+	// Readiness additionally requires an up-to-date schema and that the server
+	// is not shutting down.
+	Readiness(ctx context.Context) *models.HealthReport
+	// Drain marks the server as shutting down, so readiness starts failing
+	// and load balancers stop sending new requests.
+	Drain()
+}
+
+type healthService struct {
+	DB       *database.DB
+	Migrator *database.Migrator
+	draining atomic.Bool
+}
+
+// NewHealthService creates a new HealthService. db and migrator are nil for
+// the memory backend, whose storage checks always pass.
+func NewHealthService(db *database.DB, migrator *database.Migrator) HealthService {
+	return &healthService{DB: db, Migrator: migrator}
+}
+
+// Liveness reports whether the storage backend is reachable.
+func (s *healthService) Liveness(ctx context.Context) *models.HealthReport {
+	checks := map[string]string{"database": checkResult(s.ping(ctx))}
+	return newHealthReport(checks)
+}
+
+// Readiness reports whether the server should receive traffic.
+func (s *healthService) Readiness(ctx context.Context) *models.HealthReport {
+	checks := map[string]string{
+		"database":   checkResult(s.ping(ctx)),
+		"migrations": checkResult(s.checkMigrations()),
+	}
+	if s.draining.Load() {
+		checks["server"] = "shutting down"
+	} else {
+		checks["server"] = models.HealthOK
+	}
+	return newHealthReport(checks)
+}
+
+// Drain marks the server as shutting down.
+func (s *healthService) Drain() {
+	s.draining.Store(true)
+}
+
+func (s *healthService) ping(ctx context.Context) error {
+	if s.DB == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	return s.DB.PingContext(ctx)
+}
+
+// checkMigrations fails when an applied migration was edited or some are
+// still pending.
+func (s *healthService) checkMigrations() error {
+	if s.Migrator == nil {
+		return nil
+	}
+	if err := s.Migrator.Verify(); err != nil {
+		return err
+	}
+	pending, err := s.Migrator.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d pending migration(s)", len(pending))
+	}
+	return nil
+}
+
+func checkResult(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return models.HealthOK
+}
+
+func newHealthReport(checks map[string]string) *models.HealthReport {
+	report := &models.HealthReport{Status: models.HealthOK, Checks: checks}
+	for _, result := range checks {
+		if result != models.HealthOK {
+			report.Status = models.HealthUnavailable
+		}
+	}
+	return report
+}
@@ -3,146 +3,188 @@
 package service
 
 import (
-	"errors"
+	"context"
 
+	"redditclone/internal/apperr"
 	"redditclone/internal/models"
 	"redditclone/internal/repository"
+	"redditclone/pkg/metrics"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // MessageService defines the methods for message-related business logic.
 type MessageService interface {
-	SendMessage(message *models.Message) error
-	ReplyToMessage(message *models.Message) error
-	GetMessageByID(id int) (*models.Message, error)
-	GetMessagesForUser(userID int, limit, offset int) ([]*models.Message, error)
-	GetReplies(parentID int, limit, offset int) ([]*models.Message, error)
-	UpdateMessage(message *models.Message) error
-	DeleteMessage(id int) error
+	SendMessage(ctx context.Context, message *models.Message) error
+	ReplyToMessage(ctx context.Context, message *models.Message) error
+	GetMessageByID(ctx context.Context, id int) (*models.Message, error)
+	GetMessagesForUser(ctx context.Context, userID int, page repository.Page) (*repository.Listing[*models.Message], error)
+	GetReplies(ctx context.Context, parentID int, page repository.Page) (*repository.Listing[*models.Message], error)
+	UpdateMessage(ctx context.Context, message *models.Message) error
+	DeleteMessage(ctx context.Context, id int) error
 }
 
 type messageService struct {
 	MessageRepo repository.MessageRepository
 	UserRepo    repository.UserRepository
+	BlockRepo   repository.BlockRepository
 	// Add additional repositories if necessary
 }
 
 // NewMessageService creates a new MessageService.
-func NewMessageService(messageRepo repository.MessageRepository, userRepo repository.UserRepository) MessageService {
+func NewMessageService(messageRepo repository.MessageRepository, userRepo repository.UserRepository, blockRepo repository.BlockRepository) MessageService {
 	return &messageService{
 		MessageRepo: messageRepo,
 		UserRepo:    userRepo,
+		BlockRepo:   blockRepo,
 	}
 }
 
 // SendMessage handles sending a new direct message.
-func (s *messageService) SendMessage(message *models.Message) error {
+func (s *messageService) SendMessage(ctx context.Context, message *models.Message) error {
+	ctx, span := tracing.Start(ctx, "MessageService.SendMessage", attribute.Int("sender.id", message.SenderID), attribute.Int("receiver.id", message.ReceiverID))
+	defer span.End()
 	// Validate input
 	if message.Content == "" {
-		return errors.New("SendMessage: content is required")
+		return apperr.Validation("SendMessage: content is required", map[string]string{"content": "is required"})
 	}
 	if message.SenderID == message.ReceiverID {
-		return errors.New("SendMessage: sender and receiver cannot be the same")
+		return apperr.Validation("SendMessage: sender and receiver cannot be the same", map[string]string{"receiver_id": "must differ from sender_id"})
 	}
 
 	// Check if sender exists
-	_, err := s.UserRepo.GetUserByID(message.SenderID)
+	sender, err := activeUser(ctx, s.UserRepo, "SendMessage", message.SenderID)
 	if err != nil {
-		return errors.New("SendMessage: sender does not exist")
+		return ifNotFound(err, apperr.Validation("SendMessage: sender does not exist", map[string]string{"sender_id": "does not exist"}))
+	}
+	if sender.SuspendedAt != nil {
+		return apperr.Forbidden("SendMessage: sender is suspended")
 	}
 
 	// Check if receiver exists
-	receiver, err := s.UserRepo.GetUserByID(message.ReceiverID)
+	receiver, err := activeUser(ctx, s.UserRepo, "SendMessage", message.ReceiverID)
+	if err != nil {
+		return ifNotFound(err, apperr.Validation("SendMessage: receiver does not exist", map[string]string{"receiver_id": "does not exist"}))
+	}
+	_ = receiver
+
+	// Reject messages from users the receiver has blocked
+	blocked, err := s.BlockRepo.IsBlocked(ctx, message.ReceiverID, message.SenderID)
 	if err != nil {
-		return errors.New("SendMessage: receiver does not exist")
+		return err
+	}
+	if blocked {
+		return apperr.Forbidden("SendMessage: receiver has blocked the sender")
 	}
-	_=receiver
+
 	// Create the message via the repository
-	err = s.MessageRepo.SendMessage(message)
+	err = s.MessageRepo.SendMessage(ctx, message)
 	if err != nil {
 		return err
 	}
+	metrics.MessagesSent.Inc()
 
 	return nil
 }
 
 // ReplyToMessage handles replying to an existing message.
-func (s *messageService) ReplyToMessage(message *models.Message) error {
+func (s *messageService) ReplyToMessage(ctx context.Context, message *models.Message) error {
+	ctx, span := tracing.Start(ctx, "MessageService.ReplyToMessage", attribute.Int("sender.id", message.SenderID))
+	defer span.End()
 	// Validate input
 	if message.Content == "" {
-		return errors.New("ReplyToMessage: content is required")
+		return apperr.Validation("ReplyToMessage: content is required", map[string]string{"content": "is required"})
 	}
 	if message.SenderID == message.ReceiverID {
-		return errors.New("ReplyToMessage: sender and receiver cannot be the same")
+		return apperr.Validation("ReplyToMessage: sender and receiver cannot be the same", map[string]string{"receiver_id": "must differ from sender_id"})
 	}
 
 	// Check if sender exists
-	_, err := s.UserRepo.GetUserByID(message.SenderID)
+	sender, err := activeUser(ctx, s.UserRepo, "ReplyToMessage", message.SenderID)
 	if err != nil {
-		return errors.New("ReplyToMessage: sender does not exist")
+		return ifNotFound(err, apperr.Validation("ReplyToMessage: sender does not exist", map[string]string{"sender_id": "does not exist"}))
+	}
+	if sender.SuspendedAt != nil {
+		return apperr.Forbidden("ReplyToMessage: sender is suspended")
 	}
 
 	// Check if parent message exists
-	parentMessage, err := s.MessageRepo.GetMessageByID(*message.ParentID)
+	parentMessage, err := s.MessageRepo.GetMessageByID(ctx, *message.ParentID)
 	if err != nil {
-		return errors.New("ReplyToMessage: parent message does not exist")
+		return ifNotFound(err, apperr.NotFound("ReplyToMessage: parent message does not exist"))
 	}
 
 	// The reply should be sent to the original sender of the parent message
 	message.ReceiverID = parentMessage.SenderID
 
+	// Reject replies from users the receiver has blocked
+	blocked, err := s.BlockRepo.IsBlocked(ctx, message.ReceiverID, message.SenderID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return apperr.Forbidden("ReplyToMessage: receiver has blocked the sender")
+	}
+
 	// Create the reply via the repository
-	err = s.MessageRepo.SendMessage(message)
+	err = s.MessageRepo.SendMessage(ctx, message)
 	if err != nil {
 		return err
 	}
+	metrics.MessagesSent.Inc()
 
 	return nil
 }
 
 // GetMessageByID retrieves a message by its ID.
-func (s *messageService) GetMessageByID(id int) (*models.Message, error) {
-	message, err := s.MessageRepo.GetMessageByID(id)
+func (s *messageService) GetMessageByID(ctx context.Context, id int) (*models.Message, error) {
+	ctx, span := tracing.Start(ctx, "MessageService.GetMessageByID", attribute.Int("message.id", id))
+	defer span.End()
+	message, err := s.MessageRepo.GetMessageByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 	return message, nil
 }
 
-// GetMessagesForUser retrieves direct messages received by a user with pagination.
-func (s *messageService) GetMessagesForUser(userID int, limit, offset int) ([]*models.Message, error) {
-	messages, err := s.MessageRepo.GetMessagesForUser(userID, limit, offset)
-	if err != nil {
-		return nil, err
-	}
-	return messages, nil
+// GetMessagesForUser retrieves a page of direct messages received by a user.
+func (s *messageService) GetMessagesForUser(ctx context.Context, userID int, page repository.Page) (*repository.Listing[*models.Message], error) {
+	ctx, span := tracing.Start(ctx, "MessageService.GetMessagesForUser", attribute.Int("user.id", userID), attribute.Int("page.limit", page.Limit))
+	defer span.End()
+	return listPage(page, repository.MessageCursor, func(page repository.Page) ([]*models.Message, error) {
+		return s.MessageRepo.GetMessagesForUser(ctx, userID, page)
+	})
 }
 
-// GetReplies retrieves replies to a specific message with pagination.
-func (s *messageService) GetReplies(parentID int, limit, offset int) ([]*models.Message, error) {
+// GetReplies retrieves a page of replies to a specific message.
+func (s *messageService) GetReplies(ctx context.Context, parentID int, page repository.Page) (*repository.Listing[*models.Message], error) {
+	ctx, span := tracing.Start(ctx, "MessageService.GetReplies", attribute.Int("parent.id", parentID), attribute.Int("page.limit", page.Limit))
+	defer span.End()
 	// Check if parent message exists
-	_, err := s.MessageRepo.GetMessageByID(parentID)
+	_, err := s.MessageRepo.GetMessageByID(ctx, parentID)
 	if err != nil {
-		return nil, errors.New("GetReplies: parent message does not exist")
+		return nil, ifNotFound(err, apperr.NotFound("GetReplies: parent message does not exist"))
 	}
 
-	replies, err := s.MessageRepo.GetReplies(parentID, limit, offset)
-	if err != nil {
-		return nil, err
-	}
-	return replies, nil
+	return listPage(page, repository.MessageCursor, func(page repository.Page) ([]*models.Message, error) {
+		return s.MessageRepo.GetReplies(ctx, parentID, page)
+	})
 }
 
 // UpdateMessage updates a message's content.
-func (s *messageService) UpdateMessage(message *models.Message) error {
+func (s *messageService) UpdateMessage(ctx context.Context, message *models.Message) error {
+	ctx, span := tracing.Start(ctx, "MessageService.UpdateMessage", attribute.Int("message.id", message.ID))
+	defer span.End()
 	// Validate input
 	if message.Content == "" {
-		return errors.New("UpdateMessage: content is required")
+		return apperr.Validation("UpdateMessage: content is required", map[string]string{"content": "is required"})
 	}
 
 	// Check if message exists
-	existingMessage, err := s.MessageRepo.GetMessageByID(message.ID)
+	existingMessage, err := s.MessageRepo.GetMessageByID(ctx, message.ID)
 	if err != nil {
-		return errors.New("UpdateMessage: message does not exist")
+		return ifNotFound(err, apperr.NotFound("UpdateMessage: message does not exist"))
 	}
 
 	// Optionally, check if the user is authorized to update the message (e.g., the sender)
@@ -151,7 +193,7 @@ func (s *messageService) UpdateMessage(message *models.Message) error {
 	existingMessage.Content = message.Content
 
 	// Update the message via the repository
-	err = s.MessageRepo.UpdateMessage(existingMessage)
+	err = s.MessageRepo.UpdateMessage(ctx, existingMessage)
 	if err != nil {
 		return err
 	}
@@ -160,10 +202,12 @@ func (s *messageService) UpdateMessage(message *models.Message) error {
 }
 
 // DeleteMessage removes a message from the system.
-func (s *messageService) DeleteMessage(id int) error {
+func (s *messageService) DeleteMessage(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "MessageService.DeleteMessage", attribute.Int("message.id", id))
+	defer span.End()
 	// Optionally, check if the user is authorized to delete the message
 
-	err := s.MessageRepo.DeleteMessage(id)
+	err := s.MessageRepo.DeleteMessage(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,597 @@
+// File: internal/service/oauth_service.go
+
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+	"redditclone/pkg/logging"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// OAuthAccessTokenPrefix starts every OAuth2 access token, which tells them
+// apart from session access tokens and API keys.
+const OAuthAccessTokenPrefix = "rco_"
+
+const (
+	oauthRefreshTokenPrefix = "rcr_"
+	oauthClientSecretPrefix = "rcs_"
+	authorizationCodeTTL    = 10 * time.Minute // How long an authorization code may wait to be exchanged.
+	maxOAuthClientsPerUser  = 25               // Clients a user may register.
+	maxRedirectURIs         = 10               // Redirect URIs a client may register.
+	maxClientName           = 100              // Longest client name, in bytes.
+)
+
+// OAuth2 error codes, from RFC 6749 section 5.2.
+const (
+	OAuthInvalidRequest       = "invalid_request"
+	OAuthInvalidClient        = "invalid_client"
+	OAuthInvalidGrant         = "invalid_grant"
+	OAuthInvalidScope         = "invalid_scope"
+	OAuthUnsupportedGrantType = "unsupported_grant_type"
+)
+
+// OAuthError is a token endpoint error, reported to the client with one of
+// the OAuth2 error codes above. It wraps an apperr error, so it maps to an
+// HTTP status like any other error.
+type OAuthError struct {
+	Code string // One of the OAuth2 error codes.
+	Err  error  // The apperr error describing the problem.
+}
+
+// Error returns the wrapped error's message.
+func (e *OAuthError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped apperr error.
+func (e *OAuthError) Unwrap() error {
+	return e.Err
+}
+
+// oauthError returns an OAuthError with the given code. invalid_client is
+// apperr.Unauthorized; the other codes are apperr.BadRequest.
+func oauthError(code, message string) error {
+	if code == OAuthInvalidClient {
+		return &OAuthError{Code: code, Err: apperr.Unauthorized(message)}
+	}
+	return &OAuthError{Code: code, Err: apperr.BadRequest(message)}
+}
+
+// OAuthService defines the OAuth2 authorization server: client registration,
+// the authorization code flow with PKCE, token issue and refresh, and the
+// consents users review and revoke.
+type OAuthService interface {
+	RegisterClient(ctx context.Context, ownerID int, name string, redirectURIs, scopes []string, confidential bool) (*models.NewOAuthClient, error)
+	GetClient(ctx context.Context, clientID string) (*models.OAuthClient, error)
+	ListClients(ctx context.Context, ownerID int) ([]*models.OAuthClient, error)
+	DeleteClient(ctx context.Context, ownerID int, clientID string) error
+	PrepareAuthorization(ctx context.Context, userID int, req *models.AuthorizationRequest) (*models.AuthorizationPrompt, error)
+	Authorize(ctx context.Context, userID int, req *models.AuthorizationRequest, approve bool) (string, error)
+	Token(ctx context.Context, req *models.TokenRequest) (*models.TokenResponse, error)
+	Authenticate(ctx context.Context, accessToken string) (*models.OAuthToken, error)
+	ListConsents(ctx context.Context, userID int) ([]*models.OAuthConsent, error)
+	RevokeConsent(ctx context.Context, userID int, clientID string) error
+}
+
+// OAuthConfig configures OAuth2 token lifetimes.
+type OAuthConfig struct {
+	AccessTTL  time.Duration // How long an access token works.
+	RefreshTTL time.Duration // How long a refresh token works.
+}
+
+type oauthService struct {
+	OAuthRepo repository.OAuthRepository
+	Config    OAuthConfig
+}
+
+// NewOAuthService creates a new OAuthService.
+func NewOAuthService(oauthRepo repository.OAuthRepository, cfg OAuthConfig) OAuthService {
+	return &oauthService{OAuthRepo: oauthRepo, Config: cfg}
+}
+
+type oauthTokenKey struct{}
+
+// WithOAuthToken returns a copy of ctx carrying the OAuth2 token the request
+// was authenticated with.
+func WithOAuthToken(ctx context.Context, token *models.OAuthToken) context.Context {
+	return context.WithValue(ctx, oauthTokenKey{}, token)
+}
+
+// OAuthTokenFromContext returns the token stored by WithOAuthToken, or nil
+// for requests that did not present one.
+func OAuthTokenFromContext(ctx context.Context) *models.OAuthToken {
+	token, _ := ctx.Value(oauthTokenKey{}).(*models.OAuthToken)
+	return token
+}
+
+// IsOAuthToken reports whether a bearer token is an OAuth2 access token.
+func IsOAuthToken(token string) bool {
+	return strings.HasPrefix(token, OAuthAccessTokenPrefix)
+}
+
+// RegisterClient registers a third-party application owned by a user.
+// Confidential clients get a secret, returned only here.
+func (s *oauthService) RegisterClient(ctx context.Context, ownerID int, name string, redirectURIs, scopes []string, confidential bool) (*models.NewOAuthClient, error) {
+	ctx, span := tracing.Start(ctx, "OAuthService.RegisterClient", attribute.Int("user.id", ownerID))
+	defer span.End()
+	name = strings.TrimSpace(name)
+	fields := map[string]string{}
+	if name == "" {
+		fields["name"] = "is required"
+	} else if len(name) > maxClientName {
+		fields["name"] = "must be at most 100 characters"
+	}
+	if len(redirectURIs) == 0 || len(redirectURIs) > maxRedirectURIs {
+		fields["redirect_uris"] = "must list between 1 and 10 URIs"
+	} else {
+		for _, uri := range redirectURIs {
+			if !validRedirectURI(uri) {
+				fields["redirect_uris"] = "must be https URLs, or http on localhost, without fragments"
+				break
+			}
+		}
+	}
+	scopes, ok := normalizeScopes(scopes)
+	if !ok {
+		fields["scopes"] = "must list one or more of " + strings.Join(models.Scopes, ", ")
+	}
+	if len(fields) > 0 {
+		return nil, apperr.Validation("RegisterClient: invalid OAuth client", fields)
+	}
+
+	existing, err := s.OAuthRepo.GetClientsByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxOAuthClientsPerUser {
+		return nil, apperr.Conflict("RegisterClient: too many OAuth clients; delete one first")
+	}
+
+	id, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	client := &models.OAuthClient{
+		ID:           id[:22],
+		OwnerID:      ownerID,
+		Name:         name,
+		Confidential: confidential,
+		RedirectURIs: redirectURIs,
+		Scopes:       scopes,
+	}
+	var secret string
+	if confidential {
+		token, err := newToken()
+		if err != nil {
+			return nil, err
+		}
+		secret = oauthClientSecretPrefix + token
+		client.SecretHash = hashToken(secret)
+	}
+	if err := s.OAuthRepo.CreateClient(ctx, client); err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("OAuth client registered", "user_id", ownerID, "client_id", client.ID)
+	return &models.NewOAuthClient{OAuthClient: *client, Secret: secret}, nil
+}
+
+// GetClient retrieves a client by its client ID.
+func (s *oauthService) GetClient(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	ctx, span := tracing.Start(ctx, "OAuthService.GetClient", attribute.String("oauth.client_id", clientID))
+	defer span.End()
+	return s.OAuthRepo.GetClient(ctx, clientID)
+}
+
+// ListClients retrieves the clients a user registered, newest first.
+func (s *oauthService) ListClients(ctx context.Context, ownerID int) ([]*models.OAuthClient, error) {
+	ctx, span := tracing.Start(ctx, "OAuthService.ListClients", attribute.Int("user.id", ownerID))
+	defer span.End()
+	return s.OAuthRepo.GetClientsByOwner(ctx, ownerID)
+}
+
+// DeleteClient deletes one of a user's clients. Its tokens stop working and
+// every consent given to it is removed.
+func (s *oauthService) DeleteClient(ctx context.Context, ownerID int, clientID string) error {
+	ctx, span := tracing.Start(ctx, "OAuthService.DeleteClient", attribute.Int("user.id", ownerID), attribute.String("oauth.client_id", clientID))
+	defer span.End()
+	if err := s.OAuthRepo.DeleteClient(ctx, ownerID, clientID); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("OAuth client deleted", "user_id", ownerID, "client_id", clientID)
+	return nil
+}
+
+// PrepareAuthorization checks an authorization request and describes it for
+// the user to approve or deny.
+func (s *oauthService) PrepareAuthorization(ctx context.Context, userID int, req *models.AuthorizationRequest) (*models.AuthorizationPrompt, error) {
+	ctx, span := tracing.Start(ctx, "OAuthService.PrepareAuthorization", attribute.Int("user.id", userID), attribute.String("oauth.client_id", req.ClientID))
+	defer span.End()
+	client, redirectURI, scopes, err := s.checkAuthorizationRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	prompt := &models.AuthorizationPrompt{
+		ClientID:    client.ID,
+		ClientName:  client.Name,
+		Scopes:      scopes,
+		RedirectURI: redirectURI,
+		State:       req.State,
+	}
+	consent, err := s.OAuthRepo.GetConsent(ctx, userID, client.ID)
+	if err != nil && !errors.Is(err, apperr.ErrNotFound) {
+		return nil, err
+	}
+	prompt.PreviouslyGranted = consent != nil && containsAll(consent.Scopes, scopes)
+	return prompt, nil
+}
+
+// Authorize records the user's answer to an authorization request and
+// returns the URI to send the user back to the client with. An approval
+// saves the consent and carries an authorization code; a denial carries the
+// access_denied error.
+func (s *oauthService) Authorize(ctx context.Context, userID int, req *models.AuthorizationRequest, approve bool) (string, error) {
+	ctx, span := tracing.Start(ctx, "OAuthService.Authorize", attribute.Int("user.id", userID), attribute.String("oauth.client_id", req.ClientID))
+	defer span.End()
+	client, redirectURI, scopes, err := s.checkAuthorizationRequest(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if !approve {
+		logging.FromContext(ctx).Info("OAuth authorization denied", "user_id", userID, "client_id", client.ID)
+		return withQuery(redirectURI, "error", "access_denied", "state", req.State), nil
+	}
+
+	// Add the scopes to any the user granted the client before
+	granted := scopes
+	consent, err := s.OAuthRepo.GetConsent(ctx, userID, client.ID)
+	if err != nil && !errors.Is(err, apperr.ErrNotFound) {
+		return "", err
+	}
+	if consent != nil {
+		granted, _ = normalizeScopes(append(consent.Scopes, scopes...))
+	}
+	if err := s.OAuthRepo.SaveConsent(ctx, &models.OAuthConsent{UserID: userID, ClientID: client.ID, Scopes: granted}); err != nil {
+		return "", err
+	}
+
+	code, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	err = s.OAuthRepo.CreateCode(ctx, &models.OAuthCode{
+		CodeHash:      hashToken(code),
+		ClientID:      client.ID,
+		UserID:        userID,
+		RedirectURI:   redirectURI,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     time.Now().UTC().Add(authorizationCodeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	logging.FromContext(ctx).Info("OAuth authorization granted", "user_id", userID, "client_id", client.ID, "scopes", strings.Join(scopes, " "))
+	return withQuery(redirectURI, "code", code, "state", req.State), nil
+}
+
+// checkAuthorizationRequest validates an authorization request, returning
+// the client, the redirect URI to answer on and the scopes asked for.
+func (s *oauthService) checkAuthorizationRequest(ctx context.Context, req *models.AuthorizationRequest) (*models.OAuthClient, string, []string, error) {
+	if req.ClientID == "" {
+		return nil, "", nil, apperr.Validation("Authorize: client_id is required", map[string]string{"client_id": "is required"})
+	}
+	client, err := s.OAuthRepo.GetClient(ctx, req.ClientID)
+	if err != nil {
+		return nil, "", nil, ifNotFound(err, apperr.Validation("Authorize: unknown client", map[string]string{"client_id": "is not a registered client"}))
+	}
+
+	redirectURI := req.RedirectURI
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	fields := map[string]string{}
+	if !containsAll(client.RedirectURIs, []string{redirectURI}) {
+		fields["redirect_uri"] = "must be one of the client's redirect URIs"
+	}
+	if req.ResponseType != "code" {
+		fields["response_type"] = "must be code"
+	}
+	requested := strings.Fields(req.Scope)
+	if len(requested) == 0 {
+		requested = client.Scopes
+	}
+	scopes, ok := normalizeScopes(requested)
+	if !ok || !containsAll(client.Scopes, scopes) {
+		fields["scope"] = "must be among the client's scopes: " + strings.Join(client.Scopes, " ")
+	}
+	if req.CodeChallengeMethod != "S256" {
+		fields["code_challenge_method"] = "must be S256"
+	}
+	if len(req.CodeChallenge) != 43 {
+		fields["code_challenge"] = "must be the base64url SHA-256 of the code verifier"
+	}
+	if len(fields) > 0 {
+		return nil, "", nil, apperr.Validation("Authorize: invalid authorization request", fields)
+	}
+	return client, redirectURI, scopes, nil
+}
+
+// Token handles a token endpoint request: exchanging an authorization code,
+// or a refresh token, for a new access and refresh token. Refresh tokens
+// rotate on every use. A code or refresh token that comes back after use
+// means a copy was stolen, so every token the client holds for the user is
+// revoked. Errors are *OAuthError.
+func (s *oauthService) Token(ctx context.Context, req *models.TokenRequest) (*models.TokenResponse, error) {
+	ctx, span := tracing.Start(ctx, "OAuthService.Token", attribute.String("oauth.client_id", req.ClientID), attribute.String("oauth.grant_type", req.GrantType))
+	defer span.End()
+	switch req.GrantType {
+	case "authorization_code", "refresh_token":
+	case "":
+		return nil, oauthError(OAuthInvalidRequest, "Token: grant_type is required")
+	default:
+		return nil, oauthError(OAuthUnsupportedGrantType, "Token: grant_type must be authorization_code or refresh_token")
+	}
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if req.GrantType == "authorization_code" {
+		return s.exchangeCode(ctx, client, req)
+	}
+	return s.refresh(ctx, client, req)
+}
+
+// authenticateClient checks a token request's client credentials.
+// Confidential clients must send their secret; public clients must not.
+func (s *oauthService) authenticateClient(ctx context.Context, clientID, secret string) (*models.OAuthClient, error) {
+	invalid := oauthError(OAuthInvalidClient, "Token: client authentication failed")
+	if clientID == "" {
+		return nil, invalid
+	}
+	client, err := s.OAuthRepo.GetClient(ctx, clientID)
+	if err != nil {
+		return nil, ifNotFound(err, invalid)
+	}
+	if client.Confidential != (secret != "") {
+		return nil, invalid
+	}
+	if client.Confidential && subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, invalid
+	}
+	return client, nil
+}
+
+// exchangeCode exchanges an authorization code for tokens.
+func (s *oauthService) exchangeCode(ctx context.Context, client *models.OAuthClient, req *models.TokenRequest) (*models.TokenResponse, error) {
+	invalid := oauthError(OAuthInvalidGrant, "Token: authorization code is invalid or has expired")
+	if req.Code == "" || req.CodeVerifier == "" {
+		return nil, oauthError(OAuthInvalidRequest, "Token: code and code_verifier are required")
+	}
+	code, err := s.OAuthRepo.UseCode(ctx, hashToken(req.Code))
+	if errors.Is(err, apperr.ErrConflict) && code.ClientID == client.ID {
+		s.revokeStolenGrant(ctx, code.UserID, client.ID, models.RevokedCodeReuse)
+		return nil, oauthError(OAuthInvalidGrant, "Token: authorization code was already used; tokens issued for it have been revoked")
+	}
+	if err != nil {
+		return nil, ifNotFound(ifConflict(err, invalid), invalid)
+	}
+	if code.ClientID != client.ID || !code.ExpiresAt.After(time.Now()) {
+		return nil, invalid
+	}
+	if req.RedirectURI != code.RedirectURI {
+		return nil, oauthError(OAuthInvalidGrant, "Token: redirect_uri does not match the authorization request")
+	}
+	if !verifyCodeChallenge(req.CodeVerifier, code.CodeChallenge) {
+		return nil, oauthError(OAuthInvalidGrant, "Token: code_verifier does not match the code challenge")
+	}
+	if _, err := s.OAuthRepo.GetConsent(ctx, code.UserID, client.ID); err != nil {
+		return nil, ifNotFound(err, oauthError(OAuthInvalidGrant, "Token: the user revoked the client's access"))
+	}
+
+	token, response, err := s.newTokenPair(client.ID, code.UserID, code.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.OAuthRepo.CreateToken(ctx, token); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// refresh exchanges a refresh token for a new pair, optionally with fewer scopes.
+func (s *oauthService) refresh(ctx context.Context, client *models.OAuthClient, req *models.TokenRequest) (*models.TokenResponse, error) {
+	invalid := oauthError(OAuthInvalidGrant, "Token: refresh token is invalid or has expired")
+	if req.RefreshToken == "" {
+		return nil, oauthError(OAuthInvalidRequest, "Token: refresh_token is required")
+	}
+	old, err := s.OAuthRepo.GetTokenByRefreshHash(ctx, hashToken(req.RefreshToken))
+	if err != nil {
+		return nil, ifNotFound(err, invalid)
+	}
+	if old.ClientID != client.ID {
+		return nil, invalid
+	}
+	if old.RevokedAt != nil {
+		if old.RevokeReason == models.RevokedRotated {
+			s.revokeStolenGrant(ctx, old.UserID, client.ID, models.RevokedRefreshReuse)
+		}
+		return nil, invalid
+	}
+	if !old.RefreshExpiresAt.After(time.Now()) {
+		return nil, invalid
+	}
+
+	scopes := old.Scopes
+	if req.Scope != "" {
+		narrowed, ok := normalizeScopes(strings.Fields(req.Scope))
+		if !ok || !containsAll(old.Scopes, narrowed) {
+			return nil, oauthError(OAuthInvalidScope, "Token: scope must not exceed the scopes originally granted")
+		}
+		scopes = narrowed
+	}
+
+	next, response, err := s.newTokenPair(client.ID, old.UserID, scopes)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.OAuthRepo.RotateToken(ctx, old.ID, next); err != nil {
+		return nil, ifConflict(err, invalid)
+	}
+	return response, nil
+}
+
+// revokeStolenGrant revokes every token a client holds for a user after one
+// of its single-use credentials was replayed.
+func (s *oauthService) revokeStolenGrant(ctx context.Context, userID int, clientID, reason string) {
+	revoked, err := s.OAuthRepo.RevokeGrantTokens(ctx, userID, clientID, reason)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to revoke OAuth tokens", "user_id", userID, "client_id", clientID, "error", err.Error())
+		return
+	}
+	logging.FromContext(ctx).Warn("OAuth credential reused; revoking tokens",
+		"user_id", userID, "client_id", clientID, "reason", reason, "revoked", revoked)
+}
+
+// newTokenPair generates an access and refresh token for a client acting for
+// a user, returning the record to store and the response to send.
+func (s *oauthService) newTokenPair(clientID string, userID int, scopes []string) (*models.OAuthToken, *models.TokenResponse, error) {
+	accessToken, err := newToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	refreshToken, err := newToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	accessToken = OAuthAccessTokenPrefix + accessToken
+	refreshToken = oauthRefreshTokenPrefix + refreshToken
+	now := time.Now().UTC().Truncate(time.Second)
+	token := &models.OAuthToken{
+		ClientID:         clientID,
+		UserID:           userID,
+		AccessTokenHash:  hashToken(accessToken),
+		AccessExpiresAt:  now.Add(s.Config.AccessTTL),
+		RefreshTokenHash: hashToken(refreshToken),
+		RefreshExpiresAt: now.Add(s.Config.RefreshTTL),
+		Scopes:           scopes,
+	}
+	response := &models.TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.Config.AccessTTL.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        strings.Join(scopes, " "),
+	}
+	return token, response, nil
+}
+
+// Authenticate resolves an OAuth2 access token to its record. Unknown,
+// expired and revoked tokens are apperr.Unauthorized.
+func (s *oauthService) Authenticate(ctx context.Context, accessToken string) (*models.OAuthToken, error) {
+	ctx, span := tracing.Start(ctx, "OAuthService.Authenticate")
+	defer span.End()
+	token, err := s.OAuthRepo.GetTokenByAccessHash(ctx, hashToken(accessToken), time.Now())
+	if err != nil {
+		return nil, ifNotFound(err, apperr.Unauthorized("Authenticate: access token is invalid or has expired"))
+	}
+	span.SetAttributes(attribute.String("oauth.client_id", token.ClientID), attribute.Int("user.id", token.UserID))
+	return token, nil
+}
+
+// ListConsents retrieves the consents a user has given clients, revoked ones
+// included, most recently changed first.
+func (s *oauthService) ListConsents(ctx context.Context, userID int) ([]*models.OAuthConsent, error) {
+	ctx, span := tracing.Start(ctx, "OAuthService.ListConsents", attribute.Int("user.id", userID))
+	defer span.End()
+	return s.OAuthRepo.GetConsentsByUser(ctx, userID)
+}
+
+// RevokeConsent withdraws a user's consent for a client; the client's tokens
+// for the user stop working immediately.
+func (s *oauthService) RevokeConsent(ctx context.Context, userID int, clientID string) error {
+	ctx, span := tracing.Start(ctx, "OAuthService.RevokeConsent", attribute.Int("user.id", userID), attribute.String("oauth.client_id", clientID))
+	defer span.End()
+	if err := s.OAuthRepo.RevokeConsent(ctx, userID, clientID); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("OAuth consent revoked", "user_id", userID, "client_id", clientID)
+	return nil
+}
+
+// validRedirectURI reports whether uri may be registered as a redirect URI:
+// an absolute https URL, or http on a loopback host for native and local
+// apps, without a fragment.
+func validRedirectURI(uri string) bool {
+	if strings.ContainsAny(uri, " \t\r\n") {
+		return false
+	}
+	u, err := url.Parse(uri)
+	if err != nil || u.Host == "" || u.Fragment != "" || u.User != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := u.Hostname()
+		ip := net.ParseIP(host)
+		return host == "localhost" || (ip != nil && ip.IsLoopback())
+	}
+	return false
+}
+
+// verifyCodeChallenge reports whether a PKCE code verifier matches an S256
+// code challenge.
+func verifyCodeChallenge(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// withQuery adds query parameters, given as name and value pairs, to uri,
+// skipping empty values.
+func withQuery(uri string, pairs ...string) string {
+	u, _ := url.Parse(uri) // Checked when the client was registered.
+	query := u.Query()
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			query.Set(pairs[i], pairs[i+1])
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// containsAll reports whether every item of subset is in set.
+func containsAll(set, subset []string) bool {
+	for _, item := range subset {
+		found := false
+		for _, s := range set {
+			if s == item {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
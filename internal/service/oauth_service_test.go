@@ -0,0 +1,103 @@
+// File: internal/service/oauth_service_test.go
+
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"redditclone/internal/models"
+	"redditclone/internal/repository/memory"
+)
+
+// The code verifier and challenge from RFC 7636 appendix B.
+const (
+	rfcVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	rfcChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		want      bool
+	}{
+		{"RFC 7636 example", rfcVerifier, rfcChallenge, true},
+		{"wrong verifier", strings.Replace(rfcVerifier, "d", "e", 1), rfcChallenge, false},
+		{"plain challenge", rfcVerifier, rfcVerifier, false},
+		{"verifier too short", rfcVerifier[:42], rfcChallenge, false},
+		{"verifier too long", strings.Repeat("a", 129), rfcChallenge, false},
+		{"empty", "", "", false},
+	}
+	for _, tt := range tests {
+		if got := verifyCodeChallenge(tt.verifier, tt.challenge); got != tt.want {
+			t.Errorf("%s: verifyCodeChallenge = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestTokenPKCE exchanges authorization codes for tokens, each with a
+// different code verifier.
+func TestTokenPKCE(t *testing.T) {
+	ctx := context.Background()
+	repos := memory.NewRepositories()
+	user := &models.User{Username: "ann", Email: "ann@example.com", Password: "hash"}
+	if err := repos.Users.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	oauth := NewOAuthService(repos.OAuth, OAuthConfig{AccessTTL: time.Hour, RefreshTTL: 24 * time.Hour})
+	const redirectURI = "https://app.example/callback"
+	client, err := oauth.RegisterClient(ctx, user.ID, "app", []string{redirectURI}, []string{models.ScopeRead}, false)
+	if err != nil {
+		t.Fatalf("RegisterClient: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		verifier string
+		wantCode string // OAuth2 error code; empty for success.
+	}{
+		{"matching verifier", rfcVerifier, ""},
+		{"other verifier", strings.Repeat("a", 43), OAuthInvalidGrant},
+		{"missing verifier", "", OAuthInvalidRequest},
+	}
+	for _, tt := range tests {
+		redirect, err := oauth.Authorize(ctx, user.ID, &models.AuthorizationRequest{
+			ResponseType:        "code",
+			ClientID:            client.ID,
+			RedirectURI:         redirectURI,
+			CodeChallenge:       rfcChallenge,
+			CodeChallengeMethod: "S256",
+		}, true)
+		if err != nil {
+			t.Fatalf("%s: Authorize: %v", tt.name, err)
+		}
+		u, err := url.Parse(redirect)
+		if err != nil {
+			t.Fatalf("%s: Authorize redirected to %q: %v", tt.name, redirect, err)
+		}
+
+		response, err := oauth.Token(ctx, &models.TokenRequest{
+			GrantType:    "authorization_code",
+			ClientID:     client.ID,
+			Code:         u.Query().Get("code"),
+			RedirectURI:  redirectURI,
+			CodeVerifier: tt.verifier,
+		})
+		if tt.wantCode == "" {
+			if err != nil || response.AccessToken == "" {
+				t.Errorf("%s: Token = %+v, %v; want tokens", tt.name, response, err)
+			}
+			continue
+		}
+		var oerr *OAuthError
+		if !errors.As(err, &oerr) || oerr.Code != tt.wantCode {
+			t.Errorf("%s: Token error = %v, want %s", tt.name, err, tt.wantCode)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+// File: internal/service/pagination.go
+
+package service
+
+import "redditclone/internal/repository"
+
+// listPage fetches page with one extra row to learn whether the listing goes
+// on past it, then returns the page's rows with the cursors of the
+// neighbouring pages. A page read by offset has a Before cursor unless it is
+// the first, so clients can switch to cursors from any page.
+func listPage[T any](page repository.Page, cursorOf func(T) repository.Cursor, fetch func(repository.Page) ([]T, error)) (*repository.Listing[T], error) {
+	probe := page
+	probe.Limit++
+	rows, err := fetch(probe)
+	if err != nil {
+		return nil, err
+	}
+
+	more := len(rows) > page.Limit
+	if more && page.Backward() {
+		rows = rows[len(rows)-page.Limit:]
+	} else if more {
+		rows = rows[:page.Limit]
+	}
+
+	listing := &repository.Listing[T]{Items: rows}
+	if len(rows) == 0 {
+		return listing, nil
+	}
+	first, last := cursorOf(rows[0]), cursorOf(rows[len(rows)-1])
+	if page.Backward() {
+		listing.After = &last
+		if more {
+			listing.Before = &first
+		}
+	} else {
+		if more {
+			listing.After = &last
+		}
+		if page.After != nil || page.Offset > 0 {
+			listing.Before = &first
+		}
+	}
+	return listing, nil
+}
@@ -3,112 +3,161 @@
 package service
 
 import (
-	"errors"
+	"context"
 
+	"redditclone/internal/apperr"
 	"redditclone/internal/models"
 	"redditclone/internal/repository"
+	"redditclone/pkg/metrics"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // PostService defines the methods for post-related business logic.
 type PostService interface {
-	CreatePost(post *models.Post) error
-	GetPostByID(id int) (*models.Post, error)
-	GetPostsBySubreddit(subredditID int, limit, offset int) ([]*models.Post, error)
-	GetFeedPosts(limit, offset int) ([]*models.Post, error)
-	UpdatePost(post *models.Post) error
-	DeletePost(id int) error
+	CreatePost(ctx context.Context, post *models.Post) error
+	GetPostByID(ctx context.Context, id int) (*models.Post, error)
+	GetPostsBySubreddit(ctx context.Context, subredditID, viewerID int, page repository.Page) (*repository.Listing[*models.Post], error)
+	GetFeedPosts(ctx context.Context, viewerID int, page repository.Page) (*repository.Listing[*models.Post], error)
+	UpdatePost(ctx context.Context, post *models.Post) error
+	DeletePost(ctx context.Context, id int) error
 }
 
 type postService struct {
 	PostRepo      repository.PostRepository
 	SubredditRepo repository.SubredditRepository
 	UserRepo      repository.UserRepository
+	VoteRepo      repository.VoteRepository
 }
 
 // NewPostService creates a new PostService.
-func NewPostService(postRepo repository.PostRepository, subredditRepo repository.SubredditRepository, userRepo repository.UserRepository) PostService {
+func NewPostService(postRepo repository.PostRepository, subredditRepo repository.SubredditRepository, userRepo repository.UserRepository, voteRepo repository.VoteRepository) PostService {
 	return &postService{
 		PostRepo:      postRepo,
 		SubredditRepo: subredditRepo,
 		UserRepo:      userRepo,
+		VoteRepo:      voteRepo,
 	}
 }
 
 // CreatePost handles the creation of a new post.
-func (s *postService) CreatePost(post *models.Post) error {
+func (s *postService) CreatePost(ctx context.Context, post *models.Post) error {
+	ctx, span := tracing.Start(ctx, "PostService.CreatePost", attribute.Int("author.id", post.AuthorID), attribute.Int("subreddit.id", post.SubredditID))
+	defer span.End()
 	// Validate input
-	if post.Title == "" || post.Content == "" {
-		return errors.New("CreatePost: title and content are required")
+	fields := map[string]string{}
+	if post.Title == "" {
+		fields["title"] = "is required"
+	}
+	if post.Content == "" {
+		fields["content"] = "is required"
+	}
+	if len(fields) > 0 {
+		return apperr.Validation("CreatePost: title and content are required", fields)
 	}
 
 	// Check if author exists
-	_, err := s.UserRepo.GetUserByID(post.AuthorID)
+	author, err := activeUser(ctx, s.UserRepo, "CreatePost", post.AuthorID)
 	if err != nil {
-		return errors.New("CreatePost: author does not exist")
+		return ifNotFound(err, apperr.Validation("CreatePost: author does not exist", map[string]string{"author_id": "does not exist"}))
+	}
+	if author.SuspendedAt != nil {
+		return apperr.Forbidden("CreatePost: author is suspended")
 	}
 
 	// Check if subreddit exists
-	subreddit, err := s.SubredditRepo.GetSubredditByID(post.SubredditID)
+	subreddit, err := s.SubredditRepo.GetSubredditByID(ctx, post.SubredditID)
 	if err != nil {
-		return errors.New("CreatePost: subreddit does not exist")
+		return ifNotFound(err, apperr.NotFound("CreatePost: subreddit does not exist"))
+	}
+	if subreddit.QuarantinedAt != nil {
+		return apperr.Forbidden("CreatePost: subreddit is quarantined")
 	}
-	_=subreddit
 
 	// Optionally, check if the user has joined the subreddit
 
 	// Create the post via the repository
-	err = s.PostRepo.CreatePost(post)
+	err = s.PostRepo.CreatePost(ctx, post)
 	if err != nil {
 		return err
 	}
+	metrics.PostsCreated.Inc()
 
 	return nil
 }
 
 // GetPostByID retrieves a post by its ID.
-func (s *postService) GetPostByID(id int) (*models.Post, error) {
-	post, err := s.PostRepo.GetPostByID(id)
+func (s *postService) GetPostByID(ctx context.Context, id int) (*models.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetPostByID", attribute.Int("post.id", id))
+	defer span.End()
+	post, err := s.PostRepo.GetPostByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 	return post, nil
 }
 
-// GetPostsBySubreddit retrieves posts from a specific subreddit with pagination.
-func (s *postService) GetPostsBySubreddit(subredditID int, limit, offset int) ([]*models.Post, error) {
+// GetPostsBySubreddit retrieves a page of posts from a specific subreddit.
+// Posts by users the viewer has blocked are left out, and each post carries the viewer's vote.
+func (s *postService) GetPostsBySubreddit(ctx context.Context, subredditID, viewerID int, page repository.Page) (*repository.Listing[*models.Post], error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetPostsBySubreddit", attribute.Int("subreddit.id", subredditID), attribute.Int("viewer.id", viewerID), attribute.Int("page.limit", page.Limit))
+	defer span.End()
 	// Check if subreddit exists
-	_, err := s.SubredditRepo.GetSubredditByID(subredditID)
+	_, err := s.SubredditRepo.GetSubredditByID(ctx, subredditID)
 	if err != nil {
-		return nil, errors.New("GetPostsBySubreddit: subreddit does not exist")
+		return nil, ifNotFound(err, apperr.NotFound("GetPostsBySubreddit: subreddit does not exist"))
 	}
 
-	posts, err := s.PostRepo.GetPostsBySubreddit(subredditID, limit, offset)
+	listing, err := listPage(page, repository.PostCursor, func(page repository.Page) ([]*models.Post, error) {
+		return s.PostRepo.GetPostsBySubreddit(ctx, subredditID, viewerID, page)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return posts, nil
+	if err := annotatePostVotes(ctx, s.VoteRepo, viewerID, listing.Items); err != nil {
+		return nil, err
+	}
+	return listing, nil
 }
 
-// GetFeedPosts retrieves posts for the feed with pagination.
-func (s *postService) GetFeedPosts(limit, offset int) ([]*models.Post, error) {
-	posts, err := s.PostRepo.GetFeedPosts(limit, offset)
+// GetFeedPosts retrieves a page of the feed.
+// Posts by users the viewer has blocked are left out, and each post carries the viewer's vote.
+func (s *postService) GetFeedPosts(ctx context.Context, viewerID int, page repository.Page) (*repository.Listing[*models.Post], error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetFeedPosts", attribute.Int("viewer.id", viewerID), attribute.Int("page.limit", page.Limit))
+	defer span.End()
+	listing, err := listPage(page, repository.PostCursor, func(page repository.Page) ([]*models.Post, error) {
+		return s.PostRepo.GetFeedPosts(ctx, viewerID, page)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return posts, nil
+	if err := annotatePostVotes(ctx, s.VoteRepo, viewerID, listing.Items); err != nil {
+		return nil, err
+	}
+	return listing, nil
 }
 
 // UpdatePost updates a post's information.
-func (s *postService) UpdatePost(post *models.Post) error {
+func (s *postService) UpdatePost(ctx context.Context, post *models.Post) error {
+	ctx, span := tracing.Start(ctx, "PostService.UpdatePost", attribute.Int("post.id", post.ID))
+	defer span.End()
 	// Validate input
-	if post.Title == "" || post.Content == "" {
-		return errors.New("UpdatePost: title and content are required")
+	fields := map[string]string{}
+	if post.Title == "" {
+		fields["title"] = "is required"
+	}
+	if post.Content == "" {
+		fields["content"] = "is required"
+	}
+	if len(fields) > 0 {
+		return apperr.Validation("UpdatePost: title and content are required", fields)
 	}
 
 	// Check if post exists
-	existingPost, err := s.PostRepo.GetPostByID(post.ID)
+	existingPost, err := s.PostRepo.GetPostByID(ctx, post.ID)
 	if err != nil {
-		return errors.New("UpdatePost: post does not exist")
+		return ifNotFound(err, apperr.NotFound("UpdatePost: post does not exist"))
 	}
 
 	// Optionally, check if the user is authorized to update the post (e.g., the author)
@@ -118,7 +167,7 @@ func (s *postService) UpdatePost(post *models.Post) error {
 	existingPost.Content = post.Content
 
 	// Update the post via the repository
-	err = s.PostRepo.UpdatePost(existingPost)
+	err = s.PostRepo.UpdatePost(ctx, existingPost)
 	if err != nil {
 		return err
 	}
@@ -127,10 +176,12 @@ func (s *postService) UpdatePost(post *models.Post) error {
 }
 
 // DeletePost removes a post from the system.
-func (s *postService) DeletePost(id int) error {
+func (s *postService) DeletePost(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "PostService.DeletePost", attribute.Int("post.id", id))
+	defer span.End()
 	// Optionally, check if the user is authorized to delete the post
 
-	err := s.PostRepo.DeletePost(id)
+	err := s.PostRepo.DeletePost(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,277 @@
+// File: internal/service/session_service.go
+
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+	"redditclone/pkg/logging"
+	"redditclone/pkg/metrics"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// touchInterval is how stale a session's last-seen time may get before a
+// request updates it, so most requests do not write to the database.
+const touchInterval = time.Minute
+
+// SessionService defines login sessions: issuing and refreshing their
+// tokens, resolving access tokens, and listing and revoking sessions.
+type SessionService interface {
+	CreateSession(ctx context.Context, userID int, client models.ClientInfo) (*models.SessionTokens, error)
+	CreateImpersonationSession(ctx context.Context, userID, adminID int, client models.ClientInfo) (*models.SessionTokens, error)
+	Authenticate(ctx context.Context, accessToken string, client models.ClientInfo) (*models.Session, error)
+	Refresh(ctx context.Context, refreshToken string, client models.ClientInfo) (*models.SessionTokens, error)
+	ListSessions(ctx context.Context, userID int) ([]*models.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID int, reason string) error
+	RevokeOtherSessions(ctx context.Context, userID int, reason string) (int, error)
+	CountActiveSessions(ctx context.Context) (int, error)
+}
+
+// SessionConfig configures session lifetimes.
+type SessionConfig struct {
+	AccessTTL  time.Duration // How long an access token works before it must be refreshed.
+	RefreshTTL time.Duration // How long a session lasts without being refreshed.
+}
+
+type sessionService struct {
+	SessionRepo repository.SessionRepository
+	Config      SessionConfig
+}
+
+// NewSessionService creates a new SessionService.
+func NewSessionService(sessionRepo repository.SessionRepository, cfg SessionConfig) SessionService {
+	return &sessionService{SessionRepo: sessionRepo, Config: cfg}
+}
+
+type sessionKey struct{}
+
+// WithSession returns a copy of ctx carrying the session the request was
+// authenticated with.
+func WithSession(ctx context.Context, session *models.Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// SessionFromContext returns the session stored by WithSession, or nil for
+// requests that did not present an access token.
+func SessionFromContext(ctx context.Context) *models.Session {
+	session, _ := ctx.Value(sessionKey{}).(*models.Session)
+	return session
+}
+
+// CreateSession starts a session for a user who just logged in and returns
+// its first tokens.
+func (s *sessionService) CreateSession(ctx context.Context, userID int, client models.ClientInfo) (*models.SessionTokens, error) {
+	ctx, span := tracing.Start(ctx, "SessionService.CreateSession", attribute.Int("user.id", userID))
+	defer span.End()
+	session := &models.Session{UserID: userID, UserAgent: client.UserAgent, IP: client.IP}
+	accessToken, refreshToken, err := s.issueTokens(session)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.SessionRepo.CreateSession(ctx, session, hashToken(refreshToken)); err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("session created", "user_id", userID, "session_id", session.ID)
+	s.updateActiveGauge(ctx)
+	return sessionTokens(session, accessToken, refreshToken), nil
+}
+
+// CreateImpersonationSession starts a session in which an admin acts as a
+// user for support. It lasts as long as one access token and cannot be
+// refreshed, so no refresh token is returned.
+func (s *sessionService) CreateImpersonationSession(ctx context.Context, userID, adminID int, client models.ClientInfo) (*models.SessionTokens, error) {
+	ctx, span := tracing.Start(ctx, "SessionService.CreateImpersonationSession", attribute.Int("user.id", userID), attribute.Int("admin.id", adminID))
+	defer span.End()
+	session := &models.Session{UserID: userID, UserAgent: client.UserAgent, IP: client.IP, ImpersonatorID: &adminID}
+	accessToken, refreshToken, err := s.issueTokens(session)
+	if err != nil {
+		return nil, err
+	}
+	session.ExpiresAt = session.AccessExpiresAt
+	if err := s.SessionRepo.CreateSession(ctx, session, hashToken(refreshToken)); err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("impersonation session created", "user_id", userID, "admin_id", adminID, "session_id", session.ID)
+	s.updateActiveGauge(ctx)
+	return sessionTokens(session, accessToken, ""), nil
+}
+
+// Authenticate resolves an access token to its active session. Unknown,
+// expired and revoked tokens are apperr.Unauthorized.
+func (s *sessionService) Authenticate(ctx context.Context, accessToken string, client models.ClientInfo) (*models.Session, error) {
+	ctx, span := tracing.Start(ctx, "SessionService.Authenticate")
+	defer span.End()
+	now := time.Now()
+	session, err := s.SessionRepo.GetSessionByAccessToken(ctx, hashToken(accessToken), now)
+	if err != nil {
+		return nil, ifNotFound(err, apperr.Unauthorized("Authenticate: access token is invalid or has expired"))
+	}
+	span.SetAttributes(attribute.Int("session.id", session.ID), attribute.Int("user.id", session.UserID))
+
+	if now.Sub(session.LastSeenAt) >= touchInterval || session.IP != client.IP {
+		if err := s.SessionRepo.TouchSession(ctx, session.ID, client.IP); err != nil {
+			logging.FromContext(ctx).Warn("failed to update session last seen", "session_id", session.ID, "error", err.Error())
+		}
+	}
+	return session, nil
+}
+
+// Refresh exchanges a refresh token for new tokens, rotating both. Each
+// refresh token works once: if a used one comes back, either the client or
+// an attacker holds a stolen copy, so the whole session is revoked.
+func (s *sessionService) Refresh(ctx context.Context, refreshToken string, client models.ClientInfo) (*models.SessionTokens, error) {
+	ctx, span := tracing.Start(ctx, "SessionService.Refresh")
+	defer span.End()
+	invalid := apperr.Unauthorized("Refresh: refresh token is invalid or has expired")
+	if refreshToken == "" {
+		return nil, apperr.Validation("Refresh: refresh token is required", map[string]string{"refresh_token": "is required"})
+	}
+
+	sessionID, err := s.SessionRepo.UseRefreshToken(ctx, hashToken(refreshToken))
+	if errors.Is(err, apperr.ErrConflict) {
+		return nil, s.revokeReusedSession(ctx, sessionID, client)
+	}
+	if err != nil {
+		return nil, ifNotFound(err, invalid)
+	}
+	session, err := s.SessionRepo.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return nil, ifNotFound(err, invalid)
+	}
+	if session.RevokedAt != nil || !session.ExpiresAt.After(time.Now()) || session.ImpersonatorID != nil {
+		return nil, invalid
+	}
+
+	session.IP = client.IP
+	accessToken, newRefreshToken, err := s.issueTokens(session)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.SessionRepo.RenewSession(ctx, session, hashToken(newRefreshToken)); err != nil {
+		return nil, ifNotFound(err, invalid)
+	}
+	return sessionTokens(session, accessToken, newRefreshToken), nil
+}
+
+// revokeReusedSession revokes the session a replayed refresh token belongs to
+// and returns the error for the caller.
+func (s *sessionService) revokeReusedSession(ctx context.Context, sessionID int, client models.ClientInfo) error {
+	session, err := s.SessionRepo.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Warn("refresh token reused; revoking session",
+		"user_id", session.UserID, "session_id", session.ID, "ip", client.IP)
+	err = s.SessionRepo.RevokeSession(ctx, session.UserID, session.ID, models.RevokedTokenReuse)
+	if err != nil && !errors.Is(err, apperr.ErrNotFound) {
+		return err
+	}
+	s.updateActiveGauge(ctx)
+	return apperr.Unauthorized("Refresh: refresh token was already used; the session has been revoked")
+}
+
+// ListSessions retrieves a user's active sessions, most recently used first,
+// marking the one making the request as current.
+func (s *sessionService) ListSessions(ctx context.Context, userID int) ([]*models.Session, error) {
+	ctx, span := tracing.Start(ctx, "SessionService.ListSessions", attribute.Int("user.id", userID))
+	defer span.End()
+	sessions, err := s.SessionRepo.GetSessionsByUser(ctx, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if current := SessionFromContext(ctx); current != nil {
+		for _, session := range sessions {
+			session.Current = session.ID == current.ID
+		}
+	}
+	return sessions, nil
+}
+
+// RevokeSession revokes one of a user's sessions; its tokens stop working
+// immediately.
+func (s *sessionService) RevokeSession(ctx context.Context, userID, sessionID int, reason string) error {
+	ctx, span := tracing.Start(ctx, "SessionService.RevokeSession", attribute.Int("user.id", userID), attribute.Int("session.id", sessionID))
+	defer span.End()
+	if err := s.SessionRepo.RevokeSession(ctx, userID, sessionID, reason); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("session revoked", "user_id", userID, "session_id", sessionID, "reason", reason)
+	s.updateActiveGauge(ctx)
+	return nil
+}
+
+// RevokeOtherSessions revokes all of a user's sessions except the one making
+// the request, or all of them when the request has no session, and returns
+// how many it revoked.
+func (s *sessionService) RevokeOtherSessions(ctx context.Context, userID int, reason string) (int, error) {
+	ctx, span := tracing.Start(ctx, "SessionService.RevokeOtherSessions", attribute.Int("user.id", userID))
+	defer span.End()
+	exceptID := 0
+	if current := SessionFromContext(ctx); current != nil && current.UserID == userID {
+		exceptID = current.ID
+	}
+	revoked, err := s.SessionRepo.RevokeUserSessions(ctx, userID, exceptID, reason)
+	if err != nil {
+		return 0, err
+	}
+	if revoked > 0 {
+		logging.FromContext(ctx).Info("sessions revoked", "user_id", userID, "count", revoked, "reason", reason)
+		s.updateActiveGauge(ctx)
+	}
+	return revoked, nil
+}
+
+// CountActiveSessions returns the number of live sessions across all users
+// and publishes it as the sessions_active metric.
+func (s *sessionService) CountActiveSessions(ctx context.Context) (int, error) {
+	count, err := s.SessionRepo.CountActiveSessions(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	metrics.SessionsActive.Set(float64(count))
+	return count, nil
+}
+
+// updateActiveGauge refreshes the sessions_active metric after sessions were
+// created or revoked. Sessions that simply expire drop out at the next update.
+func (s *sessionService) updateActiveGauge(ctx context.Context) {
+	if _, err := s.CountActiveSessions(ctx); err != nil {
+		logging.FromContext(ctx).Warn("failed to count active sessions", "error", err.Error())
+	}
+}
+
+// issueTokens generates a new access and refresh token for session, setting
+// its access token hash and expiry times.
+func (s *sessionService) issueTokens(session *models.Session) (string, string, error) {
+	accessToken, err := newToken()
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, err := newToken()
+	if err != nil {
+		return "", "", err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	session.AccessTokenHash = hashToken(accessToken)
+	session.AccessExpiresAt = now.Add(s.Config.AccessTTL)
+	session.ExpiresAt = now.Add(s.Config.RefreshTTL)
+	return accessToken, refreshToken, nil
+}
+
+func sessionTokens(session *models.Session, accessToken, refreshToken string) *models.SessionTokens {
+	return &models.SessionTokens{
+		SessionID:             session.ID,
+		TokenType:             "Bearer",
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  session.AccessExpiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: session.ExpiresAt,
+	}
+}
@@ -0,0 +1,66 @@
+// File: internal/service/session_service_test.go
+
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository/memory"
+)
+
+// TestRefreshReuse replays a refresh token that was already exchanged, which
+// must revoke the whole session, including the tokens issued in exchange.
+func TestRefreshReuse(t *testing.T) {
+	ctx := context.Background()
+	repos := memory.NewRepositories()
+	user := &models.User{Username: "ann", Email: "ann@example.com", Password: "hash"}
+	if err := repos.Users.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	sessions := NewSessionService(repos.Sessions, SessionConfig{AccessTTL: time.Hour, RefreshTTL: 24 * time.Hour})
+	client := models.ClientInfo{IP: "10.0.0.1", UserAgent: "test"}
+
+	first, err := sessions.CreateSession(ctx, user.ID, client)
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	second, err := sessions.Refresh(ctx, first.RefreshToken, client)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if second.SessionID != first.SessionID || second.RefreshToken == first.RefreshToken {
+		t.Fatalf("Refresh = %+v, want new tokens for session %d", second, first.SessionID)
+	}
+
+	tests := []struct {
+		name    string
+		refresh string
+		wantMsg string
+	}{
+		{"replayed token", first.RefreshToken, "Refresh: refresh token was already used; the session has been revoked"},
+		{"token from the revoked session", second.RefreshToken, "Refresh: refresh token is invalid or has expired"},
+		{"unknown token", "nope", "Refresh: refresh token is invalid or has expired"},
+	}
+	for _, tt := range tests {
+		_, err := sessions.Refresh(ctx, tt.refresh, client)
+		if !errors.Is(err, apperr.ErrUnauthorized) || err.Error() != tt.wantMsg {
+			t.Errorf("%s: Refresh error = %v, want %q", tt.name, err, tt.wantMsg)
+		}
+	}
+
+	if _, err := sessions.Authenticate(ctx, second.AccessToken, client); !errors.Is(err, apperr.ErrUnauthorized) {
+		t.Errorf("Authenticate with the revoked session's access token: error = %v, want unauthorized", err)
+	}
+	session, err := repos.Sessions.GetSessionByID(ctx, first.SessionID)
+	if err != nil {
+		t.Fatalf("GetSessionByID: %v", err)
+	}
+	if session.RevokedAt == nil || session.RevokeReason != models.RevokedTokenReuse {
+		t.Errorf("session = %+v, want revoked for token reuse", session)
+	}
+}
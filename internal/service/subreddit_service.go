@@ -3,48 +3,64 @@
 package service
 
 import (
-	"errors"
+	"context"
 
+	"redditclone/internal/apperr"
 	"redditclone/internal/models"
 	"redditclone/internal/repository"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // SubredditService defines the methods for subreddit-related business logic.
 type SubredditService interface {
-	CreateSubreddit(subreddit *models.Subreddit) error
-	GetSubredditByID(id int) (*models.Subreddit, error)
-	GetSubredditByName(name string) (*models.Subreddit, error)
-	UpdateSubreddit(subreddit *models.Subreddit) error
-	DeleteSubreddit(id int) error
-	JoinSubreddit(userID, subredditID int) error
-	LeaveSubreddit(userID, subredditID int) error
+	CreateSubreddit(ctx context.Context, subreddit *models.Subreddit) error
+	GetSubredditByID(ctx context.Context, id int) (*models.Subreddit, error)
+	GetSubredditByName(ctx context.Context, name string) (*models.Subreddit, error)
+	UpdateSubreddit(ctx context.Context, subreddit *models.Subreddit) error
+	DeleteSubreddit(ctx context.Context, id int) error
+	JoinSubreddit(ctx context.Context, userID, subredditID int) error
+	LeaveSubreddit(ctx context.Context, userID, subredditID int) error
 }
 
 type subredditService struct {
 	SubredditRepo repository.SubredditRepository
+	UserRepo      repository.UserRepository
 	// Add additional repositories if necessary, e.g., for managing memberships
 }
 
 // NewSubredditService creates a new SubredditService.
-func NewSubredditService(subredditRepo repository.SubredditRepository) SubredditService {
-	return &subredditService{SubredditRepo: subredditRepo}
+func NewSubredditService(subredditRepo repository.SubredditRepository, userRepo repository.UserRepository) SubredditService {
+	return &subredditService{SubredditRepo: subredditRepo, UserRepo: userRepo}
 }
 
 // CreateSubreddit handles the creation of a new subreddit.
-func (s *subredditService) CreateSubreddit(subreddit *models.Subreddit) error {
+func (s *subredditService) CreateSubreddit(ctx context.Context, subreddit *models.Subreddit) error {
+	ctx, span := tracing.Start(ctx, "SubredditService.CreateSubreddit")
+	defer span.End()
 	// Validate input
 	if subreddit.Name == "" {
-		return errors.New("CreateSubreddit: name is required")
+		return apperr.Validation("CreateSubreddit: name is required", map[string]string{"name": "is required"})
+	}
+
+	// Suspended users cannot create subreddits
+	creator, err := activeUser(ctx, s.UserRepo, "CreateSubreddit", subreddit.CreatedBy)
+	if err != nil {
+		return ifNotFound(err, apperr.Validation("CreateSubreddit: creator does not exist", map[string]string{"created_by": "does not exist"}))
+	}
+	if creator.SuspendedAt != nil {
+		return apperr.Forbidden("CreateSubreddit: creator is suspended")
 	}
 
 	// Check if subreddit name already exists
-	existingSubreddit, err := s.SubredditRepo.GetSubredditByName(subreddit.Name)
+	existingSubreddit, err := s.SubredditRepo.GetSubredditByName(ctx, subreddit.Name)
 	if err == nil && existingSubreddit != nil {
-		return errors.New("CreateSubreddit: subreddit name already exists")
+		return apperr.Conflict("CreateSubreddit: subreddit name already exists")
 	}
 
 	// Create the subreddit via the repository
-	err = s.SubredditRepo.CreateSubreddit(subreddit)
+	err = s.SubredditRepo.CreateSubreddit(ctx, subreddit)
 	if err != nil {
 		return err
 	}
@@ -53,8 +69,10 @@ func (s *subredditService) CreateSubreddit(subreddit *models.Subreddit) error {
 }
 
 // GetSubredditByID retrieves a subreddit by its ID.
-func (s *subredditService) GetSubredditByID(id int) (*models.Subreddit, error) {
-	subreddit, err := s.SubredditRepo.GetSubredditByID(id)
+func (s *subredditService) GetSubredditByID(ctx context.Context, id int) (*models.Subreddit, error) {
+	ctx, span := tracing.Start(ctx, "SubredditService.GetSubredditByID", attribute.Int("subreddit.id", id))
+	defer span.End()
+	subreddit, err := s.SubredditRepo.GetSubredditByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -62,8 +80,10 @@ func (s *subredditService) GetSubredditByID(id int) (*models.Subreddit, error) {
 }
 
 // GetSubredditByName retrieves a subreddit by its name.
-func (s *subredditService) GetSubredditByName(name string) (*models.Subreddit, error) {
-	subreddit, err := s.SubredditRepo.GetSubredditByName(name)
+func (s *subredditService) GetSubredditByName(ctx context.Context, name string) (*models.Subreddit, error) {
+	ctx, span := tracing.Start(ctx, "SubredditService.GetSubredditByName")
+	defer span.End()
+	subreddit, err := s.SubredditRepo.GetSubredditByName(ctx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -71,28 +91,30 @@ func (s *subredditService) GetSubredditByName(name string) (*models.Subreddit, e
 }
 
 // UpdateSubreddit updates a subreddit's information.
-func (s *subredditService) UpdateSubreddit(subreddit *models.Subreddit) error {
+func (s *subredditService) UpdateSubreddit(ctx context.Context, subreddit *models.Subreddit) error {
+	ctx, span := tracing.Start(ctx, "SubredditService.UpdateSubreddit", attribute.Int("subreddit.id", subreddit.ID))
+	defer span.End()
 	// Validate input
 	if subreddit.Name == "" {
-		return errors.New("UpdateSubreddit: name is required")
+		return apperr.Validation("UpdateSubreddit: name is required", map[string]string{"name": "is required"})
 	}
 
 	// Check if subreddit exists
-	existingSubreddit, err := s.SubredditRepo.GetSubredditByID(subreddit.ID)
+	existingSubreddit, err := s.SubredditRepo.GetSubredditByID(ctx, subreddit.ID)
 	if err != nil {
 		return err
 	}
 
 	// Optionally, check if the new name is already taken by another subreddit
 	if existingSubreddit.Name != subreddit.Name {
-		anotherSubreddit, err := s.SubredditRepo.GetSubredditByName(subreddit.Name)
+		anotherSubreddit, err := s.SubredditRepo.GetSubredditByName(ctx, subreddit.Name)
 		if err == nil && anotherSubreddit != nil {
-			return errors.New("UpdateSubreddit: new subreddit name already exists")
+			return apperr.Conflict("UpdateSubreddit: new subreddit name already exists")
 		}
 	}
 
 	// Update the subreddit via the repository
-	err = s.SubredditRepo.UpdateSubreddit(subreddit)
+	err = s.SubredditRepo.UpdateSubreddit(ctx, subreddit)
 	if err != nil {
 		return err
 	}
@@ -101,9 +123,11 @@ func (s *subredditService) UpdateSubreddit(subreddit *models.Subreddit) error {
 }
 
 // DeleteSubreddit removes a subreddit from the system.
-func (s *subredditService) DeleteSubreddit(id int) error {
+func (s *subredditService) DeleteSubreddit(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "SubredditService.DeleteSubreddit", attribute.Int("subreddit.id", id))
+	defer span.End()
 	// Perform any additional checks if necessary
-	err := s.SubredditRepo.DeleteSubreddit(id)
+	err := s.SubredditRepo.DeleteSubreddit(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -112,13 +136,15 @@ func (s *subredditService) DeleteSubreddit(id int) error {
 
 // JoinSubreddit allows a user to join a subreddit.
 // TODO: Implement membership management if required.
-func (s *subredditService) JoinSubreddit(userID, subredditID int) error {
+func (s *subredditService) JoinSubreddit(ctx context.Context, userID, subredditID int) error {
+	ctx, span := tracing.Start(ctx, "SubredditService.JoinSubreddit", attribute.Int("user.id", userID), attribute.Int("subreddit.id", subredditID))
+	defer span.End()
 	// Placeholder for joining logic.
 	// For simplicity, assuming no separate membership table.
 	// In a real application, you'd manage a memberships table.
 
 	// Example: Check if subreddit exists
-	_, err := s.SubredditRepo.GetSubredditByID(subredditID)
+	_, err := s.SubredditRepo.GetSubredditByID(ctx, subredditID)
 	if err != nil {
 		return err
 	}
@@ -130,13 +156,15 @@ func (s *subredditService) JoinSubreddit(userID, subredditID int) error {
 
 // LeaveSubreddit allows a user to leave a subreddit.
 // TODO: Implement membership management if required.
-func (s *subredditService) LeaveSubreddit(userID, subredditID int) error {
+func (s *subredditService) LeaveSubreddit(ctx context.Context, userID, subredditID int) error {
+	ctx, span := tracing.Start(ctx, "SubredditService.LeaveSubreddit", attribute.Int("user.id", userID), attribute.Int("subreddit.id", subredditID))
+	defer span.End()
 	// Placeholder for leaving logic.
 	// For simplicity, assuming no separate membership table.
 	// In a real application, you'd manage a memberships table.
 
 	// Example: Check if subreddit exists
-	_, err := s.SubredditRepo.GetSubredditByID(subredditID)
+	_, err := s.SubredditRepo.GetSubredditByID(ctx, subredditID)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,24 @@
+// File: internal/service/tracing.go
+
+package service
+
+import (
+	"redditclone/internal/models"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// voteAttrs describes a vote and its target for a span.
+func voteAttrs(vote *models.Vote) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.Int("user.id", vote.UserID),
+		attribute.String("vote.type", vote.VoteType),
+	}
+	if vote.PostID != nil {
+		attrs = append(attrs, attribute.Int("post.id", *vote.PostID))
+	}
+	if vote.CommentID != nil {
+		attrs = append(attrs, attribute.Int("comment.id", *vote.CommentID))
+	}
+	return attrs
+}
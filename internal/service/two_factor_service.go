@@ -0,0 +1,383 @@
+// File: internal/service/two_factor_service.go
+
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+	"redditclone/pkg/logging"
+	"redditclone/pkg/secretbox"
+	"redditclone/pkg/totp"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// recoveryCodeCount is how many recovery codes a user gets at a time.
+const recoveryCodeCount = 10
+
+// totpSkew is how many 30 second steps a code may be off by either way, to
+// allow for clock drift and slow typing.
+const totpSkew = 1
+
+// TwoFactorService defines TOTP two-factor authentication: enrolling,
+// recovery codes, and the second step of logging in.
+type TwoFactorService interface {
+	GetStatus(ctx context.Context, userID int) (*models.TwoFactorStatus, error)
+	BeginEnrollment(ctx context.Context, userID int) (*models.TwoFactorEnrollment, error)
+	ConfirmEnrollment(ctx context.Context, userID int, code string) ([]string, error)
+	Disable(ctx context.Context, userID int, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error)
+	NewChallenge(userID int) (*models.TwoFactorChallenge, error)
+	CheckChallenge(token string) (int, error)
+	CheckCode(ctx context.Context, userID int, code string) (bool, error)
+}
+
+// TwoFactorConfig configures two-factor authentication.
+type TwoFactorConfig struct {
+	Issuer       string         // Name authenticator apps show for the account.
+	Box          *secretbox.Box // Encrypts TOTP secrets at rest; nil turns enrollment off.
+	ChallengeTTL time.Duration  // How long a login challenge stays valid.
+}
+
+type twoFactorService struct {
+	UserRepo         repository.UserRepository
+	RecoveryCodeRepo repository.RecoveryCodeRepository
+	Config           TwoFactorConfig
+	challengeKey     []byte
+}
+
+// NewTwoFactorService creates a new TwoFactorService. Login challenges are
+// signed with a key derived from the TOTP encryption key.
+func NewTwoFactorService(userRepo repository.UserRepository, recoveryCodeRepo repository.RecoveryCodeRepository, cfg TwoFactorConfig) TwoFactorService {
+	s := &twoFactorService{
+		UserRepo:         userRepo,
+		RecoveryCodeRepo: recoveryCodeRepo,
+		Config:           cfg,
+	}
+	if cfg.Box != nil {
+		s.challengeKey = cfg.Box.DeriveKey("two-factor login challenge")
+	}
+	return s
+}
+
+// errTwoFactorUnavailable is returned when no TOTP encryption key is configured.
+var errTwoFactorUnavailable = apperr.BadRequest("two-factor authentication is not configured on this server")
+
+// GetStatus reports whether a user has two-factor authentication on and how
+// many recovery codes they have left.
+func (s *twoFactorService) GetStatus(ctx context.Context, userID int) (*models.TwoFactorStatus, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.GetStatus", attribute.Int("user.id", userID))
+	defer span.End()
+	tf, err := s.UserRepo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &models.TwoFactorStatus{Enabled: tf.EnabledAt != nil, EnabledAt: tf.EnabledAt}
+	if status.Enabled {
+		if status.RecoveryCodesRemaining, err = s.RecoveryCodeRepo.CountRecoveryCodes(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// BeginEnrollment generates a new TOTP secret for a user and returns it with
+// its provisioning URI. Two-factor authentication stays off until the user
+// confirms a code from it; starting again replaces the pending secret.
+func (s *twoFactorService) BeginEnrollment(ctx context.Context, userID int) (*models.TwoFactorEnrollment, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.BeginEnrollment", attribute.Int("user.id", userID))
+	defer span.End()
+	if s.Config.Box == nil {
+		return nil, errTwoFactorUnavailable
+	}
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, apperr.Conflict("BeginEnrollment: two-factor authentication is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.Config.Box.Seal(secret, secretContext(userID))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.UserRepo.SetTOTPSecret(ctx, userID, sealed); err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorEnrollment{
+		Secret: secret,
+		URI:    totp.ProvisioningURI(s.Config.Issuer, user.Username, secret),
+	}, nil
+}
+
+// ConfirmEnrollment turns two-factor authentication on once the user proves
+// their authenticator works by entering a current code. It returns the
+// user's recovery codes, which are shown only this once.
+func (s *twoFactorService) ConfirmEnrollment(ctx context.Context, userID int, code string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.ConfirmEnrollment", attribute.Int("user.id", userID))
+	defer span.End()
+	if code == "" {
+		return nil, apperr.Validation("ConfirmEnrollment: code is required", map[string]string{"code": "is required"})
+	}
+	tf, err := s.UserRepo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf.EnabledAt != nil {
+		return nil, apperr.Conflict("ConfirmEnrollment: two-factor authentication is already enabled")
+	}
+	if tf.Secret == "" {
+		return nil, apperr.Conflict("ConfirmEnrollment: no pending two-factor enrollment")
+	}
+	secret, err := s.openSecret(tf)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := totp.Validate(secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, apperr.Validation("ConfirmEnrollment: code is incorrect", map[string]string{"code": "is incorrect"})
+	}
+	if err := s.UserRepo.EnableTOTP(ctx, userID, step); err != nil {
+		return nil, err
+	}
+	codes, err := s.replaceRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("two-factor authentication enabled", "user_id", userID)
+	return codes, nil
+}
+
+// Disable turns two-factor authentication off, given a current code from the
+// authenticator app, and discards the recovery codes. Recovery codes are not
+// accepted: one that leaked must not be enough to switch 2FA off.
+func (s *twoFactorService) Disable(ctx context.Context, userID int, code string) error {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.Disable", attribute.Int("user.id", userID))
+	defer span.End()
+	if err := s.requireCode(ctx, "Disable", userID, code, false); err != nil {
+		return err
+	}
+	if err := s.UserRepo.DisableTOTP(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.RecoveryCodeRepo.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("two-factor authentication disabled", "user_id", userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces a user's recovery codes, given a current
+// code or an unused recovery code, and returns the new ones.
+func (s *twoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.RegenerateRecoveryCodes", attribute.Int("user.id", userID))
+	defer span.End()
+	if err := s.requireCode(ctx, "RegenerateRecoveryCodes", userID, code, true); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(ctx, userID)
+}
+
+// requireCode checks that two-factor authentication is on and code is valid
+// for it, for changes to the settings. Recovery codes count only if
+// allowRecovery is set; otherwise it takes a current TOTP code.
+func (s *twoFactorService) requireCode(ctx context.Context, op string, userID int, code string, allowRecovery bool) error {
+	if code == "" {
+		return apperr.Validation(op+": code is required", map[string]string{"code": "is required"})
+	}
+	tf, err := s.UserRepo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if tf.EnabledAt == nil {
+		return apperr.Conflict(op + ": two-factor authentication is not enabled")
+	}
+	var ok bool
+	if allowRecovery {
+		ok, err = s.CheckCode(ctx, userID, code)
+	} else {
+		ok, err = s.checkTOTP(ctx, tf, strings.TrimSpace(code))
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return apperr.Validation(op+": code is incorrect", map[string]string{"code": "is incorrect"})
+	}
+	return nil
+}
+
+// CheckCode reports whether code lets the user in: either the current TOTP
+// code, not used before, or one of their unused recovery codes, which is
+// then used up. A user without two-factor authentication has no valid codes.
+func (s *twoFactorService) CheckCode(ctx context.Context, userID int, code string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.CheckCode", attribute.Int("user.id", userID))
+	defer span.End()
+	tf, err := s.UserRepo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if tf.EnabledAt == nil {
+		return false, nil
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		return s.checkTOTP(ctx, tf, code)
+	}
+
+	err = s.RecoveryCodeRepo.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(code)))
+	if errors.Is(err, apperr.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	remaining, err := s.RecoveryCodeRepo.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	logging.FromContext(ctx).Warn("recovery code used", "user_id", userID, "remaining", remaining)
+	return true, nil
+}
+
+// checkTOTP reports whether code is the current TOTP code for tf and was not
+// used before, recording its time step so it cannot be replayed.
+func (s *twoFactorService) checkTOTP(ctx context.Context, tf *models.TwoFactor, code string) (bool, error) {
+	secret, err := s.openSecret(tf)
+	if err != nil {
+		return false, err
+	}
+	step, ok := totp.Validate(secret, code, time.Now(), totpSkew)
+	if !ok {
+		return false, nil
+	}
+	err = s.UserRepo.UseTOTPStep(ctx, tf.UserID, step)
+	if errors.Is(err, apperr.ErrConflict) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// NewChallenge issues a signed login challenge for a user whose password was
+// correct.
+func (s *twoFactorService) NewChallenge(userID int) (*models.TwoFactorChallenge, error) {
+	if s.challengeKey == nil {
+		return nil, fmt.Errorf("NewChallenge: user %d has two-factor authentication on but no TOTP key is configured", userID)
+	}
+	expiresAt := time.Now().Add(s.Config.ChallengeTTL).UTC().Truncate(time.Second)
+	payload := strconv.Itoa(userID) + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	token := base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(s.signChallenge(payload))
+	return &models.TwoFactorChallenge{Token: token, ExpiresAt: expiresAt}, nil
+}
+
+// CheckChallenge verifies a login challenge and returns the user it was
+// issued to. Tampered and expired challenges are apperr.Unauthorized.
+func (s *twoFactorService) CheckChallenge(token string) (int, error) {
+	invalid := apperr.Unauthorized("CheckChallenge: login challenge is invalid or has expired")
+	if s.challengeKey == nil {
+		return 0, invalid
+	}
+	encodedPayload, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return 0, invalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return 0, invalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil || !hmac.Equal(mac, s.signChallenge(string(payload))) {
+		return 0, invalid
+	}
+	userPart, expiresPart, _ := strings.Cut(string(payload), ".")
+	userID, err := strconv.Atoi(userPart)
+	if err != nil {
+		return 0, invalid
+	}
+	expires, err := strconv.ParseInt(expiresPart, 10, 64)
+	if err != nil || time.Now().Unix() >= expires {
+		return 0, invalid
+	}
+	return userID, nil
+}
+
+func (s *twoFactorService) signChallenge(payload string) []byte {
+	mac := hmac.New(sha256.New, s.challengeKey)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// openSecret decrypts a user's stored TOTP secret.
+func (s *twoFactorService) openSecret(tf *models.TwoFactor) (string, error) {
+	if s.Config.Box == nil {
+		return "", fmt.Errorf("openSecret: user %d has a TOTP secret but no TOTP key is configured", tf.UserID)
+	}
+	secret, err := s.Config.Box.Open(tf.Secret, secretContext(tf.UserID))
+	if err != nil {
+		return "", fmt.Errorf("openSecret: %v", err)
+	}
+	return secret, nil
+}
+
+// replaceRecoveryCodes generates a fresh set of recovery codes for a user,
+// storing only their hashes, and returns them.
+func (s *twoFactorService) replaceRecoveryCodes(ctx context.Context, userID int) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		hashes[i] = hashToken(normalizeRecoveryCode(code))
+	}
+	if err := s.RecoveryCodeRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// secretContext binds an encrypted TOTP secret to its user, so a secret
+// copied to another row does not decrypt.
+func secretContext(userID int) string {
+	return "totp:user:" + strconv.Itoa(userID)
+}
+
+// newRecoveryCode returns a random 80-bit code in groups of four base32
+// characters, e.g. "ABCD-EFGH-IJKL-MNOP".
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	s := base32.StdEncoding.EncodeToString(b)
+	return s[0:4] + "-" + s[4:8] + "-" + s[8:12] + "-" + s[12:16], nil
+}
+
+// normalizeRecoveryCode makes recovery codes match however they were typed:
+// in any case, with or without dashes and spaces.
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToUpper(code))
+}
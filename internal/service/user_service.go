@@ -3,67 +3,160 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"redditclone/internal/apperr"
 	"redditclone/internal/models"
 	"redditclone/internal/repository"
+	"redditclone/pkg/logging"
+	"redditclone/pkg/metrics"
+	"redditclone/pkg/tracing"
 
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // UserService defines the methods for user-related business logic.
 type UserService interface {
-	RegisterUser(user *models.User) error
-	GetUserProfile(id int) (*models.User, error)
-	UpdateUserProfile(user *models.User) error
-	DeleteUser(id int) error
-	AuthenticateUser(username, password string) (*models.User, error)
+	RegisterUser(ctx context.Context, user *models.User) error
+	GetUserProfile(ctx context.Context, id int) (*models.User, error)
+	GetPublicProfile(ctx context.Context, id int) (*models.UserProfile, error)
+	GetPrivateProfile(ctx context.Context, id int) (*models.PrivateUserProfile, error)
+	UpdateUserProfile(ctx context.Context, user *models.User, currentPassword string) error
+	SetBot(ctx context.Context, userID int, isBot bool) error
+	AuthenticateUser(ctx context.Context, username, password string, client models.ClientInfo) (*models.LoginResult, error)
+	CompleteTwoFactorLogin(ctx context.Context, challenge, code string, client models.ClientInfo) (*models.LoginResult, error)
+	GetLoginHistory(ctx context.Context, userID int, limit, offset int) ([]*models.LoginAttempt, error)
+	BlockUser(ctx context.Context, blockerID, blockedID int) (*models.Block, error)
+	UnblockUser(ctx context.Context, blockerID, blockedID int) error
+	GetBlockedUsers(ctx context.Context, blockerID int, limit, offset int) ([]*models.Block, error)
+}
+
+// LoginPolicy throttles repeated failed logins. Failures are counted per
+// username, until its next successful login, and per client IP, in both cases
+// over the last Window. Once a count passes its free failures, each attempt
+// must wait Backoff after the latest failure, doubling with every further
+// failure; at the lockout threshold the wait becomes LockoutDuration.
+type LoginPolicy struct {
+	Window            time.Duration // Failures older than this are forgotten.
+	FreeFailures      int           // Failures on a username before backoff starts.
+	Backoff           time.Duration // Wait after the first failure past the free ones.
+	LockoutFailures   int           // Failures on a username that lock it.
+	LockoutDuration   time.Duration // Wait once locked.
+	IPFreeFailures    int           // Failures from one IP, on any username, before backoff starts.
+	IPLockoutFailures int           // Failures from one IP, on any username, that lock it out.
+}
+
+// wait returns how long a client with the given failures must wait before
+// its next attempt, and whether the wait is a lockout.
+func (p LoginPolicy) wait(failures *models.LoginFailures, free, lockout int, now time.Time) (time.Duration, bool) {
+	if failures.Count < free {
+		return 0, false
+	}
+	delay, locked := p.LockoutDuration, true
+	if failures.Count < lockout {
+		locked = false
+		delay = p.Backoff
+		for i := free; i < failures.Count && delay < p.LockoutDuration; i++ {
+			delay *= 2
+		}
+		delay = min(delay, p.LockoutDuration)
+	}
+	if remaining := failures.Last.Add(delay).Sub(now); remaining > 0 {
+		return remaining, locked
+	}
+	return 0, false
 }
 
 type userService struct {
-	UserRepo repository.UserRepository
+	UserRepo         repository.UserRepository
+	BlockRepo        repository.BlockRepository
+	LoginAttemptRepo repository.LoginAttemptRepository
+	Accounts         AccountService
+	TwoFactor        TwoFactorService
+	Sessions         SessionService
+	BcryptCost       int
+	LoginPolicy      LoginPolicy
+	DummyHash        string // Checked instead of a password when the user is unknown.
 }
 
-// NewUserService creates a new UserService.
-func NewUserService(userRepo repository.UserRepository) UserService {
-	return &userService{UserRepo: userRepo}
+// NewUserService creates a new UserService. New passwords are hashed with
+// bcryptCost, failed logins are throttled according to loginPolicy, new
+// email addresses are verified through accounts, logins take a second factor
+// through twoFactor for users who turned it on, and successful logins start a
+// session through sessions.
+func NewUserService(userRepo repository.UserRepository, blockRepo repository.BlockRepository, loginAttemptRepo repository.LoginAttemptRepository, accounts AccountService, twoFactor TwoFactorService, sessions SessionService, bcryptCost int, loginPolicy LoginPolicy) UserService {
+	// The configuration is validated, so bcryptCost is always in range
+	dummyHash, _ := hashPassword("not a password", bcryptCost)
+	return &userService{
+		UserRepo:         userRepo,
+		BlockRepo:        blockRepo,
+		LoginAttemptRepo: loginAttemptRepo,
+		Accounts:         accounts,
+		TwoFactor:        twoFactor,
+		Sessions:         sessions,
+		BcryptCost:       bcryptCost,
+		LoginPolicy:      loginPolicy,
+		DummyHash:        dummyHash,
+	}
 }
 
 // RegisterUser handles user registration logic.
-func (s *userService) RegisterUser(user *models.User) error {
+func (s *userService) RegisterUser(ctx context.Context, user *models.User) error {
+	ctx, span := tracing.Start(ctx, "UserService.RegisterUser")
+	defer span.End()
 	// Validate input
-	if user.Username == "" || user.Email == "" || user.Password == "" {
-		fmt.Println(user.Username+user.Email+user.Password)
+	fields := map[string]string{}
+	if user.Username == "" {
+		fields["username"] = "is required"
+	}
+	if user.Email == "" {
+		fields["email"] = "is required"
+	}
+	if user.Password == "" {
+		fields["password"] = "is required"
+	}
+	if len(fields) > 0 {
+		return apperr.Validation("RegisterUser: all fields are required", fields)
+	}
 
-		return errors.New("RegisterUser: all fields are required")
+	if strings.HasPrefix(user.Username, models.DeletedUsername) {
+		return apperr.Validation("RegisterUser: username is reserved", map[string]string{"username": "is reserved"})
 	}
 
 	// Check if username already exists
-	existingUser, err := s.UserRepo.GetUserByUsername(user.Username)
+	existingUser, err := s.UserRepo.GetUserByUsername(ctx, user.Username)
 	if err == nil && existingUser != nil {
-		return errors.New("RegisterUser: username already taken")
+		return apperr.Conflict("RegisterUser: username already taken")
 	}
 
 	// Hash the password
-	hashedPassword, err := hashPassword(user.Password)
+	hashedPassword, err := hashPassword(user.Password, s.BcryptCost)
 	if err != nil {
 		return err
 	}
 	user.Password = hashedPassword
 
 	// Create the user in the repository
-	err = s.UserRepo.CreateUser(user)
+	err = s.UserRepo.CreateUser(ctx, user)
 	if err != nil {
 		return err
 	}
 
+	logging.FromContext(ctx).Info("user registered", "user_id", user.ID)
+	s.sendVerificationEmail(ctx, user.ID)
 	return nil
 }
 
 // GetUserProfile retrieves a user's profile by ID.
-func (s *userService) GetUserProfile(id int) (*models.User, error) {
-	user, err := s.UserRepo.GetUserByID(id)
+func (s *userService) GetUserProfile(ctx context.Context, id int) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserProfile", attribute.Int("user.id", id))
+	defer span.End()
+	user, err := s.UserRepo.GetUserByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -73,22 +166,83 @@ func (s *userService) GetUserProfile(id int) (*models.User, error) {
 	return user, nil
 }
 
-// UpdateUserProfile updates a user's profile information.
-func (s *userService) UpdateUserProfile(user *models.User) error {
+// GetPublicProfile retrieves the public view of a user's profile, including karma
+// and activity counts but no contact details.
+func (s *userService) GetPublicProfile(ctx context.Context, id int) (*models.UserProfile, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetPublicProfile", attribute.Int("user.id", id))
+	defer span.End()
+	profile, err := s.UserRepo.GetUserProfile(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	profile.AccountAgeDays = int(time.Since(profile.CreatedAt).Hours() / 24)
+	if profile.Deleted {
+		profile.Username = models.DeletedUsername
+	}
+	return profile, nil
+}
+
+// GetPrivateProfile retrieves the owner's view of a user's profile, which adds
+// the email address to the public profile.
+func (s *userService) GetPrivateProfile(ctx context.Context, id int) (*models.PrivateUserProfile, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetPrivateProfile", attribute.Int("user.id", id))
+	defer span.End()
+	profile, err := s.GetPublicProfile(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.UserRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &models.PrivateUserProfile{
+		UserProfile:         *profile,
+		Email:               user.Email,
+		EmailVerified:       user.EmailVerifiedAt != nil,
+		TwoFactorEnabled:    user.TOTPEnabledAt != nil,
+		UpdatedAt:           user.UpdatedAt,
+		DeletionRequestedAt: user.DeletionRequestedAt,
+	}, nil
+}
+
+// UpdateUserProfile updates a user's profile information. Changing the
+// password or the email address also takes the current password.
+func (s *userService) UpdateUserProfile(ctx context.Context, user *models.User, currentPassword string) error {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateUserProfile", attribute.Int("user.id", user.ID))
+	defer span.End()
 	// Validate input
-	if user.Username == "" || user.Email == "" {
-		return errors.New("UpdateUserProfile: username and email are required")
+	fields := map[string]string{}
+	if user.Username == "" {
+		fields["username"] = "is required"
+	}
+	if user.Email == "" {
+		fields["email"] = "is required"
+	}
+	if len(fields) > 0 {
+		return apperr.Validation("UpdateUserProfile: username and email are required", fields)
 	}
 
 	// Retrieve existing user
-	existingUser, err := s.UserRepo.GetUserByID(user.ID)
+	existingUser, err := activeUser(ctx, s.UserRepo, "UpdateUserProfile", user.ID)
 	if err != nil {
 		return err
 	}
+	if user.Username != existingUser.Username && strings.HasPrefix(user.Username, models.DeletedUsername) {
+		return apperr.Validation("UpdateUserProfile: username is reserved", map[string]string{"username": "is reserved"})
+	}
+
+	// The password and the email address take over the account, so changing
+	// them takes the current password too
+	emailChanged := existingUser.Email != user.Email
+	if user.Password != "" || emailChanged {
+		if err := checkPassword("UpdateUserProfile", existingUser, currentPassword); err != nil {
+			return err
+		}
+	}
 
 	// If password is being updated, hash it
 	if user.Password != "" {
-		hashedPassword, err := hashPassword(user.Password)
+		hashedPassword, err := hashPassword(user.Password, s.BcryptCost)
 		if err != nil {
 			return err
 		}
@@ -100,45 +254,275 @@ func (s *userService) UpdateUserProfile(user *models.User) error {
 	existingUser.Email = user.Email
 
 	// Update the user in the repository
-	err = s.UserRepo.UpdateUser(existingUser)
+	err = s.UserRepo.UpdateUser(ctx, existingUser)
 	if err != nil {
 		return err
 	}
 
+	// A new password signs out every other session
+	if user.Password != "" {
+		if _, err := s.Sessions.RevokeOtherSessions(ctx, existingUser.ID, models.RevokedPasswordChanged); err != nil {
+			return err
+		}
+	}
+
+	// A new address has to be verified again
+	if emailChanged {
+		s.sendVerificationEmail(ctx, existingUser.ID)
+	}
 	return nil
 }
 
-// DeleteUser removes a user from the system.
-func (s *userService) DeleteUser(id int) error {
-	// Perform any additional checks if necessary
-	err := s.UserRepo.DeleteUser(id)
+// sendVerificationEmail mails a verification link for the user's email. The
+// account change has already been saved, so a failure is only logged; the
+// user can ask for another link.
+func (s *userService) sendVerificationEmail(ctx context.Context, userID int) {
+	if err := s.Accounts.SendVerificationEmail(ctx, userID); err != nil {
+		logging.FromContext(ctx).Error("failed to send verification email", "user_id", userID, "error", err.Error())
+	}
+}
+
+// SetBot marks a user's account as run by automation, or as a person's.
+func (s *userService) SetBot(ctx context.Context, userID int, isBot bool) error {
+	ctx, span := tracing.Start(ctx, "UserService.SetBot", attribute.Int("user.id", userID))
+	defer span.End()
+	return s.UserRepo.SetBot(ctx, userID, isBot)
+}
+
+// AuthenticateUser verifies user credentials, records the attempt in the
+// login audit trail, and starts a session for a successful login. While the
+// username or the client IP is backing off or locked out after repeated
+// failures, the password is not checked and the attempt is rejected as rate
+// limited. Unknown usernames are checked against a dummy hash, so the response
+// time does not reveal whether an account exists.
+//
+// For a user with two-factor authentication on, a correct password only
+// yields a challenge to complete with CompleteTwoFactorLogin; the attempt is
+// recorded once the code is checked.
+func (s *userService) AuthenticateUser(ctx context.Context, username, password string, client models.ClientInfo) (*models.LoginResult, error) {
+	ctx, span := tracing.Start(ctx, "UserService.AuthenticateUser")
+	defer span.End()
+	attempt := &models.LoginAttempt{Username: username, IP: client.IP, UserAgent: client.UserAgent}
+	user, err := s.UserRepo.GetUserByUsername(ctx, username)
+	if err != nil && !errors.Is(err, apperr.ErrNotFound) {
+		return nil, err
+	}
+	if user != nil {
+		attempt.UserID = &user.ID
+	}
+
+	// Refuse to check the password while backing off or locked out
+	userFailures, err := s.throttle(ctx, "AuthenticateUser", attempt)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+
+	// Compare hashed password with the provided password, or with the dummy
+	// hash for unknown and deleted accounts so that every failure costs a
+	// bcrypt comparison
+	hash := s.DummyHash
+	if user != nil && user.Password != "" {
+		hash = user.Password
+	}
+	if comparePasswords(hash, password) != nil || hash == s.DummyHash {
+		if err := s.recordFailedLogin(ctx, attempt, userFailures); err != nil {
+			return nil, err
+		}
+		return nil, apperr.Unauthorized("AuthenticateUser: invalid credentials")
+	}
+
+	// The second factor is still to come
+	if user.TOTPEnabledAt != nil {
+		challenge, err := s.TwoFactor.NewChallenge(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &models.LoginResult{Challenge: challenge}, nil
+	}
+
+	return s.completeLogin(ctx, user, attempt, client)
 }
 
-// AuthenticateUser verifies user credentials.
-func (s *userService) AuthenticateUser(username, password string) (*models.User, error) {
-	user, err := s.UserRepo.GetUserByUsername(username)
+// CompleteTwoFactorLogin finishes a login with the challenge AuthenticateUser
+// returned and a TOTP or recovery code. Wrong codes count as failed logins,
+// so guessing codes backs off and locks out like guessing passwords.
+func (s *userService) CompleteTwoFactorLogin(ctx context.Context, challenge, code string, client models.ClientInfo) (*models.LoginResult, error) {
+	ctx, span := tracing.Start(ctx, "UserService.CompleteTwoFactorLogin")
+	defer span.End()
+	userID, err := s.TwoFactor.CheckChallenge(challenge)
 	if err != nil {
 		return nil, err
 	}
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, ifNotFound(err, apperr.Unauthorized("CompleteTwoFactorLogin: login challenge is invalid or has expired"))
+	}
+	attempt := &models.LoginAttempt{UserID: &user.ID, Username: user.Username, IP: client.IP, UserAgent: client.UserAgent}
 
-	// Compare hashed password with the provided password
-	err = comparePasswords(user.Password, password)
+	userFailures, err := s.throttle(ctx, "CompleteTwoFactorLogin", attempt)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.TwoFactor.CheckCode(ctx, user.ID, code)
 	if err != nil {
-		return nil, errors.New("AuthenticateUser: invalid credentials")
+		return nil, err
+	}
+	if !ok {
+		if err := s.recordFailedLogin(ctx, attempt, userFailures); err != nil {
+			return nil, err
+		}
+		return nil, apperr.Unauthorized("CompleteTwoFactorLogin: invalid two-factor code")
+	}
+	return s.completeLogin(ctx, user, attempt, client)
+}
+
+// completeLogin records a successful login and starts the user's session.
+// Suspended users are refused once their credentials check out.
+func (s *userService) completeLogin(ctx context.Context, user *models.User, attempt *models.LoginAttempt, client models.ClientInfo) (*models.LoginResult, error) {
+	if user.SuspendedAt != nil {
+		attempt.Result = models.LoginSuspended
+		if err := s.recordLoginAttempt(ctx, attempt); err != nil {
+			return nil, err
+		}
+		return nil, apperr.Forbidden("AuthenticateUser: account is suspended")
+	}
+	attempt.Result = models.LoginSucceeded
+	if err := s.recordLoginAttempt(ctx, attempt); err != nil {
+		return nil, err
+	}
+	session, err := s.Sessions.CreateSession(ctx, user.ID, client)
+	if err != nil {
+		return nil, err
 	}
 
 	// Remove sensitive information before returning
 	user.Password = ""
-	return user, nil
+	return &models.LoginResult{User: user, Session: session}, nil
+}
+
+// throttle checks whether the attempt's username or IP is backing off or
+// locked out. If so it records the attempt as throttled or locked and
+// returns a rate-limited error; otherwise it returns the username's recent
+// failures.
+func (s *userService) throttle(ctx context.Context, op string, attempt *models.LoginAttempt) (*models.LoginFailures, error) {
+	now := time.Now()
+	since := now.Add(-s.LoginPolicy.Window)
+	userFailures, err := s.LoginAttemptRepo.GetUsernameFailures(ctx, attempt.Username, since)
+	if err != nil {
+		return nil, err
+	}
+	ipFailures, err := s.LoginAttemptRepo.GetIPFailures(ctx, attempt.IP, since)
+	if err != nil {
+		return nil, err
+	}
+	userWait, userLocked := s.LoginPolicy.wait(userFailures, s.LoginPolicy.FreeFailures, s.LoginPolicy.LockoutFailures, now)
+	ipWait, ipLocked := s.LoginPolicy.wait(ipFailures, s.LoginPolicy.IPFreeFailures, s.LoginPolicy.IPLockoutFailures, now)
+	wait := max(userWait, ipWait)
+	if wait == 0 {
+		return userFailures, nil
+	}
+
+	attempt.Result = models.LoginThrottled
+	if userLocked || ipLocked {
+		attempt.Result = models.LoginLocked
+	}
+	if err := s.recordLoginAttempt(ctx, attempt); err != nil {
+		return nil, err
+	}
+	wait = wait.Round(time.Second)
+	if attempt.Result == models.LoginLocked {
+		return nil, apperr.RateLimited(fmt.Sprintf("%s: temporarily locked after too many failed logins; try again in %v", op, wait), wait)
+	}
+	return nil, apperr.RateLimited(fmt.Sprintf("%s: too many failed logins; try again in %v", op, wait), wait)
+}
+
+// recordFailedLogin records a failed attempt, warning when it locks the
+// username.
+func (s *userService) recordFailedLogin(ctx context.Context, attempt *models.LoginAttempt, userFailures *models.LoginFailures) error {
+	attempt.Result = models.LoginFailed
+	if err := s.recordLoginAttempt(ctx, attempt); err != nil {
+		return err
+	}
+	if userFailures.Count+1 == s.LoginPolicy.LockoutFailures {
+		logging.FromContext(ctx).Warn("login locked after repeated failures", "username", attempt.Username, "ip", attempt.IP)
+	}
+	return nil
+}
+
+// recordLoginAttempt adds attempt to the audit trail and counts it.
+func (s *userService) recordLoginAttempt(ctx context.Context, attempt *models.LoginAttempt) error {
+	if err := s.LoginAttemptRepo.CreateLoginAttempt(ctx, attempt); err != nil {
+		return err
+	}
+	metrics.LoginAttempts.WithLabelValues(attempt.Result).Inc()
+	return nil
+}
+
+// GetLoginHistory retrieves the recent login attempts on a user's account,
+// newest first, with pagination.
+func (s *userService) GetLoginHistory(ctx context.Context, userID int, limit, offset int) ([]*models.LoginAttempt, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetLoginHistory", attribute.Int("user.id", userID), attribute.Int("page.limit", limit), attribute.Int("page.offset", offset))
+	defer span.End()
+	if _, err := s.UserRepo.GetUserByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.LoginAttemptRepo.GetLoginAttemptsByUser(ctx, userID, limit, offset)
+}
+
+// BlockUser adds blockedID to blockerID's block list.
+func (s *userService) BlockUser(ctx context.Context, blockerID, blockedID int) (*models.Block, error) {
+	ctx, span := tracing.Start(ctx, "UserService.BlockUser", attribute.Int("blocker.id", blockerID), attribute.Int("blocked.id", blockedID))
+	defer span.End()
+	// Validate input
+	if blockerID == blockedID {
+		return nil, apperr.Validation("BlockUser: users cannot block themselves", map[string]string{"blocked_id": "must differ from the blocker"})
+	}
+
+	// Check if both users exist
+	if _, err := activeUser(ctx, s.UserRepo, "BlockUser", blockerID); err != nil {
+		return nil, ifNotFound(err, apperr.NotFound("BlockUser: blocker does not exist"))
+	}
+	if _, err := activeUser(ctx, s.UserRepo, "BlockUser", blockedID); err != nil {
+		return nil, ifNotFound(err, apperr.Validation("BlockUser: blocked user does not exist", map[string]string{"blocked_id": "does not exist"}))
+	}
+
+	// Check if the block already exists
+	blocked, err := s.BlockRepo.IsBlocked(ctx, blockerID, blockedID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, apperr.Conflict("BlockUser: user is already blocked")
+	}
+
+	block := &models.Block{BlockerID: blockerID, BlockedID: blockedID}
+	if err := s.BlockRepo.CreateBlock(ctx, block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// UnblockUser removes blockedID from blockerID's block list.
+func (s *userService) UnblockUser(ctx context.Context, blockerID, blockedID int) error {
+	ctx, span := tracing.Start(ctx, "UserService.UnblockUser", attribute.Int("blocker.id", blockerID), attribute.Int("blocked.id", blockedID))
+	defer span.End()
+	return s.BlockRepo.DeleteBlock(ctx, blockerID, blockedID)
+}
+
+// GetBlockedUsers retrieves a user's block list with pagination.
+func (s *userService) GetBlockedUsers(ctx context.Context, blockerID int, limit, offset int) ([]*models.Block, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetBlockedUsers", attribute.Int("blocker.id", blockerID), attribute.Int("page.limit", limit), attribute.Int("page.offset", offset))
+	defer span.End()
+	blocks, err := s.BlockRepo.GetBlocksByUser(ctx, blockerID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return blocks, nil
 }
 
 // Helper function to hash passwords
-func hashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+func hashPassword(password string, cost int) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), cost)
 	return string(bytes), err
 }
 
@@ -146,3 +530,15 @@ func hashPassword(password string) (string, error) {
 func comparePasswords(hashedPassword, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
+
+// Helper function to confirm a sensitive account change with the user's
+// current password, reported as the current_password field.
+func checkPassword(op string, user *models.User, password string) error {
+	if password == "" {
+		return apperr.Validation(op+": current password is required", map[string]string{"current_password": "is required"})
+	}
+	if comparePasswords(user.Password, password) != nil {
+		return apperr.Validation(op+": current password is incorrect", map[string]string{"current_password": "is incorrect"})
+	}
+	return nil
+}
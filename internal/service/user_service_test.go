@@ -0,0 +1,77 @@
+// File: internal/service/user_service_test.go
+
+package service
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository/memory"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestAuthenticateUnknownUser checks that a login for an unknown username
+// fails like a wrong password, after a comparison against a hash of the
+// configured cost.
+func TestAuthenticateUnknownUser(t *testing.T) {
+	ctx := context.Background()
+	repos := memory.NewRepositories()
+	sessions := NewSessionService(repos.Sessions, SessionConfig{AccessTTL: time.Hour, RefreshTTL: 24 * time.Hour})
+	policy := LoginPolicy{Window: time.Hour, FreeFailures: 10, LockoutFailures: 20, IPFreeFailures: 10, IPLockoutFailures: 20}
+	users := NewUserService(repos.Users, repos.Blocks, repos.LoginAttempts, nil, nil, sessions, bcrypt.MinCost, policy)
+
+	if cost, err := bcrypt.Cost([]byte(users.(*userService).DummyHash)); err != nil || cost != bcrypt.MinCost {
+		t.Fatalf("dummy hash cost = %d, %v; want %d", cost, err, bcrypt.MinCost)
+	}
+
+	hash, err := hashPassword("secret123", bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hashPassword: %v", err)
+	}
+	if err := repos.Users.CreateUser(ctx, &models.User{Username: "ann", Email: "ann@example.com", Password: hash}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	tests := []struct {
+		name, username, password string
+	}{
+		{"wrong password", "ann", "wrong"},
+		{"unknown user", "nobody", "secret123"},
+		{"unknown user with the dummy password", "nobody", "not a password"},
+	}
+	client := models.ClientInfo{IP: "10.0.0.1", UserAgent: "test"}
+	for _, tt := range tests {
+		_, err := users.AuthenticateUser(ctx, tt.username, tt.password, client)
+		if !errors.Is(err, apperr.ErrUnauthorized) || err.Error() != "AuthenticateUser: invalid credentials" {
+			t.Errorf("%s: AuthenticateUser error = %v, want invalid credentials", tt.name, err)
+		}
+	}
+}
+
+// TestRegisterUserFields checks that a validation error names only the fields
+// that failed.
+func TestRegisterUserFields(t *testing.T) {
+	repos := memory.NewRepositories()
+	users := NewUserService(repos.Users, repos.Blocks, repos.LoginAttempts, nil, nil, nil, bcrypt.MinCost, LoginPolicy{})
+
+	tests := []struct {
+		name string
+		user models.User
+		want map[string]string
+	}{
+		{"no password", models.User{Username: "ann", Email: "ann@example.com"}, map[string]string{"password": "is required"}},
+		{"no username or email", models.User{Password: "secret123"}, map[string]string{"username": "is required", "email": "is required"}},
+	}
+	for _, tt := range tests {
+		err := users.RegisterUser(context.Background(), &tt.user)
+		if !errors.Is(err, apperr.ErrValidation) || !maps.Equal(apperr.FieldsOf(err), tt.want) {
+			t.Errorf("%s: RegisterUser error = %v with fields %v, want fields %v", tt.name, err, apperr.FieldsOf(err), tt.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// File: internal/service/vote_integrity_service.go
+
+package service
+
+import (
+	"context"
+	"time"
+
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Reasons a vote can be flagged as suspicious.
+const (
+	ReasonNewAccount = "new_account"
+	ReasonBurst      = "burst"
+	ReasonLockstep   = "lockstep"
+)
+
+// VoteIntegrityConfig holds the thresholds used to score votes.
+type VoteIntegrityConfig struct {
+	NewAccountAge    time.Duration // Accounts younger than this are considered new.
+	BurstWindow      time.Duration // Window in which votes on one target are counted as a burst.
+	BurstVoters      int           // Distinct voters within BurstWindow that make a burst.
+	LockstepRecent   int           // Number of the voter's recent votes compared against other accounts.
+	LockstepMinVotes int           // Minimum recent votes before lockstep voting is considered.
+	LockstepRatio    float64       // Share of recent votes another account must match to count as lockstep.
+	FlagThreshold    float64       // Votes scoring at or above this are flagged.
+}
+
+// DefaultVoteIntegrityConfig returns the thresholds used when none are configured.
+func DefaultVoteIntegrityConfig() VoteIntegrityConfig {
+	return VoteIntegrityConfig{
+		NewAccountAge:    24 * time.Hour,
+		BurstWindow:      time.Minute,
+		BurstVoters:      10,
+		LockstepRecent:   20,
+		LockstepMinVotes: 5,
+		LockstepRatio:    0.8,
+		FlagThreshold:    1.0,
+	}
+}
+
+// Score contributed by each reason. A single weak signal is not enough to flag
+// a vote; two weak signals or one strong signal are.
+var reasonWeights = map[string]float64{
+	ReasonNewAccount: 0.5,
+	ReasonBurst:      0.5,
+	ReasonLockstep:   1.0,
+}
+
+// VoteIntegrityService defines the methods for detecting vote manipulation.
+type VoteIntegrityService interface {
+	ScoreVote(ctx context.Context, vote *models.Vote) (*models.VoteFlag, error)
+	GetReport(ctx context.Context, limit, offset int) (*models.VoteIntegrityReport, error)
+}
+
+type voteIntegrityService struct {
+	IntegrityRepo repository.VoteIntegrityRepository
+	UserRepo      repository.UserRepository
+	Config        VoteIntegrityConfig
+}
+
+// NewVoteIntegrityService creates a new VoteIntegrityService.
+func NewVoteIntegrityService(integrityRepo repository.VoteIntegrityRepository, userRepo repository.UserRepository, config VoteIntegrityConfig) VoteIntegrityService {
+	return &voteIntegrityService{
+		IntegrityRepo: integrityRepo,
+		UserRepo:      userRepo,
+		Config:        config,
+	}
+}
+
+// ScoreVote scores a freshly stored vote for suspicious patterns. If the score
+// reaches the flag threshold the vote is flagged, which takes it back out of
+// karma, and the flag is returned; otherwise it returns nil.
+func (s *voteIntegrityService) ScoreVote(ctx context.Context, vote *models.Vote) (*models.VoteFlag, error) {
+	ctx, span := tracing.Start(ctx, "VoteIntegrityService.ScoreVote", voteAttrs(vote)...)
+	defer span.End()
+	var reasons []string
+
+	// New accounts
+	voter, err := s.UserRepo.GetUserByID(ctx, vote.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(voter.CreatedAt) < s.Config.NewAccountAge {
+		reasons = append(reasons, ReasonNewAccount)
+	}
+
+	// Bursts from many accounts on one target
+	voters, err := s.IntegrityRepo.CountRecentVoters(ctx, vote.PostID, vote.CommentID, time.Now().Add(-s.Config.BurstWindow))
+	if err != nil {
+		return nil, err
+	}
+	if voters >= s.Config.BurstVoters {
+		reasons = append(reasons, ReasonBurst)
+	}
+
+	// Accounts that always vote together
+	total, maxShared, err := s.IntegrityRepo.GetLockstepStats(ctx, vote.UserID, s.Config.LockstepRecent)
+	if err != nil {
+		return nil, err
+	}
+	if total >= s.Config.LockstepMinVotes && float64(maxShared) >= s.Config.LockstepRatio*float64(total) {
+		reasons = append(reasons, ReasonLockstep)
+	}
+
+	var score float64
+	for _, reason := range reasons {
+		score += reasonWeights[reason]
+	}
+	if score < s.Config.FlagThreshold {
+		return nil, nil
+	}
+
+	flag := &models.VoteFlag{
+		VoteID:    vote.ID,
+		UserID:    vote.UserID,
+		PostID:    vote.PostID,
+		CommentID: vote.CommentID,
+		VoteType:  vote.VoteType,
+		Score:     score,
+		Reasons:   reasons,
+	}
+	if err := s.IntegrityRepo.CreateVoteFlag(ctx, flag); err != nil {
+		return nil, err
+	}
+	return flag, nil
+}
+
+// GetReport summarizes flagged votes for administrators, with the list of
+// flags paginated.
+func (s *voteIntegrityService) GetReport(ctx context.Context, limit, offset int) (*models.VoteIntegrityReport, error) {
+	ctx, span := tracing.Start(ctx, "VoteIntegrityService.GetReport", attribute.Int("page.limit", limit), attribute.Int("page.offset", offset))
+	defer span.End()
+	total, err := s.IntegrityRepo.CountVoteFlags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byReason := make(map[string]int, len(reasonWeights))
+	for reason := range reasonWeights {
+		count, err := s.IntegrityRepo.CountVoteFlagsByReason(ctx, reason)
+		if err != nil {
+			return nil, err
+		}
+		byReason[reason] = count
+	}
+
+	targets, err := s.IntegrityRepo.GetTopFlaggedTargets(ctx, 10)
+	if err != nil {
+		return nil, err
+	}
+
+	flags, err := s.IntegrityRepo.GetVoteFlags(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.VoteIntegrityReport{
+		TotalFlagged: total,
+		ByReason:     byReason,
+		TopTargets:   targets,
+		Flags:        flags,
+	}, nil
+}
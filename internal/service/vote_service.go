@@ -3,230 +3,273 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
+	"redditclone/internal/apperr"
 	"redditclone/internal/models"
 	"redditclone/internal/repository"
+	"redditclone/pkg/logging"
+	"redditclone/pkg/metrics"
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // VoteService defines the methods for vote-related business logic.
 type VoteService interface {
-	CastVote(vote *models.Vote) error
-	ChangeVote(vote *models.Vote) error
-	RemoveVote(userID, postID, commentID int) error
-	GetVote(userID, postID, commentID int) (*models.Vote, error)
-	UpdateKarma(targetType string, targetID int, delta int) error
+	CastVote(ctx context.Context, vote *models.Vote) error
+	ChangeVote(ctx context.Context, vote *models.Vote) error
+	RemoveVote(ctx context.Context, userID, postID, commentID int) error
+	GetVote(ctx context.Context, userID, postID, commentID int) (*models.Vote, error)
 }
 
 type voteService struct {
 	VoteRepo    repository.VoteRepository
 	PostRepo    repository.PostRepository
 	CommentRepo repository.CommentRepository
+	UserRepo    repository.UserRepository
+	Integrity   VoteIntegrityService
 }
 
 // NewVoteService creates a new VoteService.
-func NewVoteService(voteRepo repository.VoteRepository, postRepo repository.PostRepository, commentRepo repository.CommentRepository) VoteService {
+func NewVoteService(voteRepo repository.VoteRepository, postRepo repository.PostRepository, commentRepo repository.CommentRepository, userRepo repository.UserRepository, integrity VoteIntegrityService) VoteService {
 	return &voteService{
 		VoteRepo:    voteRepo,
 		PostRepo:    postRepo,
 		CommentRepo: commentRepo,
+		UserRepo:    userRepo,
+		Integrity:   integrity,
 	}
 }
 
 // CastVote allows a user to cast a vote on a post or comment.
-func (s *voteService) CastVote(vote *models.Vote) error {
+func (s *voteService) CastVote(ctx context.Context, vote *models.Vote) error {
+	ctx, span := tracing.Start(ctx, "VoteService.CastVote", voteAttrs(vote)...)
+	defer span.End()
 	// Validate input
-	// if vote.VoteType != models.Upvote && vote.VoteType != models.Downvote {
-	// 	return errors.New("CastVote: invalid vote type")
-	// }
+	if vote.VoteType != models.Upvote && vote.VoteType != models.Downvote {
+		return apperr.Validation("CastVote: invalid vote type", map[string]string{"vote_type": "must be upvote or downvote"})
+	}
 	if (vote.PostID == nil && vote.CommentID == nil) || (vote.PostID != nil && vote.CommentID != nil) {
-		return errors.New("CastVote: vote must be on either a post or a comment")
+		return apperr.Validation("CastVote: vote must be on either a post or a comment", map[string]string{"post_id": "exactly one of post_id and comment_id is required"})
 	}
 
-	// Check if user has already voted on the target
-	var existingVote *models.Vote
+	// Suspended users cannot vote
+	if err := s.checkVoter(ctx, "CastVote", vote.UserID); err != nil {
+		return err
+	}
+
+	// Check that the target exists
 	var err error
 	if vote.PostID != nil {
-		existingVote, err = s.VoteRepo.GetVoteByUserAndPost(vote.UserID, *vote.PostID)
+		_, err = s.PostRepo.GetPostByID(ctx, *vote.PostID)
+		err = ifNotFound(err, apperr.NotFound("CastVote: post not found"))
 	} else {
-		existingVote, err = s.VoteRepo.GetVoteByUserAndComment(vote.UserID, *vote.CommentID)
-	}
-
-	if err == nil && existingVote != nil {
-		return errors.New("CastVote: user has already voted on this target")
-	} else if err != nil && err.Error() != "GetVoteByUserAndPost: vote not found" && err.Error() != "GetVoteByUserAndComment: vote not found" {
-		return fmt.Errorf("CastVote: %v", err)
+		_, err = s.CommentRepo.GetCommentByID(ctx, *vote.CommentID)
+		err = ifNotFound(err, apperr.NotFound("CastVote: comment not found"))
 	}
-
-	// Create the vote via the repository
-	err = s.VoteRepo.CreateVote(vote)
 	if err != nil {
 		return err
 	}
 
-	// Update karma on the target
-	var delta int
-	if vote.VoteType =="upvote" {
-		delta = 1
+	// Check if user has already voted on the target
+	var existingVote *models.Vote
+	if vote.PostID != nil {
+		existingVote, err = s.VoteRepo.GetVoteByUserAndPost(ctx, vote.UserID, *vote.PostID)
 	} else {
-		delta = -1
+		existingVote, err = s.VoteRepo.GetVoteByUserAndComment(ctx, vote.UserID, *vote.CommentID)
 	}
 
-	if vote.PostID != nil {
-		err = s.UpdateKarma("post", *vote.PostID, delta)
-	} else {
-		err = s.UpdateKarma("comment", *vote.CommentID, delta)
+	if err == nil && existingVote != nil {
+		return apperr.Conflict("CastVote: user has already voted on this target")
+	} else if err != nil && !errors.Is(err, apperr.ErrNotFound) {
+		return fmt.Errorf("CastVote: %v", err)
 	}
+
+	// Create the vote via the repository, which also updates karma
+	err = s.VoteRepo.CreateVote(ctx, vote)
 	if err != nil {
 		return err
 	}
+	metrics.VotesCast.WithLabelValues(vote.VoteType).Inc()
 
+	// Suspicious votes are kept, but flagging one takes it back out of karma
+	s.scoreVote(ctx, vote)
 	return nil
 }
 
 // ChangeVote allows a user to change their existing vote.
-func (s *voteService) ChangeVote(vote *models.Vote) error {
+func (s *voteService) ChangeVote(ctx context.Context, vote *models.Vote) error {
+	ctx, span := tracing.Start(ctx, "VoteService.ChangeVote", voteAttrs(vote)...)
+	defer span.End()
 	// Validate input
-	if vote.VoteType != "upvote" && vote.VoteType != "downvote" {
-		return errors.New("ChangeVote: invalid vote type")
+	if vote.VoteType != models.Upvote && vote.VoteType != models.Downvote {
+		return apperr.Validation("ChangeVote: invalid vote type", map[string]string{"vote_type": "must be upvote or downvote"})
 	}
 	if (vote.PostID == nil && vote.CommentID == nil) || (vote.PostID != nil && vote.CommentID != nil) {
-		return errors.New("ChangeVote: vote must be on either a post or a comment")
+		return apperr.Validation("ChangeVote: vote must be on either a post or a comment", map[string]string{"post_id": "exactly one of post_id and comment_id is required"})
+	}
+
+	// Suspended users cannot vote
+	if err := s.checkVoter(ctx, "ChangeVote", vote.UserID); err != nil {
+		return err
 	}
 
 	// Retrieve existing vote
 	var existingVote *models.Vote
 	var err error
 	if vote.PostID != nil {
-		existingVote, err = s.VoteRepo.GetVoteByUserAndPost(vote.UserID, *vote.PostID)
+		existingVote, err = s.VoteRepo.GetVoteByUserAndPost(ctx, vote.UserID, *vote.PostID)
 	} else {
-		existingVote, err = s.VoteRepo.GetVoteByUserAndComment(vote.UserID, *vote.CommentID)
+		existingVote, err = s.VoteRepo.GetVoteByUserAndComment(ctx, vote.UserID, *vote.CommentID)
 	}
-	if err != nil {
+	if errors.Is(err, apperr.ErrNotFound) {
+		return apperr.NotFound("ChangeVote: vote not found")
+	} else if err != nil {
 		return fmt.Errorf("ChangeVote: %v", err)
 	}
 
 	// If the vote type is the same, do nothing
 	if existingVote.VoteType == vote.VoteType {
-		return errors.New("ChangeVote: vote type is already set to the desired value")
+		return apperr.Conflict("ChangeVote: vote type is already set to the desired value")
 	}
 
-	// Update the vote via the repository
+	// Update the vote via the repository, which also moves its karma unless
+	// the vote is flagged
 	existingVote.VoteType = vote.VoteType
-	err = s.VoteRepo.UpdateVote(existingVote)
-	if err != nil {
-		return err
-	}
-
-	// Update karma on the target
-	var delta int
-	if vote.VoteType == "upvote" {
-		delta = 2 // From -1 to +1
-	} else {
-		delta = -2 // From +1 to -1
-	}
-
-	if vote.PostID != nil {
-		err = s.UpdateKarma("post", *vote.PostID, delta)
-	} else {
-		err = s.UpdateKarma("comment", *vote.CommentID, delta)
-	}
-	if err != nil {
+	if err := s.VoteRepo.UpdateVote(ctx, existingVote); err != nil {
 		return err
 	}
 
+	// A changed vote is scored like a new one
+	s.scoreVote(ctx, existingVote)
 	return nil
 }
 
 // RemoveVote allows a user to remove their vote from a post or comment.
-func (s *voteService) RemoveVote(userID, postID, commentID int) error {
+func (s *voteService) RemoveVote(ctx context.Context, userID, postID, commentID int) error {
+	ctx, span := tracing.Start(ctx, "VoteService.RemoveVote", attribute.Int("user.id", userID), attribute.Int("post.id", postID), attribute.Int("comment.id", commentID))
+	defer span.End()
 	// Retrieve existing vote
 	var existingVote *models.Vote
 	var err error
 	if postID != 0 {
-		existingVote, err = s.VoteRepo.GetVoteByUserAndPost(userID, postID)
+		existingVote, err = s.VoteRepo.GetVoteByUserAndPost(ctx, userID, postID)
 	} else {
-		existingVote, err = s.VoteRepo.GetVoteByUserAndComment(userID, commentID)
+		existingVote, err = s.VoteRepo.GetVoteByUserAndComment(ctx, userID, commentID)
 	}
-	if err != nil {
+	if errors.Is(err, apperr.ErrNotFound) {
+		return apperr.NotFound("RemoveVote: vote not found")
+	} else if err != nil {
 		return fmt.Errorf("RemoveVote: %v", err)
 	}
 
-	// Determine the delta based on the vote type
-	var delta int
-	if existingVote.VoteType == "upvote" {
-		delta = -1
+	// Delete the vote via the repository, which also reverses its karma
+	// unless the vote is flagged
+	return s.VoteRepo.DeleteVote(ctx, existingVote)
+}
+
+// GetVote retrieves a user's vote on a specific post or comment.
+func (s *voteService) GetVote(ctx context.Context, userID, postID, commentID int) (*models.Vote, error) {
+	ctx, span := tracing.Start(ctx, "VoteService.GetVote", attribute.Int("user.id", userID), attribute.Int("post.id", postID), attribute.Int("comment.id", commentID))
+	defer span.End()
+	if postID != 0 && commentID != 0 {
+		return nil, apperr.Validation("GetVote: vote must be on either a post or a comment", map[string]string{"post_id": "exactly one of post_id and comment_id is required"})
+	}
+
+	var vote *models.Vote
+	var err error
+	if postID != 0 {
+		vote, err = s.VoteRepo.GetVoteByUserAndPost(ctx, userID, postID)
 	} else {
-		delta = 1
+		vote, err = s.VoteRepo.GetVoteByUserAndComment(ctx, userID, commentID)
 	}
 
-	// Delete the vote via the repository
-	err = s.VoteRepo.DeleteVote(existingVote)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return vote, nil
+}
+
+// scoreVote scores a stored vote for manipulation. The vote is already
+// committed, so scoring is best effort: a failure is logged rather than
+// returned, and a vote that is already flagged stays flagged.
+func (s *voteService) scoreVote(ctx context.Context, vote *models.Vote) {
+	_, err := s.Integrity.ScoreVote(ctx, vote)
+	if err != nil && !errors.Is(err, apperr.ErrConflict) {
+		logging.FromContext(ctx).Error("failed to score vote", "vote_id", vote.ID, "error", err.Error())
 	}
+}
 
-	// Update karma on the target
-	if existingVote.PostID != nil {
-		err = s.UpdateKarma("post", *existingVote.PostID, delta)
-	} else if existingVote.CommentID != nil {
-		err = s.UpdateKarma("comment", *existingVote.CommentID, delta)
-	} else {
-		return errors.New("RemoveVote: invalid vote target")
+// annotatePostVotes fills in MyVote on each post with the viewer's vote, using
+// one batched query. It does nothing for anonymous viewers.
+func annotatePostVotes(ctx context.Context, voteRepo repository.VoteRepository, viewerID int, posts []*models.Post) error {
+	if viewerID == 0 || len(posts) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
 	}
+	votes, err := voteRepo.GetUserVotesOnPosts(ctx, viewerID, ids)
 	if err != nil {
 		return err
 	}
-
+	for _, post := range posts {
+		post.MyVote = votes[post.ID]
+	}
 	return nil
 }
 
-// GetVote retrieves a user's vote on a specific post or comment.
-func (s *voteService) GetVote(userID, postID, commentID int) (*models.Vote, error) {
-	if postID != 0 && commentID != 0 {
-		return nil, errors.New("GetVote: vote must be on either a post or a comment")
+// annotateCommentVotes fills in MyVote on each comment with the viewer's vote,
+// using one batched query. It does nothing for anonymous viewers.
+func annotateCommentVotes(ctx context.Context, voteRepo repository.VoteRepository, viewerID int, comments []*models.Comment) error {
+	if viewerID == 0 || len(comments) == 0 {
+		return nil
 	}
-
-	var vote *models.Vote
-	var err error
-	if postID != 0 {
-		vote, err = s.VoteRepo.GetVoteByUserAndPost(userID, postID)
-	} else {
-		vote, err = s.VoteRepo.GetVoteByUserAndComment(userID, commentID)
+	ids := make([]int, 0, len(comments))
+	for _, comment := range comments {
+		ids = append(ids, comment.ID)
 	}
-
+	votes, err := voteRepo.GetUserVotesOnComments(ctx, viewerID, ids)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return vote, nil
+	for _, comment := range comments {
+		comment.MyVote = votes[comment.ID]
+	}
+	return nil
 }
 
-// UpdateKarma updates the karma of a post or comment.
-func (s *voteService) UpdateKarma(targetType string, targetID int, delta int) error {
-	switch targetType {
-	case "post":
-		post, err := s.PostRepo.GetPostByID(targetID)
-		if err != nil {
-			return err
+// annotateActivityVotes fills in MyVote on the posts and comments of a mixed
+// activity listing, using one batched query per kind.
+func annotateActivityVotes(ctx context.Context, voteRepo repository.VoteRepository, viewerID int, items []*models.ActivityItem) error {
+	var posts []*models.Post
+	var comments []*models.Comment
+	for _, item := range items {
+		if item.Post != nil {
+			posts = append(posts, item.Post)
 		}
-		post.Karma += delta
-		err = s.PostRepo.UpdatePost(post)
-		if err != nil {
-			return err
+		if item.Comment != nil {
+			comments = append(comments, item.Comment)
 		}
-	case "comment":
-		comment, err := s.CommentRepo.GetCommentByID(targetID)
-		if err != nil {
-			return err
-		}
-		comment.Karma += delta
-		err = s.CommentRepo.UpdateComment(comment)
-		if err != nil {
-			return err
-		}
-	default:
-		return errors.New("UpdateKarma: invalid target type")
+	}
+	if err := annotatePostVotes(ctx, voteRepo, viewerID, posts); err != nil {
+		return err
+	}
+	return annotateCommentVotes(ctx, voteRepo, viewerID, comments)
+}
+
+// checkVoter refuses votes from users who do not exist or are suspended.
+func (s *voteService) checkVoter(ctx context.Context, op string, userID int) error {
+	user, err := activeUser(ctx, s.UserRepo, op, userID)
+	if err != nil {
+		return ifNotFound(err, apperr.Validation(op+": user does not exist", map[string]string{"user_id": "does not exist"}))
+	}
+	if user.SuspendedAt != nil {
+		return apperr.Forbidden(op + ": user is suspended")
 	}
 	return nil
 }
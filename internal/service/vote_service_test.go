@@ -0,0 +1,143 @@
+// File: internal/service/vote_service_test.go
+
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+	"redditclone/internal/repository/memory"
+)
+
+// TestCastVoteErrors checks that bad votes are refused before they reach the
+// database's CHECK and foreign key constraints.
+func TestCastVoteErrors(t *testing.T) {
+	ctx := context.Background()
+	repos := memory.NewRepositories()
+	integrity := NewVoteIntegrityService(repos.VoteIntegrity, repos.Users, DefaultVoteIntegrityConfig())
+	votes := NewVoteService(repos.Votes, repos.Posts, repos.Comments, repos.Users, integrity)
+
+	ann, posts := seedPosts(t, repos, 1)
+	post := posts[0]
+	missing := 999
+
+	tests := []struct {
+		name    string
+		vote    models.Vote
+		kind    error
+		wantMsg string
+	}{
+		{"bad type", models.Vote{PostID: &post.ID, VoteType: "sideways"}, apperr.ErrValidation, "CastVote: invalid vote type"},
+		{"missing post", models.Vote{PostID: &missing, VoteType: models.Upvote}, apperr.ErrNotFound, "CastVote: post not found"},
+		{"missing comment", models.Vote{CommentID: &missing, VoteType: models.Downvote}, apperr.ErrNotFound, "CastVote: comment not found"},
+		{"no target", models.Vote{VoteType: models.Upvote}, apperr.ErrValidation, "CastVote: vote must be on either a post or a comment"},
+	}
+	for _, tt := range tests {
+		vote := tt.vote
+		vote.UserID = ann.ID
+		err := votes.CastVote(ctx, &vote)
+		if !errors.Is(err, tt.kind) || err.Error() != tt.wantMsg {
+			t.Errorf("%s: CastVote error = %v, want %q", tt.name, err, tt.wantMsg)
+		}
+	}
+
+	if err := votes.CastVote(ctx, &models.Vote{UserID: ann.ID, PostID: &post.ID, VoteType: models.Upvote}); err != nil {
+		t.Fatalf("CastVote: %v", err)
+	}
+}
+
+// failingIntegrity is a VoteIntegrityService whose scoring always fails.
+type failingIntegrity struct {
+	VoteIntegrityService
+}
+
+func (failingIntegrity) ScoreVote(ctx context.Context, vote *models.Vote) (*models.VoteFlag, error) {
+	return nil, errors.New("scoring is down")
+}
+
+// TestScoringBestEffort checks that a vote whose scoring fails is still cast
+// and counted.
+func TestScoringBestEffort(t *testing.T) {
+	ctx := context.Background()
+	repos := memory.NewRepositories()
+	votes := NewVoteService(repos.Votes, repos.Posts, repos.Comments, repos.Users, failingIntegrity{})
+	ann, posts := seedPosts(t, repos, 1)
+
+	if err := votes.CastVote(ctx, &models.Vote{UserID: ann.ID, PostID: &posts[0].ID, VoteType: models.Upvote}); err != nil {
+		t.Fatalf("CastVote: %v", err)
+	}
+	if post, err := repos.Posts.GetPostByID(ctx, posts[0].ID); err != nil || post.Karma != 1 {
+		t.Errorf("post = %+v, %v; want karma 1", post, err)
+	}
+}
+
+// TestChangeVoteScored checks that changing votes to match another account's
+// is flagged as lockstep voting, like casting them would be.
+func TestChangeVoteScored(t *testing.T) {
+	ctx := context.Background()
+	repos := memory.NewRepositories()
+	integrity := NewVoteIntegrityService(repos.VoteIntegrity, repos.Users, DefaultVoteIntegrityConfig())
+	votes := NewVoteService(repos.Votes, repos.Posts, repos.Comments, repos.Users, integrity)
+	ann, posts := seedPosts(t, repos, 5)
+	bob := &models.User{Username: "bob", Email: "bob@example.com", Password: "hash"}
+	if err := repos.Users.CreateUser(ctx, bob); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	for _, post := range posts {
+		for _, vote := range []*models.Vote{
+			{UserID: ann.ID, PostID: &post.ID, VoteType: models.Upvote},
+			{UserID: bob.ID, PostID: &post.ID, VoteType: models.Downvote},
+		} {
+			if err := votes.CastVote(ctx, vote); err != nil {
+				t.Fatalf("CastVote: %v", err)
+			}
+		}
+	}
+	if flags, err := repos.VoteIntegrity.CountVoteFlags(ctx); err != nil || flags != 0 {
+		t.Fatalf("CountVoteFlags = %d, %v; want no flags before the changes", flags, err)
+	}
+
+	// The fourth matching vote of five reaches the lockstep ratio.
+	for _, post := range posts[:4] {
+		if err := votes.ChangeVote(ctx, &models.Vote{UserID: bob.ID, PostID: &post.ID, VoteType: models.Upvote}); err != nil {
+			t.Fatalf("ChangeVote: %v", err)
+		}
+	}
+	changed, err := repos.Votes.GetVoteByUserAndPost(ctx, bob.ID, posts[3].ID)
+	if err != nil {
+		t.Fatalf("GetVoteByUserAndPost: %v", err)
+	}
+	if flagged, err := repos.VoteIntegrity.IsVoteFlagged(ctx, changed.ID); err != nil || !flagged {
+		t.Errorf("IsVoteFlagged = %v, %v; want the fourth changed vote flagged", flagged, err)
+	}
+	if post, err := repos.Posts.GetPostByID(ctx, posts[3].ID); err != nil || post.Karma != 1 {
+		t.Errorf("post = %+v, %v; want karma 1 from ann's vote alone", post, err)
+	}
+}
+
+// seedPosts creates a user who owns a subreddit with n posts in it.
+func seedPosts(t *testing.T, repos repository.Repositories, n int) (*models.User, []*models.Post) {
+	t.Helper()
+	ctx := context.Background()
+	author := &models.User{Username: "ann", Email: "ann@example.com", Password: "hash"}
+	if err := repos.Users.CreateUser(ctx, author); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	sub := &models.Subreddit{Name: "golang", Description: "d", CreatedBy: author.ID}
+	if err := repos.Subreddits.CreateSubreddit(ctx, sub); err != nil {
+		t.Fatalf("CreateSubreddit: %v", err)
+	}
+	posts := make([]*models.Post, n)
+	for i := range posts {
+		posts[i] = &models.Post{Title: "t", Content: "c", AuthorID: author.ID, SubredditID: sub.ID}
+		if err := repos.Posts.CreatePost(ctx, posts[i]); err != nil {
+			t.Fatalf("CreatePost: %v", err)
+		}
+	}
+	return author, posts
+}
@@ -0,0 +1,331 @@
+// File: pkg/config/config.go
+
+// Package config defines the server configuration and loads it from, in
+// increasing order of precedence: built-in defaults, a YAML or JSON file,
+// environment variables and command-line flags.
+//
+// Every setting has a dotted name such as database.driver. It is the key in the
+// config file (section database, key driver), and the flag is -database.driver.
+// Most settings can also be set from an environment variable, named in the
+// setting's env tag.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	netmail "net/mail"
+	"net/url"
+	"runtime"
+	"strings"
+	"time"
+
+	"redditclone/pkg/database"
+	"redditclone/pkg/mail"
+	"redditclone/pkg/ratelimit"
+	"redditclone/pkg/secretbox"
+	"redditclone/pkg/tracing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Config is the effective server configuration.
+type Config struct {
+	Server     Server     `yaml:"server"`
+	Database   Database   `yaml:"database"`
+	Auth       Auth       `yaml:"auth"`
+	Pagination Pagination `yaml:"pagination"`
+	Log        Log        `yaml:"log"`
+	Tracing    Tracing    `yaml:"tracing"`
+	RateLimit  RateLimit  `yaml:"rate_limit"`
+	Mail       Mail       `yaml:"mail"`
+}
+
+// Server configures the HTTP server.
+type Server struct {
+	Port              int           `yaml:"port" env:"PORT" usage:"HTTP listen port"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" usage:"time allowed to read request headers"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT" usage:"time allowed to read a whole request"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" usage:"time allowed to handle a request and write the response"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" usage:"how long idle keep-alive connections stay open"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" usage:"how long shutdown waits for in-flight requests"`
+	CursorSecret      string        `yaml:"cursor_secret" env:"CURSOR_SECRET" secret:"true" usage:"key signing pagination cursors; empty picks a random key per run"`
+	TrustedProxies    int           `yaml:"trusted_proxies" env:"TRUSTED_PROXIES" usage:"number of reverse proxies in front of the server that append to X-Forwarded-For; 0 ignores the header"`
+}
+
+// Database configures the storage backend.
+type Database struct {
+	Driver       string        `yaml:"driver" env:"DB_DRIVER" usage:"storage backend: sqlite, postgres or memory"`
+	DSN          string        `yaml:"dsn" env:"DATABASE_URL" secret:"url" usage:"SQLite file name or PostgreSQL connection URL"`
+	MaxOpenConns int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS" usage:"size of the read pool (SQLite) or the shared pool (PostgreSQL)"`
+	BusyTimeout  time.Duration `yaml:"busy_timeout" env:"DB_BUSY_TIMEOUT" usage:"how long SQLite waits on a locked database"`
+	AutoMigrate  bool          `yaml:"auto_migrate" env:"AUTO_MIGRATE" usage:"apply pending migrations on start-up instead of refusing to start"`
+}
+
+// Auth configures password handling and login throttling. Failed logins are
+// counted per username (until the next successful login) and per client IP
+// over login_failure_window. Past the free failures, each further failure
+// doubles the wait before the next attempt, starting at login_backoff; at the
+// lockout threshold the wait becomes login_lockout_duration.
+type Auth struct {
+	BcryptCost             int           `yaml:"bcrypt_cost" env:"BCRYPT_COST" usage:"bcrypt cost used to hash new passwords"`
+	LoginFailureWindow     time.Duration `yaml:"login_failure_window" env:"LOGIN_FAILURE_WINDOW" usage:"how long failed logins count against a username or IP"`
+	LoginFreeFailures      int           `yaml:"login_free_failures" env:"LOGIN_FREE_FAILURES" usage:"failed logins on a username before backoff starts"`
+	LoginBackoff           time.Duration `yaml:"login_backoff" env:"LOGIN_BACKOFF" usage:"wait after the first failure past the free ones; doubles with each further failure"`
+	LoginLockoutFailures   int           `yaml:"login_lockout_failures" env:"LOGIN_LOCKOUT_FAILURES" usage:"failed logins on a username that lock it"`
+	LoginLockoutDuration   time.Duration `yaml:"login_lockout_duration" env:"LOGIN_LOCKOUT_DURATION" usage:"how long a lockout lasts after the latest failure"`
+	LoginIPFreeFailures    int           `yaml:"login_ip_free_failures" env:"LOGIN_IP_FREE_FAILURES" usage:"failed logins from one IP, on any username, before backoff starts"`
+	LoginIPLockoutFailures int           `yaml:"login_ip_lockout_failures" env:"LOGIN_IP_LOCKOUT_FAILURES" usage:"failed logins from one IP, on any username, that lock it out"`
+	EmailVerificationTTL   time.Duration `yaml:"email_verification_ttl" env:"EMAIL_VERIFICATION_TTL" usage:"how long an email verification link stays valid"`
+	PasswordResetTTL       time.Duration `yaml:"password_reset_ttl" env:"PASSWORD_RESET_TTL" usage:"how long a password reset link stays valid"`
+	TOTPIssuer             string        `yaml:"totp_issuer" env:"TOTP_ISSUER" usage:"name authenticator apps show for accounts"`
+	TOTPEncryptionKey      string        `yaml:"totp_encryption_key" env:"TOTP_ENCRYPTION_KEY" secret:"true" usage:"base64 32-byte key encrypting TOTP secrets; empty turns two-factor enrollment off"`
+	TwoFactorChallengeTTL  time.Duration `yaml:"two_factor_challenge_ttl" env:"TWO_FACTOR_CHALLENGE_TTL" usage:"how long after the password a two-factor code may be entered"`
+	AccessTokenTTL         time.Duration `yaml:"access_token_ttl" env:"ACCESS_TOKEN_TTL" usage:"how long a session access token works before it must be refreshed"`
+	RefreshTokenTTL        time.Duration `yaml:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL" usage:"how long a session lasts without being refreshed"`
+	AccountDeletionGrace   time.Duration `yaml:"account_deletion_grace" env:"ACCOUNT_DELETION_GRACE" usage:"how long a deleted account can still be restored before it is anonymized"`
+	AccountPurgeInterval   time.Duration `yaml:"account_purge_interval" env:"ACCOUNT_PURGE_INTERVAL" usage:"how often accounts past their deletion grace period are anonymized"`
+}
+
+// Pagination configures listing page sizes.
+type Pagination struct {
+	DefaultLimit int `yaml:"default_limit" env:"PAGE_DEFAULT_LIMIT" usage:"page size when a request gives no limit"`
+	MaxLimit     int `yaml:"max_limit" env:"PAGE_MAX_LIMIT" usage:"largest page size a request may ask for"`
+}
+
+// Log configures the structured logger.
+type Log struct {
+	Level string `yaml:"level" env:"LOG_LEVEL" usage:"log level: debug, info, warn or error"`
+}
+
+// Tracing configures trace export.
+type Tracing struct {
+	Exporter string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER" usage:"trace exporter: none, otlp or stdout"`
+}
+
+// RateLimit configures request rate limiting. Rates are written as
+// requests/period, e.g. 10/1m. An empty rate makes its routes use the default
+// rate, and an empty default leaves other routes unlimited.
+type RateLimit struct {
+	Enabled  bool   `yaml:"enabled" env:"RATE_LIMIT_ENABLED" usage:"reject clients that exceed their request rate with 429"`
+	Default  string `yaml:"default" env:"RATE_LIMIT_DEFAULT" usage:"rate for routes without a policy of their own"`
+	Register string `yaml:"register" env:"RATE_LIMIT_REGISTER" usage:"rate for registrations, per client IP"`
+	Login    string `yaml:"login" env:"RATE_LIMIT_LOGIN" usage:"rate for login attempts and mailed-link submissions, per client IP"`
+	Email    string `yaml:"email" env:"RATE_LIMIT_EMAIL" usage:"rate for verification and password reset emails, per client IP"`
+	Posting  string `yaml:"posting" env:"RATE_LIMIT_POSTING" usage:"rate for new posts, comments and replies"`
+	Messages string `yaml:"messages" env:"RATE_LIMIT_MESSAGES" usage:"rate for direct messages and message replies"`
+	Votes    string `yaml:"votes" env:"RATE_LIMIT_VOTES" usage:"rate for casting, changing and removing votes"`
+}
+
+// Mail configures outgoing email.
+type Mail struct {
+	Driver       string `yaml:"driver" env:"MAIL_DRIVER" usage:"how email is delivered: smtp, file (save .eml files) or log"`
+	From         string `yaml:"from" env:"MAIL_FROM" usage:"sender address"`
+	BaseURL      string `yaml:"base_url" env:"PUBLIC_URL" usage:"public URL that links in emails point at"`
+	Dir          string `yaml:"dir" env:"MAIL_DIR" usage:"directory the file driver saves emails to"`
+	SMTPHost     string `yaml:"smtp_host" env:"SMTP_HOST" usage:"SMTP server host"`
+	SMTPPort     int    `yaml:"smtp_port" env:"SMTP_PORT" usage:"SMTP server port"`
+	SMTPUsername string `yaml:"smtp_username" env:"SMTP_USERNAME" usage:"SMTP user; empty sends without authenticating"`
+	SMTPPassword string `yaml:"smtp_password" env:"SMTP_PASSWORD" secret:"true" usage:"SMTP password"`
+}
+
+// defaultSQLiteFile is the SQLite database used when no DSN is configured.
+const defaultSQLiteFile = "reddit_clone.db"
+
+// Default returns the configuration used when nothing overrides it. The DSN is
+// left empty; Load fills in defaultSQLiteFile when the driver is SQLite.
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Port:              8080,
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Database: Database{
+			Driver:       database.DriverSQLite,
+			MaxOpenConns: runtime.NumCPU() * 2,
+			BusyTimeout:  5 * time.Second,
+			AutoMigrate:  true,
+		},
+		Auth: Auth{
+			BcryptCost:             bcrypt.DefaultCost,
+			LoginFailureWindow:     time.Hour,
+			LoginFreeFailures:      3,
+			LoginBackoff:           time.Second,
+			LoginLockoutFailures:   10,
+			LoginLockoutDuration:   15 * time.Minute,
+			LoginIPFreeFailures:    10,
+			LoginIPLockoutFailures: 50,
+			EmailVerificationTTL:   24 * time.Hour,
+			PasswordResetTTL:       time.Hour,
+			TOTPIssuer:             "Reddit Clone",
+			TwoFactorChallengeTTL:  5 * time.Minute,
+			AccessTokenTTL:         15 * time.Minute,
+			RefreshTokenTTL:        30 * 24 * time.Hour,
+			AccountDeletionGrace:   14 * 24 * time.Hour,
+			AccountPurgeInterval:   time.Hour,
+		},
+		Pagination: Pagination{
+			DefaultLimit: 10,
+			MaxLimit:     100,
+		},
+		Log: Log{
+			Level: "info",
+		},
+		Tracing: Tracing{
+			Exporter: tracing.ExporterNone,
+		},
+		RateLimit: RateLimit{
+			Enabled:  true,
+			Default:  "300/1m",
+			Register: "10/1h",
+			Login:    "10/1m",
+			Email:    "5/1h",
+			Posting:  "20/10m",
+			Messages: "30/10m",
+			Votes:    "60/1m",
+		},
+		Mail: Mail{
+			Driver:   mail.DriverLog,
+			From:     "Reddit Clone <no-reply@localhost>",
+			BaseURL:  "http://localhost:8080",
+			Dir:      "mail",
+			SMTPPort: 587,
+		},
+	}
+}
+
+// Validate reports every setting that is out of range, naming each by its
+// dotted name.
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(ok bool, name, format string, args ...any) {
+		if !ok {
+			problems = append(problems, name+": "+fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port", "must be between 1 and 65535")
+	check(c.Server.ReadHeaderTimeout > 0, "server.read_header_timeout", "must be positive")
+	check(c.Server.ReadTimeout > 0, "server.read_timeout", "must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout", "must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout", "must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout", "must be positive")
+	check(c.Server.TrustedProxies >= 0, "server.trusted_proxies", "must not be negative")
+
+	switch c.Database.Driver {
+	case database.DriverSQLite, database.DriverPostgres:
+		check(c.Database.DSN != "", "database.dsn", "is required for the %s driver", c.Database.Driver)
+	case database.DriverMemory:
+	default:
+		check(false, "database.driver", "must be sqlite, postgres or memory")
+	}
+	check(c.Database.MaxOpenConns > 0, "database.max_open_conns", "must be positive")
+	check(c.Database.BusyTimeout > 0, "database.busy_timeout", "must be positive")
+
+	check(c.Auth.BcryptCost >= bcrypt.MinCost && c.Auth.BcryptCost <= bcrypt.MaxCost,
+		"auth.bcrypt_cost", "must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	check(c.Auth.LoginFailureWindow > 0, "auth.login_failure_window", "must be positive")
+	check(c.Auth.LoginFreeFailures >= 0, "auth.login_free_failures", "must not be negative")
+	check(c.Auth.LoginBackoff > 0, "auth.login_backoff", "must be positive")
+	check(c.Auth.LoginLockoutFailures > c.Auth.LoginFreeFailures,
+		"auth.login_lockout_failures", "must be greater than auth.login_free_failures")
+	check(c.Auth.LoginLockoutDuration > 0, "auth.login_lockout_duration", "must be positive")
+	check(c.Auth.LoginIPFreeFailures >= 0, "auth.login_ip_free_failures", "must not be negative")
+	check(c.Auth.LoginIPLockoutFailures > c.Auth.LoginIPFreeFailures,
+		"auth.login_ip_lockout_failures", "must be greater than auth.login_ip_free_failures")
+	check(c.Auth.EmailVerificationTTL > 0, "auth.email_verification_ttl", "must be positive")
+	check(c.Auth.PasswordResetTTL > 0, "auth.password_reset_ttl", "must be positive")
+	check(c.Auth.TOTPIssuer != "" && !strings.Contains(c.Auth.TOTPIssuer, ":"), "auth.totp_issuer", "must be set and not contain a colon")
+	if c.Auth.TOTPEncryptionKey != "" {
+		_, err := secretbox.ParseKey(c.Auth.TOTPEncryptionKey)
+		check(err == nil, "auth.totp_encryption_key", fmt.Sprint(err))
+	}
+	check(c.Auth.TwoFactorChallengeTTL > 0, "auth.two_factor_challenge_ttl", "must be positive")
+	check(c.Auth.AccessTokenTTL > 0, "auth.access_token_ttl", "must be positive")
+	check(c.Auth.RefreshTokenTTL > c.Auth.AccessTokenTTL, "auth.refresh_token_ttl", "must be longer than auth.access_token_ttl")
+	check(c.Auth.AccountDeletionGrace >= 0, "auth.account_deletion_grace", "must not be negative")
+	check(c.Auth.AccountPurgeInterval > 0, "auth.account_purge_interval", "must be positive")
+
+	check(c.Pagination.MaxLimit > 0, "pagination.max_limit", "must be positive")
+	check(c.Pagination.DefaultLimit > 0 && c.Pagination.DefaultLimit <= c.Pagination.MaxLimit,
+		"pagination.default_limit", "must be between 1 and pagination.max_limit")
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level", "must be debug, info, warn or error")
+
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout:
+	default:
+		check(false, "tracing.exporter", "must be none, otlp or stdout")
+	}
+
+	for _, r := range []struct{ name, rate string }{
+		{"default", c.RateLimit.Default},
+		{"register", c.RateLimit.Register},
+		{"login", c.RateLimit.Login},
+		{"email", c.RateLimit.Email},
+		{"posting", c.RateLimit.Posting},
+		{"messages", c.RateLimit.Messages},
+		{"votes", c.RateLimit.Votes},
+	} {
+		if r.rate == "" {
+			continue
+		}
+		_, err := ratelimit.ParsePolicy(r.name, r.rate)
+		check(err == nil, "rate_limit."+r.name, "%v", err)
+	}
+
+	switch c.Mail.Driver {
+	case mail.DriverSMTP:
+		check(c.Mail.SMTPHost != "", "mail.smtp_host", "is required for the smtp driver")
+		check(c.Mail.SMTPPort > 0 && c.Mail.SMTPPort <= 65535, "mail.smtp_port", "must be between 1 and 65535")
+	case mail.DriverFile:
+		check(c.Mail.Dir != "", "mail.dir", "is required for the file driver")
+	case mail.DriverLog:
+	default:
+		check(false, "mail.driver", "must be smtp, file or log")
+	}
+	_, err := netmail.ParseAddress(c.Mail.From)
+	check(err == nil, "mail.from", "must be an email address")
+	u, err := url.Parse(c.Mail.BaseURL)
+	check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "mail.base_url", "must be an http or https URL")
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration:\n  " + strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// LogLevel returns the validated log level.
+func (c *Config) LogLevel() slog.Level {
+	var level slog.Level
+	level.UnmarshalText([]byte(c.Log.Level))
+	return level
+}
+
+// MailConfig returns the settings mail.New needs.
+func (c *Config) MailConfig() mail.Config {
+	return mail.Config{
+		Driver:       c.Mail.Driver,
+		From:         c.Mail.From,
+		Dir:          c.Mail.Dir,
+		SMTPHost:     c.Mail.SMTPHost,
+		SMTPPort:     c.Mail.SMTPPort,
+		SMTPUsername: c.Mail.SMTPUsername,
+		SMTPPassword: c.Mail.SMTPPassword,
+	}
+}
+
+// DatabaseConfig returns the settings database.Connect needs.
+func (c *Config) DatabaseConfig() database.Config {
+	return database.Config{
+		Driver:       c.Database.Driver,
+		DSN:          c.Database.DSN,
+		MaxOpenConns: c.Database.MaxOpenConns,
+		BusyTimeout:  c.Database.BusyTimeout,
+	}
+}
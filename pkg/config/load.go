@@ -0,0 +1,249 @@
+// File: pkg/config/load.go
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"redditclone/pkg/database"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileEnv names the environment variable holding the config file path when
+// the -config flag is not given.
+const fileEnv = "CONFIG_FILE"
+
+// masked replaces secret values in printed configurations.
+const masked = "********"
+
+// setting is one leaf of Config, addressed by its dotted name.
+type setting struct {
+	name   string // Dotted name, e.g. "database.driver"; also the flag name.
+	env    string // Environment variable, or "" if none.
+	secret string // "true" to mask the whole value, "url" to mask a URL's password.
+	usage  string
+	path   []int // Field index path from Config.
+}
+
+// settings lists every leaf of Config in declaration order.
+var settings = collectSettings(reflect.TypeOf(Config{}), "", nil)
+
+func collectSettings(t reflect.Type, prefix string, path []int) []setting {
+	var out []setting
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := prefix + strings.Split(field.Tag.Get("yaml"), ",")[0]
+		fieldPath := append(append([]int(nil), path...), i)
+		if field.Type.Kind() == reflect.Struct {
+			out = append(out, collectSettings(field.Type, name+".", fieldPath)...)
+			continue
+		}
+		out = append(out, setting{
+			name:   name,
+			env:    field.Tag.Get("env"),
+			secret: field.Tag.Get("secret"),
+			usage:  field.Tag.Get("usage"),
+			path:   fieldPath,
+		})
+	}
+	return out
+}
+
+// Loader collects the config file path and flag overrides from a flag set and
+// builds the configuration from them.
+type Loader struct {
+	file      string
+	overrides map[string]string
+}
+
+// Flags registers -config and one flag per setting on fs. Parse fs, then call
+// Load.
+func Flags(fs *flag.FlagSet) *Loader {
+	l := &Loader{overrides: make(map[string]string)}
+	fs.StringVar(&l.file, "config", "", "path to a YAML or JSON config file (default $"+fileEnv+")")
+	defaults := Default()
+	for _, s := range settings {
+		s := s
+		usage := s.usage
+		if s.env != "" {
+			usage += " ($" + s.env + ")"
+		}
+		if def := format(field(defaults, s)); def != "" && def != "0s" && s.secret == "" {
+			usage += " (default " + def + ")"
+		}
+		fs.Func(s.name, usage, func(value string) error {
+			if err := set(field(Default(), s), value); err != nil {
+				return err
+			}
+			l.overrides[s.name] = value
+			return nil
+		})
+	}
+	return l
+}
+
+// Load builds the configuration: defaults, then the config file, then
+// environment variables, then flags. The result is validated.
+func (l *Loader) Load() (*Config, error) {
+	cfg := Default()
+
+	path := l.file
+	if path == "" {
+		path = os.Getenv(fileEnv)
+	}
+	if path != "" {
+		if err := loadFile(cfg, path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if s.env == "" {
+			continue
+		}
+		value, ok := os.LookupEnv(s.env)
+		if !ok || value == "" {
+			continue
+		}
+		if err := set(field(cfg, s), value); err != nil {
+			return nil, fmt.Errorf("%s: %v", s.env, err)
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := l.overrides[s.name]; ok {
+			// Values were checked when the flag was parsed.
+			set(field(cfg, s), value)
+		}
+	}
+
+	if cfg.Database.Driver == database.DriverSQLite && cfg.Database.DSN == "" {
+		cfg.Database.DSN = defaultSQLiteFile
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile overlays the YAML or JSON file at path onto cfg. Unknown keys are
+// an error, so typos do not go unnoticed.
+func loadFile(cfg *Config, path string) error {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml", ".json":
+	default:
+		return fmt.Errorf("config file %s: unsupported format %q; use .yaml, .yml or .json", path, ext)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %v", err)
+	}
+	// JSON is a subset of YAML, so one decoder reads both.
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// field returns the settable field of cfg that s describes.
+func field(cfg *Config, s setting) reflect.Value {
+	return reflect.ValueOf(cfg).Elem().FieldByIndex(s.path)
+}
+
+// set parses value into v according to v's type.
+func set(v reflect.Value, value string) error {
+	switch v.Interface().(type) {
+	case string:
+		v.SetString(value)
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		v.SetBool(b)
+	case int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		v.SetInt(int64(n))
+	case time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		v.SetInt(int64(d))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+// format renders v the way set parses it.
+func format(v reflect.Value) string {
+	switch value := v.Interface().(type) {
+	case string:
+		return value
+	case time.Duration:
+		return value.String()
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Masked returns a copy of c with secrets hidden: tokens are replaced by
+// ********, and connection strings keep everything but their password.
+func (c *Config) Masked() *Config {
+	out := *c
+	for _, s := range settings {
+		v := field(&out, s)
+		if s.secret == "" || v.String() == "" {
+			continue
+		}
+		switch s.secret {
+		case "url":
+			v.SetString(maskURL(v.String()))
+		default:
+			v.SetString(masked)
+		}
+	}
+	return &out
+}
+
+// keywordPassword matches the password in a key=value PostgreSQL DSN.
+var keywordPassword = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// maskURL hides the password in a connection URL or key=value DSN. Other
+// values, such as SQLite file names, are returned unchanged.
+func maskURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return keywordPassword.ReplaceAllString(raw, "${1}"+masked)
+	}
+	return u.Redacted()
+}
+
+// Print writes c as YAML with secrets masked.
+func (c *Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c.Masked()); err != nil {
+		return err
+	}
+	return enc.Close()
+}
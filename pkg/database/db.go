@@ -3,141 +3,248 @@
 package database
 
 import (
+	"context"
 	"database/sql"
-    "sync"
-	_ "modernc.org/sqlite"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"redditclone/pkg/logging"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
 )
-  
-var (
-    DB   *sql.DB
-    DBMu sync.Mutex
+
+// Supported storage drivers.
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	// DriverMemory keeps all data in process memory. It has no connection;
+	// callers use the in-memory repositories instead of calling Connect.
+	DriverMemory = "memory"
 )
 
-  func Connect() (*sql.DB, error) {
-	// For an in-memory database:
-	db, err := sql.Open("sqlite", "reddit_clone.db")
+// Config selects the storage driver and the data source to connect to.
+type Config struct {
+	Driver       string        // DriverSQLite, DriverPostgres or DriverMemory.
+	DSN          string        // File name for SQLite, connection URL for PostgreSQL.
+	MaxOpenConns int           // Size of the read pool (SQLite) or the shared pool (PostgreSQL).
+	BusyTimeout  time.Duration // How long SQLite waits on a locked database before returning SQLITE_BUSY.
+}
+
+// DB holds the connection pools used by the repositories. Writes go through
+// Write and reads through Read, so reads never queue behind writes.
+//
+// SQLite allows a single writer at a time, so its Write pool holds one
+// connection and transactions take the write lock up front; the Read pool is
+// opened read-only and runs concurrently thanks to WAL mode. PostgreSQL
+// handles concurrency itself, so both fields share one pool.
+type DB struct {
+	Write  *sql.DB
+	Read   *sql.DB
+	Driver string
+}
+
+// Close closes both connection pools.
+func (db *DB) Close() error {
+	err := db.Write.Close()
+	if db.Read != db.Write {
+		if rerr := db.Read.Close(); err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+// PingContext checks that both connection pools can reach the database.
+func (db *DB) PingContext(ctx context.Context) error {
+	if err := db.Write.PingContext(ctx); err != nil {
+		return fmt.Errorf("PingContext: %v", err)
+	}
+	if db.Read != db.Write {
+		if err := db.Read.PingContext(ctx); err != nil {
+			return fmt.Errorf("PingContext: %v", err)
+		}
+	}
+	return nil
+}
+
+// Connect opens the connection pools for the configured driver.
+func Connect(cfg Config) (*DB, error) {
+	if cfg.MaxOpenConns <= 0 {
+		cfg.MaxOpenConns = runtime.NumCPU() * 2
+	}
+	switch cfg.Driver {
+	case DriverSQLite:
+		return connectSQLite(cfg)
+	case DriverPostgres:
+		if cfg.DSN == "" {
+			return nil, fmt.Errorf("Connect: a DSN is required for the %s driver", cfg.Driver)
+		}
+		pool, err := open("postgres", cfg.DSN)
+		if err != nil {
+			return nil, err
+		}
+		pool.SetMaxOpenConns(cfg.MaxOpenConns)
+		pool.SetMaxIdleConns(cfg.MaxOpenConns)
+		pool.SetConnMaxLifetime(30 * time.Minute)
+		return &DB{Write: pool, Read: pool, Driver: cfg.Driver}, nil
+	default:
+		return nil, fmt.Errorf("Connect: unsupported database driver %q", cfg.Driver)
+	}
+}
+
+// connectSQLite opens a single-connection writer and a read-only reader pool
+// on the same file. Pragmas are set in the DSN so that every pooled connection
+// gets them, not just the first one.
+func connectSQLite(cfg Config) (*DB, error) {
+	pragmas := []string{
+		"foreign_keys(1)", // SQLite doesn't enforce foreign keys by default.
+		"journal_mode(WAL)",
+		"synchronous(NORMAL)",
+		fmt.Sprintf("busy_timeout(%d)", cfg.BusyTimeout.Milliseconds()),
+	}
+
+	write, err := open("sqlite", withSQLitePragmas(cfg.DSN, pragmas)+"&_txlock=immediate")
 	if err != nil {
-	  return nil, err
+		return nil, err
 	}
-  
-	// By default, SQLite doesn't enforce foreign keys without PRAGMA
-	_, err = db.Exec("PRAGMA foreign_keys = ON;")
+	write.SetMaxOpenConns(1)
+	write.SetMaxIdleConns(1)
+	write.SetConnMaxLifetime(0)
+
+	read, err := open("sqlite", withSQLitePragmas(cfg.DSN, append(pragmas, "query_only(1)")))
 	if err != nil {
-	  return nil, err
-	}
-    DB = db
-	return DB, nil
-  }
-  
-
-  func InitializeSchema(db *sql.DB) error {
-    // Enable foreign key enforcement in SQLite
-    if _, err := db.Exec("PRAGMA foreign_keys = ON;"); err != nil {
-        return err
-    }
-
-    // Users table
-    createUserTable := `
-    CREATE TABLE IF NOT EXISTS users (
-        id INTEGER PRIMARY KEY AUTOINCREMENT,
-        username TEXT UNIQUE NOT NULL,
-        email TEXT UNIQUE NOT NULL,
-        password TEXT NOT NULL,
-        created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-        updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-    );`
-    if _, err := db.Exec(createUserTable); err != nil {
-        return err
-    }
-
-    // Subreddits table
-    createSubredditTable := `
-    CREATE TABLE IF NOT EXISTS subreddits (
-        id INTEGER PRIMARY KEY AUTOINCREMENT,
-        name TEXT UNIQUE NOT NULL,
-        description TEXT,
-        created_by INTEGER NOT NULL,
-        created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-        updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-        FOREIGN KEY(created_by) REFERENCES users(id) ON DELETE CASCADE
-    );`
-    if _, err := db.Exec(createSubredditTable); err != nil {
-        return err
-    }
-
-    // Posts table
-    createPostTable := `
-    CREATE TABLE IF NOT EXISTS posts (
-        id INTEGER PRIMARY KEY AUTOINCREMENT,
-        title TEXT NOT NULL,
-        content TEXT NOT NULL,
-        author_id INTEGER NOT NULL,
-        subreddit_id INTEGER NOT NULL,
-        karma INTEGER NOT NULL DEFAULT 0,
-        created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-        updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-        FOREIGN KEY(author_id) REFERENCES users(id) ON DELETE CASCADE,
-        FOREIGN KEY(subreddit_id) REFERENCES subreddits(id) ON DELETE CASCADE
-    );`
-    if _, err := db.Exec(createPostTable); err != nil {
-        return err
-    }
-
-    // Comments table
-    createCommentTable := `
-    CREATE TABLE IF NOT EXISTS comments (
-        id INTEGER PRIMARY KEY AUTOINCREMENT,
-        content TEXT NOT NULL,
-        author_id INTEGER NOT NULL,
-        post_id INTEGER NOT NULL,
-        parent_id INTEGER,
-        karma INTEGER NOT NULL DEFAULT 0,
-        created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-        updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-        FOREIGN KEY(author_id) REFERENCES users(id) ON DELETE CASCADE,
-        FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
-        FOREIGN KEY(parent_id) REFERENCES comments(id) ON DELETE CASCADE
-    );`
-    if _, err := db.Exec(createCommentTable); err != nil {
-        return err
-    }
-
-    // Votes table
-    createVoteTable := `
-    CREATE TABLE IF NOT EXISTS votes (
-        id INTEGER PRIMARY KEY AUTOINCREMENT,
-        user_id INTEGER NOT NULL,
-        post_id INTEGER,
-        comment_id INTEGER,
-        vote_type TEXT NOT NULL CHECK (vote_type IN ('upvote', 'downvote')),
-        created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-        updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-        FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
-        FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
-        FOREIGN KEY(comment_id) REFERENCES comments(id) ON DELETE CASCADE,
-        UNIQUE(user_id, post_id, comment_id)
-    );`
-    if _, err := db.Exec(createVoteTable); err != nil {
-        return err
-    }
-
-    // Messages table
-    createMessageTable := `
-    CREATE TABLE IF NOT EXISTS messages (
-        id INTEGER PRIMARY KEY AUTOINCREMENT,
-        sender_id INTEGER NOT NULL,
-        receiver_id INTEGER NOT NULL,
-        content TEXT NOT NULL,
-        parent_id INTEGER,
-        created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-        updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-        FOREIGN KEY(sender_id) REFERENCES users(id) ON DELETE CASCADE,
-        FOREIGN KEY(receiver_id) REFERENCES users(id) ON DELETE CASCADE,
-        FOREIGN KEY(parent_id) REFERENCES messages(id) ON DELETE CASCADE
-    );`
-    if _, err := db.Exec(createMessageTable); err != nil {
-        return err
-    }
-
-    return nil
+		write.Close()
+		return nil, err
+	}
+	read.SetMaxOpenConns(cfg.MaxOpenConns)
+	read.SetMaxIdleConns(cfg.MaxOpenConns)
+	read.SetConnMaxLifetime(0)
+
+	return &DB{Write: write, Read: read, Driver: cfg.Driver}, nil
+}
+
+// open opens and pings a connection pool.
+func open(driver, dsn string) (*sql.DB, error) {
+	pool, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := pool.Ping(); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return pool, nil
+}
+
+// withSQLitePragmas appends _pragma query parameters to a SQLite DSN.
+func withSQLitePragmas(dsn string, pragmas []string) string {
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	for _, pragma := range pragmas {
+		dsn += sep + "_pragma=" + pragma
+		sep = "&"
+	}
+	return dsn
+}
+
+// Retry settings for writes that fail because the database is busy.
+const (
+	maxRetries   = 5
+	retryBackoff = 20 * time.Millisecond
+)
+
+// IsBusy reports whether err is SQLite reporting the database as busy or locked.
+func IsBusy(err error) bool {
+	var serr *sqlite.Error
+	if !errors.As(err, &serr) {
+		return false
+	}
+	code := serr.Code() & 0xff // Strip the extended result code.
+	return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+}
+
+// IsUniqueViolation reports whether err is a UNIQUE or PRIMARY KEY constraint
+// failure from either driver.
+func IsUniqueViolation(err error) bool {
+	var serr *sqlite.Error
+	if errors.As(err, &serr) {
+		code := serr.Code()
+		return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	var perr *pq.Error
+	if errors.As(err, &perr) {
+		return perr.Code == "23505" // unique_violation
+	}
+	return false
+}
+
+// Retry runs a write, retrying with exponential backoff while SQLite reports
+// the database as busy. The busy timeout already makes SQLite wait for the
+// lock, so this only covers contention that outlasts it, such as another
+// process holding the file during a checkpoint.
+//
+// Each retry is logged against the request carried by ctx and recorded as an
+// event on its span, and the wait ends early when ctx is cancelled.
+func Retry(ctx context.Context, op func() error) error {
+	backoff := retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || !IsBusy(err) || attempt == maxRetries {
+			return err
+		}
+		logging.FromContext(ctx).Warn("database busy, retrying write",
+			"attempt", attempt, "backoff_ms", backoff.Milliseconds())
+		trace.SpanFromContext(ctx).AddEvent("database busy, retrying write",
+			trace.WithAttributes(attribute.Int("attempt", attempt)))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// ExecContext runs a write statement on the writer pool, retrying while the database is busy.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	var result sql.Result
+	err := Retry(ctx, func() error {
+		var err error
+		result, err = db.Write.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+// sqliteTimeFormat is how SQLite's CURRENT_TIMESTAMP renders, and so how
+// timestamp columns are stored in SQLite.
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
+// TimeArg converts t into a query argument that compares correctly against
+// timestamp columns. SQLite compares timestamps as text, so the value must
+// use the stored format; PostgreSQL takes the time as is.
+func (db *DB) TimeArg(t time.Time) any {
+	if db.Driver == DriverSQLite {
+		return t.UTC().Format(sqliteTimeFormat)
+	}
+	return t
+}
+
+// ForUpdate returns the clause that locks the rows a SELECT reads until the
+// write transaction ends. SQLite write transactions already hold the
+// database's write lock from the start (_txlock=immediate), so it needs none.
+func (db *DB) ForUpdate() string {
+	if db.Driver == DriverSQLite {
+		return ""
+	}
+	return " FOR UPDATE"
 }
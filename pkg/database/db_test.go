@@ -0,0 +1,162 @@
+// File: pkg/database/db_test.go
+
+package database_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"redditclone/pkg/database"
+)
+
+// openSQLite connects to a fresh SQLite file with every migration applied.
+func openSQLite(tb testing.TB, maxOpenConns int) *database.DB {
+	tb.Helper()
+	cfg := database.Config{
+		Driver:       database.DriverSQLite,
+		DSN:          filepath.Join(tb.TempDir(), "bench.db"),
+		MaxOpenConns: maxOpenConns,
+		BusyTimeout:  5 * time.Second,
+	}
+	db, err := database.Connect(cfg)
+	if err != nil {
+		tb.Fatalf("Connect: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+	migrator, err := database.NewMigrator(db.Write, cfg.Driver, io.Discard)
+	if err != nil {
+		tb.Fatalf("NewMigrator: %v", err)
+	}
+	if _, err := migrator.Up(0); err != nil {
+		tb.Fatalf("Up: %v", err)
+	}
+	return db
+}
+
+// seedUsers inserts n users and returns the number inserted.
+func seedUsers(tb testing.TB, db *database.DB, n int) int {
+	tb.Helper()
+	for i := 0; i < n; i++ {
+		_, err := db.ExecContext(context.Background(),
+			`INSERT INTO users (username, email, password) VALUES ($1, $2, 'hash')`,
+			fmt.Sprintf("seed%d", i), fmt.Sprintf("seed%d@example.com", i))
+		if err != nil {
+			tb.Fatalf("seeding users: %v", err)
+		}
+	}
+	return n
+}
+
+func TestConnectSQLite(t *testing.T) {
+	db := openSQLite(t, 4)
+
+	var mode string
+	if err := db.Read.QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil {
+		t.Fatalf("journal_mode: %v", err)
+	}
+	if mode != "wal" {
+		t.Errorf("journal_mode = %q, want wal", mode)
+	}
+
+	tests := []struct {
+		name    string
+		pool    func() error
+		wantErr bool
+	}{
+		{"write pool writes", func() error {
+			_, err := db.Write.Exec(`INSERT INTO users (username, email, password) VALUES ('w', 'w@example.com', 'hash')`)
+			return err
+		}, false},
+		{"read pool is read-only", func() error {
+			_, err := db.Read.Exec(`INSERT INTO users (username, email, password) VALUES ('r', 'r@example.com', 'hash')`)
+			return err
+		}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.pool(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+// BenchmarkReadPool runs point reads from parallel goroutines, once through a
+// single connection, as every query ran under the old global mutex, and once
+// through the WAL read pool.
+func BenchmarkReadPool(b *testing.B) {
+	for _, conns := range []int{1, runtime.NumCPU() * 2} {
+		b.Run(fmt.Sprintf("conns=%d", conns), func(b *testing.B) {
+			db := openSQLite(b, conns)
+			users := seedUsers(b, db, 1000)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				rng := rand.New(rand.NewSource(rand.Int63()))
+				var username string
+				for pb.Next() {
+					err := db.Read.QueryRow(`SELECT username FROM users WHERE id = $1`, rng.Intn(users)+1).Scan(&username)
+					if err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkWritePool runs inserts from parallel goroutines through the
+// single-connection writer, retrying while the database is busy.
+func BenchmarkWritePool(b *testing.B) {
+	db := openSQLite(b, 0)
+	var next atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			n := next.Add(1)
+			_, err := db.ExecContext(context.Background(),
+				`INSERT INTO users (username, email, password) VALUES ($1, $2, 'hash')`,
+				fmt.Sprintf("bench%d", n), fmt.Sprintf("bench%d@example.com", n))
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// BenchmarkMixed runs one write to every nine reads from parallel goroutines,
+// so reads on the read pool overlap with writes.
+func BenchmarkMixed(b *testing.B) {
+	db := openSQLite(b, 0)
+	users := seedUsers(b, db, 1000)
+	var next atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		rng := rand.New(rand.NewSource(rand.Int63()))
+		var username string
+		for pb.Next() {
+			if rng.Intn(10) == 0 {
+				n := next.Add(1)
+				_, err := db.ExecContext(context.Background(),
+					`INSERT INTO users (username, email, password) VALUES ($1, $2, 'hash')`,
+					fmt.Sprintf("mixed%d", n), fmt.Sprintf("mixed%d@example.com", n))
+				if err != nil {
+					b.Error(err)
+					return
+				}
+				continue
+			}
+			err := db.Read.QueryRow(`SELECT username FROM users WHERE id = $1`, rng.Intn(users)+1).Scan(&username)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,304 @@
+// File: pkg/database/migrate.go
+
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationTableDDL creates the table that records applied migrations.
+var migrationTableDDL = map[string]string{
+	DriverSQLite: `
+    CREATE TABLE IF NOT EXISTS schema_migrations (
+        version INTEGER PRIMARY KEY,
+        name TEXT NOT NULL,
+        checksum TEXT NOT NULL,
+        applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );`,
+	DriverPostgres: `
+    CREATE TABLE IF NOT EXISTS schema_migrations (
+        version INTEGER PRIMARY KEY,
+        name TEXT NOT NULL,
+        checksum TEXT NOT NULL,
+        applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );`,
+}
+
+// migrationName matches files such as 0002_activity_indexes.up.sql.
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one numbered schema change with its up and down SQL.
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 of the up SQL, recorded when the migration is applied.
+}
+
+// MigrationStatus reports whether a migration has been applied.
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// LoadMigrations reads the embedded migrations for a driver, ordered by version.
+func LoadMigrations(driver string) ([]Migration, error) {
+	dir := path.Join("migrations", driver)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("LoadMigrations: no migrations for driver %q", driver)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("LoadMigrations: unexpected file %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		body, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("LoadMigrations: %v", err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("LoadMigrations: version %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+			sum := sha256.Sum256(body)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("LoadMigrations: migration %04d_%s needs both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies and reverts the embedded migrations for one database.
+type Migrator struct {
+	DB         *sql.DB
+	Driver     string
+	Migrations []Migration
+	DryRun     bool      // When set, SQL is written to Out instead of being executed.
+	Out        io.Writer // Receives progress lines and, in dry-run mode, the SQL.
+}
+
+// NewMigrator creates a Migrator and ensures the schema_migrations table exists.
+func NewMigrator(db *sql.DB, driver string, out io.Writer) (*Migrator, error) {
+	ddl, ok := migrationTableDDL[driver]
+	if !ok {
+		return nil, fmt.Errorf("NewMigrator: unsupported database driver %q", driver)
+	}
+	migrations, err := LoadMigrations(driver)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(ddl); err != nil {
+		return nil, fmt.Errorf("NewMigrator: %v", err)
+	}
+	return &Migrator{DB: db, Driver: driver, Migrations: migrations, Out: out}, nil
+}
+
+// applied returns the checksum and time of each applied migration, keyed by version.
+func (m *Migrator) applied() (map[int]appliedMigration, error) {
+	rows, err := m.DB.Query(`SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int]appliedMigration)
+	for rows.Next() {
+		var a appliedMigration
+		var version int
+		if err := rows.Scan(&version, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		result[version] = a
+	}
+	return result, rows.Err()
+}
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+// Verify checks that every applied migration still matches its embedded SQL
+// and that the database is not ahead of this build.
+func (m *Migrator) Verify() error {
+	applied, err := m.applied()
+	if err != nil {
+		return fmt.Errorf("Verify: %v", err)
+	}
+	known := make(map[int]bool, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		known[migration.Version] = true
+		a, ok := applied[migration.Version]
+		if ok && a.checksum != migration.Checksum {
+			return fmt.Errorf("Verify: migration %04d_%s was modified after it was applied (checksum mismatch)", migration.Version, migration.Name)
+		}
+	}
+	for version := range applied {
+		if !known[version] {
+			return fmt.Errorf("Verify: database has migration %04d applied, which this build does not know about", version)
+		}
+	}
+	return nil
+}
+
+// Status lists every known migration and whether it has been applied.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, fmt.Errorf("Status: %v", err)
+	}
+	statuses := make([]MigrationStatus, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if a, ok := applied[migration.Version]; ok {
+			appliedAt := a.appliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations that have not been applied yet.
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, fmt.Errorf("Pending: %v", err)
+	}
+	var pending []Migration
+	for _, migration := range m.Migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up verifies checksums and applies up to steps pending migrations in order.
+// A steps value of 0 or less applies all of them.
+func (m *Migrator) Up(steps int) ([]Migration, error) {
+	if err := m.Verify(); err != nil {
+		return nil, err
+	}
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+	if steps > 0 && steps < len(pending) {
+		pending = pending[:steps]
+	}
+
+	for _, migration := range pending {
+		err := m.run(migration, "up", migration.Up, func(tx *sql.Tx) error {
+			_, err := tx.Exec(`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+				migration.Version, migration.Name, migration.Checksum)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pending, nil
+}
+
+// Down verifies checksums and reverts up to steps applied migrations, newest
+// first. A steps value of 0 or less reverts one migration.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	if err := m.Verify(); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return nil, fmt.Errorf("Down: %v", err)
+	}
+	if steps <= 0 {
+		steps = 1
+	}
+
+	var reverted []Migration
+	for i := len(m.Migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := m.Migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		err := m.run(migration, "down", migration.Down, func(tx *sql.Tx) error {
+			_, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		reverted = append(reverted, migration)
+	}
+	return reverted, nil
+}
+
+// run executes one migration script and its bookkeeping in a single transaction,
+// or prints the script when DryRun is set.
+func (m *Migrator) run(migration Migration, direction, script string, record func(tx *sql.Tx) error) error {
+	label := fmt.Sprintf("%04d_%s", migration.Version, migration.Name)
+	if m.DryRun {
+		fmt.Fprintf(m.Out, "-- dry run: %s (%s)\n%s\n", label, direction, script)
+		return nil
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("migration %s: %v", label, err)
+	}
+	if _, err := tx.Exec(script); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %s: %v", label, err)
+	}
+	if err := record(tx); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %s: %v", label, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("migration %s: %v", label, err)
+	}
+	if direction == "up" {
+		fmt.Fprintf(m.Out, "applied %s\n", label)
+	} else {
+		fmt.Fprintf(m.Out, "reverted %s\n", label)
+	}
+	return nil
+}
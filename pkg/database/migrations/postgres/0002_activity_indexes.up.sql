@@ -0,0 +1,6 @@
+-- Indexes for listing a user's activity and counting votes per target.
+CREATE INDEX IF NOT EXISTS idx_posts_author_id ON posts(author_id);
+CREATE INDEX IF NOT EXISTS idx_comments_author_id ON comments(author_id);
+CREATE INDEX IF NOT EXISTS idx_comments_post_id ON comments(post_id);
+CREATE INDEX IF NOT EXISTS idx_votes_post_id ON votes(post_id);
+CREATE INDEX IF NOT EXISTS idx_votes_comment_id ON votes(comment_id);
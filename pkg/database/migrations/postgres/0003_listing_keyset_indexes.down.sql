@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_messages_parent_created_at_id;
+DROP INDEX IF EXISTS idx_messages_receiver_created_at_id;
+DROP INDEX IF EXISTS idx_comments_parent_created_at_id;
+DROP INDEX IF EXISTS idx_comments_post_created_at_id;
+DROP INDEX IF EXISTS idx_posts_subreddit_created_at_id;
+DROP INDEX IF EXISTS idx_posts_created_at_id;
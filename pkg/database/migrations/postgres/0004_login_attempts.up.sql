@@ -0,0 +1,16 @@
+-- Audit trail of login attempts, also used to throttle repeated failures.
+-- user_id is NULL when the username matches no account.
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER,
+    username TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    result TEXT NOT NULL CHECK (result IN ('success', 'invalid_credentials', 'throttled', 'locked')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_username_id ON login_attempts(username, id);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip_created_at ON login_attempts(ip, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_user_created_at_id ON login_attempts(user_id, created_at, id);
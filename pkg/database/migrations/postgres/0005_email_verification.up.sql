@@ -0,0 +1,19 @@
+-- Email verification and password reset.
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+-- Single-use tokens mailed to users. Only a SHA-256 hash of each token is stored.
+-- email is the address a token was sent to, so a verification link stops
+-- working once the user changes their email.
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    purpose TEXT NOT NULL CHECK (purpose IN ('verify_email', 'reset_password')),
+    token_hash TEXT UNIQUE NOT NULL,
+    email TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_tokens_user_purpose ON user_tokens(user_id, purpose);
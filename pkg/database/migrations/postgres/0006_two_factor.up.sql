@@ -0,0 +1,20 @@
+-- TOTP two-factor authentication.
+-- totp_secret is encrypted with the server's TOTP key. It is set while
+-- enrolling, and 2FA is on once totp_enabled_at is set. totp_last_step is
+-- the time step of the last accepted code, so a code cannot be used twice.
+ALTER TABLE users ADD COLUMN totp_secret TEXT;
+ALTER TABLE users ADD COLUMN totp_enabled_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN totp_last_step BIGINT;
+
+-- One-time recovery codes for signing in without the authenticator.
+-- Only a SHA-256 hash of each code is stored.
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_hash ON recovery_codes(user_id, code_hash);
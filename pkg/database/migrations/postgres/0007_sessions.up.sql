@@ -0,0 +1,34 @@
+-- Login sessions. Each session has one current access token and a chain of
+-- refresh tokens; only SHA-256 hashes of the tokens are stored.
+-- expires_at is when the session ends unless refreshed; every refresh
+-- pushes it out again.
+CREATE TABLE IF NOT EXISTS sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    access_token_hash TEXT UNIQUE NOT NULL,
+    access_expires_at TIMESTAMPTZ NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    revoke_reason TEXT,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id, revoked_at);
+
+-- Every refresh token ever issued for a session. A refresh token is used once,
+-- in exchange for the next one; presenting a used token again means it was
+-- stolen, and the session is revoked.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    session_id INTEGER NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session ON refresh_tokens(session_id);
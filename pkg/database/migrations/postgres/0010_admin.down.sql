@@ -0,0 +1,12 @@
+DELETE FROM login_attempts WHERE result = 'suspended';
+ALTER TABLE login_attempts DROP CONSTRAINT IF EXISTS login_attempts_result_check;
+ALTER TABLE login_attempts ADD CONSTRAINT login_attempts_result_check
+    CHECK (result IN ('success', 'invalid_credentials', 'throttled', 'locked'));
+
+DROP TABLE IF EXISTS audit_log;
+ALTER TABLE sessions DROP COLUMN impersonator_id;
+ALTER TABLE subreddits DROP COLUMN quarantine_reason;
+ALTER TABLE subreddits DROP COLUMN quarantined_at;
+ALTER TABLE users DROP COLUMN suspension_reason;
+ALTER TABLE users DROP COLUMN suspended_at;
+ALTER TABLE users DROP COLUMN role;
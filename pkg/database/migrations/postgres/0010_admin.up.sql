@@ -0,0 +1,37 @@
+-- Site administrators have the role 'admin'; everyone else has 'user'.
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+
+-- A suspended account cannot sign in, use its API keys or OAuth grants, or
+-- post, comment, vote or send messages anywhere on the site.
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN suspension_reason TEXT NOT NULL DEFAULT '';
+
+-- A quarantined subreddit stays reachable directly but is left out of the
+-- feed and takes no new posts or comments.
+ALTER TABLE subreddits ADD COLUMN quarantined_at TIMESTAMPTZ;
+ALTER TABLE subreddits ADD COLUMN quarantine_reason TEXT NOT NULL DEFAULT '';
+
+-- Sessions an admin opened as another user for support name the admin.
+-- Not a foreign key, so the column can be dropped again.
+ALTER TABLE sessions ADD COLUMN impersonator_id INTEGER;
+
+-- Every admin action, and every request made while impersonating a user.
+-- actor_id is not a foreign key, so entries outlive the accounts they name.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id SERIAL PRIMARY KEY,
+    actor_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id INTEGER NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(target_type, target_id, id);
+
+-- Logins refused because the account is suspended are recorded as such.
+ALTER TABLE login_attempts DROP CONSTRAINT IF EXISTS login_attempts_result_check;
+ALTER TABLE login_attempts ADD CONSTRAINT login_attempts_result_check
+    CHECK (result IN ('success', 'invalid_credentials', 'throttled', 'locked', 'suspended'));
@@ -0,0 +1,10 @@
+-- Deleting an account first schedules it: during the grace period the owner
+-- can still sign in and undo it, but API keys and OAuth grants stop working.
+ALTER TABLE users ADD COLUMN deletion_requested_at TIMESTAMPTZ;
+
+-- Once the grace period is over the account is anonymized rather than
+-- removed: its posts, comments, votes and messages stay, under a "[deleted]"
+-- author, and everything private about it goes.
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_users_deletion_requested_at ON users(deletion_requested_at);
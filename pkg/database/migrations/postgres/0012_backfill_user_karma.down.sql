@@ -0,0 +1,2 @@
+-- Nothing to undo: the up migration only recomputed user_karma, which
+-- reverting leaves as it is.
@@ -0,0 +1,20 @@
+-- user_karma was added after votes were already being cast, and is only
+-- maintained incrementally from then on. Recompute it from the votes table so
+-- older votes count too: an upvote is +1, a downvote -1, and flagged votes do
+-- not count, as in VoteService.
+INSERT INTO user_karma (user_id, post_karma, comment_karma)
+SELECT author_id, SUM(post_karma), SUM(comment_karma)
+FROM (
+    SELECT p.author_id, CASE WHEN v.vote_type = 'upvote' THEN 1 ELSE -1 END AS post_karma, 0 AS comment_karma
+    FROM votes v
+    JOIN posts p ON p.id = v.post_id
+    WHERE NOT EXISTS (SELECT 1 FROM vote_flags f WHERE f.vote_id = v.id)
+    UNION ALL
+    SELECT c.author_id, 0, CASE WHEN v.vote_type = 'upvote' THEN 1 ELSE -1 END
+    FROM votes v
+    JOIN comments c ON c.id = v.comment_id
+    WHERE NOT EXISTS (SELECT 1 FROM vote_flags f WHERE f.vote_id = v.id)
+) AS karma
+WHERE author_id IN (SELECT id FROM users)
+GROUP BY author_id
+ON CONFLICT (user_id) DO UPDATE SET post_karma = excluded.post_karma, comment_karma = excluded.comment_karma;
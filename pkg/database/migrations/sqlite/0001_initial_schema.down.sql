@@ -0,0 +1,10 @@
+-- Drops every table in reverse dependency order.
+DROP TABLE IF EXISTS vote_flags;
+DROP TABLE IF EXISTS user_karma;
+DROP TABLE IF EXISTS blocks;
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS votes;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS posts;
+DROP TABLE IF EXISTS subreddits;
+DROP TABLE IF EXISTS users;
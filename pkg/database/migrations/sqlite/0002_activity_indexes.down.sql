@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_votes_comment_id;
+DROP INDEX IF EXISTS idx_votes_post_id;
+DROP INDEX IF EXISTS idx_comments_post_id;
+DROP INDEX IF EXISTS idx_comments_author_id;
+DROP INDEX IF EXISTS idx_posts_author_id;
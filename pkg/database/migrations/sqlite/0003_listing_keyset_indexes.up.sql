@@ -0,0 +1,7 @@
+-- Indexes matching the (created_at, id) order of cursor-paginated listings.
+CREATE INDEX IF NOT EXISTS idx_posts_created_at_id ON posts(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_posts_subreddit_created_at_id ON posts(subreddit_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_comments_post_created_at_id ON comments(post_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_comments_parent_created_at_id ON comments(parent_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_messages_receiver_created_at_id ON messages(receiver_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_messages_parent_created_at_id ON messages(parent_id, created_at, id);
@@ -0,0 +1,23 @@
+-- Bot accounts are run by automation rather than a person, and are labelled
+-- as such wherever the account is shown.
+ALTER TABLE users ADD COLUMN is_bot BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Long-lived API keys for scripts and bots. Only a SHA-256 hash of each key
+-- is stored, with its first characters kept so the owner can tell keys apart.
+-- scopes is a space-separated list such as "read vote".
+CREATE TABLE IF NOT EXISTS api_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT UNIQUE NOT NULL,
+    scopes TEXT NOT NULL,
+    expires_at DATETIME,
+    last_used_at DATETIME,
+    last_used_ip TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at DATETIME,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys(user_id, revoked_at);
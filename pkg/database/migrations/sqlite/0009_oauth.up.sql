@@ -0,0 +1,68 @@
+-- OAuth2 clients: third-party applications registered by a user. id is the
+-- public client_id; confidential clients also hold a secret, of which only a
+-- SHA-256 hash is stored. redirect_uris and scopes are space-separated.
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id TEXT PRIMARY KEY,
+    owner_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    secret_hash TEXT,
+    redirect_uris TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(owner_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_clients_owner ON oauth_clients(owner_id);
+
+-- The scopes a user has granted a client. Revoking a consent keeps the
+-- record, for the user to review, and revokes the client's tokens.
+CREATE TABLE IF NOT EXISTS oauth_consents (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    client_id TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at DATETIME,
+    UNIQUE(user_id, client_id),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(client_id) REFERENCES oauth_clients(id) ON DELETE CASCADE
+);
+
+-- Short-lived, single-use authorization codes, bound to the redirect URI and
+-- the PKCE code challenge of the request that produced them.
+CREATE TABLE IF NOT EXISTS oauth_codes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    code_hash TEXT UNIQUE NOT NULL,
+    client_id TEXT NOT NULL,
+    user_id INTEGER NOT NULL,
+    redirect_uri TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    code_challenge TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(client_id) REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Access and refresh token pairs issued to clients. Refreshing revokes the
+-- pair with reason "rotated" and issues a new one, so a rotated refresh token
+-- that comes back is known to be stolen.
+CREATE TABLE IF NOT EXISTS oauth_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    client_id TEXT NOT NULL,
+    user_id INTEGER NOT NULL,
+    access_token_hash TEXT UNIQUE NOT NULL,
+    access_expires_at DATETIME NOT NULL,
+    refresh_token_hash TEXT UNIQUE NOT NULL,
+    refresh_expires_at DATETIME NOT NULL,
+    scopes TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at DATETIME,
+    revoke_reason TEXT,
+    FOREIGN KEY(client_id) REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_tokens_grant ON oauth_tokens(user_id, client_id, revoked_at);
@@ -0,0 +1,25 @@
+DELETE FROM login_attempts WHERE result = 'suspended';
+CREATE TABLE login_attempts_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER,
+    username TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    result TEXT NOT NULL CHECK (result IN ('success', 'invalid_credentials', 'throttled', 'locked')),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+INSERT INTO login_attempts_old SELECT id, user_id, username, ip, user_agent, result, created_at FROM login_attempts;
+DROP TABLE login_attempts;
+ALTER TABLE login_attempts_old RENAME TO login_attempts;
+CREATE INDEX IF NOT EXISTS idx_login_attempts_username_id ON login_attempts(username, id);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip_created_at ON login_attempts(ip, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_user_created_at_id ON login_attempts(user_id, created_at, id);
+
+DROP TABLE IF EXISTS audit_log;
+ALTER TABLE sessions DROP COLUMN impersonator_id;
+ALTER TABLE subreddits DROP COLUMN quarantine_reason;
+ALTER TABLE subreddits DROP COLUMN quarantined_at;
+ALTER TABLE users DROP COLUMN suspension_reason;
+ALTER TABLE users DROP COLUMN suspended_at;
+ALTER TABLE users DROP COLUMN role;
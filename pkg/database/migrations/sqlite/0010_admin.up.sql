@@ -0,0 +1,51 @@
+-- Site administrators have the role 'admin'; everyone else has 'user'.
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+
+-- A suspended account cannot sign in, use its API keys or OAuth grants, or
+-- post, comment, vote or send messages anywhere on the site.
+ALTER TABLE users ADD COLUMN suspended_at DATETIME;
+ALTER TABLE users ADD COLUMN suspension_reason TEXT NOT NULL DEFAULT '';
+
+-- A quarantined subreddit stays reachable directly but is left out of the
+-- feed and takes no new posts or comments.
+ALTER TABLE subreddits ADD COLUMN quarantined_at DATETIME;
+ALTER TABLE subreddits ADD COLUMN quarantine_reason TEXT NOT NULL DEFAULT '';
+
+-- Sessions an admin opened as another user for support name the admin.
+-- Not a foreign key, so the column can be dropped again.
+ALTER TABLE sessions ADD COLUMN impersonator_id INTEGER;
+
+-- Every admin action, and every request made while impersonating a user.
+-- actor_id is not a foreign key, so entries outlive the accounts they name.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id INTEGER NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(target_type, target_id, id);
+
+-- Logins refused because the account is suspended are recorded as such.
+-- SQLite cannot alter a CHECK constraint, so the table is rebuilt.
+CREATE TABLE login_attempts_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER,
+    username TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    result TEXT NOT NULL CHECK (result IN ('success', 'invalid_credentials', 'throttled', 'locked', 'suspended')),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+INSERT INTO login_attempts_new SELECT id, user_id, username, ip, user_agent, result, created_at FROM login_attempts;
+DROP TABLE login_attempts;
+ALTER TABLE login_attempts_new RENAME TO login_attempts;
+CREATE INDEX IF NOT EXISTS idx_login_attempts_username_id ON login_attempts(username, id);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip_created_at ON login_attempts(ip, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_user_created_at_id ON login_attempts(user_id, created_at, id);
@@ -0,0 +1,82 @@
+// File: pkg/logging/logging.go
+
+// Package logging sets up the server's structured logger and carries the
+// request ID through contexts, so every layer can log lines that tie back to
+// the HTTP request that caused them.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+
+	"redditclone/pkg/tracing"
+)
+
+// RequestIDKey is the attribute holding the request ID on every log line.
+const RequestIDKey = "request_id"
+
+// TraceIDKey is the attribute holding the trace ID on log lines written while
+// a trace is recording.
+const TraceIDKey = "trace_id"
+
+// redacted replaces the value of sensitive attributes.
+const redacted = "[REDACTED]"
+
+// sensitiveKeys lists attribute keys whose values are never written out.
+// Keys are compared case-insensitively.
+var sensitiveKeys = map[string]bool{
+	"password":      true,
+	"new_password":  true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"secret":        true,
+	"authorization": true,
+	"cookie":        true,
+	"api_key":       true,
+	"x-admin-token": true,
+}
+
+// New returns a logger writing JSON lines to w at the given level. Values of
+// sensitive attributes such as passwords and tokens are redacted.
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redact,
+	}))
+}
+
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(attr.Key)] {
+		return slog.String(attr.Key, redacted)
+	}
+	return attr
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FromContext returns the default logger, tagged with the request ID and
+// trace ID when ctx carries them.
+func FromContext(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+	if id := RequestID(ctx); id != "" {
+		logger = logger.With(RequestIDKey, id)
+	}
+	if id := tracing.TraceID(ctx); id != "" {
+		logger = logger.With(TraceIDKey, id)
+	}
+	return logger
+}
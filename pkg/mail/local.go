@@ -0,0 +1,74 @@
+// File: pkg/mail/local.go
+
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"redditclone/pkg/logging"
+)
+
+// FileMailer saves each message as an .eml file in a directory instead of
+// sending it, for local development. Open the files with any mail client.
+type FileMailer struct {
+	dir  string
+	from string
+	seq  atomic.Int64
+}
+
+// NewFileMailer creates a FileMailer writing to dir, which is created if
+// needed.
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating mail directory: %v", err)
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+// unsafeFileChars matches characters kept out of .eml file names.
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9@._-]+`)
+
+// Send writes msg to a new file named after the time and the recipient.
+func (m *FileMailer) Send(ctx context.Context, msg *Message) error {
+	if msg.From == "" {
+		msg.From = m.from
+	}
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%03d-%s.eml",
+		time.Now().UTC().Format("20060102T150405"), m.seq.Add(1)%1000, unsafeFileChars.ReplaceAllString(msg.To, "_"))
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("saving mail: %v", err)
+	}
+	logging.FromContext(ctx).Info("mail saved", "to", msg.To, "subject", msg.Subject, "path", path)
+	return nil
+}
+
+// LogMailer writes each message's plain-text body to the log instead of
+// sending it, for local development.
+type LogMailer struct {
+	from string
+}
+
+// NewLogMailer creates a LogMailer.
+func NewLogMailer(from string) *LogMailer {
+	return &LogMailer{from: from}
+}
+
+// Send logs msg at info level.
+func (m *LogMailer) Send(ctx context.Context, msg *Message) error {
+	if msg.From == "" {
+		msg.From = m.from
+	}
+	logging.FromContext(ctx).Info("mail not sent (log mailer)", "from", msg.From, "to", msg.To, "subject", msg.Subject, "body", msg.Text)
+	return nil
+}
@@ -0,0 +1,121 @@
+// File: pkg/mail/mail.go
+
+// Package mail composes the server's emails from templates and delivers them
+// through a Mailer. SMTPMailer sends real mail; FileMailer and LogMailer keep
+// it local for development.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+)
+
+// Message is one email, with plain-text and HTML bodies.
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers messages. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Mailer drivers accepted by New.
+const (
+	DriverSMTP = "smtp"
+	DriverFile = "file"
+	DriverLog  = "log"
+)
+
+// Config selects and configures a Mailer.
+type Config struct {
+	Driver       string // DriverSMTP, DriverFile or DriverLog.
+	From         string // Sender address, e.g. "Reddit Clone <no-reply@example.com>".
+	Dir          string // Directory FileMailer writes to.
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string // Empty to send without authenticating.
+	SMTPPassword string
+}
+
+// New creates the Mailer cfg describes.
+func New(cfg Config) (Mailer, error) {
+	switch cfg.Driver {
+	case DriverSMTP:
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From), nil
+	case DriverFile:
+		return NewFileMailer(cfg.Dir, cfg.From)
+	case DriverLog:
+		return NewLogMailer(cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
+
+// Template names, one per email the server sends.
+const (
+	TemplateVerifyEmail     = "verify_email"
+	TemplateResetPassword   = "reset_password"
+	TemplatePasswordChanged = "password_changed"
+)
+
+// Each email has a NAME.txt template, which also defines "subject", and a
+// NAME.html template.
+//
+//go:embed templates
+var templateFiles embed.FS
+
+// emailTemplate holds the parsed templates of one email.
+type emailTemplate struct {
+	text *template.Template
+	html *htmltemplate.Template
+}
+
+var templates = parseTemplates(TemplateVerifyEmail, TemplateResetPassword, TemplatePasswordChanged)
+
+// parseTemplates parses the templates of each named email, panicking if one is
+// missing or malformed since they are embedded in the binary.
+func parseTemplates(names ...string) map[string]emailTemplate {
+	parsed := make(map[string]emailTemplate, len(names))
+	for _, name := range names {
+		parsed[name] = emailTemplate{
+			text: template.Must(template.ParseFS(templateFiles, "templates/"+name+".txt")),
+			html: htmltemplate.Must(htmltemplate.ParseFS(templateFiles, "templates/"+name+".html")),
+		}
+	}
+	return parsed
+}
+
+// Compose renders the named email for the recipient to. data fills in the
+// templates; values are escaped in the HTML body.
+func Compose(name, to string, data any) (*Message, error) {
+	t, ok := templates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+	var subject, text, html bytes.Buffer
+	if err := t.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("rendering %s subject: %v", name, err)
+	}
+	if err := t.text.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("rendering %s text: %v", name, err)
+	}
+	if err := t.html.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("rendering %s HTML: %v", name, err)
+	}
+	return &Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
@@ -0,0 +1,82 @@
+// File: pkg/mail/message.go
+
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Bytes encodes msg as a MIME message with text and HTML alternatives, ready
+// to hand to an SMTP server or save as an .eml file.
+func (msg *Message) Bytes() ([]byte, error) {
+	for _, v := range []string{msg.From, msg.To, msg.Subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, fmt.Errorf("mail header contains a line break: %q", v)
+		}
+	}
+
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+	header := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	header("From", msg.From)
+	header("To", msg.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", "<"+messageID()+"@"+domainOf(msg.From)+">")
+	header("MIME-Version", "1.0")
+	header("Content-Type", "multipart/alternative; boundary="+body.Boundary())
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// messageID returns a random Message-ID local part.
+func messageID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// domainOf returns the domain of an address such as "Name <user@example.com>",
+// or "localhost".
+func domainOf(address string) string {
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		if domain := strings.TrimRight(address[at+1:], "> "); domain != "" {
+			return domain
+		}
+	}
+	return "localhost"
+}
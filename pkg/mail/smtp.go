@@ -0,0 +1,63 @@
+// File: pkg/mail/smtp.go
+
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strconv"
+)
+
+// SMTPMailer sends messages through an SMTP server, upgrading the connection
+// with STARTTLS when the server offers it.
+type SMTPMailer struct {
+	host string
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer creates an SMTPMailer for the server at host:port, sending
+// from the given address. With a username it authenticates with PLAIN, which
+// net/smtp only allows over TLS or to localhost.
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	m := &SMTPMailer{
+		host: host,
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		from: from,
+	}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+// Send delivers msg, filling in the sender if msg has none. net/smtp has no
+// context support, so ctx is only checked before connecting.
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if msg.From == "" {
+		msg.From = m.from
+	}
+	from, err := netmail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %v", msg.From, err)
+	}
+	to, err := netmail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %v", msg.To, err)
+	}
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := smtp.SendMail(m.addr, m.auth, from.Address, []string{to.Address}, data); err != nil {
+		return fmt.Errorf("sending mail via %s: %v", m.addr, err)
+	}
+	return nil
+}
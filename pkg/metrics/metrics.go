@@ -0,0 +1,132 @@
+// File: pkg/metrics/metrics.go
+
+// Package metrics defines the server's Prometheus metrics and serves them on
+// /metrics. Metrics live in a dedicated registry rather than the global one, so
+// only what is registered here is exported.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "redditclone"
+
+// Registry holds every metric exported by the server.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to handle HTTP requests, by method and route template.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Time taken by SQL repository methods, by repository and method.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"repository", "method"})
+
+	// PostsCreated counts posts created.
+	PostsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "posts_created_total",
+		Help:      "Posts created.",
+	})
+
+	// VotesCast counts votes cast, by vote type.
+	VotesCast = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "votes_cast_total",
+		Help:      "Votes cast, by vote type.",
+	}, []string{"vote_type"})
+
+	// MessagesSent counts direct messages and message replies sent.
+	MessagesSent = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_sent_total",
+		Help:      "Direct messages and replies sent.",
+	})
+
+	// LoginAttempts counts login attempts, by result.
+	LoginAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_attempts_total",
+		Help:      "Login attempts, by result: success, invalid_credentials, throttled or locked.",
+	}, []string{"result"})
+
+	// RateLimited counts requests rejected by the rate limiter, by policy.
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected by the rate limiter, by policy.",
+	}, []string{"policy"})
+
+	// SessionsActive is the number of live login sessions.
+	SessionsActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sessions_active",
+		Help:      "Live login sessions.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		dbQueryDuration,
+		PostsCreated,
+		VotesCast,
+		MessagesSent,
+		LoginAttempts,
+		RateLimited,
+		SessionsActive,
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records one handled HTTP request.
+func ObserveRequest(method, route string, status int, elapsed time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(elapsed.Seconds())
+}
+
+// TimeQuery starts timing a repository method. Call the returned function when
+// the method returns:
+//
+//	defer metrics.TimeQuery("post", "GetPostByID")()
+func TimeQuery(repository, method string) func() {
+	start := time.Now()
+	return func() {
+		dbQueryDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// RegisterDBPool exports a connection pool's statistics, labelled with
+// db_name="name". They include how long callers waited for a free connection
+// (go_sql_wait_duration_seconds_total), which is where writers queue now that
+// SQLite writes share a single connection.
+func RegisterDBPool(name string, db *sql.DB) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
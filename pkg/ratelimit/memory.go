@@ -0,0 +1,61 @@
+// File: pkg/ratelimit/memory.go
+
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often MemoryStore drops buckets that have refilled.
+const sweepInterval = time.Minute
+
+// MemoryStore keeps buckets in process memory. Limits are per process, so
+// several server instances each allow the full rate.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	fullAt time.Time // When the bucket will be full again and can be forgotten.
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*memoryBucket)}
+}
+
+// Take removes one token from the bucket for key under policy.
+func (s *MemoryStore) Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+	key = policy.Name + ":" + key
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{}
+		s.buckets[key] = b
+	}
+	result := b.take(policy, now)
+	b.fullAt = now.Add(result.ResetAfter)
+	return result, nil
+}
+
+// sweep forgets full buckets, which behave exactly like missing ones, so
+// memory stays proportional to recently active clients.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !now.Before(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// File: pkg/ratelimit/ratelimit.go
+
+// Package ratelimit implements token-bucket rate limiting. A Policy sets how
+// many requests a client may make per period; a Store keeps one bucket per
+// client and policy. MemoryStore keeps buckets in process memory, and other
+// stores (say, one shared through Redis) can be plugged in through the Store
+// interface.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy allows Limit requests per Period. Buckets start full and refill
+// continuously, so a client may burst up to Limit requests and then make one
+// more every Period/Limit.
+type Policy struct {
+	Name   string        // Identifies the policy in bucket keys, metrics and logs.
+	Limit  int           // Bucket capacity, and requests allowed per Period.
+	Period time.Duration // Time to refill an empty bucket.
+}
+
+// ParsePolicy parses a rate such as "10/1m" (ten requests a minute) into a
+// policy called name.
+func ParsePolicy(name, rate string) (Policy, error) {
+	limit, period, ok := strings.Cut(rate, "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("rate %q must look like 10/1m", rate)
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 {
+		return Policy{}, fmt.Errorf("rate %q: limit must be a positive integer", rate)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Policy{}, fmt.Errorf("rate %q: period must be a positive duration", rate)
+	}
+	return Policy{Name: name, Limit: n, Period: d}, nil
+}
+
+// interval is the time it takes to earn one token.
+func (p Policy) interval() time.Duration {
+	return p.Period / time.Duration(p.Limit)
+}
+
+// Result describes a bucket after a request was counted against it.
+type Result struct {
+	Allowed    bool          // Whether the request may proceed.
+	Limit      int           // The policy's limit.
+	Remaining  int           // Whole tokens left after this request.
+	RetryAfter time.Duration // When denied, how long until a token is available.
+	ResetAfter time.Duration // How long until the bucket is full again.
+}
+
+// Store takes tokens from buckets. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	// Take removes one token from the bucket for key under policy, if one
+	// is available at now.
+	Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error)
+}
+
+// bucket is the state of one token bucket. Tokens are stored as the time at
+// which the bucket would be empty, which avoids floating-point drift: the
+// bucket holds (now - emptyAt) / interval tokens, capped at the limit.
+type bucket struct {
+	emptyAt time.Time
+}
+
+// take applies one request at now to b and returns the outcome.
+func (b *bucket) take(policy Policy, now time.Time) Result {
+	interval := policy.interval()
+	full := now.Add(-policy.Period)
+	if b.emptyAt.Before(full) {
+		b.emptyAt = full
+	}
+
+	next := b.emptyAt.Add(interval)
+	if next.After(now) {
+		return Result{
+			Allowed:    false,
+			Limit:      policy.Limit,
+			Remaining:  0,
+			RetryAfter: next.Sub(now),
+			ResetAfter: b.emptyAt.Add(policy.Period).Sub(now),
+		}
+	}
+	b.emptyAt = next
+	return Result{
+		Allowed:    true,
+		Limit:      policy.Limit,
+		Remaining:  int(math.Floor(float64(now.Sub(b.emptyAt)) / float64(interval))),
+		ResetAfter: b.emptyAt.Add(policy.Period).Sub(now),
+	}
+}
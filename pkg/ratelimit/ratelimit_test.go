@@ -0,0 +1,115 @@
+// File: pkg/ratelimit/ratelimit_test.go
+
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		rate    string
+		want    Policy
+		wantErr bool
+	}{
+		{"10/1m", Policy{Name: "p", Limit: 10, Period: time.Minute}, false},
+		{"3/30s", Policy{Name: "p", Limit: 3, Period: 30 * time.Second}, false},
+		{"10", Policy{}, true},
+		{"0/1m", Policy{}, true},
+		{"x/1m", Policy{}, true},
+		{"10/soon", Policy{}, true},
+		{"10/-1m", Policy{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePolicy("p", tt.rate)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePolicy(%q) error = %v, want error %v", tt.rate, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePolicy(%q) = %+v, want %+v", tt.rate, got, tt.want)
+		}
+	}
+}
+
+// TestMemoryStoreTake drains a bucket of three tokens refilling one every ten
+// seconds, and checks each outcome in turn.
+func TestMemoryStoreTake(t *testing.T) {
+	policy := Policy{Name: "test", Limit: 3, Period: 30 * time.Second}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+
+	tests := []struct {
+		name          string
+		at            time.Duration
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     time.Duration
+	}{
+		{"first request", 0, true, 2, 0},
+		{"burst", 0, true, 1, 0},
+		{"last token", 0, true, 0, 0},
+		{"empty", 0, false, 0, 10 * time.Second},
+		{"half refilled", 5 * time.Second, false, 0, 5 * time.Second},
+		{"one token refilled", 10 * time.Second, true, 0, 0},
+		{"refilled past the limit", 5 * time.Minute, true, 2, 0},
+	}
+	for _, tt := range tests {
+		got, err := store.Take(context.Background(), "client", policy, start.Add(tt.at))
+		if err != nil {
+			t.Fatalf("%s: Take: %v", tt.name, err)
+		}
+		if got.Allowed != tt.wantAllowed || got.Remaining != tt.wantRemaining || got.RetryAfter != tt.wantRetry {
+			t.Errorf("%s: Take = %+v, want allowed %v, remaining %d, retry after %v",
+				tt.name, got, tt.wantAllowed, tt.wantRemaining, tt.wantRetry)
+		}
+		if got.Limit != policy.Limit {
+			t.Errorf("%s: Limit = %d, want %d", tt.name, got.Limit, policy.Limit)
+		}
+	}
+}
+
+// TestMemoryStoreKeys checks that clients and policies get separate buckets.
+func TestMemoryStoreKeys(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	login := Policy{Name: "login", Limit: 1, Period: time.Minute}
+	write := Policy{Name: "write", Limit: 1, Period: time.Minute}
+	store := NewMemoryStore()
+
+	tests := []struct {
+		key         string
+		policy      Policy
+		wantAllowed bool
+	}{
+		{"alice", login, true},
+		{"alice", login, false},
+		{"bob", login, true},
+		{"alice", write, true},
+	}
+	for _, tt := range tests {
+		got, err := store.Take(context.Background(), tt.key, tt.policy, now)
+		if err != nil {
+			t.Fatalf("Take: %v", err)
+		}
+		if got.Allowed != tt.wantAllowed {
+			t.Errorf("Take(%s, %s).Allowed = %v, want %v", tt.key, tt.policy.Name, got.Allowed, tt.wantAllowed)
+		}
+	}
+}
+
+// TestMemoryStoreSweep checks that full buckets are forgotten.
+func TestMemoryStoreSweep(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	policy := Policy{Name: "test", Limit: 1, Period: time.Second}
+	store := NewMemoryStore()
+	store.Take(context.Background(), "client", policy, now)
+	store.Take(context.Background(), "other", policy, now.Add(2*sweepInterval))
+	if _, ok := store.buckets["test:client"]; ok {
+		t.Error("the refilled bucket was not swept")
+	}
+	if len(store.buckets) != 1 {
+		t.Errorf("%d buckets left, want 1", len(store.buckets))
+	}
+}
@@ -0,0 +1,211 @@
+// File: pkg/router/auth.go
+
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"redditclone/internal/api/handlers"
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/service"
+	"redditclone/pkg/logging"
+)
+
+// noScope marks routes API keys and OAuth2 tokens may not call at all:
+// account settings, credentials and grants stay with the signed-in user.
+const noScope = ""
+
+// routeScopes names the scope an API key or OAuth2 token needs for each
+// route, keyed by method and route template. Other GET routes need
+// models.ScopeRead; other routes cannot be called with a key or token.
+var routeScopes = map[string]string{
+	"POST /subreddits":               models.ScopeModerate,
+	"PUT /subreddits/{id}":           models.ScopeModerate,
+	"DELETE /subreddits/{id}":        models.ScopeModerate,
+	"POST /subreddits/{id}/join":     models.ScopeSubmit,
+	"POST /subreddits/{id}/leave":    models.ScopeSubmit,
+	"POST /subreddits/{id}/posts":    models.ScopeSubmit,
+	"PUT /posts/{id}":                models.ScopeSubmit,
+	"DELETE /posts/{id}":             models.ScopeSubmit,
+	"POST /posts/{id}/comments":      models.ScopeSubmit,
+	"POST /comments/{id}/reply":      models.ScopeSubmit,
+	"PUT /comments/{id}":             models.ScopeSubmit,
+	"DELETE /comments/{id}":          models.ScopeSubmit,
+	"POST /posts/{id}/vote":          models.ScopeVote,
+	"PUT /posts/{id}/vote":           models.ScopeVote,
+	"DELETE /posts/{id}/vote":        models.ScopeVote,
+	"POST /comments/{id}/vote":       models.ScopeVote,
+	"PUT /comments/{id}/vote":        models.ScopeVote,
+	"DELETE /comments/{id}/vote":     models.ScopeVote,
+	"POST /messages":                 models.ScopeMessage,
+	"POST /messages/{id}/reply":      models.ScopeMessage,
+	"GET /messages/{id}":             models.ScopeMessage,
+	"PUT /messages/{id}":             models.ScopeMessage,
+	"DELETE /messages/{id}":          models.ScopeMessage,
+	"GET /messages/{id}/replies":     models.ScopeMessage,
+	"GET /users/{id}/messages":       models.ScopeMessage,
+	"GET /users/{id}/logins":         noScope,
+	"GET /users/{id}/2fa":            noScope,
+	"GET /users/{id}/sessions":       noScope,
+	"GET /users/{id}/api-keys":       noScope,
+	"GET /oauth/authorize":           noScope,
+	"GET /users/{id}/oauth/clients":  noScope,
+	"GET /users/{id}/oauth/consents": noScope,
+	"GET /admin/audit-log":           noScope,
+	"GET /admin/votes/report":        noScope,
+}
+
+// requiredScope returns the scope an API key or OAuth2 token needs to call
+// the route of r, or noScope if they may not call it.
+func requiredScope(r *http.Request) string {
+	if scope, ok := routeScopes[r.Method+" "+routeTemplate(r)]; ok {
+		return scope
+	}
+	if r.Method == http.MethodGet {
+		return models.ScopeRead
+	}
+	return noScope
+}
+
+// authenticate resolves the credentials on a request: a session access
+// token, an OAuth2 access token or an API key, sent as "Authorization:
+// Bearer <token>", or an API key sent as "X-API-Key: <key>". The session,
+// token or key goes into the request context, and its user is put in the
+// X-User-ID header, so the handlers, the rate limiter and the request log all
+// see the signed-in user. Any X-User-ID header the client sent is dropped
+// first, so the header only ever names a verified user. Requests with an
+// invalid or expired credential are rejected with 401, and keys and tokens
+// without the route's scope with 403; requests without credentials go on
+// anonymously. The OAuth2 token endpoint is left alone, since clients
+// authenticate to it with HTTP Basic.
+func authenticate(sessions service.SessionService, apiKeys service.APIKeyService, oauth service.OAuthService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Del("X-User-ID")
+			if routeTemplate(r) == "/oauth/token" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			token := r.Header.Get("X-API-Key")
+			if header := r.Header.Get("Authorization"); header != "" {
+				scheme, bearer, _ := strings.Cut(header, " ")
+				if !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(bearer) == "" {
+					w.Header().Set("WWW-Authenticate", `Bearer`)
+					handlers.WriteError(w, r, apperr.Unauthorized("Authorization header must be \"Bearer <access token>\""))
+					return
+				}
+				token = strings.TrimSpace(bearer)
+			}
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := models.ClientInfo{IP: handlers.ClientIP(r), UserAgent: r.UserAgent()}
+			switch {
+			case service.IsAPIKey(token):
+				key, err := apiKeys.Authenticate(r.Context(), token, client)
+				if err != nil {
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					handlers.WriteError(w, r, err)
+					return
+				}
+				if !checkScope(w, r, "API key", key.HasScope) {
+					return
+				}
+				r.Header.Set("X-User-ID", strconv.Itoa(key.UserID))
+				next.ServeHTTP(w, r.WithContext(service.WithAPIKey(r.Context(), key)))
+
+			case service.IsOAuthToken(token):
+				grant, err := oauth.Authenticate(r.Context(), token)
+				if err != nil {
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					handlers.WriteError(w, r, err)
+					return
+				}
+				if !checkScope(w, r, "OAuth token", grant.HasScope) {
+					return
+				}
+				r.Header.Set("X-User-ID", strconv.Itoa(grant.UserID))
+				next.ServeHTTP(w, r.WithContext(service.WithOAuthToken(r.Context(), grant)))
+
+			default:
+				session, err := sessions.Authenticate(r.Context(), token, client)
+				if err != nil {
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					handlers.WriteError(w, r, err)
+					return
+				}
+				r.Header.Set("X-User-ID", strconv.Itoa(session.UserID))
+				next.ServeHTTP(w, r.WithContext(service.WithSession(r.Context(), session)))
+			}
+		})
+	}
+}
+
+// authenticatedUserID returns the ID of the user whose session, API key or
+// OAuth2 token authenticate verified for r, or 0 for anonymous requests.
+func authenticatedUserID(r *http.Request) int {
+	ctx := r.Context()
+	if session := service.SessionFromContext(ctx); session != nil {
+		return session.UserID
+	}
+	if key := service.APIKeyFromContext(ctx); key != nil {
+		return key.UserID
+	}
+	if token := service.OAuthTokenFromContext(ctx); token != nil {
+		return token.UserID
+	}
+	return 0
+}
+
+// checkScope rejects the request with 403 unless the route of r allows the
+// kind of credential named and hasScope grants the scope it needs.
+func checkScope(w http.ResponseWriter, r *http.Request, kind string, hasScope func(string) bool) bool {
+	scope := requiredScope(r)
+	if scope == noScope {
+		handlers.WriteError(w, r, apperr.Forbidden(kind+"s cannot be used for this endpoint; sign in instead"))
+		return false
+	}
+	if !hasScope(scope) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+		handlers.WriteError(w, r, apperr.Forbidden(kind+" lacks the "+scope+" scope"))
+		return false
+	}
+	return true
+}
+
+// auditImpersonation records every request made with an admin's
+// impersonation session in the audit log, with the status it got. It runs
+// after authenticate, which puts the session in the request context.
+//
+// An impersonation session may call what an API key with every scope could,
+// and sign itself out, but not touch the user's account settings,
+// credentials or grants; refused requests are audited too.
+func auditImpersonation(admin service.AdminService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session := service.SessionFromContext(r.Context())
+			if session == nil || session.ImpersonatorID == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			if requiredScope(r) == noScope && routeTemplate(r) != "/logout" {
+				handlers.WriteError(rec, r, apperr.Forbidden("Impersonation sessions cannot be used for this endpoint"))
+			} else {
+				next.ServeHTTP(rec, r)
+			}
+
+			request := fmt.Sprintf("%s %s -> %d", r.Method, r.URL.Path, rec.status)
+			if err := admin.RecordImpersonatedRequest(r.Context(), session, handlers.ClientIP(r), request); err != nil {
+				logging.FromContext(r.Context()).Error("failed to audit impersonated request",
+					"admin_id", *session.ImpersonatorID, "user_id", session.UserID, "request", request, "error", err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+// File: pkg/router/auth_test.go
+
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"redditclone/internal/apperr"
+	"redditclone/internal/models"
+	"redditclone/internal/repository"
+	"redditclone/internal/repository/memory"
+	"redditclone/internal/service"
+)
+
+// TestTokenActsForItsUser checks that an OAuth2 token for one user cannot
+// vote, send messages or read the inbox as another, whatever user IDs the
+// request names.
+func TestTokenActsForItsUser(t *testing.T) {
+	ctx := context.Background()
+	repos := memory.NewRepositories()
+	ann := &models.User{Username: "ann", Email: "ann@example.com", Password: "hash"}
+	bob := &models.User{Username: "bob", Email: "bob@example.com", Password: "hash"}
+	for _, user := range []*models.User{ann, bob} {
+		if err := repos.Users.CreateUser(ctx, user); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+	}
+	sub := &models.Subreddit{Name: "golang", Description: "d", CreatedBy: bob.ID}
+	if err := repos.Subreddits.CreateSubreddit(ctx, sub); err != nil {
+		t.Fatalf("CreateSubreddit: %v", err)
+	}
+	post := &models.Post{Title: "t", Content: "c", AuthorID: bob.ID, SubredditID: sub.ID}
+	if err := repos.Posts.CreatePost(ctx, post); err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+
+	// An app ann has authorized to vote and message for her.
+	oauth := service.NewOAuthService(repos.OAuth, service.OAuthConfig{AccessTTL: time.Hour, RefreshTTL: 24 * time.Hour})
+	const redirectURI = "https://app.example/callback"
+	scopes := []string{models.ScopeRead, models.ScopeVote, models.ScopeMessage}
+	client, err := oauth.RegisterClient(ctx, ann.ID, "app", []string{redirectURI}, scopes, false)
+	if err != nil {
+		t.Fatalf("RegisterClient: %v", err)
+	}
+	const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk" // RFC 7636 appendix B.
+	redirect, err := oauth.Authorize(ctx, ann.ID, &models.AuthorizationRequest{
+		ResponseType:        "code",
+		ClientID:            client.ID,
+		RedirectURI:         redirectURI,
+		CodeChallenge:       "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		CodeChallengeMethod: "S256",
+	}, true)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	u, err := url.Parse(redirect)
+	if err != nil {
+		t.Fatalf("Authorize redirected to %q: %v", redirect, err)
+	}
+	tokens, err := oauth.Token(ctx, &models.TokenRequest{
+		GrantType:    "authorization_code",
+		ClientID:     client.ID,
+		Code:         u.Query().Get("code"),
+		RedirectURI:  redirectURI,
+		CodeVerifier: verifier,
+	})
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+
+	sessions := service.NewSessionService(repos.Sessions, service.SessionConfig{AccessTTL: time.Hour, RefreshTTL: 24 * time.Hour})
+	integrity := service.NewVoteIntegrityService(repos.VoteIntegrity, repos.Users, service.DefaultVoteIntegrityConfig())
+	votes := service.NewVoteService(repos.Votes, repos.Posts, repos.Comments, repos.Users, integrity)
+	messages := service.NewMessageService(repos.Messages, repos.Users, repos.Blocks)
+	router := NewRouter(nil, nil, nil, nil, votes, messages, nil, nil, nil, nil, sessions, service.NewAPIKeyService(repos.APIKeys), oauth, nil, nil, Config{DefaultPageLimit: 10, MaxPageLimit: 100})
+
+	send := func(method, path, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+		req.Header.Set("X-User-ID", strconv.Itoa(bob.ID))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	body := fmt.Sprintf(`{"post_id": %d, "user_id": %d, "vote_type": "upvote"}`, post.ID, bob.ID)
+	if code := send("POST", fmt.Sprintf("/posts/%d/vote", post.ID), body); code != http.StatusOK {
+		t.Fatalf("POST vote status = %d, want %d", code, http.StatusOK)
+	}
+	if _, err := repos.Votes.GetVoteByUserAndPost(ctx, bob.ID, post.ID); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("vote by bob: error = %v, want not found", err)
+	}
+	if _, err := repos.Votes.GetVoteByUserAndPost(ctx, ann.ID, post.ID); err != nil {
+		t.Errorf("vote by ann: %v", err)
+	}
+
+	body = fmt.Sprintf(`{"sender_id": %d, "receiver_id": %d, "content": "hi"}`, bob.ID, bob.ID)
+	if code := send("POST", "/messages", body); code != http.StatusCreated {
+		t.Fatalf("POST message status = %d, want %d", code, http.StatusCreated)
+	}
+	inbox, err := repos.Messages.GetMessagesForUser(ctx, bob.ID, repository.Page{Limit: 10})
+	if err != nil || len(inbox) != 1 || inbox[0].SenderID != ann.ID {
+		t.Errorf("bob's inbox = %v, %v; want one message from ann", inbox, err)
+	}
+
+	if code := send("GET", fmt.Sprintf("/users/%d/messages", bob.ID), ""); code != http.StatusForbidden {
+		t.Errorf("GET bob's messages status = %d, want %d", code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,52 @@
+// File: pkg/router/client.go
+
+package router
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"redditclone/internal/api/handlers"
+)
+
+// identifyClient stores the client IP in the request context for the rate
+// limiter and the login audit trail. Behind trustedProxies reverse proxies,
+// each appending the address it was connected from to X-Forwarded-For, the
+// client is the trustedProxies-th address from the right; anything to the
+// left of it came from the client, which can write whatever it likes there.
+// Without trusted proxies the header is ignored and the connection's remote
+// address is used.
+func identifyClient(trustedProxies int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if trustedProxies <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := forwardedClient(r.Header.Values("X-Forwarded-For"), trustedProxies); ip != "" {
+				r = r.WithContext(handlers.WithClientIP(r.Context(), ip))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedClient returns the address the outermost of trustedProxies proxies
+// added to the X-Forwarded-For headers, or "" if the headers hold fewer
+// addresses than that or it is not an IP.
+func forwardedClient(headers []string, trustedProxies int) string {
+	var hops []string
+	for _, header := range headers {
+		for _, hop := range strings.Split(header, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	if len(hops) < trustedProxies {
+		return ""
+	}
+	ip := net.ParseIP(hops[len(hops)-trustedProxies])
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
@@ -0,0 +1,114 @@
+// File: pkg/router/logging.go
+
+package router
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"redditclone/pkg/logging"
+	"redditclone/pkg/metrics"
+
+	"github.com/gorilla/mux"
+)
+
+// requestIDHeader carries the request ID in both directions.
+const requestIDHeader = "X-Request-ID"
+
+// logRequests assigns each request an ID, stores it in the request context and
+// the X-Request-ID response header, and logs one structured line per request
+// once it completes, recording it in the HTTP request metrics too. A
+// well-formed X-Request-ID from the client is reused so IDs can be followed
+// across services.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		ctx := logging.WithRequestID(r.Context(), id)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		route := routeTemplate(r)
+		elapsed := time.Since(start)
+		metrics.ObserveRequest(r.Method, route, rec.status, elapsed)
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("route", route),
+			slog.Int("status", rec.status),
+			slog.Float64("latency_ms", float64(elapsed.Microseconds())/1000),
+		}
+		if userID, err := strconv.Atoi(r.Header.Get("X-User-ID")); err == nil {
+			attrs = append(attrs, slog.Int("user_id", userID))
+		}
+		logging.FromContext(ctx).LogAttrs(ctx, level, "request", attrs...)
+	})
+}
+
+// routeTemplate returns the template of the route r matched, such as
+// /posts/{id}, or "unmatched".
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if tmpl, err := current.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+	return "unmatched"
+}
+
+// statusRecorder remembers the status code written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	return rec.ResponseWriter.Write(b)
+}
+
+// validRequestID accepts IDs of up to 128 letters, digits, '-', '_' and '.',
+// so a client cannot inject arbitrary text into the logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random 128-bit ID in hex.
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
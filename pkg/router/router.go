@@ -8,8 +8,18 @@ import (
 	"github.com/gorilla/mux"
 	"redditclone/internal/api/handlers"
 	"redditclone/internal/service"
+	"redditclone/pkg/metrics"
 )
 
+// Config holds the router settings that do not come from services.
+type Config struct {
+	CursorSecret     string     // Key signing pagination cursors; empty picks a random key.
+	DefaultPageLimit int        // Page size when a request gives no limit.
+	MaxPageLimit     int        // Largest page size a request may ask for.
+	TrustedProxies   int        // Reverse proxies in front of the server that append to X-Forwarded-For; 0 ignores the header.
+	RateLimits       RateLimits // Per-route request rates; a nil store disables limiting.
+}
+
 // NewRouter initializes the HTTP router with all routes and handlers.
 func NewRouter(
 	userService service.UserService,
@@ -18,16 +28,37 @@ func NewRouter(
 	commentService service.CommentService,
 	voteService service.VoteService,
 	messageService service.MessageService,
+	activityService service.ActivityService,
+	healthService service.HealthService,
+	accountService service.AccountService,
+	twoFactorService service.TwoFactorService,
+	sessionService service.SessionService,
+	apiKeyService service.APIKeyService,
+	oauthService service.OAuthService,
+	adminService service.AdminService,
+	accountDeletionService service.AccountDeletionService,
+	cfg Config,
 ) http.Handler {
 	r := mux.NewRouter()
+	handlers.SetPageLimits(cfg.DefaultPageLimit, cfg.MaxPageLimit)
 
 	// Initialize handlers with the services
+	cursors := handlers.NewCursorCodec(cfg.CursorSecret)
 	userHandler := handlers.NewUserHandler(userService)
 	subredditHandler := handlers.NewSubredditHandler(subredditService)
-	postHandler := handlers.NewPostHandler(postService)
-	commentHandler := handlers.NewCommentHandler(commentService)
+	postHandler := handlers.NewPostHandler(postService, cursors)
+	commentHandler := handlers.NewCommentHandler(commentService, cursors)
 	voteHandler := handlers.NewVoteHandler(voteService)
-	messageHandler := handlers.NewMessageHandler(messageService)
+	messageHandler := handlers.NewMessageHandler(messageService, cursors)
+	activityHandler := handlers.NewActivityHandler(activityService)
+	healthHandler := handlers.NewHealthHandler(healthService)
+	accountHandler := handlers.NewAccountHandler(accountService)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService)
+	sessionHandler := handlers.NewSessionHandler(sessionService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	oauthHandler := handlers.NewOAuthHandler(oauthService)
+	adminHandler := handlers.NewAdminHandler(adminService)
+	accountDeletionHandler := handlers.NewAccountDeletionHandler(accountDeletionService)
 
 	// Define API routes and associate them with handlers.
 
@@ -36,7 +67,50 @@ func NewRouter(
 	r.HandleFunc("/login", userHandler.Login).Methods("POST")
 	r.HandleFunc("/users/{id}", userHandler.GetProfile).Methods("GET")
 	r.HandleFunc("/users/{id}", userHandler.UpdateProfile).Methods("PUT")
-	r.HandleFunc("/users/{id}", userHandler.DeleteUser).Methods("DELETE")
+	r.HandleFunc("/users/{id}", accountDeletionHandler.RequestDeletion).Methods("DELETE")
+	r.HandleFunc("/users/{id}/deletion", accountDeletionHandler.CancelDeletion).Methods("DELETE")
+	r.HandleFunc("/users/{id}/blocks", userHandler.BlockUser).Methods("POST")
+	r.HandleFunc("/users/{id}/blocks", userHandler.GetBlockedUsers).Methods("GET")
+	r.HandleFunc("/users/{id}/blocks/{blockedID}", userHandler.UnblockUser).Methods("DELETE")
+	r.HandleFunc("/users/{id}/logins", userHandler.GetLoginHistory).Methods("GET")
+
+	// Email verification and password reset routes
+	r.HandleFunc("/users/{id}/verify-email", accountHandler.SendVerificationEmail).Methods("POST")
+	r.HandleFunc("/verify-email", accountHandler.VerifyEmail).Methods("POST")
+	r.HandleFunc("/password/forgot", accountHandler.ForgotPassword).Methods("POST")
+	r.HandleFunc("/password/reset", accountHandler.ResetPassword).Methods("POST")
+
+	// Two-factor authentication routes
+	r.HandleFunc("/login/2fa", userHandler.LoginTwoFactor).Methods("POST")
+	r.HandleFunc("/users/{id}/2fa", twoFactorHandler.GetStatus).Methods("GET")
+	r.HandleFunc("/users/{id}/2fa", twoFactorHandler.BeginEnrollment).Methods("POST")
+	r.HandleFunc("/users/{id}/2fa/confirm", twoFactorHandler.ConfirmEnrollment).Methods("POST")
+	r.HandleFunc("/users/{id}/2fa/disable", twoFactorHandler.Disable).Methods("POST")
+	r.HandleFunc("/users/{id}/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes).Methods("POST")
+
+	// Session routes
+	r.HandleFunc("/sessions/refresh", sessionHandler.Refresh).Methods("POST")
+	r.HandleFunc("/logout", sessionHandler.Logout).Methods("POST")
+	r.HandleFunc("/users/{id}/sessions", sessionHandler.ListSessions).Methods("GET")
+	r.HandleFunc("/users/{id}/sessions", sessionHandler.RevokeOtherSessions).Methods("DELETE")
+	r.HandleFunc("/users/{id}/sessions/{sessionID}", sessionHandler.RevokeSession).Methods("DELETE")
+
+	// API key and bot routes
+	r.HandleFunc("/users/{id}/api-keys", apiKeyHandler.CreateAPIKey).Methods("POST")
+	r.HandleFunc("/users/{id}/api-keys", apiKeyHandler.ListAPIKeys).Methods("GET")
+	r.HandleFunc("/users/{id}/api-keys/{keyID}", apiKeyHandler.RevokeAPIKey).Methods("DELETE")
+	r.HandleFunc("/users/{id}/bot", userHandler.SetBot).Methods("PUT")
+
+	// OAuth2 routes
+	r.HandleFunc("/oauth/clients", oauthHandler.RegisterClient).Methods("POST")
+	r.HandleFunc("/oauth/clients/{clientID}", oauthHandler.GetClient).Methods("GET")
+	r.HandleFunc("/oauth/authorize", oauthHandler.PrepareAuthorization).Methods("GET")
+	r.HandleFunc("/oauth/authorize", oauthHandler.Authorize).Methods("POST")
+	r.HandleFunc("/oauth/token", oauthHandler.Token).Methods("POST")
+	r.HandleFunc("/users/{id}/oauth/clients", oauthHandler.ListClients).Methods("GET")
+	r.HandleFunc("/users/{id}/oauth/clients/{clientID}", oauthHandler.DeleteClient).Methods("DELETE")
+	r.HandleFunc("/users/{id}/oauth/consents", oauthHandler.ListConsents).Methods("GET")
+	r.HandleFunc("/users/{id}/oauth/consents/{clientID}", oauthHandler.RevokeConsent).Methods("DELETE")
 
 	// Subreddit routes
 	r.HandleFunc("/subreddits", subredditHandler.CreateSubreddit).Methods("POST")
@@ -48,6 +122,7 @@ func NewRouter(
 
 	// Post routes
 	r.HandleFunc("/subreddits/{id}/posts", postHandler.CreatePost).Methods("POST")
+	r.HandleFunc("/subreddits/{id}/posts", postHandler.GetSubredditPosts).Methods("GET")
 	r.HandleFunc("/posts/{id}", postHandler.GetPost).Methods("GET")
 	r.HandleFunc("/posts/{id}", postHandler.UpdatePost).Methods("PUT")
 	r.HandleFunc("/posts/{id}", postHandler.DeletePost).Methods("DELETE")
@@ -66,9 +141,11 @@ func NewRouter(
 	r.HandleFunc("/posts/{id}/vote", voteHandler.CastVote).Methods("POST")
 	r.HandleFunc("/posts/{id}/vote", voteHandler.ChangeVote).Methods("PUT")
 	r.HandleFunc("/posts/{id}/vote", voteHandler.RemoveVote).Methods("DELETE")
+	r.HandleFunc("/posts/{id}/vote", voteHandler.GetPostVote).Methods("GET")
 	r.HandleFunc("/comments/{id}/vote", voteHandler.CastVote).Methods("POST")
 	r.HandleFunc("/comments/{id}/vote", voteHandler.ChangeVote).Methods("PUT")
 	r.HandleFunc("/comments/{id}/vote", voteHandler.RemoveVote).Methods("DELETE")
+	r.HandleFunc("/comments/{id}/vote", voteHandler.GetCommentVote).Methods("GET")
 
 	// Message routes
 	r.HandleFunc("/messages", messageHandler.SendMessage).Methods("POST")
@@ -79,11 +156,45 @@ func NewRouter(
 	r.HandleFunc("/users/{id}/messages", messageHandler.GetMessagesForUser).Methods("GET")
 	r.HandleFunc("/messages/{id}/replies", messageHandler.GetReplies).Methods("GET")
 
+	// User activity routes
+	r.HandleFunc("/users/{id}/posts", activityHandler.GetUserPosts).Methods("GET")
+	r.HandleFunc("/users/{id}/comments", activityHandler.GetUserComments).Methods("GET")
+	r.HandleFunc("/users/{id}/overview", activityHandler.GetUserOverview).Methods("GET")
+	r.HandleFunc("/users/{id}/upvoted", activityHandler.GetUpvoted).Methods("GET")
+	r.HandleFunc("/users/{id}/downvoted", activityHandler.GetDownvoted).Methods("GET")
+
+	// Admin routes
+	r.HandleFunc("/admin/votes/report", adminHandler.GetVoteReport).Methods("GET")
+	r.HandleFunc("/admin/users/{id}/role", adminHandler.SetRole).Methods("PUT")
+	r.HandleFunc("/admin/users/{id}/suspension", adminHandler.SuspendUser).Methods("PUT")
+	r.HandleFunc("/admin/users/{id}/suspension", adminHandler.UnsuspendUser).Methods("DELETE")
+	r.HandleFunc("/admin/users/{id}/impersonate", adminHandler.Impersonate).Methods("POST")
+	r.HandleFunc("/admin/subreddits/{id}", adminHandler.DeleteSubreddit).Methods("DELETE")
+	r.HandleFunc("/admin/subreddits/{id}/quarantine", adminHandler.QuarantineSubreddit).Methods("PUT")
+	r.HandleFunc("/admin/subreddits/{id}/quarantine", adminHandler.UnquarantineSubreddit).Methods("DELETE")
+	r.HandleFunc("/admin/subreddits/{id}/owner", adminHandler.TransferSubreddit).Methods("PUT")
+	r.HandleFunc("/admin/audit-log", adminHandler.GetAuditLog).Methods("GET")
+
+	// Health and readiness probes
+	r.HandleFunc("/healthz", healthHandler.Healthz).Methods("GET")
+	r.HandleFunc("/readyz", healthHandler.Readyz).Methods("GET")
+
+	// Prometheus metrics
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
+
 	// Add more routes as needed
-	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	r.NotFoundHandler = logRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		http.Error(w, `{"error":"Endpoint Not Found"}`, http.StatusNotFound)
-	})
-	
+	}))
+	r.MethodNotAllowedHandler = logRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+
+	// Trace every matched request, then log it with its request and trace IDs,
+	// then resolve the client IP, authenticate, audit impersonated requests
+	// and apply the rate limit
+	r.Use(traceRequests, logRequests, identifyClient(cfg.TrustedProxies), authenticate(sessionService, apiKeyService, oauthService), auditImpersonation(adminService), rateLimit(cfg.RateLimits))
+
 	return r
 }
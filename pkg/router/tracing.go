@@ -0,0 +1,39 @@
+// File: pkg/router/tracing.go
+
+package router
+
+import (
+	"net/http"
+
+	"redditclone/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// traceRequests records a server span for each matched request, named after
+// its method and route template, and continues any trace the client passed in
+// a traceparent header. Service and repository spans hang off it through the
+// request context.
+func traceRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+
+		ctx, span := tracing.StartServer(r.Context(), r.Method+" "+route,
+			propagation.HeaderCarrier(r.Header),
+			attribute.String("http.method", r.Method),
+			attribute.String("http.route", route),
+			attribute.String("http.target", r.URL.RequestURI()),
+		)
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.status_code", rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+	})
+}
@@ -0,0 +1,90 @@
+// File: pkg/secretbox/secretbox.go
+
+// Package secretbox encrypts small secrets, such as TOTP keys, before they
+// are stored, using AES-256-GCM under a key from the configuration.
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the length of a key in bytes.
+const KeySize = 32
+
+// Box encrypts and decrypts values with one key.
+type Box struct {
+	aead cipher.AEAD
+	key  []byte
+}
+
+// ParseKey decodes a base64 key of KeySize bytes, as generated by
+// `openssl rand -base64 32`.
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("key is not valid base64: %v", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+// New creates a Box for a key of KeySize bytes.
+func New(key []byte) (*Box, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead, key: key}, nil
+}
+
+// Seal encrypts plaintext under a random nonce and returns it base64
+// encoded, nonce first. additionalData, such as the ID of the row the value
+// belongs to, must be passed to Open unchanged, so a value copied to another
+// row does not decrypt.
+func (b *Box) Seal(plaintext, additionalData string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), []byte(additionalData))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value returned by Seal.
+func (b *Box) Open(sealed, additionalData string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return "", errors.New("secretbox: malformed value")
+	}
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, []byte(additionalData))
+	if err != nil {
+		return "", errors.New("secretbox: value does not decrypt with this key")
+	}
+	return string(plaintext), nil
+}
+
+// DeriveKey returns a key for another purpose, such as signing, derived from
+// the box's key, so one configured secret can serve several uses without
+// the uses sharing a key.
+func (b *Box) DeriveKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, b.key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
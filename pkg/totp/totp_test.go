@@ -0,0 +1,92 @@
+// File: pkg/totp/totp_test.go
+
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors, "12345678901234567890", base32 encoded.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// TestCodeRFC6238 checks the SHA-1 vectors of RFC 6238 appendix B. The RFC
+// lists eight-digit codes; six-digit codes are their last six digits.
+func TestCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code at %d: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeLowercaseSecret(t *testing.T) {
+	upper, _ := Code(rfcSecret, 1)
+	lower, err := Code("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", 1)
+	if err != nil || lower != upper {
+		t.Errorf("Code with a lowercase secret = %s, %v; want %s", lower, err, upper)
+	}
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code accepted an invalid secret")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+	code := func(step int64) string {
+		c, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		skew     int
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", code(step), 0, step, true},
+		{"surrounding spaces", " " + code(step) + " ", 0, step, true},
+		{"previous step within skew", code(step - 1), 1, step - 1, true},
+		{"next step within skew", code(step + 1), 1, step + 1, true},
+		{"previous step without skew", code(step - 1), 0, 0, false},
+		{"two steps old", code(step - 2), 1, 0, false},
+		{"wrong code", "000000", 1, 0, false},
+		{"too short", code(step)[:5], 1, 0, false},
+	}
+	for _, tt := range tests {
+		gotStep, ok := Validate(rfcSecret, tt.code, now, tt.skew)
+		if ok != tt.wantOK || gotStep != tt.wantStep {
+			t.Errorf("%s: Validate = %d, %v; want %d, %v", tt.name, gotStep, ok, tt.wantStep, tt.wantOK)
+		}
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	if key, err := encoding.DecodeString(secret); err != nil || len(key) != secretSize {
+		t.Errorf("GenerateSecret = %q, which decodes to %d bytes (%v), want %d", secret, len(key), err, secretSize)
+	}
+}
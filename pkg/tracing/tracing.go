@@ -0,0 +1,118 @@
+// File: pkg/tracing/tracing.go
+
+// Package tracing sets up OpenTelemetry tracing and starts the spans recorded
+// by the router, services and repositories. Spans travel in the context, so a
+// repository query shows up under the service call and HTTP request that made it.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// serviceName names this server in exported traces unless OTEL_SERVICE_NAME
+// overrides it.
+const serviceName = "redditclone"
+
+// Exporters accepted by Setup.
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// tracer starts every span. It is taken from the global provider, so spans are
+// no-ops until Setup installs a real one.
+var tracer = otel.Tracer("redditclone")
+
+// Setup installs the global tracer provider and W3C trace-context propagation.
+// exporterName picks where spans go: ExporterOTLP sends them over OTLP/HTTP to
+// OTEL_EXPORTER_OTLP_ENDPOINT (default http://localhost:4318), ExporterStdout
+// prints them for local runs, and ExporterNone records nothing. The returned
+// function flushes pending spans and must be called before exiting.
+func Setup(ctx context.Context, exporterName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch exporterName {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporterName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating trace exporter: %v", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL, semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("creating trace resource: %v", err)
+	}
+	// Let OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES win over the
+	// built-in service name.
+	if env, err := resource.New(ctx, resource.WithFromEnv()); err == nil {
+		if merged, err := resource.Merge(res, env); err == nil {
+			res = merged
+		}
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span named name as a child of any span in ctx. End the
+// returned span when the traced operation finishes.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartServer starts the span for an incoming request, continuing any trace
+// the caller propagated in carrier.
+func StartServer(ctx context.Context, name string, carrier propagation.TextMapCarrier, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+}
+
+// Rows returns the attribute recording how many rows a query returned.
+func Rows(n int) attribute.KeyValue {
+	return attribute.Int("db.rows", n)
+}
+
+// Fail marks span as failed with err.
+func Fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// TraceID returns the ID of the trace carried by ctx, or "".
+func TraceID(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
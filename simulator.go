@@ -216,7 +216,7 @@ func (u *UserActor) joinSubreddit() error {
 	}
 
 	payload := map[string]interface{}{
-		"user_id":  u.userID,
+		"user_id": u.userID,
 	}
 
 	// If the server identifies user from X-User-ID, no payload needed:
@@ -230,7 +230,7 @@ func (u *UserActor) leaveSubreddit() error {
 	}
 
 	payload := map[string]interface{}{
-		"user_id":  u.userID,
+		"user_id": u.userID,
 	}
 
 	_, err := u.makeRequest("POST", fmt.Sprintf("/subreddits/%d/leave", u.subredditID), payload, u.userID)
@@ -260,10 +260,10 @@ func (u *UserActor) createComment(replyToComment bool) error {
 	}
 
 	payload := map[string]interface{}{
-		"content":    "This is a comment",
-		"post_id":    postID,
-		"author_id":  u.userID,      // must include author_id
-		"parent_id":  parentID,      // for top-level comment, this can be nil
+		"content":   "This is a comment",
+		"post_id":   postID,
+		"author_id": u.userID, // must include author_id
+		"parent_id": parentID, // for top-level comment, this can be nil
 	}
 	_, err := u.makeRequest("POST", fmt.Sprintf("/posts/%d/comments", postID), payload, u.userID)
 	return err